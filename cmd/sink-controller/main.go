@@ -16,28 +16,83 @@ limitations under the License.
 package main
 
 import (
+	_ "expvar"
 	"flag"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
 	"github.com/knative/observability/pkg/client/clientset/versioned"
 	informers "github.com/knative/observability/pkg/client/informers/externalversions"
+	"github.com/knative/observability/pkg/leader"
+	"github.com/knative/observability/pkg/metric"
 	"github.com/knative/observability/pkg/sink"
 	"github.com/knative/pkg/signals"
+	coreV1Types "k8s.io/api/core/v1"
+	extensionsV1beta1Types "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	coordinationV1beta1 "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
 	coreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	extensionsV1beta1 "k8s.io/client-go/kubernetes/typed/extensions/v1beta1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 type config struct {
-	Namespace string `env:"NAMESPACE,required,report"`
+	Namespace   string `env:"NAMESPACE,required,report"`
+	MetricsPort string `env:"METRICS_PORT,report"`
 }
 
+var (
+	workers = flag.Int("workers", 1, "number of workers writing the fluent-bit config, 0 writes it from the informers")
+
+	maxRetryBackoff = flag.Duration("max-retry-backoff", 5*time.Minute, "longest delay before retrying a write of the fluent-bit config that failed transiently, 0 waits for the next change")
+
+	fluentBitBufferMaxSize = flag.Int("fluent-bit-buffer-max-size", 1<<20, "Buffer_Max_Size in bytes of the tail input of fluent-bit, sinks with a larger max_message_bytes are logged, 0 disables the warning")
+
+	allowedNamespaces = flag.String("allowed-namespaces", "", "comma separated namespaces whose LogSinks are reconciled, empty allows every namespace")
+	deniedNamespaces  = flag.String("denied-namespaces", "", "comma separated namespaces whose LogSinks are ignored even when allowed")
+
+	debugAddr = flag.String("debug-addr", "", "address serving the config last written at /config and the managed sinks at /sinks, empty disables it, the config holds the sinks' credentials")
+
+	fluentBitBinary  = flag.String("fluent-bit-binary", "", "fluent-bit binary validating the config with --dry-run before it is written, empty writes it unvalidated")
+	fluentBitParsers = flag.String("fluent-bit-parsers", "", "parsers.conf of the fluent-bit ConfigMap the config is validated with, required with --fluent-bit-binary")
+	fluentBitPlugins = flag.String("fluent-bit-plugins", "", "comma separated output plugins the fluent-bit binary loads when validating the config, such as out_syslog.so")
+
+	fluentBitImage = flag.String("fluent-bit-image", sink.DefaultFluentBitImage, "image of the fluent-bit container of the DaemonSet")
+
+	fluentBitBaseMemory = flag.Int("fluent-bit-base-memory", 100, "memory in MiB of the fluent-bit container before the buffers of the sinks are added to it, 0 leaves its resources alone")
+
+	backpressureThreshold = flag.Uint64("backpressure-threshold-bytes", 8<<20, "bytes the stream of a sink may buffer before it is Backpressured, 0 only marks streams that reached their memory limit")
+
+	enableLeaderElection    = flag.Bool("enable-leader-election", false, "only reconcile while holding the lease so replicas do not write the config concurrently")
+	leaderElectionNamespace = flag.String("leader-election-namespace", "", "namespace of the leader election lease, defaults to NAMESPACE")
+	leaderElectionName      = flag.String("leader-election-name", "sink-controller", "name of the leader election lease")
+
+	socketDirs = flag.String("socket-dirs", "", "comma separated directories of the nodes the socket_path of ClusterLogSinks may be in, they must not overlap the directories mounted into fluent-bit, empty allows no socket")
+
+	clusterName = flag.String("cluster-name", "", "name of the cluster set in the cluster field of every record the sinks forward, empty sets none")
+
+	shutdownGracePeriod = flag.Duration("shutdown-grace-period", 25*time.Second, "longest wait after SIGTERM for the writes of the fluent-bit config in flight to finish, should be below the terminationGracePeriodSeconds of the pod")
+)
+
+// leaseDuration is how long a standby waits for the leader to renew the
+// lease before it takes over.
+const leaseDuration = 15 * time.Second
+
 func main() {
 	flag.Parse()
 	stopCh := signals.SetupSignalHandler()
 
-	var conf config
+	conf := config{
+		MetricsPort: "6060",
+	}
 	err := envstruct.Load(&conf)
 	if err != nil {
 		log.Fatal(err.Error())
@@ -46,6 +101,54 @@ func main() {
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	if *workers < 0 {
+		log.Fatalf("--workers must not be negative, got %d", *workers)
+	}
+	if *maxRetryBackoff < 0 {
+		log.Fatalf("--max-retry-backoff must not be negative, got %s", *maxRetryBackoff)
+	}
+	if *fluentBitBufferMaxSize < 0 {
+		log.Fatalf("--fluent-bit-buffer-max-size must not be negative, got %d", *fluentBitBufferMaxSize)
+	}
+	if *shutdownGracePeriod < 0 {
+		log.Fatalf("--shutdown-grace-period must not be negative, got %s", *shutdownGracePeriod)
+	}
+	if *fluentBitBaseMemory < 0 {
+		log.Fatalf("--fluent-bit-base-memory must not be negative, got %d", *fluentBitBaseMemory)
+	}
+	if err := sink.ValidateSocketDirs(commaSeparated(*socketDirs)); err != nil {
+		log.Fatalf("--socket-dirs: %s", err)
+	}
+	if *fluentBitBinary != "" && *fluentBitParsers == "" {
+		log.Fatal("--fluent-bit-parsers is required with --fluent-bit-binary")
+	}
+	if err := sink.ValidateImage(*fluentBitImage); err != nil {
+		log.Fatalf("--fluent-bit-image: %s", err)
+	}
+	if *clusterName != "" {
+		if err := sink.ValidateClusterName(*clusterName); err != nil {
+			log.Fatalf("--cluster-name: %s", err)
+		}
+	}
+
+	metricsHandler, err := sink.NewMetricsHandler()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	http.Handle("/metrics", metricsHandler)
+
+	sinkConfig := sink.NewConfig()
+	sinkConfig.SetClusterName(*clusterName)
+	sinkConfig.SetSocketDirs(commaSeparated(*socketDirs))
+	probe := sink.NewProbe(sinkConfig)
+	http.Handle("/healthz", probe.Handler())
+	http.Handle("/readyz", probe.Handler())
+	// The probes are served along with the metrics, the controller is not
+	// healthy without them.
+	go func() {
+		err := http.ListenAndServe(net.JoinHostPort("", conf.MetricsPort), http.DefaultServeMux)
+		log.Fatalf("metrics server stopped: %s", err)
+	}()
 
 	cfg, err := rest.InClusterConfig()
 	if err != nil {
@@ -62,18 +165,111 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
-	sinkConfig := sink.NewConfig()
+	extensionsV1beta1Client, err := extensionsV1beta1.NewForConfig(cfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	recorder := sink.NewEventRecorder(coreV1Client)
+
+	reloader := sink.NewFluentBitReloader(
+		coreV1Client.Pods(conf.Namespace),
+		extensionsV1beta1Client.DaemonSets(conf.Namespace),
+		2020,
+	)
+
+	sinkOptions := []sink.Option{
+		sink.WithWorkers(*workers),
+		sink.WithEventRecorder(recorder),
+		sink.WithTLSSecret(coreV1Client.Secrets(conf.Namespace)),
+		sink.WithBufferMaxSize(*fluentBitBufferMaxSize),
+		sink.WithNamespaces(commaSeparated(*allowedNamespaces), commaSeparated(*deniedNamespaces)),
+		sink.WithSocketMounts(extensionsV1beta1Client.DaemonSets(conf.Namespace)),
+		sink.WithImage(extensionsV1beta1Client.DaemonSets(conf.Namespace), *fluentBitImage),
+	}
+	if *maxRetryBackoff > 0 {
+		sinkOptions = append(sinkOptions, sink.WithRetryBackoff(*maxRetryBackoff))
+	}
+	if *fluentBitBinary != "" {
+		sinkOptions = append(sinkOptions, sink.WithValidator(
+			sink.NewDryRunValidator(*fluentBitBinary, *fluentBitParsers, commaSeparated(*fluentBitPlugins)...),
+		))
+	}
+	if *fluentBitBaseMemory > 0 {
+		sinkOptions = append(sinkOptions, sink.WithResources(
+			extensionsV1beta1Client.DaemonSets(conf.Namespace),
+			*fluentBitBaseMemory,
+		))
+	}
 
 	controller := sink.NewController(
 		coreV1Client.ConfigMaps(conf.Namespace),
-		coreV1Client.Pods(conf.Namespace),
+		reloader,
 		sinkConfig,
+		sinkOptions...,
 	)
 
 	clusterController := sink.NewClusterController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		reloader,
+		sinkConfig,
+		sinkOptions...,
+	)
+
+	secretController := sink.NewSecretController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		reloader,
+		sinkConfig,
+		sinkOptions...,
+	)
+
+	parserController := sink.NewParserController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		reloader,
+		sinkConfig,
+		sinkOptions...,
+	)
+
+	nodeController := sink.NewNodeController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		reloader,
+		sinkConfig,
+		sinkOptions...,
+	)
+
+	if *debugAddr != "" {
+		go func() {
+			err := http.ListenAndServe(*debugAddr, sink.NewDebugHandler(sinkConfig))
+			log.Printf("debug server stopped: %s", err)
+		}()
+	}
+
+	driftController := sink.NewDriftController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		reloader,
+		sinkConfig,
+		sinkOptions...,
+	)
+
+	metricConfig := metric.NewConfig()
+
+	metricController := metric.NewController(
 		coreV1Client.ConfigMaps(conf.Namespace),
 		coreV1Client.Pods(conf.Namespace),
+		metricConfig,
+	)
+
+	clusterMetricController := metric.NewClusterController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		metricConfig,
+	)
+
+	reporter := sink.NewHealthReporter(
 		sinkConfig,
+		sink.NewFluentBitMetrics(coreV1Client.Pods(conf.Namespace), 2020),
+		client.ObservabilityV1alpha1(),
+		sink.WithBackpressureThreshold(*backpressureThreshold),
 	)
 
 	sinkInformerFactory := informers.NewSharedInformerFactory(client, time.Second*30)
@@ -84,6 +280,172 @@ func main() {
 	clusterSinkInformer := sinkInformerFactory.Observability().V1alpha1().ClusterLogSinks().Informer()
 	clusterSinkInformer.AddEventHandler(clusterController)
 
-	go sinkInformer.Run(stopCh)
-	clusterSinkInformer.Run(stopCh)
+	parserInformer := sinkInformerFactory.Observability().V1alpha1().ClusterLogParsers().Informer()
+	parserInformer.AddEventHandler(parserController)
+
+	secretInformer := cache.NewSharedInformer(
+		cache.NewListWatchFromClient(
+			coreV1Client.RESTClient(),
+			"secrets",
+			metav1.NamespaceAll,
+			fields.Everything(),
+		),
+		&coreV1Types.Secret{},
+		time.Second*30,
+	)
+	secretInformer.AddEventHandler(secretController)
+
+	nodeInformer := cache.NewSharedInformer(
+		cache.NewListWatchFromClient(
+			coreV1Client.RESTClient(),
+			"nodes",
+			metav1.NamespaceAll,
+			fields.Everything(),
+		),
+		&coreV1Types.Node{},
+		time.Second*30,
+	)
+	nodeInformer.AddEventHandler(nodeController)
+	nodeInformer.AddEventHandler(sink.NewQuiesceController(
+		coreV1Client.Nodes(),
+		coreV1Client.Pods(conf.Namespace),
+	))
+
+	// Only the fluent-bit ConfigMap and DaemonSet carry the managed-by
+	// label, the metric-agent ConfigMap is not repaired.
+	managed := func(o *metav1.ListOptions) {
+		o.LabelSelector = sink.ManagedSelector
+	}
+	configMapInformer := cache.NewSharedInformer(
+		cache.NewFilteredListWatchFromClient(
+			coreV1Client.RESTClient(),
+			"configmaps",
+			conf.Namespace,
+			managed,
+		),
+		&coreV1Types.ConfigMap{},
+		time.Second*30,
+	)
+	configMapInformer.AddEventHandler(driftController)
+
+	daemonSetInformer := cache.NewSharedInformer(
+		cache.NewFilteredListWatchFromClient(
+			extensionsV1beta1Client.RESTClient(),
+			"daemonsets",
+			conf.Namespace,
+			managed,
+		),
+		&extensionsV1beta1Types.DaemonSet{},
+		time.Second*30,
+	)
+	daemonSetInformer.AddEventHandler(driftController)
+
+	metricSinkInformer := sinkInformerFactory.Observability().V1alpha1().MetricSinks().Informer()
+	metricSinkInformer.AddEventHandler(metricController)
+
+	clusterMetricSinkInformer := sinkInformerFactory.Observability().V1alpha1().ClusterMetricSinks().Informer()
+	clusterMetricSinkInformer.AddEventHandler(clusterMetricController)
+
+	run := func(stopCh <-chan struct{}) {
+		probe.WaitFor(
+			sinkInformer.HasSynced,
+			clusterSinkInformer.HasSynced,
+			parserInformer.HasSynced,
+			secretInformer.HasSynced,
+			nodeInformer.HasSynced,
+			configMapInformer.HasSynced,
+			daemonSetInformer.HasSynced,
+			metricSinkInformer.HasSynced,
+			clusterMetricSinkInformer.HasSynced,
+		)
+		// The informers stop delivering changes once stopCh is closed,
+		// the controllers finish the writes they started before exiting.
+		// fluent-bit runs as its own DaemonSet and keeps running the
+		// config written last, there is nothing for the sink-controller
+		// to flush. fluent-bit flushes its buffers itself when its pods
+		// stop, within the Grace of its [SERVICE] section, which is kept
+		// below the terminationGracePeriodSeconds of the DaemonSet.
+		var controllers sync.WaitGroup
+		for _, c := range []interface{ Run(<-chan struct{}) }{
+			controller,
+			clusterController,
+			secretController,
+			parserController,
+			nodeController,
+			driftController,
+		} {
+			controllers.Add(1)
+			go func(c interface{ Run(<-chan struct{}) }) {
+				defer controllers.Done()
+				c.Run(stopCh)
+			}(c)
+		}
+		defer waitForShutdown(&controllers, *shutdownGracePeriod)
+		go reporter.Run(30*time.Second, stopCh)
+		go metricSinkInformer.Run(stopCh)
+		go clusterMetricSinkInformer.Run(stopCh)
+		go secretInformer.Run(stopCh)
+		go nodeInformer.Run(stopCh)
+		go parserInformer.Run(stopCh)
+		go configMapInformer.Run(stopCh)
+		go daemonSetInformer.Run(stopCh)
+		go sinkInformer.Run(stopCh)
+		clusterSinkInformer.Run(stopCh)
+	}
+	if !*enableLeaderElection {
+		run(stopCh)
+		return
+	}
+
+	// A standby does not run the informers either, the informers write
+	// the config when there are no workers.
+	coordinationV1beta1Client, err := coordinationV1beta1.NewForConfig(cfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	namespace := *leaderElectionNamespace
+	if namespace == "" {
+		namespace = conf.Namespace
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	elector := leader.NewElector(
+		coordinationV1beta1Client.Leases(namespace),
+		*leaderElectionName,
+		identity,
+		leaseDuration,
+	)
+	if err := elector.Run(stopCh, run); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// waitForShutdown waits for the controllers to finish their writes, giving
+// up after the grace period.
+func waitForShutdown(controllers *sync.WaitGroup, grace time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		controllers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("controllers stopped")
+	case <-time.After(grace):
+		log.Printf("controllers did not stop within %s, exiting with writes in flight", grace)
+	}
+}
+
+// commaSeparated splits the comma separated values of a flag, dropping
+// empty ones.
+func commaSeparated(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
 }
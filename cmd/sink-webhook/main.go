@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	envstruct "code.cloudfoundry.org/go-envstruct"
+	coreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/knative/observability/pkg/client/clientset/versioned"
+	"github.com/knative/observability/pkg/sink"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+type config struct {
+	Port     string `env:"PORT,report"`
+	CertFile string `env:"CERT_FILE,required,report"`
+	KeyFile  string `env:"KEY_FILE,required,report"`
+
+	// PreflightCheck rejects sinks whose receivers do not accept a TCP
+	// connection within PreflightTimeout.
+	PreflightCheck   bool          `env:"PREFLIGHT_CHECK,report"`
+	PreflightTimeout time.Duration `env:"PREFLIGHT_TIMEOUT,report"`
+
+	// SocketDirs are the directories the socket_path of ClusterLogSinks
+	// may be in, the --socket-dirs of the sink-controller.
+	SocketDirs []string `env:"SOCKET_DIRS,report"`
+}
+
+func main() {
+	conf := config{
+		Port:             "8443",
+		PreflightTimeout: 2 * time.Second,
+	}
+	err := envstruct.Load(&conf)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	err = envstruct.WriteReport(&conf)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	err = sink.ValidateSocketDirs(conf.SocketDirs)
+	if err != nil {
+		log.Fatalf("SOCKET_DIRS: %s", err)
+	}
+
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	coreV1Client, err := coreV1.NewForConfig(cfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	sinkClient, err := versioned.NewForConfig(cfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	opts := []webhook.Option{
+		webhook.WithClusterLogSinks(sinkClient.ObservabilityV1alpha1()),
+		webhook.WithClusterLogParsers(sinkClient.ObservabilityV1alpha1()),
+		webhook.WithSocketDirs(conf.SocketDirs),
+	}
+	if conf.PreflightCheck {
+		opts = append(opts, webhook.WithPreflight(&net.Dialer{Timeout: conf.PreflightTimeout}))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", webhook.NewHandler(coreV1Client, opts...))
+	mux.Handle("/default", webhook.NewDefaultingHandler())
+	mux.Handle("/convert", webhook.NewConversionHandler())
+
+	err = http.ListenAndServeTLS(
+		net.JoinHostPort("", conf.Port),
+		conf.CertFile,
+		conf.KeyFile,
+		mux,
+	)
+	log.Fatal(err)
+}
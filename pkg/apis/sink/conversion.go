@@ -0,0 +1,33 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "k8s.io/apimachinery/pkg/runtime"
+
+// Hub is the version of a kind every other version converts through when
+// the API server asks the sink-webhook for another version than the one it
+// stored.
+type Hub interface {
+	runtime.Object
+	Hub()
+}
+
+// Convertible is a version of a kind that converts to and from its Hub.
+type Convertible interface {
+	runtime.Object
+	ConvertTo(Hub) error
+	ConvertFrom(Hub) error
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	"fmt"
+
+	"github.com/knative/observability/pkg/apis/sink"
+)
+
+// v1alpha1 is the only version of its kinds and therefore their hub. It
+// converts to and from itself unchanged until a newer version takes over
+// as the hub.
+
+func (*LogSink) Hub()          {}
+func (*ClusterLogSink) Hub()   {}
+func (*ClusterLogParser) Hub() {}
+
+// ConvertTo copies the LogSink into the hub.
+func (s *LogSink) ConvertTo(h sink.Hub) error {
+	dst, ok := h.(*LogSink)
+	if !ok {
+		return unexpectedHub(s, h)
+	}
+	s.DeepCopyInto(dst)
+	return nil
+}
+
+// ConvertFrom copies the hub into the LogSink.
+func (s *LogSink) ConvertFrom(h sink.Hub) error {
+	src, ok := h.(*LogSink)
+	if !ok {
+		return unexpectedHub(s, h)
+	}
+	src.DeepCopyInto(s)
+	return nil
+}
+
+// ConvertTo copies the ClusterLogSink into the hub.
+func (s *ClusterLogSink) ConvertTo(h sink.Hub) error {
+	dst, ok := h.(*ClusterLogSink)
+	if !ok {
+		return unexpectedHub(s, h)
+	}
+	s.DeepCopyInto(dst)
+	return nil
+}
+
+// ConvertFrom copies the hub into the ClusterLogSink.
+func (s *ClusterLogSink) ConvertFrom(h sink.Hub) error {
+	src, ok := h.(*ClusterLogSink)
+	if !ok {
+		return unexpectedHub(s, h)
+	}
+	src.DeepCopyInto(s)
+	return nil
+}
+
+// ConvertTo copies the ClusterLogParser into the hub.
+func (p *ClusterLogParser) ConvertTo(h sink.Hub) error {
+	dst, ok := h.(*ClusterLogParser)
+	if !ok {
+		return unexpectedHub(p, h)
+	}
+	p.DeepCopyInto(dst)
+	return nil
+}
+
+// ConvertFrom copies the hub into the ClusterLogParser.
+func (p *ClusterLogParser) ConvertFrom(h sink.Hub) error {
+	src, ok := h.(*ClusterLogParser)
+	if !ok {
+		return unexpectedHub(p, h)
+	}
+	src.DeepCopyInto(p)
+	return nil
+}
+
+func unexpectedHub(obj sink.Convertible, h sink.Hub) error {
+	return fmt.Errorf("unable to convert %T through hub %T", obj, h)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink"
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+func TestIdentityConversion(t *testing.T) {
+	tests := []struct {
+		obj  sink.Convertible
+		hub  sink.Hub
+		back sink.Convertible
+	}{
+		{
+			&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "ns1"},
+				Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+			},
+			&v1alpha1.LogSink{},
+			&v1alpha1.LogSink{},
+		},
+		{
+			&v1alpha1.ClusterLogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+				Spec: v1alpha1.SinkSpec{
+					Type:        "syslog",
+					Host:        "example.com",
+					Port:        514,
+					PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&v1alpha1.ClusterLogSink{},
+			&v1alpha1.ClusterLogSink{},
+		},
+		{
+			&v1alpha1.ClusterLogParser{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+				Spec:       v1alpha1.ParserSpec{Format: "json"},
+			},
+			&v1alpha1.ClusterLogParser{},
+			&v1alpha1.ClusterLogParser{},
+		},
+	}
+
+	for _, test := range tests {
+		if err := test.obj.ConvertTo(test.hub); err != nil {
+			t.Fatalf("Unexpected error converting %T to the hub: %s", test.obj, err)
+		}
+		if err := test.back.ConvertFrom(test.hub); err != nil {
+			t.Fatalf("Unexpected error converting %T from the hub: %s", test.obj, err)
+		}
+		if diff := cmp.Diff(test.obj, test.back); diff != "" {
+			t.Errorf("Round trip of %T not equal (-want +got):\n%s", test.obj, diff)
+		}
+	}
+}
+
+func TestConversionThroughOtherHub(t *testing.T) {
+	s := &v1alpha1.LogSink{}
+	if err := s.ConvertTo(&v1alpha1.ClusterLogSink{}); err == nil {
+		t.Errorf("Expected an error converting a LogSink to a ClusterLogSink")
+	}
+	if err := s.ConvertFrom(&v1alpha1.ClusterLogParser{}); err == nil {
+		t.Errorf("Expected an error converting a LogSink from a ClusterLogParser")
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+type printerColumn struct {
+	Name     string `json:"name"`
+	JSONPath string `json:"JSONPath"`
+}
+
+func TestSinkPrinterColumns(t *testing.T) {
+	expected := []printerColumn{
+		{"Type", ".spec.type"},
+		{"Host", ".spec.host"},
+		{"Port", ".spec.port"},
+		{"Ready", `.status.conditions[?(@.type=="Ready")].status`},
+	}
+	for _, file := range []string{"100-log-sink-crd.yaml", "100-cluster-log-sink-crd.yaml"} {
+		t.Run(file, func(t *testing.T) {
+			columns := make(map[string]string)
+			for _, c := range crdPrinterColumns(t, file) {
+				columns[c.Name] = c.JSONPath
+			}
+			for _, c := range expected {
+				path, ok := columns[c.Name]
+				if !ok {
+					t.Errorf("Expected a %s column", c.Name)
+					continue
+				}
+				if path != c.JSONPath {
+					t.Errorf("JSONPath of the %s column not equal: Expected: %s Actual: %s", c.Name, c.JSONPath, path)
+				}
+			}
+		})
+	}
+}
+
+// crdPrinterColumns returns the additionalPrinterColumns of the CRD in the
+// file of the config directory.
+func crdPrinterColumns(t *testing.T, file string) []printerColumn {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "..", "config", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var crd struct {
+		Spec struct {
+			AdditionalPrinterColumns []printerColumn `json:"additionalPrinterColumns"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(b, &crd); err != nil {
+		t.Fatal(err)
+	}
+	return crd.Spec.AdditionalPrinterColumns
+}
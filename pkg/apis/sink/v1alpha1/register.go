@@ -47,6 +47,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&LogSinkList{},
 		&ClusterLogSink{},
 		&ClusterLogSinkList{},
+		&ClusterLogParser{},
+		&ClusterLogParserList{},
+		&MetricSink{},
+		&MetricSinkList{},
+		&ClusterMetricSink{},
+		&ClusterMetricSinkList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
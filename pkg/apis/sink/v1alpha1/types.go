@@ -16,6 +16,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,17 +34,559 @@ type LogSink struct {
 
 // SinkSpec is the spec for a Sink resource
 type SinkSpec struct {
-	Type               string `json:"type"`
+	Type string `json:"type"`
+	// Host may reference environment variables of the fluent-bit
+	// container as ${NAME}, e.g. ${RECEIVER_HOST}, which fluent-bit
+	// replaces with their values when it loads the config. There is no
+	// escaping, every $ must start a reference, and a variable the
+	// container does not set is replaced with nothing. The webhook cannot
+	// resolve such hosts and does not check they are reachable. The hosts
+	// of http and loki destinations cannot reference variables.
 	Host               string `json:"host"`
 	Port               int    `json:"port"`
+	Protocol           string `json:"protocol,omitempty"`
 	EnableTLS          bool   `json:"enable_tls"`
 	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	// TLSSecretRef is a Secret holding the tls.crt and tls.key the sink
+	// authenticates with to receivers requiring mutual TLS, and optionally
+	// the ca.crt verifying them. It requires EnableTLS. The Secret of a
+	// LogSink is in its namespace, ClusterLogSinks name the namespace. The
+	// files are copied into the fluent-bit-tls Secret mounted by fluent-bit.
+	TLSSecretRef *SecretReference `json:"tls_secret_ref,omitempty"`
+
+	// TLSServerName is the name the sink sends in the TLS handshake and
+	// verifies the receiver's certificate against instead of the host it
+	// dials, e.g. for a receiver behind a load balancer whose certificate
+	// names the receiver. It requires TLS, which otlp sinks use unless
+	// Insecure is set. Sinks of type kafka, s3 and datadog do not support
+	// it.
+	TLSServerName string `json:"tls_server_name,omitempty"`
+
+	// URI, Headers and Format configure sinks of type http.
+	URI     string            `json:"uri,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Format  string            `json:"format,omitempty"`
+
+	// Endpoint is the host:port of the OTLP/HTTP receiver of sinks of type
+	// otlp, which are also sent the Headers. Logs are sent over TLS unless
+	// Insecure is set.
+	Endpoint string `json:"endpoint,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+
+	// Index is the Elasticsearch index sinks of type elasticsearch write
+	// to at Host and Port. With an IndexDateFormat, a strftime format such
+	// as %Y.%m.%d, they write to a daily index named Index-date instead.
+	// Such an Index may partition the records by referencing their keys,
+	// e.g. logs-${kubernetes.namespace_name}. Records missing one of the
+	// keys go to the index of its leading literal, logs here.
+	// Pipeline is the ingest pipeline the records go through.
+	Index           string `json:"index,omitempty"`
+	IndexDateFormat string `json:"index_date_format,omitempty"`
+	Pipeline        string `json:"pipeline,omitempty"`
+
+	// Brokers are the host:port of the Kafka brokers sinks of type kafka
+	// produce the records to, as JSON on the Topic.
+	Brokers []string `json:"brokers,omitempty"`
+	Topic   string   `json:"topic,omitempty"`
+
+	// URL is where sinks of type loki push the records, the push API path
+	// of Loki is used when it has none. TenantID is the tenant they push
+	// to in a multi-tenant Loki. The streams are labeled with the
+	// namespace of the pods and the record fields in LabelKeys, written
+	// like kubernetes.pod_name.
+	URL       string   `json:"url,omitempty"`
+	TenantID  string   `json:"tenant_id,omitempty"`
+	LabelKeys []string `json:"label_keys,omitempty"`
+
+	// Bucket and Region are where sinks of type s3 archive the records.
+	// Unlike an Index, the Bucket cannot reference record keys.
+	// An object is uploaded once it reaches TotalFileSizeMB or after
+	// UploadTimeoutSeconds, unset keeps fluent-bit's 100MB and 10 minutes.
+	// The sinks authenticate with the access_key_id:secret_access_key in
+	// SecretRef or, without one, with the IAM role of the fluent-bit
+	// service account (IRSA).
+	Bucket               string `json:"bucket,omitempty"`
+	Region               string `json:"region,omitempty"`
+	TotalFileSizeMB      int    `json:"total_file_size_mb,omitempty"`
+	UploadTimeoutSeconds int    `json:"upload_timeout_seconds,omitempty"`
+
+	// Sinks of type splunk send the records to the HTTP Event Collector at
+	// Host and Port, which defaults to 8088, authenticating with the HEC
+	// token in SecretRef. The events go to the Splunk Index with the
+	// SourceType, unset leaves both to the defaults of the token.
+	SourceType string `json:"source_type,omitempty"`
+
+	// Sinks of type datadog ship the records to the logs intake of the
+	// Site, one of the Datadog regions, e.g. datadoghq.eu. Unset is
+	// datadoghq.com. They authenticate with the API key in SecretRef and
+	// set the service, source and tags of the logs to Service, DDSource and
+	// Tags, which are key or key:value, e.g. env:prod. Source already
+	// chooses the logs of a ClusterLogSink, hence DDSource.
+	Site     string   `json:"site,omitempty"`
+	Service  string   `json:"service,omitempty"`
+	DDSource string   `json:"dd_source,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// Sinks of type forward send the records to a fluentd or fluent-bit
+	// aggregator at Host and Port, which defaults to 24224, with the
+	// forward protocol. With a SecretRef they authenticate with its shared
+	// key, introducing themselves as SelfHostname. Unset is localhost.
+	SelfHostname string `json:"self_hostname,omitempty"`
+
+	// Sinks of type gelf send the records to a Graylog GELF input at Host
+	// and Port, which defaults to 12201, over the Mode udp, tcp or tls.
+	// Unset is udp. GELFKeys are the record fields the GELF fields are
+	// taken from.
+	Mode     string    `json:"mode,omitempty"`
+	GELFKeys *GELFKeys `json:"gelf_keys,omitempty"`
+
+	// Compression is none or gzip and compresses the payloads of sinks of
+	// type http and otlp. Unset is none. Syslog sinks do not support it.
+	Compression string `json:"compression,omitempty"`
+
+	// SecretRef is a key of a Secret holding a token sent by sinks of type
+	// http, otlp and loki as an Authorization: Bearer header, or the
+	// user:password sinks of type elasticsearch authenticate with. Sinks of
+	// type kafka use the user:password for SASL PLAIN and sinks of type s3
+	// hold an access_key_id:secret_access_key. Sinks of type splunk send it
+	// as their HEC token, sinks of type datadog as their API key and sinks
+	// of type forward as their shared key. The Secret of a LogSink is in
+	// its namespace, ClusterLogSinks name the namespace. The credentials
+	// are written to the fluent-bit-tls Secret, the fluent-bit config only
+	// references them.
+	SecretRef *SecretKeyRef `json:"secret_ref,omitempty"`
+
+	// SyslogFormat, AppName and MessageTemplate configure the messages of
+	// sinks of type syslog. An unset SyslogFormat is rfc5424. The template
+	// replaces the message with its text, where {{key}} is the value of
+	// the record key, e.g. {{kubernetes.pod_name}}: {{log}}.
+	SyslogFormat    string `json:"syslog_format,omitempty"`
+	AppName         string `json:"app_name,omitempty"`
+	MessageTemplate string `json:"message_template,omitempty"`
+
+	// SyslogFacility and DefaultSeverity set the PRI of the messages of
+	// sinks of type syslog to a syslog facility, e.g. local4, and
+	// severity, e.g. notice. Unset the syslog output uses its own.
+	SyslogFacility  string `json:"syslog_facility,omitempty"`
+	DefaultSeverity string `json:"default_severity,omitempty"`
+
+	// PodSelector limits the sink to logs from pods whose labels match.
+	// It narrows the logs the sink would otherwise receive: for a LogSink
+	// that is the pods in its namespace and for a ClusterLogSink the pods
+	// in every namespace. A nil or empty selector forwards all of them.
+	PodSelector *metav1.LabelSelector `json:"pod_selector,omitempty"`
+
+	// NodeSelector limits the sink to logs from pods running on nodes
+	// whose labels match all of it, e.g. the GPU nodes of the cluster.
+	// The sink-controller watches the Nodes and re-renders the config
+	// when their labels change. A selector matching no nodes forwards
+	// nothing, an empty one forwards the logs of every node.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+
+	// Stream limits the sink to the lines containers write to stdout or
+	// stderr. Unset is all, which forwards both.
+	Stream string `json:"stream,omitempty"`
+
+	// ExcludeNamespaces drops the logs of pods in these namespaces before
+	// they reach a ClusterLogSink, e.g. to leave out kube-system. LogSinks
+	// only receive their own namespace and do not support it.
+	ExcludeNamespaces []string `json:"exclude_namespaces,omitempty"`
+
+	// NamespaceGlobs limit a ClusterLogSink to the logs of pods in the
+	// namespaces matching any of them, e.g. team-* for team-a and team-b.
+	// A glob is a namespace name where * matches any run of characters and
+	// ? a single one. ExcludeNamespaces still apply to the matching
+	// namespaces. Empty forwards every namespace. LogSinks only receive
+	// their own namespace and do not support it, and neither do sinks with
+	// HostPaths since their lines are not from a namespace.
+	NamespaceGlobs []string `json:"namespace_globs,omitempty"`
+
+	// SystemOnly limits a ClusterLogSink to the logs of the platform's own
+	// components, the pods in SystemNamespaces, and drops the logs of the
+	// application namespaces. It cannot be combined with NamespaceGlobs,
+	// which select namespaces as well. LogSinks only receive their own
+	// namespace and do not support it.
+	SystemOnly bool `json:"system_only,omitempty"`
+
+	// SystemNamespaces are the namespaces a ClusterLogSink with SystemOnly
+	// forwards, empty is kube-system, kube-public, kube-node-lease and
+	// knative-observability. ExcludeNamespaces still apply to them.
+	SystemNamespaces []string `json:"system_namespaces,omitempty"`
+
+	// HostPaths are globs of files on the nodes a ClusterLogSink tails in
+	// addition to the container logs, e.g. /data/app/*.log for workloads
+	// logging to a hostPath volume. Their lines go through the sink's
+	// filters to its outputs, with the path of their file in log_path to
+	// tell them apart. The files must be mounted into the fluent-bit
+	// DaemonSet. LogSinks do not support it.
+	HostPaths []string `json:"host_paths,omitempty"`
+
+	// SocketPath is a Unix domain socket on the nodes a syslog sink
+	// forwards to instead of its Host and Port, e.g. of a collector
+	// running on every node. The directory of the socket is mounted into
+	// the fluent-bit DaemonSet. It cannot be combined with Protocol,
+	// EnableTLS or Destinations. Only ClusterLogSinks support it, in the
+	// directories the operator allows with the --socket-dirs of the
+	// sink-controller.
+	SocketPath string `json:"socket_path,omitempty"`
+
+	// Source is where the logs of a ClusterLogSink come from: containers,
+	// the default, or audit for the Kubernetes API audit log of the
+	// control-plane nodes. An audit sink tails the HostPaths, or
+	// /var/log/kubernetes/audit/audit.log when there are none, on every
+	// node fluent-bit runs on and so only forwards events from nodes where
+	// the log exists, fluent-bit only runs on tainted control-plane nodes
+	// with config/overlays/fluent-bit-control-plane.yaml applied. Its
+	// events are parsed from JSON and it receives no container logs, so it
+	// cannot select pods or namespaces or join lines. LogSinks do not
+	// support it.
+	Source string `json:"source,omitempty"`
+
+	// ExclusiveMatch makes a LogSink claim the logs of its namespace. By
+	// default every sink matching a pod forwards its logs, so a pod
+	// matched by a LogSink and a ClusterLogSink is shipped by both. While
+	// a LogSink with ExclusiveMatch is rendered, the ClusterLogSinks skip
+	// its namespace as if it were in their ExcludeNamespaces, and only the
+	// LogSinks of the namespace forward its logs. It claims the whole
+	// namespace and so cannot be combined with a PodSelector. ClusterLogSinks
+	// do not support it.
+	ExclusiveMatch bool `json:"exclusive_match,omitempty"`
+
+	// CatchAll makes a ClusterLogSink receive only the container logs no
+	// other sink selects by its namespace, namespace globs, pod selector,
+	// node selector or stream. A record another sink drops later on, e.g.
+	// with its drop patterns, was still selected. There is at most one
+	// catch-all, LogSinks do not support it.
+	CatchAll bool `json:"catch_all,omitempty"`
+
+	// Multiline joins the lines of a multi-line message, such as a stack
+	// trace, into a single record before it is forwarded. A line starts a
+	// new record when it matches StartPattern and is appended to the
+	// record before it otherwise.
+	Multiline *Multiline `json:"multiline,omitempty"`
+
+	// DropPatterns are regular expressions matched against the log line of
+	// every record. Records matching any of them are dropped before they
+	// leave the cluster, e.g. lines holding card numbers. They match the
+	// line before ParseJSON takes it apart.
+	DropPatterns []string `json:"drop_patterns,omitempty"`
+
+	// RedactPatterns replace the matches of their patterns in the log line
+	// of every record, one rule after the other, e.g. to mask email
+	// addresses. Records are forwarded with the masked line, after
+	// DropPatterns and before ParseJSON.
+	RedactPatterns []RedactRule `json:"redact_patterns,omitempty"`
+
+	// ParseJSON promotes the fields of JSON log lines into the record.
+	// Lines that are not JSON are forwarded unchanged.
+	ParseJSON bool `json:"parse_json,omitempty"`
+
+	// ParserName is a ClusterLogParser the log line of every record is
+	// parsed with instead, its fields are promoted into the record. Lines
+	// the parser does not match are forwarded unchanged.
+	ParserName string `json:"parser_name,omitempty"`
+
+	// ParserNames are ClusterLogParsers the records are parsed with one
+	// after the other instead, each parser's fields are promoted before
+	// the next one parses the record. Lines wrapped in JSON are parsed by
+	// a json parser first, then a regex parser with the key_name of the
+	// wrapped message parses it.
+	ParserNames []string `json:"parser_names,omitempty"`
+
+	// TimeKey and TimeFormat normalize the timestamps of the records. The
+	// value of the TimeKey field is read in the strftime TimeFormat, e.g.
+	// %d/%b/%Y:%H:%M:%S %z, and replaced with the same time in RFC3339 in
+	// UTC. Records without the field, or with a value in another format,
+	// get the time fluent-bit read them at instead. Unset leaves the
+	// records as they are.
+	TimeKey    string `json:"time_key,omitempty"`
+	TimeFormat string `json:"time_format,omitempty"`
+
+	// MinSeverity drops the records less severe than it, e.g. warning
+	// forwards warnings, errors and worse. It is read from the level or
+	// severity field parsed from the log line by ParseJSON or ParserName,
+	// one of them is required. The syslog severities emerg, alert, crit,
+	// err, warning, notice, info and debug are supported along with the
+	// log levels panic, fatal, error, warn and trace, the fields match in
+	// any case. Records without either field are forwarded. Unset forwards
+	// every severity.
+	MinSeverity string `json:"min_severity,omitempty"`
+
+	// MatchExpr makes the sink only forward the records whose field Key
+	// matches the regular expression Regex, e.g. log lines containing
+	// panic for an alerting receiver. It narrows the records selected by
+	// the namespace and pod selection of the sink. It matches the record
+	// after ParseJSON or ParserName, so Key may be one of the fields parsed
+	// from the line. Records without the field are not forwarded.
+	MatchExpr *MatchExpr `json:"match_expr,omitempty"`
+
+	// IncludeNodeName sets the NodeNameKey field of every record to the
+	// node its pod runs on, for receivers that want the emitting host at
+	// the top level. A field of the same name parsed from the line is
+	// replaced. Unset NodeNameKey is hostname.
+	IncludeNodeName bool   `json:"include_node_name,omitempty"`
+	NodeNameKey     string `json:"node_name_key,omitempty"`
+
+	// MetadataFields are the fields of the kubernetes metadata of a record
+	// the sink keeps, e.g. pod_name and namespace_name, the others are
+	// dropped to keep the records small. An empty list keeps all of them.
+	MetadataFields []string `json:"metadata_fields,omitempty"`
+
+	// Labels are set as fields on every record the sink forwards, e.g. a
+	// cluster_name the receiver cannot tell otherwise. They replace fields
+	// of the same name parsed from the log line but may not replace the
+	// log line or the kubernetes metadata.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// RenameKeys renames the record fields named by its keys to its
+	// values before the sink forwards them, e.g. log to message for
+	// receivers expecting another schema. Fields parsed from the log line
+	// are renamed too, Labels are set after the renames. A field is not
+	// renamed if the record already has a field of the new name, and no
+	// two fields may be renamed to the same name.
+	RenameKeys map[string]string `json:"rename_keys,omitempty"`
+
+	// OutputFields are the record fields the sink forwards, e.g. log and
+	// kubernetes for a receiver that only wants those, the others are
+	// dropped once every other filter ran. Only the top level of a record
+	// is selected, MetadataFields trims the kubernetes metadata. Fields
+	// renamed by RenameKeys are listed by their new names and Labels are
+	// dropped unless they are listed. The cluster field of the
+	// sink-controller's cluster name and the es_index field of a templated
+	// Index are always kept. An empty list forwards the whole record.
+	OutputFields []string `json:"output_fields,omitempty"`
+
+	// MaxRecordsPerSecond drops the sink's records above the rate so a
+	// noisy namespace cannot overwhelm its receiver. Zero is unlimited.
+	MaxRecordsPerSecond int `json:"max_records_per_second,omitempty"`
+
+	// SampleRate is the fraction of the sink's records between 0 and 1 it
+	// forwards, each record is kept at random with the probability. Zero
+	// forwards no record, unset and 1 forward every record. It cannot be
+	// combined with MaxRecordsPerSecond.
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+
+	// DedupWindowSeconds drops the records whose log line the same
+	// container logged within that many seconds, such as lines its
+	// application repeats when it retries. Deduplication is best-effort:
+	// every fluent-bit pod only remembers the last
+	// sink.DedupMaxRecords records it read and forgets them when it
+	// restarts. Zero keeps the duplicates.
+	DedupWindowSeconds int `json:"dedup_window_seconds,omitempty"`
+
+	// MaxMessageBytes truncates log lines longer than it to that many
+	// bytes, ending them with a marker, rather than leaving receivers to
+	// drop them. Lines longer than the Buffer_Max_Size of the tail input of
+	// the fluent-bit DaemonSet are skipped before they reach the sink. Zero
+	// leaves the lines as they are.
+	MaxMessageBytes int `json:"max_message_bytes,omitempty"`
+
+	// Encoding is the charset the sink forwards the strings of its records
+	// in for receivers that cannot read UTF-8: utf-8, iso-8859-1 or
+	// us-ascii. Characters the charset lacks are replaced by a question
+	// mark. Unset and utf-8 forward the records as fluent-bit reads them.
+	Encoding string `json:"encoding,omitempty"`
+
+	// RetryLimit is the number of times fluent-bit retries delivering a
+	// chunk of logs before dropping it, -1 retries forever. Zero keeps
+	// fluent-bit's default. The wait between retries is up to fluent-bit's
+	// scheduler, it has no setting per output.
+	RetryLimit int `json:"retry_limit,omitempty"`
+	// KeepAliveSeconds is how long fluent-bit keeps an idle connection to
+	// the receiver open for reuse, shorter than the receiver's own idle
+	// timeout spares reconnecting to connections the receiver closed. Zero
+	// disables keepalive, fluent-bit opens a connection per flush. Unset
+	// keeps fluent-bit's default of 30 seconds.
+	KeepAliveSeconds *int `json:"keepalive_seconds,omitempty"`
+	// FlushTimeoutSeconds bounds how long fluent-bit waits to connect to
+	// the receiver and for every read and write on the connection. A flush
+	// to a stuck receiver then fails and is retried rather than holding
+	// the sink's chunks, and the workers of the output, indefinitely. The
+	// webhook defaults it to 30 seconds, zero keeps fluent-bit's defaults.
+	FlushTimeoutSeconds int `json:"flush_timeout_seconds,omitempty"`
+	// PreserveOrder has a single worker flush the records of the sink, so
+	// the records of a pod reach the receiver in the order fluent-bit read
+	// them at the cost of throughput. It gives the sink an output of its
+	// own. A chunk that fails is retried after the chunks flushed
+	// meanwhile, so the order only holds while deliveries succeed.
+	PreserveOrder bool `json:"preserve_order,omitempty"`
+
+	// BufferSizeMB and BufferType bound the logs fluent-bit holds for the
+	// sink while it cannot deliver them, so an unreachable receiver does
+	// not grow fluent-bit's memory without limit. Unset keeps the logs in
+	// fluent-bit's shared buffer.
+	BufferSizeMB int    `json:"buffer_size_mb,omitempty"`
+	BufferType   string `json:"buffer_type,omitempty"`
+
+	// Destinations are additional receivers of the same logs. Every other
+	// field, including filtering, applies to all of them. Host and Port,
+	// or URI for http sinks, are an implicit first destination when set,
+	// which weighted destinations cannot have.
+	Destinations []Destination `json:"destinations,omitempty"`
+
+	// Tag is the fluent-bit tag of the records in the sink's own stream,
+	// which the outputs of the sink match and forward with them. It is
+	// dot separated words of alphanumerics and hyphens, the prefixes kube.
+	// and sink. are reserved. Unset derives the tag from the sink's name,
+	// a sink with a Tag always gets a stream and keeps the tag when it is
+	// recreated. Sinks sharing a Tag are not rendered.
+	Tag string `json:"tag,omitempty"`
+
+	// Disabled leaves the sink out of the fluent-bit config, its logs are
+	// not forwarded until it is enabled again. Its Ready condition is False
+	// with the reason Disabled meanwhile.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Multiline configures how the lines of a container's log are joined into
+// records.
+type Multiline struct {
+	// StartPattern is a regular expression matched against the start of
+	// every line, e.g. ^\d{4}-\d{2}-\d{2} for lines starting with a date.
+	StartPattern string `json:"start_pattern"`
+	// MaxLines is the most lines joined into a record, the line after
+	// them starts a new one. Zero is unlimited.
+	MaxLines int `json:"max_lines,omitempty"`
+	// FlushTimeoutMs is how long fluent-bit waits for the next line of a
+	// record before forwarding it. Zero keeps fluent-bit's default.
+	FlushTimeoutMs int `json:"flush_timeout_ms,omitempty"`
+}
+
+// RedactRule replaces the matches of Pattern with Replacement. fluent-bit
+// applies them as Lua patterns, so Pattern is a regular expression without
+// alternation, anchors other than ^ and $, word boundaries or repetition of
+// anything but single characters. Replacement is literal.
+type RedactRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// MatchExpr matches the value of a record field against a regular
+// expression.
+type MatchExpr struct {
+	Key   string `json:"key"`
+	Regex string `json:"regex"`
+}
+
+// Destination is a receiver of a sink's logs. An empty Type is the type of
+// the sink.
+type Destination struct {
+	Type string `json:"type,omitempty"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Weight shares the sink's records between its destinations rather
+	// than sending all of them to each, a destination gets Weight out of
+	// the sum of the weights. It must be set on every destination or none.
+	Weight int `json:"weight,omitempty"`
+}
+
+// GELFKeys are the top-level record fields the GELF fields of a gelf sink
+// are taken from. Unset ShortMessage is the log line and unset Host is the
+// node the pod runs on, which is copied from the kubernetes metadata into
+// the host field of the records. The other GELF fields are left out unless
+// they are set.
+type GELFKeys struct {
+	ShortMessage string `json:"short_message,omitempty"`
+	FullMessage  string `json:"full_message,omitempty"`
+	Host         string `json:"host,omitempty"`
+	Level        string `json:"level,omitempty"`
+	Timestamp    string `json:"timestamp,omitempty"`
 }
 
+// SecretReference selects a Secret.
+type SecretReference struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// SecretKeyRef selects a key of a Secret.
+type SecretKeyRef struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+const (
+	SinkTypeSyslog = "syslog"
+	SinkTypeHTTP   = "http"
+	SinkTypeOTLP   = "otlp"
+
+	SinkTypeElasticsearch = "elasticsearch"
+	SinkTypeKafka         = "kafka"
+	SinkTypeLoki          = "loki"
+	SinkTypeS3            = "s3"
+	SinkTypeSplunk        = "splunk"
+	SinkTypeDatadog       = "datadog"
+	SinkTypeForward       = "forward"
+	SinkTypeGELF          = "gelf"
+)
+
+const (
+	FormatJSONLines = "json_lines"
+	FormatJSONArray = "json_array"
+)
+
+const (
+	SyslogFormatRFC3164 = "rfc3164"
+	SyslogFormatRFC5424 = "rfc5424"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+const (
+	BufferTypeMemory     = "memory"
+	BufferTypeFilesystem = "filesystem"
+)
+
+const (
+	LogSourceContainers = "containers"
+	LogSourceAudit      = "audit"
+)
+
+const (
+	StreamAll    = "all"
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
 // SinkStatus is the status for a Sink resource
 type SinkStatus struct {
-	State   SinkState `json:"state,omitempty"`
-	Message string    `json:"message,omitempty"`
+	State      SinkState   `json:"state,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// BufferedChunks and BufferedBytes are what the stream of the sink
+	// holds across the fluent-bit pods, waiting for its outputs. fluent-bit
+	// does not count the records of the chunks, nor the bytes of chunks
+	// only on the filesystem. Sinks without a stream of their own share
+	// the buffer of the container logs and report none.
+	BufferedChunks int64 `json:"bufferedChunks,omitempty"`
+	BufferedBytes  int64 `json:"bufferedBytes,omitempty"`
+}
+
+type ConditionType string
+
+const (
+	// SinkConditionReady is True when fluent-bit is delivering logs to the
+	// sink without errors.
+	SinkConditionReady ConditionType = "Ready"
+	// SinkConditionBackpressured is True when the stream of the sink
+	// buffers more than the threshold of the sink-controller or reached
+	// its memory limit because its receivers do not keep up.
+	SinkConditionBackpressured ConditionType = "Backpressured"
+)
+
+// Condition describes the state of an aspect of a Sink at a point in time
+type Condition struct {
+	Type               ConditionType          `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
 }
 
 type SinkState string
@@ -84,3 +627,110 @@ type ClusterLogSinkList struct {
 
 	Items []ClusterLogSink `json:"items"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterLogParser is a fluent-bit parser for a log format sinks reference
+// by name
+type ClusterLogParser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ParserSpec `json:"spec"`
+}
+
+// ParserSpec is the spec for a ClusterLogParser resource
+type ParserSpec struct {
+	// Format is regex or json. Regex parsers promote the named groups of
+	// Regex, e.g. (?<level>[A-Z]+), into the record.
+	Format string `json:"format"`
+	Regex  string `json:"regex,omitempty"`
+
+	// KeyName is the field of the records the parser parses, the log line
+	// when unset.
+	KeyName string `json:"key_name,omitempty"`
+
+	// TimeKey and TimeFormat set the time of the records from one of the
+	// parsed fields, in the strptime format.
+	TimeKey    string `json:"time_key,omitempty"`
+	TimeFormat string `json:"time_format,omitempty"`
+}
+
+const (
+	ParserFormatRegex = "regex"
+	ParserFormatJSON  = "json"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterLogParserList is a list of ClusterLogParser resources
+type ClusterLogParserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterLogParser `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MetricSink is a specification for a MetricSink resource
+type MetricSink struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   MetricSinkSpec `json:"spec"`
+	Status SinkStatus     `json:"status,omitempty"`
+}
+
+// MetricSinkSpec is the spec for a MetricSink resource
+type MetricSinkSpec struct {
+	Type string `json:"type"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// Interval is how often metrics are flushed to the destination. The
+	// agent's default is used when it is not set.
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+const (
+	// MetricSinkTypePrometheus sends metrics with the Prometheus remote
+	// write protocol to http://host:port/api/v1/write.
+	MetricSinkTypePrometheus = "prometheus"
+	// MetricSinkTypeStatsd sends metrics as statsd over UDP.
+	MetricSinkTypeStatsd = "statsd"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MetricSinkList is a list of MetricSink resources
+type MetricSinkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []MetricSink `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterMetricSink is a specification for a ClusterMetricSink resource
+type ClusterMetricSink struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   MetricSinkSpec `json:"spec"`
+	Status SinkStatus     `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterMetricSinkList is a list of ClusterMetricSink resources
+type ClusterMetricSinkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterMetricSink `json:"items"`
+}
@@ -28,14 +28,26 @@ type FakeObservabilityV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeObservabilityV1alpha1) ClusterLogParsers(namespace string) v1alpha1.ClusterLogParserInterface {
+	return &FakeClusterLogParsers{c, namespace}
+}
+
 func (c *FakeObservabilityV1alpha1) ClusterLogSinks(namespace string) v1alpha1.ClusterLogSinkInterface {
 	return &FakeClusterLogSinks{c, namespace}
 }
 
+func (c *FakeObservabilityV1alpha1) ClusterMetricSinks(namespace string) v1alpha1.ClusterMetricSinkInterface {
+	return &FakeClusterMetricSinks{c, namespace}
+}
+
 func (c *FakeObservabilityV1alpha1) LogSinks(namespace string) v1alpha1.LogSinkInterface {
 	return &FakeLogSinks{c, namespace}
 }
 
+func (c *FakeObservabilityV1alpha1) MetricSinks(namespace string) v1alpha1.MetricSinkInterface {
+	return &FakeMetricSinks{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeObservabilityV1alpha1) RESTClient() rest.Interface {
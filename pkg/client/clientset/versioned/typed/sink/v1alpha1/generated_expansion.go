@@ -18,6 +18,12 @@ limitations under the License.
 
 package v1alpha1
 
+type ClusterLogParserExpansion interface{}
+
 type ClusterLogSinkExpansion interface{}
 
+type ClusterMetricSinkExpansion interface{}
+
 type LogSinkExpansion interface{}
+
+type MetricSinkExpansion interface{}
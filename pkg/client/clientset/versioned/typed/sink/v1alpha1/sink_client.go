@@ -27,8 +27,11 @@ import (
 
 type ObservabilityV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterLogParsersGetter
 	ClusterLogSinksGetter
+	ClusterMetricSinksGetter
 	LogSinksGetter
+	MetricSinksGetter
 }
 
 // ObservabilityV1alpha1Client is used to interact with features provided by the observability.knative.dev group.
@@ -36,14 +39,26 @@ type ObservabilityV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ObservabilityV1alpha1Client) ClusterLogParsers(namespace string) ClusterLogParserInterface {
+	return newClusterLogParsers(c, namespace)
+}
+
 func (c *ObservabilityV1alpha1Client) ClusterLogSinks(namespace string) ClusterLogSinkInterface {
 	return newClusterLogSinks(c, namespace)
 }
 
+func (c *ObservabilityV1alpha1Client) ClusterMetricSinks(namespace string) ClusterMetricSinkInterface {
+	return newClusterMetricSinks(c, namespace)
+}
+
 func (c *ObservabilityV1alpha1Client) LogSinks(namespace string) LogSinkInterface {
 	return newLogSinks(c, namespace)
 }
 
+func (c *ObservabilityV1alpha1Client) MetricSinks(namespace string) MetricSinkInterface {
+	return newMetricSinks(c, namespace)
+}
+
 // NewForConfig creates a new ObservabilityV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*ObservabilityV1alpha1Client, error) {
 	config := *c
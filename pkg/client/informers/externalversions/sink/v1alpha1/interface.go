@@ -24,10 +24,16 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterLogParsers returns a ClusterLogParserInformer.
+	ClusterLogParsers() ClusterLogParserInformer
 	// ClusterLogSinks returns a ClusterLogSinkInformer.
 	ClusterLogSinks() ClusterLogSinkInformer
+	// ClusterMetricSinks returns a ClusterMetricSinkInformer.
+	ClusterMetricSinks() ClusterMetricSinkInformer
 	// LogSinks returns a LogSinkInformer.
 	LogSinks() LogSinkInformer
+	// MetricSinks returns a MetricSinkInformer.
+	MetricSinks() MetricSinkInformer
 }
 
 type version struct {
@@ -41,12 +47,27 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterLogParsers returns a ClusterLogParserInformer.
+func (v *version) ClusterLogParsers() ClusterLogParserInformer {
+	return &clusterLogParserInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // ClusterLogSinks returns a ClusterLogSinkInformer.
 func (v *version) ClusterLogSinks() ClusterLogSinkInformer {
 	return &clusterLogSinkInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// ClusterMetricSinks returns a ClusterMetricSinkInformer.
+func (v *version) ClusterMetricSinks() ClusterMetricSinkInformer {
+	return &clusterMetricSinkInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // LogSinks returns a LogSinkInformer.
 func (v *version) LogSinks() LogSinkInformer {
 	return &logSinkInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// MetricSinks returns a MetricSinkInformer.
+func (v *version) MetricSinks() MetricSinkInformer {
+	return &metricSinkInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+// ClusterLogParserListerExpansion allows custom methods to be added to
+// ClusterLogParserLister.
+type ClusterLogParserListerExpansion interface{}
+
+// ClusterLogParserNamespaceListerExpansion allows custom methods to be added to
+// ClusterLogParserNamespaceLister.
+type ClusterLogParserNamespaceListerExpansion interface{}
+
 // ClusterLogSinkListerExpansion allows custom methods to be added to
 // ClusterLogSinkLister.
 type ClusterLogSinkListerExpansion interface{}
@@ -26,6 +34,14 @@ type ClusterLogSinkListerExpansion interface{}
 // ClusterLogSinkNamespaceLister.
 type ClusterLogSinkNamespaceListerExpansion interface{}
 
+// ClusterMetricSinkListerExpansion allows custom methods to be added to
+// ClusterMetricSinkLister.
+type ClusterMetricSinkListerExpansion interface{}
+
+// ClusterMetricSinkNamespaceListerExpansion allows custom methods to be added to
+// ClusterMetricSinkNamespaceLister.
+type ClusterMetricSinkNamespaceListerExpansion interface{}
+
 // LogSinkListerExpansion allows custom methods to be added to
 // LogSinkLister.
 type LogSinkListerExpansion interface{}
@@ -33,3 +49,11 @@ type LogSinkListerExpansion interface{}
 // LogSinkNamespaceListerExpansion allows custom methods to be added to
 // LogSinkNamespaceLister.
 type LogSinkNamespaceListerExpansion interface{}
+
+// MetricSinkListerExpansion allows custom methods to be added to
+// MetricSinkLister.
+type MetricSinkListerExpansion interface{}
+
+// MetricSinkNamespaceListerExpansion allows custom methods to be added to
+// MetricSinkNamespaceLister.
+type MetricSinkNamespaceListerExpansion interface{}
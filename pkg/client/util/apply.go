@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1alpha1i "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+)
+
+// ApplySinks creates the LogSinks that do not exist and updates the spec
+// and labels of those that do, leaving sinks that already match alone so
+// applying the same sinks again does not change them. Every sink must have
+// a namespace. A sink that cannot be applied does not stop the others, the
+// returned error aggregates the failures of all of them. Once the context
+// is done the remaining sinks are not applied.
+func ApplySinks(
+	ctx context.Context,
+	client v1alpha1i.LogSinksGetter,
+	sinks []v1alpha1.LogSink,
+) error {
+	var errs []error
+	for i := range sinks {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		s := &sinks[i]
+		if err := applySink(client, s); err != nil {
+			errs = append(errs, fmt.Errorf("unable to apply LogSink %s/%s: %s", s.Namespace, s.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func applySink(client v1alpha1i.LogSinksGetter, s *v1alpha1.LogSink) error {
+	if s.Namespace == "" {
+		return fmt.Errorf("namespace must be set")
+	}
+	sinks := client.LogSinks(s.Namespace)
+	current, err := sinks.Get(s.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = sinks.Create(s)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(current.Spec, s.Spec) && reflect.DeepEqual(current.Labels, s.Labels) {
+		return nil
+	}
+	updated := current.DeepCopy()
+	updated.Spec = s.Spec
+	updated.Labels = s.Labels
+	_, err = sinks.Update(updated)
+	return err
+}
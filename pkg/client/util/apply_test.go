@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktesting "k8s.io/client-go/testing"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/client/util"
+)
+
+func TestApplySinksCreates(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	sinks := []v1alpha1.LogSink{
+		newLogSink("ns-1", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+		newLogSink("ns-2", "sink-b", v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs"}),
+	}
+
+	err := util.ApplySinks(context.Background(), client.ObservabilityV1alpha1(), sinks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range sinks {
+		got, err := client.ObservabilityV1alpha1().LogSinks(s.Namespace).Get(s.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(s.Spec, got.Spec); diff != "" {
+			t.Errorf("Spec of %s/%s not equal (-want, +got) = %v", s.Namespace, s.Name, diff)
+		}
+	}
+}
+
+func TestApplySinksUpdates(t *testing.T) {
+	existing := newLogSink("ns-1", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "old.example.com", Port: 514})
+	existing.Annotations = map[string]string{"owner": "team-a"}
+	existing.Status.Conditions = []v1alpha1.Condition{{Type: v1alpha1.SinkConditionReady, Status: "True"}}
+	client := fake.NewSimpleClientset(&existing)
+
+	s := newLogSink("ns-1", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "new.example.com", Port: 514})
+	s.Labels = map[string]string{"team": "a"}
+	err := util.ApplySinks(context.Background(), client.ObservabilityV1alpha1(), []v1alpha1.LogSink{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.ObservabilityV1alpha1().LogSinks("ns-1").Get("sink-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(s.Spec, got.Spec); diff != "" {
+		t.Errorf("Spec not equal (-want, +got) = %v", diff)
+	}
+	if diff := cmp.Diff(s.Labels, got.Labels); diff != "" {
+		t.Errorf("Labels not equal (-want, +got) = %v", diff)
+	}
+	if diff := cmp.Diff(existing.Annotations, got.Annotations); diff != "" {
+		t.Errorf("Expected the annotations to be kept (-want, +got) = %v", diff)
+	}
+	if diff := cmp.Diff(existing.Status, got.Status); diff != "" {
+		t.Errorf("Expected the status to be kept (-want, +got) = %v", diff)
+	}
+}
+
+func TestApplySinksUnchanged(t *testing.T) {
+	s := newLogSink("ns-1", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514})
+	client := fake.NewSimpleClientset(s.DeepCopy())
+
+	err := util.ApplySinks(context.Background(), client.ObservabilityV1alpha1(), []v1alpha1.LogSink{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range client.Actions() {
+		if a.GetVerb() != "get" {
+			t.Errorf("Expected only gets of the unchanged sink, got: %s", a.GetVerb())
+		}
+	}
+}
+
+func TestApplySinksAggregatesErrors(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "logsinks", func(a ktesting.Action) (bool, runtime.Object, error) {
+		if a.GetNamespace() == "ns-2" {
+			return true, nil, errors.New("some error")
+		}
+		return false, nil, nil
+	})
+	sinks := []v1alpha1.LogSink{
+		newLogSink("ns-2", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+		newLogSink("ns-1", "sink-b", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+		newLogSink("", "sink-c", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+	}
+
+	err := util.ApplySinks(context.Background(), client.ObservabilityV1alpha1(), sinks)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, msg := range []string{
+		"unable to apply LogSink ns-2/sink-a: some error",
+		"unable to apply LogSink /sink-c: namespace must be set",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected error to contain %q, got: %s", msg, err)
+		}
+	}
+	if _, err := client.ObservabilityV1alpha1().LogSinks("ns-1").Get("sink-b", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the other sink to be created: %s", err)
+	}
+}
+
+func TestApplySinksContextDone(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := util.ApplySinks(ctx, client.ObservabilityV1alpha1(), []v1alpha1.LogSink{
+		newLogSink("ns-1", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+	})
+	if err == nil || err.Error() != context.Canceled.Error() {
+		t.Errorf("Expected context error, got: %v", err)
+	}
+	if len(client.Actions()) != 0 {
+		t.Errorf("Expected no requests, got: %v", client.Actions())
+	}
+}
+
+func newLogSink(namespace, name string, spec v1alpha1.SinkSpec) v1alpha1.LogSink {
+	return v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       spec,
+	}
+}
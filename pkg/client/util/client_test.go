@@ -0,0 +1,37 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util_test
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/knative/observability/pkg/client/util"
+)
+
+func TestNewInClusterClientsetOutsideCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+
+	cs, err := util.NewInClusterClientset()
+	if err != rest.ErrNotInCluster {
+		t.Errorf("Error not equal: Expected: %v, Actual: %v", rest.ErrNotInCluster, err)
+	}
+	if cs != nil {
+		t.Errorf("Expected no clientset, got %v", cs)
+	}
+}
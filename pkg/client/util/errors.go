@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Category classifies a failed client operation by what the caller can do
+// about it.
+type Category string
+
+const (
+	// CategoryUnknown is an error the other categories do not cover, e.g.
+	// a forbidden request or a done context.
+	CategoryUnknown Category = "Unknown"
+	// CategoryNotFound is an object that does not exist.
+	CategoryNotFound Category = "NotFound"
+	// CategoryConflict is an object that already exists or was modified
+	// since it was read. It succeeds once the object is read again.
+	CategoryConflict Category = "Conflict"
+	// CategoryValidation is a request the API server rejected as invalid,
+	// it fails the same way again.
+	CategoryValidation Category = "Validation"
+	// CategoryTransient is an API server that is unavailable, overloaded
+	// or timed out, or that could not be reached. The same request may
+	// succeed when it is retried.
+	CategoryTransient Category = "Transient"
+)
+
+// ClientError is an error of the clientset along with its category.
+type ClientError struct {
+	Category Category
+	Err      error
+}
+
+func (e *ClientError) Error() string {
+	return e.Err.Error()
+}
+
+// WrapError returns the error of a client operation as a *ClientError, nil
+// stays nil.
+func WrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*ClientError); ok {
+		return err
+	}
+	return &ClientError{Category: Classify(err), Err: err}
+}
+
+// Classify returns the category of the error of a client operation, either
+// a *ClientError or an error of the clientset.
+func Classify(err error) Category {
+	if ce, ok := err.(*ClientError); ok {
+		return ce.Category
+	}
+	switch {
+	case err == nil:
+		return CategoryUnknown
+	case apierrors.IsNotFound(err):
+		return CategoryNotFound
+	case apierrors.IsConflict(err),
+		apierrors.IsAlreadyExists(err):
+		return CategoryConflict
+	case apierrors.IsInvalid(err),
+		apierrors.IsBadRequest(err):
+		return CategoryValidation
+	case apierrors.IsServiceUnavailable(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsTooManyRequests(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsUnexpectedServerError(err):
+		return CategoryTransient
+	}
+	// The clientset returns the errors of the connection as they are.
+	if _, ok := err.(net.Error); ok {
+		return CategoryTransient
+	}
+	return CategoryUnknown
+}
+
+// IsNotFound reports whether the error is in CategoryNotFound.
+func IsNotFound(err error) bool {
+	return Classify(err) == CategoryNotFound
+}
+
+// IsConflict reports whether the error is in CategoryConflict.
+func IsConflict(err error) bool {
+	return Classify(err) == CategoryConflict
+}
+
+// IsValidation reports whether the error is in CategoryValidation.
+func IsValidation(err error) bool {
+	return Classify(err) == CategoryValidation
+}
+
+// IsTransient reports whether the error is in CategoryTransient, so
+// retrying the operation may succeed.
+func IsTransient(err error) bool {
+	return Classify(err) == CategoryTransient
+}
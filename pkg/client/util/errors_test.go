@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ktesting "k8s.io/client-go/testing"
+
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/client/util"
+)
+
+var logSinkResource = schema.GroupResource{Group: "observability.knative.dev", Resource: "logsinks"}
+
+func TestClassifyServerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		category util.Category
+	}{
+		{"not found", apierrors.NewNotFound(logSinkResource, "some-sink"), util.CategoryNotFound},
+		{"conflict", apierrors.NewConflict(logSinkResource, "some-sink", errors.New("modified")), util.CategoryConflict},
+		{"already exists", apierrors.NewAlreadyExists(logSinkResource, "some-sink"), util.CategoryConflict},
+		{
+			"invalid",
+			apierrors.NewInvalid(
+				schema.GroupKind{Group: "observability.knative.dev", Kind: "LogSink"},
+				"some-sink",
+				field.ErrorList{field.Invalid(field.NewPath("spec", "port"), 0, "must be set")},
+			),
+			util.CategoryValidation,
+		},
+		{"bad request", apierrors.NewBadRequest("malformed"), util.CategoryValidation},
+		{"service unavailable", apierrors.NewServiceUnavailable("etcd is unavailable"), util.CategoryTransient},
+		{"server timeout", apierrors.NewServerTimeout(logSinkResource, "get", 1), util.CategoryTransient},
+		{"timeout", apierrors.NewTimeoutError("request timed out", 1), util.CategoryTransient},
+		{"too many requests", apierrors.NewTooManyRequests("slow down", 1), util.CategoryTransient},
+		{"internal error", apierrors.NewInternalError(errors.New("panic")), util.CategoryTransient},
+		{"unexpected server error", apierrors.NewGenericServerResponse(502, "get", logSinkResource, "some-sink", "", 0, true), util.CategoryTransient},
+		{"forbidden", apierrors.NewForbidden(logSinkResource, "some-sink", errors.New("denied")), util.CategoryUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("get", "logsinks", func(ktesting.Action) (bool, runtime.Object, error) {
+				return true, nil, test.err
+			})
+
+			_, err := client.ObservabilityV1alpha1().LogSinks("test-ns").Get("some-sink", metav1.GetOptions{})
+			if c := util.Classify(err); c != test.category {
+				t.Errorf("Category not equal: Expected: %s, Actual: %s", test.category, c)
+			}
+
+			predicates := map[util.Category]func(error) bool{
+				util.CategoryNotFound:   util.IsNotFound,
+				util.CategoryConflict:   util.IsConflict,
+				util.CategoryValidation: util.IsValidation,
+				util.CategoryTransient:  util.IsTransient,
+			}
+			for c, is := range predicates {
+				if is(err) != (c == test.category) {
+					t.Errorf("Expected Is%s to be %t", c, c == test.category)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyClientErrors(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	if !util.IsTransient(dial) {
+		t.Errorf("Expected an unreachable API server to be transient")
+	}
+
+	for _, err := range []error{nil, context.Canceled, errors.New("some error"), &util.TimeoutError{Timeout: time.Second}} {
+		if c := util.Classify(err); c != util.CategoryUnknown {
+			t.Errorf("Expected %v to be of category Unknown, got: %s", err, c)
+		}
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	if util.WrapError(nil) != nil {
+		t.Errorf("Expected a nil error to stay nil")
+	}
+
+	notFound := apierrors.NewNotFound(logSinkResource, "some-sink")
+	err := util.WrapError(notFound)
+	ce, ok := err.(*util.ClientError)
+	if !ok {
+		t.Fatalf("Expected a ClientError, got: %T", err)
+	}
+	if ce.Category != util.CategoryNotFound || ce.Err != notFound {
+		t.Errorf("Unexpected ClientError: %+v", ce)
+	}
+	if err.Error() != notFound.Error() {
+		t.Errorf("Expected the message of the wrapped error: %s", err)
+	}
+	if !util.IsNotFound(err) {
+		t.Errorf("Expected predicates to classify the wrapped error")
+	}
+	if util.WrapError(err) != err {
+		t.Errorf("Expected a ClientError not to be wrapped again")
+	}
+}
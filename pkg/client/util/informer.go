@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned"
+	informers "github.com/knative/observability/pkg/client/informers/externalversions"
+	listers "github.com/knative/observability/pkg/client/listers/sink/v1alpha1"
+)
+
+// SinkInformer caches the LogSinks in all namespaces and the
+// ClusterLogSinks, so tools reading them repeatedly watch the API server
+// once instead of listing the sinks every time. The sinks it returns are
+// shared with the cache and must not be modified, DeepCopy them first.
+type SinkInformer struct {
+	factory           informers.SharedInformerFactory
+	sinks             cache.SharedIndexInformer
+	clusterSinks      cache.SharedIndexInformer
+	sinkLister        listers.LogSinkLister
+	clusterSinkLister listers.ClusterLogSinkLister
+}
+
+// NewSinkInformer returns a SinkInformer watching the sinks with client.
+// With a non-zero resync the event handlers are also called with every
+// cached sink at that interval. It does not watch until Start is called.
+func NewSinkInformer(client versioned.Interface, resync time.Duration) *SinkInformer {
+	factory := informers.NewSharedInformerFactory(client, resync)
+	sinks := factory.Observability().V1alpha1().LogSinks()
+	clusterSinks := factory.Observability().V1alpha1().ClusterLogSinks()
+	return &SinkInformer{
+		factory:           factory,
+		sinks:             sinks.Informer(),
+		clusterSinks:      clusterSinks.Informer(),
+		sinkLister:        sinks.Lister(),
+		clusterSinkLister: clusterSinks.Lister(),
+	}
+}
+
+// Start watches the sinks until stopCh is closed. It does not wait for the
+// cache to fill, see WaitForCacheSync.
+func (i *SinkInformer) Start(stopCh <-chan struct{}) {
+	i.factory.Start(stopCh)
+}
+
+// WaitForCacheSync blocks until the cache holds the sinks the API server
+// listed first and reports whether it does, it returns false when stopCh is
+// closed first. Get and List only return sinks that were cached.
+func (i *SinkInformer) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	return cache.WaitForCacheSync(stopCh, i.sinks.HasSynced, i.clusterSinks.HasSynced)
+}
+
+// AddLogSinkEventHandler calls h when a LogSink is added, updated or
+// deleted. Handlers added after Start are first called with the cached
+// LogSinks as added.
+func (i *SinkInformer) AddLogSinkEventHandler(h cache.ResourceEventHandler) {
+	i.sinks.AddEventHandler(h)
+}
+
+// AddClusterLogSinkEventHandler calls h when a ClusterLogSink is added,
+// updated or deleted. Handlers added after Start are first called with the
+// cached ClusterLogSinks as added.
+func (i *SinkInformer) AddClusterLogSinkEventHandler(h cache.ResourceEventHandler) {
+	i.clusterSinks.AddEventHandler(h)
+}
+
+// GetLogSink returns the cached LogSink, or a NotFound error if it is not
+// cached.
+func (i *SinkInformer) GetLogSink(namespace, name string) (*v1alpha1.LogSink, error) {
+	return i.sinkLister.LogSinks(namespace).Get(name)
+}
+
+// ListLogSinks returns the cached LogSinks of the namespace whose labels
+// match the selector. An empty namespace lists the LogSinks of every
+// namespace and labels.Everything() selects all of them.
+func (i *SinkInformer) ListLogSinks(namespace string, selector labels.Selector) ([]*v1alpha1.LogSink, error) {
+	if namespace == metav1.NamespaceAll {
+		return i.sinkLister.List(selector)
+	}
+	return i.sinkLister.LogSinks(namespace).List(selector)
+}
+
+// GetClusterLogSink returns the cached ClusterLogSink, or a NotFound error
+// if it is not cached.
+func (i *SinkInformer) GetClusterLogSink(name string) (*v1alpha1.ClusterLogSink, error) {
+	// The generated lister looks ClusterLogSinks up by namespace, their
+	// keys in the cache are their names.
+	obj, ok, err := i.clusterSinks.GetIndexer().GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, apierrors.NewNotFound(v1alpha1.Resource("clusterlogsink"), name)
+	}
+	return obj.(*v1alpha1.ClusterLogSink), nil
+}
+
+// ListClusterLogSinks returns the cached ClusterLogSinks whose labels match
+// the selector.
+func (i *SinkInformer) ListClusterLogSinks(selector labels.Selector) ([]*v1alpha1.ClusterLogSink, error) {
+	return i.clusterSinkLister.List(selector)
+}
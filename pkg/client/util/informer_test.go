@@ -0,0 +1,249 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/client/util"
+)
+
+func TestSinkInformerLogSinks(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "listed", Namespace: "ns1"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informer, events := startSinkInformer(t, client, stopCh)
+	sinks := client.ObservabilityV1alpha1().LogSinks("ns2")
+
+	// The sinks listed first are cached once the cache synced.
+	awaitEvent(t, events, "add ns1/listed")
+	if _, err := informer.GetLogSink("ns1", "listed"); err != nil {
+		t.Errorf("Expected the listed LogSink to be cached: %s", err)
+	}
+
+	_, err := sinks.Create(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "ns2", Labels: map[string]string{"team": "a"}},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(t, events, "add ns2/some-name")
+	s, err := informer.GetLogSink("ns2", "some-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Spec.Port != 514 {
+		t.Errorf("Port not equal: Expected: 514 Actual: %d", s.Spec.Port)
+	}
+	assertLogSinks(t, informer, "", labels.Everything(), 2)
+	assertLogSinks(t, informer, "ns2", labels.Everything(), 1)
+	assertLogSinks(t, informer, "", labels.SelectorFromSet(labels.Set{"team": "a"}), 1)
+
+	updated := s.DeepCopy()
+	updated.Spec.Port = 6514
+	if _, err := sinks.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(t, events, "update ns2/some-name")
+	s, err = informer.GetLogSink("ns2", "some-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Spec.Port != 6514 {
+		t.Errorf("Port not equal: Expected: 6514 Actual: %d", s.Spec.Port)
+	}
+
+	if err := sinks.Delete("some-name", &metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(t, events, "delete ns2/some-name")
+	if _, err := informer.GetLogSink("ns2", "some-name"); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the deleted LogSink to be not found: %v", err)
+	}
+	assertLogSinks(t, informer, "", labels.Everything(), 1)
+}
+
+func TestSinkInformerClusterLogSinks(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informer, events := startSinkInformer(t, client, stopCh)
+	sinks := client.ObservabilityV1alpha1().ClusterLogSinks("")
+
+	_, err := sinks.Create(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(t, events, "add some-name")
+	s, err := informer.GetClusterLogSink("some-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertClusterLogSinks(t, informer, 1)
+
+	updated := s.DeepCopy()
+	updated.Spec.Host = "example.org"
+	if _, err := sinks.Update(updated); err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(t, events, "update some-name")
+	eventually(t, func() bool {
+		s, err := informer.GetClusterLogSink("some-name")
+		return err == nil && s.Spec.Host == "example.org"
+	}, "Expected the cached ClusterLogSink to be updated")
+
+	if err := sinks.Delete("some-name", &metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	awaitEvent(t, events, "delete some-name")
+	if _, err := informer.GetClusterLogSink("some-name"); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the deleted ClusterLogSink to be not found: %v", err)
+	}
+	assertClusterLogSinks(t, informer, 0)
+}
+
+func TestSinkInformerStopped(t *testing.T) {
+	informer := util.NewSinkInformer(fake.NewSimpleClientset(), 0)
+	stopCh := make(chan struct{})
+	close(stopCh)
+
+	if informer.WaitForCacheSync(stopCh) {
+		t.Errorf("Expected the cache not to sync without being started")
+	}
+}
+
+// startSinkInformer starts a SinkInformer until stopCh is closed and
+// returns it along with the events of its handlers, e.g. "add
+// ns1/some-name" or "delete some-name". It returns once the informer
+// watches both kinds of sinks, the fake watches miss the events before
+// that.
+func startSinkInformer(t *testing.T, client *fake.Clientset, stopCh <-chan struct{}) (*util.SinkInformer, <-chan string) {
+	t.Helper()
+	events := make(chan string, 10)
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			events <- "add " + objectKey(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			events <- "update " + objectKey(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			events <- "delete " + objectKey(obj)
+		},
+	}
+
+	informer := util.NewSinkInformer(client, 0)
+	informer.AddLogSinkEventHandler(handler)
+	informer.AddClusterLogSinkEventHandler(handler)
+	informer.Start(stopCh)
+	if !informer.WaitForCacheSync(stopCh) {
+		t.Fatal("Cache did not sync")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for watches(client.Actions()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Informer did not watch the sinks")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return informer, events
+}
+
+func watches(actions []clienttesting.Action) int {
+	var n int
+	for _, a := range actions {
+		if a.GetVerb() == "watch" {
+			n++
+		}
+	}
+	return n
+}
+
+func objectKey(obj interface{}) string {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return err.Error()
+	}
+	return key
+}
+
+// awaitEvent skips the events until the expected one. The fake clientset
+// sends the watch events of ClusterLogSinks twice, the second add is
+// handled as an update.
+func awaitEvent(t *testing.T, events <-chan string, expected string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e == expected {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for event %s", expected)
+		}
+	}
+}
+
+func eventually(t *testing.T, f func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func assertLogSinks(t *testing.T, informer *util.SinkInformer, namespace string, selector labels.Selector, expected int) {
+	t.Helper()
+	sinks, err := informer.ListLogSinks(namespace, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sinks) != expected {
+		t.Errorf("LogSinks in namespace %q matching %q not equal: Expected: %d Actual: %d", namespace, selector, expected, len(sinks))
+	}
+}
+
+func assertClusterLogSinks(t *testing.T, informer *util.SinkInformer, expected int) {
+	t.Helper()
+	sinks, err := informer.ListClusterLogSinks(labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sinks) != expected {
+		t.Errorf("ClusterLogSinks not equal: Expected: %d Actual: %d", expected, len(sinks))
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1alpha1i "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+// SinkSummary describes a single receiver of a LogSink or ClusterLogSink.
+// A sink with several destinations has a summary for each of them.
+type SinkSummary struct {
+	// Kind is LogSink or ClusterLogSink.
+	Kind string
+	// Namespace is empty for ClusterLogSinks.
+	Namespace string
+	Name      string
+	// Type is the type of the destination, which may differ from the
+	// type of the sink.
+	Type string
+	// Destination is the host:port of syslog and otlp receivers, or the
+	// socket path of syslog receivers on the nodes, and the URI of http
+	// receivers.
+	Destination string
+}
+
+// ListAllSinks returns a summary of every receiver of the LogSinks in all
+// namespaces and of the ClusterLogSinks. They are sorted by namespace and
+// name, which puts the ClusterLogSinks first, with the destinations of a
+// sink in the order they are rendered.
+func ListAllSinks(
+	ctx context.Context,
+	client v1alpha1i.ObservabilityV1alpha1Interface,
+) ([]SinkSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sinks, err := client.LogSinks(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	clusterSinks, err := client.ClusterLogSinks("").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []SinkSummary
+	for _, s := range sinks.Items {
+		summaries = append(summaries, summarize("LogSink", s.Namespace, s.Name, s.Spec)...)
+	}
+	for _, s := range clusterSinks.Items {
+		summaries = append(summaries, summarize("ClusterLogSink", "", s.Name, s.Spec)...)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return summaries, nil
+}
+
+func summarize(kind, namespace, name string, spec v1alpha1.SinkSpec) []SinkSummary {
+	var summaries []SinkSummary
+	for _, d := range sink.Destinations(spec) {
+		summaries = append(summaries, SinkSummary{
+			Kind:        kind,
+			Namespace:   namespace,
+			Name:        name,
+			Type:        d.Type,
+			Destination: destination(d),
+		})
+	}
+	return summaries
+}
+
+func destination(spec v1alpha1.SinkSpec) string {
+	switch spec.Type {
+	case v1alpha1.SinkTypeHTTP:
+		return spec.URI
+	case v1alpha1.SinkTypeOTLP:
+		return spec.Endpoint
+	case v1alpha1.SinkTypeKafka:
+		return strings.Join(spec.Brokers, ",")
+	case v1alpha1.SinkTypeLoki:
+		return spec.URL
+	case v1alpha1.SinkTypeS3:
+		return "s3://" + spec.Bucket
+	case v1alpha1.SinkTypeSplunk:
+		return sink.HostPort(spec.Host, sink.SplunkPort(spec.Port))
+	case v1alpha1.SinkTypeDatadog:
+		return sink.DatadogHost(spec.Site)
+	case v1alpha1.SinkTypeForward:
+		return sink.HostPort(spec.Host, sink.ForwardPort(spec.Port))
+	case v1alpha1.SinkTypeGELF:
+		return sink.HostPort(spec.Host, sink.GELFPort(spec.Port))
+	default:
+		if spec.SocketPath != "" {
+			return spec.SocketPath
+		}
+		return sink.HostPort(spec.Host, spec.Port)
+	}
+}
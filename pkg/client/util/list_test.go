@@ -0,0 +1,150 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktesting "k8s.io/client-go/testing"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/client/util"
+)
+
+func TestListAllSinks(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-b", Namespace: "ns-1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.com",
+				Port: 514,
+				Destinations: []v1alpha1.Destination{
+					{Type: "http", Host: "backup.example.com", Port: 8080},
+				},
+			},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-a", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-a", Namespace: "ns-1"},
+			Spec:       v1alpha1.SinkSpec{Type: "otlp", Endpoint: "collector:4318"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-c", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "kafka", Brokers: []string{"kafka-0:9092", "kafka-1:9092"}, Topic: "logs"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-d", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "loki", URL: "http://loki.logging:3100"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-e", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "s3", Bucket: "team-logs", Region: "us-east-1"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-f", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "splunk", Host: "hec.example.com"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-h", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "datadog", Site: "datadoghq.eu"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-i", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "forward", Host: "fluentd.logging"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-j", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "gelf", Host: "graylog.logging"},
+		},
+		&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "sink-g", Namespace: "ns-2"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock"},
+		},
+		&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "cluster.example.com", Port: 601},
+		},
+	)
+
+	summaries, err := util.ListAllSinks(context.Background(), client.ObservabilityV1alpha1())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []util.SinkSummary{
+		{Kind: "ClusterLogSink", Name: "cluster-sink", Type: "syslog", Destination: "cluster.example.com:601"},
+		{Kind: "LogSink", Namespace: "ns-1", Name: "sink-a", Type: "otlp", Destination: "collector:4318"},
+		{Kind: "LogSink", Namespace: "ns-1", Name: "sink-b", Type: "syslog", Destination: "example.com:514"},
+		{Kind: "LogSink", Namespace: "ns-1", Name: "sink-b", Type: "http", Destination: "http://backup.example.com:8080/"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-a", Type: "http", Destination: "https://example.com/logs"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-c", Type: "kafka", Destination: "kafka-0:9092,kafka-1:9092"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-d", Type: "loki", Destination: "http://loki.logging:3100"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-e", Type: "s3", Destination: "s3://team-logs"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-f", Type: "splunk", Destination: "hec.example.com:8088"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-g", Type: "syslog", Destination: "/var/run/collector/syslog.sock"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-h", Type: "datadog", Destination: "http-intake.logs.datadoghq.eu"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-i", Type: "forward", Destination: "fluentd.logging:24224"},
+		{Kind: "LogSink", Namespace: "ns-2", Name: "sink-j", Type: "gelf", Destination: "graylog.logging:12201"},
+	}
+	if diff := cmp.Diff(expected, summaries); diff != "" {
+		t.Errorf("Summaries not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestListAllSinksEmpty(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	summaries, err := util.ListAllSinks(context.Background(), client.ObservabilityV1alpha1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 0 {
+		t.Errorf("Expected no summaries, got: %v", summaries)
+	}
+}
+
+func TestListAllSinksError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "clusterlogsinks", func(ktesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("some error")
+	})
+
+	_, err := util.ListAllSinks(context.Background(), client.ObservabilityV1alpha1())
+	if err == nil || err.Error() != "some error" {
+		t.Errorf("Expected the list error, got: %v", err)
+	}
+}
+
+func TestListAllSinksContextDone(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := util.ListAllSinks(ctx, client.ObservabilityV1alpha1())
+	if err != context.Canceled {
+		t.Errorf("Expected context error, got: %v", err)
+	}
+}
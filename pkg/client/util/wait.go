@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1alpha1i "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+)
+
+// TimeoutError is returned when a sink does not become Ready in time.
+type TimeoutError struct {
+	Namespace string
+	Name      string
+	Timeout   time.Duration
+	// Condition is the last Ready condition observed, nil if the sink
+	// never had one.
+	Condition *v1alpha1.Condition
+}
+
+func (e *TimeoutError) Error() string {
+	last := "no Ready condition"
+	if c := e.Condition; c != nil {
+		last = fmt.Sprintf("Ready=%s", c.Status)
+		if c.Reason != "" || c.Message != "" {
+			last += fmt.Sprintf(" (%s: %s)", c.Reason, c.Message)
+		}
+	}
+	return fmt.Sprintf(
+		"timed out after %s waiting for LogSink %s/%s to become Ready, last observed %s",
+		e.Timeout,
+		e.Namespace,
+		e.Name,
+		last,
+	)
+}
+
+// WaitForLogSinkReady blocks until the LogSink's Ready condition is True and
+// returns the sink. It returns a *TimeoutError when the timeout elapses first
+// and the context's error when it is done first.
+func WaitForLogSinkReady(
+	ctx context.Context,
+	client v1alpha1i.LogSinksGetter,
+	namespace string,
+	name string,
+	timeout time.Duration,
+) (*v1alpha1.LogSink, error) {
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sinks := client.LogSinks(namespace)
+	for {
+		s, err := sinks.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		last := s.Status.GetCondition(v1alpha1.SinkConditionReady)
+		if ready(last) {
+			return s, nil
+		}
+
+		w, err := sinks.Watch(metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion: s.ResourceVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s, last = waitForReady(wctx, w, name, last)
+		w.Stop()
+		if s != nil {
+			return s, nil
+		}
+
+		if wctx.Err() != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, &TimeoutError{
+				Namespace: namespace,
+				Name:      name,
+				Timeout:   timeout,
+				Condition: last,
+			}
+		}
+		// The watch ended early so start over from the current state.
+	}
+}
+
+// waitForReady consumes events until the sink is Ready, the watch ends or
+// the context is done. It returns a nil sink unless the sink became Ready
+// along with the last Ready condition it observed.
+func waitForReady(
+	ctx context.Context,
+	w watch.Interface,
+	name string,
+	last *v1alpha1.Condition,
+) (*v1alpha1.LogSink, *v1alpha1.Condition) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, last
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				return nil, last
+			}
+			s, isSink := e.Object.(*v1alpha1.LogSink)
+			if !isSink || s.Name != name {
+				continue
+			}
+			if e.Type == watch.Deleted {
+				last = nil
+				continue
+			}
+			last = s.Status.GetCondition(v1alpha1.SinkConditionReady)
+			if ready(last) {
+				return s, last
+			}
+		}
+	}
+}
+
+func ready(c *v1alpha1.Condition) bool {
+	return c != nil && c.Status == coreV1.ConditionTrue
+}
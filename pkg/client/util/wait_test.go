@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/client/util"
+)
+
+func TestWaitForLogSinkReadyAlreadyReady(t *testing.T) {
+	client := fake.NewSimpleClientset(logSink(coreV1.ConditionTrue))
+
+	s, err := util.WaitForLogSinkReady(
+		context.Background(),
+		client.ObservabilityV1alpha1(),
+		"test-ns",
+		"some-sink",
+		time.Second,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "some-sink" {
+		t.Errorf("Unexpected sink: %s", s.Name)
+	}
+}
+
+func TestWaitForLogSinkReadyWatchesForReady(t *testing.T) {
+	client := fake.NewSimpleClientset(logSink(coreV1.ConditionFalse))
+
+	done := make(chan error)
+	go func() {
+		_, err := util.WaitForLogSinkReady(
+			context.Background(),
+			client.ObservabilityV1alpha1(),
+			"test-ns",
+			"some-sink",
+			5*time.Second,
+		)
+		done <- err
+	}()
+
+	// The fake watch only delivers events sent after it started.
+	for len(client.Actions()) < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err := client.ObservabilityV1alpha1().LogSinks("test-ns").UpdateStatus(logSink(coreV1.ConditionTrue))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected wait to return once the sink is Ready")
+	}
+}
+
+func TestWaitForLogSinkReadyTimeout(t *testing.T) {
+	client := fake.NewSimpleClientset(logSink(coreV1.ConditionFalse))
+
+	_, err := util.WaitForLogSinkReady(
+		context.Background(),
+		client.ObservabilityV1alpha1(),
+		"test-ns",
+		"some-sink",
+		50*time.Millisecond,
+	)
+
+	terr, ok := err.(*util.TimeoutError)
+	if !ok {
+		t.Fatalf("Expected a TimeoutError, got: %v", err)
+	}
+	if terr.Condition == nil || terr.Condition.Reason != "ConnectionRefused" {
+		t.Errorf("Expected the last condition, got: %v", terr.Condition)
+	}
+	if !strings.Contains(err.Error(), "Ready=False (ConnectionRefused: some message)") {
+		t.Errorf("Expected message to include the last condition: %s", err)
+	}
+}
+
+func TestWaitForLogSinkReadyContextDone(t *testing.T) {
+	client := fake.NewSimpleClientset(logSink(coreV1.ConditionFalse))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := util.WaitForLogSinkReady(
+		ctx,
+		client.ObservabilityV1alpha1(),
+		"test-ns",
+		"some-sink",
+		time.Minute,
+	)
+	if err != context.Canceled {
+		t.Errorf("Expected context error, got: %v", err)
+	}
+}
+
+func TestWaitForLogSinkReadyNotFound(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	_, err := util.WaitForLogSinkReady(
+		context.Background(),
+		client.ObservabilityV1alpha1(),
+		"test-ns",
+		"some-sink",
+		time.Second,
+	)
+	if err == nil {
+		t.Error("Expected an error for a missing sink")
+	}
+}
+
+func logSink(status coreV1.ConditionStatus) *v1alpha1.LogSink {
+	s := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "test-ns",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 514,
+		},
+	}
+	c := v1alpha1.Condition{
+		Type:   v1alpha1.SinkConditionReady,
+		Status: status,
+	}
+	if status == coreV1.ConditionFalse {
+		c.Reason = "ConnectionRefused"
+		c.Message = "some message"
+	}
+	s.Status.SetCondition(c)
+	return s
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package leader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"time"
+
+	coordinationV1beta1 "k8s.io/api/coordination/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LeaseClient gets and writes the Lease held by the leader. A
+// coordinationv1beta1.LeaseInterface satisfies it.
+type LeaseClient interface {
+	Get(name string, options metav1.GetOptions) (*coordinationV1beta1.Lease, error)
+	Create(*coordinationV1beta1.Lease) (*coordinationV1beta1.Lease, error)
+	Update(*coordinationV1beta1.Lease) (*coordinationV1beta1.Lease, error)
+}
+
+// Elector elects a single leader among the replicas sharing a Lease. The
+// vendored client-go predates the Lease lock of its leaderelection package,
+// so the Lease is written here with the same semantics.
+type Elector struct {
+	leases   LeaseClient
+	name     string
+	identity string
+	duration time.Duration
+	retry    time.Duration
+
+	// The expiry of a Lease held by another replica is measured from when
+	// this replica last saw it change rather than from its renew time, so
+	// skewed clocks cannot cut a leader's term short.
+	observed     *coordinationV1beta1.LeaseSpec
+	observedTime time.Time
+}
+
+// NewElector returns an Elector competing for the Lease of the name as
+// identity. The leader renews the Lease well within the duration, the
+// other replicas take it over once it was not renewed for the duration.
+func NewElector(leases LeaseClient, name, identity string, duration time.Duration) *Elector {
+	return &Elector{
+		leases:   leases,
+		name:     name,
+		identity: identity,
+		duration: duration,
+		retry:    duration / 5,
+	}
+}
+
+// Run blocks until the replica acquires the Lease and then calls lead.
+// The channel passed to lead is closed when stopCh is closed or when the
+// Lease could not be renewed before another replica could acquire it. In
+// the former case Run keeps renewing the Lease until lead returns and only
+// then releases it, so no other replica leads while lead still writes. Run
+// returns an error in the latter case, the replica should then exit rather
+// than keep writing alongside the new leader.
+func (e *Elector) Run(stopCh <-chan struct{}, lead func(stopCh <-chan struct{})) error {
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+	for !e.tryAcquireOrRenew() {
+		select {
+		case <-stopCh:
+			return nil
+		case <-ticker.C:
+		}
+	}
+	log.Printf("acquired lease %s as %s", e.name, e.identity)
+
+	leading := make(chan struct{})
+	led := make(chan struct{})
+	go func() {
+		defer close(led)
+		lead(leading)
+	}()
+
+	var (
+		renewed  = time.Now()
+		deadline = e.duration * 2 / 3
+		stop     = stopCh
+		done     <-chan struct{}
+	)
+	for {
+		select {
+		case <-stop:
+			close(leading)
+			stop, done = nil, led
+			continue
+		case <-done:
+			e.release()
+			return nil
+		case <-ticker.C:
+		}
+		if e.tryAcquireOrRenew() {
+			renewed = time.Now()
+			continue
+		}
+		if time.Since(renewed) > deadline {
+			if done == nil {
+				close(leading)
+			}
+			return fmt.Errorf("lost lease %s: not renewed for %s", e.name, time.Since(renewed))
+		}
+	}
+}
+
+// tryAcquireOrRenew writes the Lease with this replica as its holder unless
+// another replica holds it, and reports whether this replica is the leader.
+func (e *Elector) tryAcquireOrRenew() bool {
+	now := time.Now()
+	lease, err := e.leases.Get(e.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease, err = e.leases.Create(&coordinationV1beta1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: e.name},
+			Spec:       e.spec(now, coordinationV1beta1.LeaseSpec{}),
+		})
+		if err != nil {
+			log.Printf("unable to create lease %s: %s", e.name, err)
+			return false
+		}
+		e.observe(lease.Spec, now)
+		return true
+	}
+	if err != nil {
+		log.Printf("unable to get lease %s: %s", e.name, err)
+		return false
+	}
+
+	e.observe(lease.Spec, now)
+	holder := holderIdentity(lease.Spec)
+	if holder != "" && holder != e.identity && now.Before(e.observedTime.Add(e.duration)) {
+		return false
+	}
+
+	lease = lease.DeepCopy()
+	lease.Spec = e.spec(now, lease.Spec)
+	// The resource version of the Lease fails the update when another
+	// replica wrote it since it was read.
+	lease, err = e.leases.Update(lease)
+	if err != nil {
+		log.Printf("unable to update lease %s: %s", e.name, err)
+		return false
+	}
+	e.observe(lease.Spec, now)
+	return true
+}
+
+// release clears the holder of the Lease so another replica can acquire it
+// without waiting for it to expire.
+func (e *Elector) release() {
+	lease, err := e.leases.Get(e.name, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("unable to release lease %s: %s", e.name, err)
+		return
+	}
+	if holderIdentity(lease.Spec) != e.identity {
+		return
+	}
+	lease = lease.DeepCopy()
+	lease.Spec.HolderIdentity = nil
+	if _, err := e.leases.Update(lease); err != nil {
+		log.Printf("unable to release lease %s: %s", e.name, err)
+	}
+}
+
+// spec returns the current spec of the Lease renewed by this replica. A
+// replica taking the Lease over starts a new term.
+func (e *Elector) spec(now time.Time, current coordinationV1beta1.LeaseSpec) coordinationV1beta1.LeaseSpec {
+	var (
+		identity = e.identity
+		seconds  = int32(math.Ceil(e.duration.Seconds()))
+		t        = metav1.NewMicroTime(now)
+		spec     = coordinationV1beta1.LeaseSpec{
+			HolderIdentity:       &identity,
+			LeaseDurationSeconds: &seconds,
+			AcquireTime:          current.AcquireTime,
+			RenewTime:            &t,
+			LeaseTransitions:     current.LeaseTransitions,
+		}
+	)
+	if holderIdentity(current) != e.identity {
+		var transitions int32
+		if current.LeaseTransitions != nil {
+			transitions = *current.LeaseTransitions
+		}
+		// A Lease renewed before was held by another replica.
+		if current.RenewTime != nil {
+			transitions++
+		}
+		spec.AcquireTime = &t
+		spec.LeaseTransitions = &transitions
+	}
+	return spec
+}
+
+func (e *Elector) observe(spec coordinationV1beta1.LeaseSpec, now time.Time) {
+	if e.observed != nil && reflect.DeepEqual(*e.observed, spec) {
+		return
+	}
+	e.observed = spec.DeepCopy()
+	e.observedTime = now
+}
+
+func holderIdentity(spec coordinationV1beta1.LeaseSpec) string {
+	if spec.HolderIdentity == nil {
+		return ""
+	}
+	return *spec.HolderIdentity
+}
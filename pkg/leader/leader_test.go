@@ -0,0 +1,290 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package leader_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	coordinationV1beta1 "k8s.io/api/coordination/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/knative/observability/pkg/leader"
+)
+
+const leaseDuration = 250 * time.Millisecond
+
+func TestElectorLeads(t *testing.T) {
+	leases := newSpyLeaseClient()
+	e := leader.NewElector(leases, "sink-controller", "replica-a", leaseDuration)
+
+	stopCh := make(chan struct{})
+	leading := make(chan (<-chan struct{}), 1)
+	done := make(chan error)
+	go func() {
+		done <- e.Run(stopCh, func(stopCh <-chan struct{}) { leading <- stopCh })
+	}()
+
+	var leadStop <-chan struct{}
+	select {
+	case leadStop = <-leading:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the only replica to lead")
+	}
+	if h := leases.holder(); h != "replica-a" {
+		t.Errorf("Expected the lease to be held by replica-a, was %q", h)
+	}
+
+	close(stopCh)
+	if err := <-done; err != nil {
+		t.Errorf("Expected no error when stopped, got %s", err)
+	}
+	select {
+	case <-leadStop:
+	default:
+		t.Error("Expected the leader to be stopped")
+	}
+	if h := leases.holder(); h != "" {
+		t.Errorf("Expected the lease to be released, was held by %q", h)
+	}
+}
+
+func TestElectorReleasesLeaseOnceLeadReturned(t *testing.T) {
+	leases := newSpyLeaseClient()
+	e := leader.NewElector(leases, "sink-controller", "replica-a", leaseDuration)
+
+	stopCh := make(chan struct{})
+	leading := make(chan struct{})
+	holders := make(chan string, 1)
+	done := make(chan error)
+	go func() {
+		done <- e.Run(stopCh, func(stopCh <-chan struct{}) {
+			close(leading)
+			<-stopCh
+			// A final write outlasting the lease duration.
+			time.Sleep(2 * leaseDuration)
+			holders <- leases.holder()
+		})
+	}()
+	select {
+	case <-leading:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the only replica to lead")
+	}
+
+	close(stopCh)
+	if err := <-done; err != nil {
+		t.Errorf("Expected no error when stopped, got %s", err)
+	}
+	select {
+	case h := <-holders:
+		if h != "replica-a" {
+			t.Errorf("Expected the lease to be held until lead returned, was held by %q", h)
+		}
+	default:
+		t.Fatal("Expected Run to return after lead")
+	}
+	if h := leases.holder(); h != "" {
+		t.Errorf("Expected the lease to be released, was held by %q", h)
+	}
+}
+
+func TestStandbyDoesNotLead(t *testing.T) {
+	leases := newSpyLeaseClient()
+	a := leader.NewElector(leases, "sink-controller", "replica-a", leaseDuration)
+	b := leader.NewElector(leases, "sink-controller", "replica-b", leaseDuration)
+
+	stopA := make(chan struct{})
+	leadingA := make(chan struct{}, 1)
+	doneA := make(chan error)
+	go func() {
+		doneA <- a.Run(stopA, func(<-chan struct{}) { leadingA <- struct{}{} })
+	}()
+	select {
+	case <-leadingA:
+	case <-time.After(time.Second):
+		t.Fatal("Expected replica-a to lead")
+	}
+
+	stopB := make(chan struct{})
+	defer close(stopB)
+	leadingB := make(chan struct{}, 1)
+	go b.Run(stopB, func(<-chan struct{}) { leadingB <- struct{}{} })
+
+	// The standby would write the config when it led, it must not while
+	// the leader renews the lease.
+	select {
+	case <-leadingB:
+		t.Fatal("Expected replica-b not to lead while replica-a renews the lease")
+	case <-time.After(3 * leaseDuration):
+	}
+
+	close(stopA)
+	if err := <-doneA; err != nil {
+		t.Errorf("Expected no error when stopped, got %s", err)
+	}
+	select {
+	case <-leadingB:
+	case <-time.After(leaseDuration / 2):
+		t.Fatal("Expected replica-b to lead once replica-a released the lease")
+	}
+	if h := leases.holder(); h != "replica-b" {
+		t.Errorf("Expected the lease to be held by replica-b, was %q", h)
+	}
+}
+
+func TestElectorTakesOverExpiredLease(t *testing.T) {
+	leases := newSpyLeaseClient()
+	holder := "replica-a"
+	now := metav1.NewMicroTime(time.Now())
+	leases.lease = &coordinationV1beta1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink-controller", ResourceVersion: "1"},
+		Spec: coordinationV1beta1.LeaseSpec{
+			HolderIdentity: &holder,
+			AcquireTime:    &now,
+			RenewTime:      &now,
+		},
+	}
+	b := leader.NewElector(leases, "sink-controller", "replica-b", leaseDuration)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	start := time.Now()
+	leading := make(chan struct{}, 1)
+	go b.Run(stopCh, func(<-chan struct{}) { leading <- struct{}{} })
+
+	select {
+	case <-leading:
+	case <-time.After(4 * leaseDuration):
+		t.Fatal("Expected replica-b to take over the lease that is no longer renewed")
+	}
+	if d := time.Since(start); d < leaseDuration {
+		t.Errorf("Expected replica-b to wait for the lease to expire, led after %s", d)
+	}
+	if ts := leases.transitions(); ts != 1 {
+		t.Errorf("Expected 1 lease transition, got %d", ts)
+	}
+}
+
+func TestElectorStopsLeadingWhenRenewalsFail(t *testing.T) {
+	leases := newSpyLeaseClient()
+	e := leader.NewElector(leases, "sink-controller", "replica-a", leaseDuration)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	leading := make(chan (<-chan struct{}), 1)
+	done := make(chan error)
+	go func() {
+		done <- e.Run(stopCh, func(stopCh <-chan struct{}) { leading <- stopCh })
+	}()
+	leadStop := <-leading
+
+	leases.fail()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error when the lease was lost")
+		}
+	case <-time.After(2 * leaseDuration):
+		t.Fatal("Expected replica-a to stop before its lease expires")
+	}
+	select {
+	case <-leadStop:
+	default:
+		t.Error("Expected the leader to be stopped")
+	}
+}
+
+type spyLeaseClient struct {
+	mu      sync.Mutex
+	lease   *coordinationV1beta1.Lease
+	failing bool
+}
+
+func newSpyLeaseClient() *spyLeaseClient {
+	return &spyLeaseClient{}
+}
+
+var leaseResource = schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}
+
+func (s *spyLeaseClient) Get(name string, _ metav1.GetOptions) (*coordinationV1beta1.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing {
+		return nil, apierrors.NewServiceUnavailable("unavailable")
+	}
+	if s.lease == nil {
+		return nil, apierrors.NewNotFound(leaseResource, name)
+	}
+	return s.lease.DeepCopy(), nil
+}
+
+func (s *spyLeaseClient) Create(l *coordinationV1beta1.Lease) (*coordinationV1beta1.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease != nil {
+		return nil, apierrors.NewAlreadyExists(leaseResource, l.Name)
+	}
+	s.lease = l.DeepCopy()
+	s.lease.ResourceVersion = "1"
+	return s.lease.DeepCopy(), nil
+}
+
+func (s *spyLeaseClient) Update(l *coordinationV1beta1.Lease) (*coordinationV1beta1.Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing {
+		return nil, apierrors.NewServiceUnavailable("unavailable")
+	}
+	if s.lease == nil {
+		return nil, apierrors.NewNotFound(leaseResource, l.Name)
+	}
+	if l.ResourceVersion != s.lease.ResourceVersion {
+		return nil, apierrors.NewConflict(leaseResource, l.Name, nil)
+	}
+	v, _ := strconv.Atoi(s.lease.ResourceVersion)
+	s.lease = l.DeepCopy()
+	s.lease.ResourceVersion = strconv.Itoa(v + 1)
+	return s.lease.DeepCopy(), nil
+}
+
+func (s *spyLeaseClient) holder() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease == nil || s.lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *s.lease.Spec.HolderIdentity
+}
+
+func (s *spyLeaseClient) transitions() int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lease == nil || s.lease.Spec.LeaseTransitions == nil {
+		return 0
+	}
+	return *s.lease.Spec.LeaseTransitions
+}
+
+func (s *spyLeaseClient) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = true
+}
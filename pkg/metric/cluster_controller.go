@@ -0,0 +1,70 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"reflect"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+type ClusterController struct {
+	cmp sink.ConfigMapPatcher
+	dsp sink.DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewClusterController(cmp sink.ConfigMapPatcher, dsp sink.DaemonSetPodDeleter, sc *Config) *ClusterController {
+	return &ClusterController{
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+func (c *ClusterController) OnAdd(o interface{}) {
+	d, ok := o.(*v1alpha1.ClusterMetricSink)
+	if !ok {
+		return
+	}
+
+	c.sc.UpsertClusterSink(d)
+	patchConfig(c.sc.String(), c.cmp, c.dsp)
+}
+
+func (c *ClusterController) OnDelete(o interface{}) {
+	d, ok := o.(*v1alpha1.ClusterMetricSink)
+	if !ok {
+		return
+	}
+
+	c.sc.DeleteClusterSink(d)
+	patchConfig(c.sc.String(), c.cmp, c.dsp)
+}
+
+func (c *ClusterController) OnUpdate(old, new interface{}) {
+	o, _ := old.(*v1alpha1.ClusterMetricSink)
+	n, ok := new.(*v1alpha1.ClusterMetricSink)
+	if !ok {
+		return
+	}
+	if o != nil && reflect.DeepEqual(o.Spec, n.Spec) {
+		c.sc.UpsertClusterSink(n)
+		return
+	}
+	c.OnAdd(n)
+}
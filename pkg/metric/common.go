@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/knative/observability/pkg/sink"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// TODO: allow these to be configurable
+	ConfigMapName = "metric-agent"
+	DaemonSetName = "metric-agent"
+)
+
+type patch struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+func patchConfig(conf string, cmp sink.ConfigMapPatcher, dsp sink.DaemonSetPodDeleter) {
+	data, err := json.Marshal([]patch{
+		{
+			Op:    "replace",
+			Path:  "/data/sinks.toml",
+			Value: conf,
+		},
+	})
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	_, err = cmp.Patch(ConfigMapName, types.JSONPatchType, data)
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	err = dsp.DeleteCollection(
+		nil,
+		metav1.ListOptions{
+			LabelSelector: "app=metric-agent-ds",
+		},
+	)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// Sources defined by the static metric-agent config. Container metrics
+// carry the namespace of their pod as a tag, node metrics belong to no
+// namespace and are only sent to ClusterMetricSinks.
+const (
+	containerSource = "cadvisor"
+	nodeSource      = "host"
+)
+
+const nullConfig = "\n[sinks.null]\n  type = \"blackhole\"\n  inputs = [\"" + containerSource + "\", \"" + nodeSource + "\"]\n"
+
+type Config struct {
+	mu           sync.Mutex
+	sinks        map[string]*v1alpha1.MetricSink
+	clusterSinks map[string]*v1alpha1.ClusterMetricSink
+}
+
+func NewConfig() *Config {
+	return &Config{
+		sinks:        make(map[string]*v1alpha1.MetricSink),
+		clusterSinks: make(map[string]*v1alpha1.ClusterMetricSink),
+	}
+}
+
+// String returns the sinks config of the metric-agent. Sinks are ordered so
+// the config is stable across reconciles: MetricSinks by namespace and then
+// name followed by ClusterMetricSinks by name.
+func (sc *Config) String() string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var b strings.Builder
+	for _, s := range sortedSinks(sc.sinks) {
+		ns := canonicalNamespace(s.Namespace)
+		id := componentID("ns", ns, s.Name)
+		out, err := renderSink(id, []string{id}, s.Spec)
+		if err != nil {
+			log.Printf("unable to render metric sink %s/%s: %s", ns, s.Name, err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n[transforms.%s]\n", id)
+		b.WriteString("  type = \"filter\"\n")
+		fmt.Fprintf(&b, "  inputs = %s\n", list([]string{containerSource}))
+		// vector 0.10 has no expressions, check_fields compares the
+		// tags of metrics by their name.
+		fmt.Fprintf(&b, "\n[transforms.%s.condition]\n", id)
+		b.WriteString("  type = \"check_fields\"\n")
+		fmt.Fprintf(&b, "  \"namespace.eq\" = %q\n", ns)
+		b.WriteString(out)
+	}
+	for _, s := range sortedClusterSinks(sc.clusterSinks) {
+		id := componentID("cluster", s.Name)
+		out, err := renderSink(id, []string{containerSource, nodeSource}, s.Spec)
+		if err != nil {
+			log.Printf("unable to render cluster metric sink %s: %s", s.Name, err)
+			continue
+		}
+		b.WriteString(out)
+	}
+	if b.Len() == 0 {
+		return nullConfig
+	}
+	return b.String()
+}
+
+func renderSink(id string, inputs []string, spec v1alpha1.MetricSinkSpec) (string, error) {
+	addr := net.JoinHostPort(spec.Host, strconv.Itoa(spec.Port))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n[sinks.%s]\n", id)
+	switch spec.Type {
+	case v1alpha1.MetricSinkTypePrometheus:
+		b.WriteString("  type = \"prometheus_remote_write\"\n")
+		fmt.Fprintf(&b, "  inputs = %s\n", list(inputs))
+		fmt.Fprintf(&b, "  endpoint = %q\n", "http://"+addr+"/api/v1/write")
+	case v1alpha1.MetricSinkTypeStatsd:
+		b.WriteString("  type = \"statsd\"\n")
+		fmt.Fprintf(&b, "  inputs = %s\n", list(inputs))
+		b.WriteString("  mode = \"udp\"\n")
+		fmt.Fprintf(&b, "  address = %q\n", addr)
+	default:
+		return "", fmt.Errorf("unknown type %q", spec.Type)
+	}
+	if spec.Interval.Duration > 0 {
+		fmt.Fprintf(&b, "  batch.timeout_secs = %s\n", strconv.FormatFloat(spec.Interval.Seconds(), 'f', -1, 64))
+	}
+	return b.String(), nil
+}
+
+func list(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// componentID joins parts into an identifier that is valid as a TOML bare
+// key.
+func componentID(parts ...string) string {
+	return strings.Replace(strings.Join(parts, "__"), ".", "_", -1)
+}
+
+func sortedSinks(m map[string]*v1alpha1.MetricSink) []*v1alpha1.MetricSink {
+	sinks := make([]*v1alpha1.MetricSink, 0, len(m))
+	for _, s := range m {
+		sinks = append(sinks, s)
+	}
+	sort.Slice(sinks, func(i, j int) bool {
+		ni, nj := canonicalNamespace(sinks[i].Namespace), canonicalNamespace(sinks[j].Namespace)
+		if ni != nj {
+			return ni < nj
+		}
+		return sinks[i].Name < sinks[j].Name
+	})
+	return sinks
+}
+
+func sortedClusterSinks(m map[string]*v1alpha1.ClusterMetricSink) []*v1alpha1.ClusterMetricSink {
+	sinks := make([]*v1alpha1.ClusterMetricSink, 0, len(m))
+	for _, s := range m {
+		sinks = append(sinks, s)
+	}
+	sort.Slice(sinks, func(i, j int) bool {
+		return sinks[i].Name < sinks[j].Name
+	})
+	return sinks
+}
+
+func (sc *Config) UpsertSink(s *v1alpha1.MetricSink) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.sinks[key(s)] = s
+}
+
+func (sc *Config) UpsertClusterSink(cs *v1alpha1.ClusterMetricSink) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.clusterSinks[clusterKey(cs)] = cs
+}
+
+func (sc *Config) DeleteSink(s *v1alpha1.MetricSink) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.sinks, key(s))
+}
+
+func (sc *Config) DeleteClusterSink(s *v1alpha1.ClusterMetricSink) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.clusterSinks, clusterKey(s))
+}
+
+func canonicalNamespace(ns string) string {
+	if ns == "" {
+		return "default"
+	}
+	return ns
+}
+
+func key(s *v1alpha1.MetricSink) string {
+	return fmt.Sprintf("%s|%s", s.Namespace, s.Name)
+}
+
+func clusterKey(s *v1alpha1.ClusterMetricSink) string {
+	return fmt.Sprintf("%s|%s", s.ClusterName, s.Name)
+}
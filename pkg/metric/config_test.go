@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEmptyConfig(t *testing.T) {
+	sc := metric.NewConfig()
+	expected := "\n[sinks.null]\n  type = \"blackhole\"\n  inputs = [\"cadvisor\", \"host\"]\n"
+	if diff := cmp.Diff(expected, sc.String()); diff != "" {
+		t.Errorf("Config not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestConfigRendersSinks(t *testing.T) {
+	sc := metric.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterMetricSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster.sink"},
+		Spec: v1alpha1.MetricSinkSpec{
+			Type: "statsd",
+			Host: "statsd.example.com",
+			Port: 8125,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.MetricSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "prom", Namespace: "ns1"},
+		Spec: v1alpha1.MetricSinkSpec{
+			Type:     "prometheus",
+			Host:     "prometheus.example.com",
+			Port:     9090,
+			Interval: metav1.Duration{Duration: 15 * time.Second},
+		},
+	})
+	sc.UpsertSink(&v1alpha1.MetricSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "statsd"},
+		Spec: v1alpha1.MetricSinkSpec{
+			Type:     "statsd",
+			Host:     "10.0.0.1",
+			Port:     8125,
+			Interval: metav1.Duration{Duration: 1500 * time.Millisecond},
+		},
+	})
+
+	expected := "" +
+		"\n[transforms.ns__default__statsd]\n" +
+		"  type = \"filter\"\n" +
+		"  inputs = [\"cadvisor\"]\n" +
+		"\n[transforms.ns__default__statsd.condition]\n" +
+		"  type = \"check_fields\"\n" +
+		"  \"namespace.eq\" = \"default\"\n" +
+		"\n[sinks.ns__default__statsd]\n" +
+		"  type = \"statsd\"\n" +
+		"  inputs = [\"ns__default__statsd\"]\n" +
+		"  mode = \"udp\"\n" +
+		"  address = \"10.0.0.1:8125\"\n" +
+		"  batch.timeout_secs = 1.5\n" +
+		"\n[transforms.ns__ns1__prom]\n" +
+		"  type = \"filter\"\n" +
+		"  inputs = [\"cadvisor\"]\n" +
+		"\n[transforms.ns__ns1__prom.condition]\n" +
+		"  type = \"check_fields\"\n" +
+		"  \"namespace.eq\" = \"ns1\"\n" +
+		"\n[sinks.ns__ns1__prom]\n" +
+		"  type = \"prometheus_remote_write\"\n" +
+		"  inputs = [\"ns__ns1__prom\"]\n" +
+		"  endpoint = \"http://prometheus.example.com:9090/api/v1/write\"\n" +
+		"  batch.timeout_secs = 15\n" +
+		"\n[sinks.cluster__cluster_sink]\n" +
+		"  type = \"statsd\"\n" +
+		"  inputs = [\"cadvisor\", \"host\"]\n" +
+		"  mode = \"udp\"\n" +
+		"  address = \"statsd.example.com:8125\"\n"
+	if diff := cmp.Diff(expected, sc.String()); diff != "" {
+		t.Errorf("Config not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestConfigSkipsUnknownTypes(t *testing.T) {
+	sc := metric.NewConfig()
+	sc.UpsertSink(&v1alpha1.MetricSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "bad"},
+		Spec: v1alpha1.MetricSinkSpec{
+			Type: "graphite",
+			Host: "example.com",
+			Port: 2003,
+		},
+	})
+	expected := "\n[sinks.null]\n  type = \"blackhole\"\n  inputs = [\"cadvisor\", \"host\"]\n"
+	if diff := cmp.Diff(expected, sc.String()); diff != "" {
+		t.Errorf("Config not equal (-want, +got) = %v", diff)
+	}
+}
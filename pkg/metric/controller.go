@@ -0,0 +1,70 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"reflect"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+type Controller struct {
+	cmp sink.ConfigMapPatcher
+	dsp sink.DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewController(cmp sink.ConfigMapPatcher, dsp sink.DaemonSetPodDeleter, sc *Config) *Controller {
+	return &Controller{
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+func (c *Controller) OnAdd(o interface{}) {
+	d, ok := o.(*v1alpha1.MetricSink)
+	if !ok {
+		return
+	}
+
+	c.sc.UpsertSink(d)
+	patchConfig(c.sc.String(), c.cmp, c.dsp)
+}
+
+func (c *Controller) OnDelete(o interface{}) {
+	d, ok := o.(*v1alpha1.MetricSink)
+	if !ok {
+		return
+	}
+
+	c.sc.DeleteSink(d)
+	patchConfig(c.sc.String(), c.cmp, c.dsp)
+}
+
+func (c *Controller) OnUpdate(old, new interface{}) {
+	o, _ := old.(*v1alpha1.MetricSink)
+	n, ok := new.(*v1alpha1.MetricSink)
+	if !ok {
+		return
+	}
+	if o != nil && reflect.DeepEqual(o.Spec, n.Spec) {
+		c.sc.UpsertSink(n)
+		return
+	}
+	c.OnAdd(n)
+}
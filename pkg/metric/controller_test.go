@@ -0,0 +1,210 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const statsdConfig = "" +
+	"\n[transforms.ns__default__]\n" +
+	"  type = \"filter\"\n" +
+	"  inputs = [\"cadvisor\"]\n" +
+	"\n[transforms.ns__default__.condition]\n" +
+	"  type = \"check_fields\"\n" +
+	"  \"namespace.eq\" = \"default\"\n" +
+	"\n[sinks.ns__default__]\n" +
+	"  type = \"statsd\"\n" +
+	"  inputs = [\"ns__default__\"]\n" +
+	"  mode = \"udp\"\n" +
+	"  address = \"example.com:8125\"\n"
+
+const nullConfig = "\n[sinks.null]\n  type = \"blackhole\"\n  inputs = [\"cadvisor\", \"host\"]\n"
+
+func TestSinkModification(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDeleter := &spyDaemonSetPodDeleter{}
+	c := metric.NewController(spyPatcher, spyDeleter, metric.NewConfig())
+
+	s := &v1alpha1.MetricSink{
+		Spec: v1alpha1.MetricSinkSpec{
+			Type: "statsd",
+			Host: "example.com",
+			Port: 8125,
+		},
+	}
+	c.OnAdd(s)
+	c.OnDelete(s)
+
+	spyPatcher.expectPatches([]string{statsdConfig, nullConfig}, t)
+	if spyDeleter.Selector != "app=metric-agent-ds" {
+		t.Errorf("DaemonSet PodDeleter not equal: Expected: %s, Actual: %s", "app=metric-agent-ds", spyDeleter.Selector)
+	}
+}
+
+func TestClusterSinkModification(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDeleter := &spyDaemonSetPodDeleter{}
+	c := metric.NewClusterController(spyPatcher, spyDeleter, metric.NewConfig())
+
+	s := &v1alpha1.ClusterMetricSink{
+		Spec: v1alpha1.MetricSinkSpec{
+			Type: "prometheus",
+			Host: "example.com",
+			Port: 9090,
+		},
+	}
+	c.OnAdd(s)
+	c.OnDelete(s)
+
+	spyPatcher.expectPatches([]string{
+		"\n[sinks.cluster__]\n" +
+			"  type = \"prometheus_remote_write\"\n" +
+			"  inputs = [\"cadvisor\", \"host\"]\n" +
+			"  endpoint = \"http://example.com:9090/api/v1/write\"\n",
+		nullConfig,
+	}, t)
+	if spyDeleter.Selector != "app=metric-agent-ds" {
+		t.Errorf("DaemonSet PodDeleter not equal: Expected: %s, Actual: %s", "app=metric-agent-ds", spyDeleter.Selector)
+	}
+}
+
+func TestNoChanges(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDeleter := &spyDaemonSetPodDeleter{}
+	c := metric.NewController(spyPatcher, spyDeleter, metric.NewConfig())
+
+	s1 := &v1alpha1.MetricSink{
+		Spec: v1alpha1.MetricSinkSpec{Type: "statsd", Host: "example.com", Port: 8125},
+	}
+	s2 := &v1alpha1.MetricSink{
+		Spec: v1alpha1.MetricSinkSpec{Type: "statsd", Host: "example.com", Port: 8125},
+	}
+	c.OnUpdate(s1, s2)
+
+	if spyPatcher.patchCalled {
+		t.Errorf("Expected patch to not be called")
+	}
+	if spyDeleter.deleteCollectionCalled {
+		t.Errorf("Expected delete to not be called")
+	}
+}
+
+func TestBadInputs(t *testing.T) {
+	c := metric.NewController(
+		&spyConfigMapPatcher{},
+		&spyDaemonSetPodDeleter{},
+		metric.NewConfig(),
+	)
+	cc := metric.NewClusterController(
+		&spyConfigMapPatcher{},
+		&spyDaemonSetPodDeleter{},
+		metric.NewConfig(),
+	)
+	//shouldn't panic
+	c.OnAdd("")
+	c.OnDelete(1)
+	c.OnUpdate(nil, nil)
+	cc.OnAdd("")
+	cc.OnDelete(1)
+	cc.OnUpdate(nil, nil)
+}
+
+type jsonPatch struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+type patch struct {
+	name string
+	pt   types.PatchType
+	data []byte
+}
+
+type spyConfigMapPatcher struct {
+	patchCalled bool
+	patches     []patch
+}
+
+func (s *spyConfigMapPatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*coreV1.ConfigMap, error) {
+	s.patchCalled = true
+	s.patches = append(s.patches, patch{
+		name: name,
+		pt:   pt,
+		data: data,
+	})
+	return nil, nil
+}
+
+func (s *spyConfigMapPatcher) expectPatches(patches []string, t *testing.T) {
+	if len(s.patches) != len(patches) {
+		t.Fatalf("Expected %d patches, got %d", len(patches), len(s.patches))
+	}
+	for i, p := range patches {
+		if s.patches[i].name != metric.ConfigMapName {
+			t.Errorf("Config map name does not equal Got: %s, Expected %s", s.patches[i].name, metric.ConfigMapName)
+		}
+
+		if s.patches[i].pt != types.JSONPatchType {
+			t.Errorf("Patch Type does not equal Got: %s, Expected %s", s.patches[i].pt, types.JSONPatchType)
+		}
+
+		jpExpected := []jsonPatch{
+			{
+				Op:    "replace",
+				Path:  "/data/sinks.toml",
+				Value: p,
+			},
+		}
+		var jpActual []jsonPatch
+		err := json.Unmarshal(s.patches[i].data, &jpActual)
+		if err != nil {
+			t.Errorf("Could not Unmarshal json patch: %s", err)
+		}
+
+		if diff := cmp.Diff(jpExpected, jpActual); diff != "" {
+			t.Errorf("Patches not equal (-want, +got) = %v", diff)
+		}
+	}
+}
+
+type spyDaemonSetPodDeleter struct {
+	deleteCollectionCalled bool
+	Selector               string
+}
+
+func (s *spyDaemonSetPodDeleter) DeleteCollection(
+	options *metav1.DeleteOptions,
+	listOptions metav1.ListOptions,
+) error {
+	s.deleteCollectionCalled = true
+	s.Selector = listOptions.LabelSelector
+	return nil
+}
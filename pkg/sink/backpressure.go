@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// ReasonBufferOverLimit is the reason set on the Backpressured condition of
+// sinks whose stream reached its memory limit, fluent-bit pauses the inputs
+// feeding it.
+const ReasonBufferOverLimit = "BufferOverLimit"
+
+// ReasonBufferAboveThreshold is the reason set on the Backpressured
+// condition of sinks buffering more bytes than the threshold.
+const ReasonBufferAboveThreshold = "BufferAboveThreshold"
+
+// InputStorage is the buffer fluent-bit reports for an input instance.
+type InputStorage struct {
+	// Chunks are the chunks of records buffered in memory or on the
+	// filesystem.
+	Chunks uint64
+	// MemBytes are the bytes of the chunks in memory.
+	MemBytes uint64
+	// Overlimit is whether the input reached its memory limit.
+	Overlimit bool
+}
+
+type storageMetrics struct {
+	InputChunks map[string]struct {
+		Status struct {
+			Overlimit bool   `json:"overlimit"`
+			MemSize   string `json:"mem_size"`
+		} `json:"status"`
+		Chunks struct {
+			Total uint64 `json:"total"`
+		} `json:"chunks"`
+	} `json:"input_chunks"`
+}
+
+// podStorage returns the buffers of the inputs of a fluent-bit pod. The
+// storage metrics require storage.metrics in the service config.
+func (f *fluentBitMetrics) podStorage(ip string) (map[string]InputStorage, error) {
+	u := fmt.Sprintf("http://%s/api/v1/storage", net.JoinHostPort(ip, strconv.Itoa(f.port)))
+	resp, err := f.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var m storageMetrics
+	err = json.NewDecoder(resp.Body).Decode(&m)
+	if err != nil {
+		return nil, err
+	}
+	storage := make(map[string]InputStorage, len(m.InputChunks))
+	for name, in := range m.InputChunks {
+		bytes, err := parseSize(in.Status.MemSize)
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %s", name, err)
+		}
+		storage[name] = InputStorage{
+			Chunks:    in.Chunks.Total,
+			MemBytes:  bytes,
+			Overlimit: in.Status.Overlimit,
+		}
+	}
+	return storage, nil
+}
+
+// parseSize parses the sizes fluent-bit reports, such as 0b, 512.0K or
+// 1.5M, in units of 1024.
+func parseSize(s string) (uint64, error) {
+	units := map[string]float64{
+		"b": 1,
+		"K": 1 << 10,
+		"M": 1 << 20,
+		"G": 1 << 30,
+		"T": 1 << 40,
+	}
+	if s == "" {
+		return 0, nil
+	}
+	unit := units[s[len(s)-1:]]
+	if unit == 0 {
+		return 0, fmt.Errorf("unknown unit of size %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return uint64(n * unit), nil
+}
+
+// emitterBuffers returns the buffers of the emitters of the rewrite_tag
+// filters feeding the streams of the sinks, summed by sink. Sinks in the
+// shared stream share the buffer of the tail input and have none.
+func emitterBuffers(filters map[string][]entry, storage map[string]InputStorage) map[string]InputStorage {
+	buffers := make(map[string]InputStorage)
+	for name, entries := range filters {
+		if !strings.HasPrefix(name, "rewrite_tag.") || len(entries) != 1 {
+			continue
+		}
+		// rewrite_tag names its emitter after the filter instance.
+		in, ok := storage["emitter_for_"+name]
+		if !ok {
+			continue
+		}
+		k := entries[0].String()
+		b := buffers[k]
+		b.Chunks += in.Chunks
+		b.MemBytes += in.MemBytes
+		b.Overlimit = b.Overlimit || in.Overlimit
+		buffers[k] = b
+	}
+	return buffers
+}
+
+// backpressured returns the Backpressured condition of a sink with the
+// buffer.
+func backpressured(b InputStorage, threshold uint64) v1alpha1.Condition {
+	c := v1alpha1.Condition{
+		Type:   v1alpha1.SinkConditionBackpressured,
+		Status: coreV1.ConditionFalse,
+	}
+	switch {
+	case b.Overlimit:
+		c.Status = coreV1.ConditionTrue
+		c.Reason = ReasonBufferOverLimit
+		c.Message = "the stream of the sink reached its memory limit, fluent-bit paused the inputs feeding it"
+	case threshold != 0 && b.MemBytes > threshold:
+		c.Status = coreV1.ConditionTrue
+		c.Reason = ReasonBufferAboveThreshold
+		c.Message = fmt.Sprintf("the sink buffers more than the threshold of %d bytes", threshold)
+	}
+	return c
+}
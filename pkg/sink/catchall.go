@@ -0,0 +1,155 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// catchAllFilter returns a lua filter dropping the records any of the other
+// sinks selects from the container logs, and false if there are no such
+// sinks. A sink selects the records of its namespace, namespace globs,
+// system namespaces, pods, nodes and stream, records it drops later on,
+// e.g. with its drop_patterns, still count as matched.
+func catchAllFilter(catchAll entry, entries []entry) (section, bool) {
+	var selections []string
+	for _, e := range entries {
+		// Neither the audit log nor files on the host are container logs.
+		if (e.cluster() && e.name == catchAll.name) || e.audit() || len(e.spec.HostPaths) != 0 {
+			continue
+		}
+		selections = append(selections, "("+selection(e)+")")
+	}
+	if len(selections) == 0 {
+		return section{}, false
+	}
+
+	f := newFilter("lua", catchAll.match())
+	f.add("call", "catch_all")
+	f.add("code",
+		`function catch_all(tag, timestamp, record) local k = record["kubernetes"] `+
+			`if type(k) ~= "table" then k = {} end `+
+			`local ns, labels, host = k["namespace_name"], k["labels"], k["host"] `+
+			`if type(ns) ~= "string" then ns = "" end `+
+			`if type(labels) ~= "table" then labels = {} end `+
+			`if `+strings.Join(selections, " or ")+` then return -1, 0, 0 end `+
+			`return 0, timestamp, record end`,
+	)
+	return f, true
+}
+
+// selection returns the lua condition a record of the container logs meets
+// when the sink selects it. It reads the locals ns, labels and host of
+// catchAllFilter.
+func selection(e entry) string {
+	var terms []string
+	if !e.cluster() {
+		terms = append(terms, "ns == "+luaString(e.namespace))
+	}
+	if len(e.spec.NamespaceGlobs) != 0 {
+		globs := make([]string, len(e.spec.NamespaceGlobs))
+		for i, g := range e.spec.NamespaceGlobs {
+			globs[i] = fmt.Sprintf("string.find(ns, %s) ~= nil", luaString(globLuaPattern(g)))
+		}
+		terms = append(terms, "("+strings.Join(globs, " or ")+")")
+	}
+	if e.spec.SystemOnly {
+		terms = append(terms, oneOf("ns", SystemNamespaces(e.spec)))
+	}
+	for _, ns := range e.spec.ExcludeNamespaces {
+		terms = append(terms, "ns ~= "+luaString(ns))
+	}
+	if ls := e.spec.PodSelector; Selects(ls) {
+		terms = append(terms, labelTerms(ls)...)
+	}
+	if len(e.spec.NodeSelector) != 0 {
+		terms = append(terms, oneOf("host", e.nodes))
+	}
+	if s := e.spec.Stream; s != "" && s != v1alpha1.StreamAll {
+		terms = append(terms, `record["stream"] == `+luaString(s))
+	}
+	if len(terms) == 0 {
+		return "true"
+	}
+	return strings.Join(terms, " and ")
+}
+
+// labelTerms returns the lua conditions of the label selector, the way
+// podSelectorFilter selects the pods.
+func labelTerms(ls *metav1.LabelSelector) []string {
+	keys := make([]string, 0, len(ls.MatchLabels))
+	for k := range ls.MatchLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var terms []string
+	for _, k := range keys {
+		terms = append(terms, fmt.Sprintf("labels[%s] == %s", luaString(k), luaString(ls.MatchLabels[k])))
+	}
+	for _, r := range ls.MatchExpressions {
+		label := fmt.Sprintf("labels[%s]", luaString(r.Key))
+		switch r.Operator {
+		case metav1.LabelSelectorOpIn:
+			terms = append(terms, oneOf(label, r.Values))
+		case metav1.LabelSelectorOpNotIn:
+			terms = append(terms, "not "+oneOf(label, r.Values))
+		case metav1.LabelSelectorOpExists:
+			terms = append(terms, label+" ~= nil")
+		case metav1.LabelSelectorOpDoesNotExist:
+			terms = append(terms, label+" == nil")
+		}
+	}
+	return terms
+}
+
+// oneOf returns the lua condition of the value equaling any of the values.
+func oneOf(value string, values []string) string {
+	if len(values) == 0 {
+		return "false"
+	}
+	eq := make([]string, len(values))
+	for i, v := range values {
+		eq[i] = value + " == " + luaString(v)
+	}
+	return "(" + strings.Join(eq, " or ") + ")"
+}
+
+// globLuaPattern returns the lua pattern of a namespace glob, the wildcards
+// only match the characters of a namespace name.
+func globLuaPattern(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, c := range glob {
+		switch c {
+		case '*':
+			b.WriteString("[a-z0-9%-]*")
+		case '?':
+			b.WriteString("[a-z0-9%-]")
+		case '-':
+			b.WriteString("%-")
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
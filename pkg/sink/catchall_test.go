@@ -0,0 +1,235 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestCatchAll(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-sink",
+			Namespace: "app",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12345,
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:              "syslog",
+			Host:              "example.net",
+			Port:              12345,
+			ExcludeNamespaces: []string{"kube-system"},
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"test"}},
+				},
+			},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "teams",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:           "syslog",
+			Host:           "example.net",
+			Port:           12346,
+			NamespaceGlobs: []string{"team-*"},
+			Stream:         "stderr",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "audit",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:   "syslog",
+			Host:   "example.net",
+			Port:   12347,
+			Source: "audit",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "rest",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "syslog",
+			Host:     "example.com",
+			Port:     12345,
+			CatchAll: true,
+		},
+	})
+
+	// The catch-all drops the records of the app namespace, the stderr of
+	// the team namespaces and the web pods outside of kube-system. The
+	// audit sink gets no container logs.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.rest true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.cluster.rest\n    call catch_all\n" +
+		`    code function catch_all(tag, timestamp, record) local k = record["kubernetes"] ` +
+		`if type(k) ~= "table" then k = {} end ` +
+		`local ns, labels, host = k["namespace_name"], k["labels"], k["host"] ` +
+		`if type(ns) ~= "string" then ns = "" end ` +
+		`if type(labels) ~= "table" then labels = {} end ` +
+		`if (ns == "app") ` +
+		`or ((string.find(ns, "^team%-[a-z0-9%-]*$") ~= nil) and record["stream"] == "stderr") ` +
+		`or (ns ~= "kube-system" and labels["app"] == "web" and not (labels["tier"] == "test")) ` +
+		`then return -1, 0, 0 end return 0, timestamp, record end` + "\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.rest\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if !strings.Contains(sc.String(), expected) {
+		t.Errorf("Config does not contain the catch-all: Expected: %q Actual: %q", expected, sc.String())
+	}
+	// The other sinks still receive the records they select.
+	if strings.Count(sc.String(), "catch_all") != 2 {
+		t.Errorf("Expected only the catch-all to drop records: %s", sc.String())
+	}
+}
+
+func TestCatchAllNodeSelector(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertNode(node("node-1", map[string]string{"pool": "gpu"}))
+	sc.UpsertNode(node("node-2", map[string]string{"pool": "default"}))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "gpu",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.net",
+			Port:         12345,
+			NodeSelector: map[string]string{"pool": "gpu"},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "none",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.net",
+			Port:         12346,
+			NodeSelector: map[string]string{"pool": "tpu"},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "rest",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "syslog",
+			Host:     "example.com",
+			Port:     12345,
+			CatchAll: true,
+		},
+	})
+
+	// A sink selecting no nodes matches no records.
+	expected := `if ((host == "node-1")) or (false) then return -1, 0, 0 end`
+	if !strings.Contains(sc.String(), expected) {
+		t.Errorf("Config does not contain the node selection: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestCatchAllWithoutOtherSinks(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "rest",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "syslog",
+			Host:     "example.com",
+			Port:     12345,
+			CatchAll: true,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestSecondCatchAll(t *testing.T) {
+	sc := sink.NewConfig()
+	for _, name := range []string{"rest", "other-rest"} {
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:     "syslog",
+				Host:     name + ".example.com",
+				Port:     12345,
+				CatchAll: true,
+			},
+		})
+	}
+
+	// The first catch-all by name is rendered.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"other-rest.example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidCatchAll(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, CatchAll: true, Source: "audit"},
+		{Type: "syslog", Host: "example.com", Port: 12345, CatchAll: true, HostPaths: []string{"/data/app/*.log"}},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+		}
+	}
+
+	// Only ClusterLogSinks can be the catch-all.
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, CatchAll: true},
+	})
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
@@ -22,16 +22,12 @@ import (
 )
 
 type ClusterController struct {
-	cmp ConfigMapPatcher
-	dsp DaemonSetPodDeleter
-	sc  *Config
+	*reconciler
 }
 
-func NewClusterController(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config) *ClusterController {
+func NewClusterController(cmp ConfigMapPatcher, r Reloader, sc *Config, opts ...Option) *ClusterController {
 	return &ClusterController{
-		cmp: cmp,
-		dsp: dsp,
-		sc:  sc,
+		reconciler: newReconciler(cmp, r, sc, opts),
 	}
 }
 
@@ -43,14 +39,7 @@ func (c *ClusterController) OnAdd(o interface{}) {
 
 	c.sc.UpsertClusterSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
-	}
-	patchConfig(patches, c.cmp, c.dsp)
+	c.reconcile()
 }
 
 func (c *ClusterController) OnDelete(o interface{}) {
@@ -61,18 +50,20 @@ func (c *ClusterController) OnDelete(o interface{}) {
 
 	c.sc.DeleteClusterSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
-	}
-	patchConfig(patches, c.cmp, c.dsp)
+	c.reconcile()
 }
 
 func (c *ClusterController) OnUpdate(old, new interface{}) {
-	if !reflect.DeepEqual(old, new) {
-		c.OnAdd(new)
+	o, _ := old.(*v1alpha1.ClusterLogSink)
+	n, ok := new.(*v1alpha1.ClusterLogSink)
+	if !ok {
+		return
+	}
+	// Status updates from the health reporter only need the stored sink
+	// refreshed, reloading fluent-bit for them would reset its metrics.
+	if o != nil && reflect.DeepEqual(o.Spec, n.Spec) {
+		c.sc.UpsertClusterSink(n)
+		return
 	}
+	c.OnAdd(n)
 }
@@ -100,9 +100,9 @@ func TestClusterSinkModification(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			spyConfigMapPatcher := &spyConfigMapPatcher{}
-			spyDaemonSetPodDeleter := &spyDaemonSetPodDeleter{}
+			spyReloader := &spyReloader{}
 
-			c := sink.NewClusterController(spyConfigMapPatcher, spyDaemonSetPodDeleter, sink.NewConfig())
+			c := sink.NewClusterController(spyConfigMapPatcher, spyReloader, sink.NewConfig())
 			for i, spec := range test.specs {
 				d := &v1alpha1.ClusterLogSink{
 					Spec: spec,
@@ -117,8 +117,8 @@ func TestClusterSinkModification(t *testing.T) {
 				}
 			}
 			spyConfigMapPatcher.expectPatches(test.patches, t)
-			if spyDaemonSetPodDeleter.Selector != "app=fluent-bit-ds" {
-				t.Errorf("DaemonSet PodDeleter not equal: Expected: %s, Actual: %s", spyDaemonSetPodDeleter.Selector, "app=fluent-bit-ds")
+			if spyReloader.reloads != len(test.patches) {
+				t.Errorf("Reloads not equal: Expected: %d, Actual: %d", len(test.patches), spyReloader.reloads)
 			}
 		})
 	}
@@ -126,8 +126,8 @@ func TestClusterSinkModification(t *testing.T) {
 
 func TestNoopChange(t *testing.T) {
 	spyPatcher := &spyConfigMapPatcher{}
-	spyDeleter := &spyDaemonSetPodDeleter{}
-	c := sink.NewClusterController(spyPatcher, spyDeleter, sink.NewConfig())
+	spyReloader := &spyReloader{}
+	c := sink.NewClusterController(spyPatcher, spyReloader, sink.NewConfig())
 
 	s1 := &v1alpha1.ClusterLogSink{
 		Spec: v1alpha1.SinkSpec{
@@ -147,15 +147,15 @@ func TestNoopChange(t *testing.T) {
 	if spyPatcher.patchCalled {
 		t.Errorf("Expected patch to not be called")
 	}
-	if spyDeleter.deleteCollectionCalled {
-		t.Errorf("Expected delete to not be called")
+	if spyReloader.reloads != 0 {
+		t.Errorf("Expected reload to not be called")
 	}
 }
 
 func TestBadInputs(t *testing.T) {
 	c := sink.NewClusterController(
 		&spyConfigMapPatcher{},
-		&spyDaemonSetPodDeleter{},
+		&spyReloader{},
 		sink.NewConfig(),
 	)
 	//shouldn't panic
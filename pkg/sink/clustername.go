@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ClusterNameKey is the record field holding the name of the cluster, so a
+// receiver collecting the logs of several clusters can tell them apart.
+const ClusterNameKey = "cluster"
+
+// ValidateClusterName returns why the name cannot be set on the records or
+// nil if it can. It must be a valid label value.
+func ValidateClusterName(name string) error {
+	if errs := validation.IsValidLabelValue(name); len(errs) != 0 {
+		return fmt.Errorf("invalid cluster name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// SetClusterName has every record forwarded by any of the sinks carry the
+// name in its cluster field, empty leaves the records alone. The labels of a
+// sink are set after it and may replace it.
+func (sc *Config) SetClusterName(name string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.clusterName = name
+}
+
+// clusterNameFilter returns a modify filter setting the cluster name on
+// every record before the sinks copy them to their streams, the copies pass
+// it again and keep the same value.
+func clusterNameFilter(name string) section {
+	f := newFilter("modify", matchAll)
+	f.add("Set", fmt.Sprintf("%s %s", ClusterNameKey, name))
+	return f
+}
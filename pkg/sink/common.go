@@ -16,6 +16,7 @@ limitations under the License.
 package sink
 
 import (
+	v1alpha1i "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
 	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -25,6 +26,19 @@ const (
 	// TODO: allow these to be configurable
 	ConfigMapName = "fluent-bit"
 	DaemonSetName = "fluent-bit"
+	// TLSSecretName is the Secret holding the client certificates, the AWS
+	// access keys, the HEC tokens, the Datadog API keys and the forward
+	// shared keys of the sinks. fluent-bit mounts it along with its
+	// ConfigMap.
+	TLSSecretName = "fluent-bit-tls"
+
+	// ManagedByLabel marks the fluent-bit ConfigMap and DaemonSet the
+	// sink-controller repairs when they are modified out-of-band.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedBy      = "sink-controller"
+	// ManagedSelector selects the objects labeled as managed by the
+	// sink-controller.
+	ManagedSelector = ManagedByLabel + "=" + ManagedBy
 )
 
 type ConfigMapPatcher interface {
@@ -36,6 +50,15 @@ type ConfigMapPatcher interface {
 	) (*coreV1.ConfigMap, error)
 }
 
+type SecretPatcher interface {
+	Patch(
+		name string,
+		pt types.PatchType,
+		data []byte,
+		subresources ...string,
+	) (*coreV1.Secret, error)
+}
+
 type DaemonSetPodDeleter interface {
 	DeleteCollection(
 		options *metav1.DeleteOptions,
@@ -43,8 +66,20 @@ type DaemonSetPodDeleter interface {
 	) error
 }
 
+type SinkStatusUpdater interface {
+	v1alpha1i.LogSinksGetter
+	v1alpha1i.ClusterLogSinksGetter
+}
+
 type patch struct {
 	Op    string `json:"op"`
 	Path  string `json:"path"`
 	Value string `json:"value"`
 }
+
+// secretPatch patches the data of a Secret, which is base64 encoded.
+type secretPatch struct {
+	Op    string            `json:"op"`
+	Path  string            `json:"path"`
+	Value map[string][]byte `json:"value"`
+}
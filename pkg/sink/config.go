@@ -17,24 +17,37 @@ package sink
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
 )
 
 // TODO: make sure the omitempty on namespace doesn't break tests
 type sink struct {
-	Addr      string `json:"addr"`
-	Namespace string `json:"namespace,omitempty"`
-	TLS       *tls   `json:"tls,omitempty"`
-	name      string
+	Addr            string `json:"addr"`
+	Namespace       string `json:"namespace,omitempty"`
+	Protocol        string `json:"protocol,omitempty"`
+	TLS             *tls   `json:"tls,omitempty"`
+	Format          string `json:"format,omitempty"`
+	AppName         string `json:"app_name,omitempty"`
+	MessageTemplate string `json:"message_template,omitempty"`
+	Facility        string `json:"facility,omitempty"`
+	Severity        string `json:"severity,omitempty"`
 }
 
 type tls struct {
-	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
 }
 
 const nullConfig = "\n[OUTPUT]\n    Name null\n    Match *\n"
@@ -43,103 +56,710 @@ type Config struct {
 	mu           sync.Mutex
 	sinks        map[string]*v1alpha1.LogSink
 	clusterSinks map[string]*v1alpha1.ClusterLogSink
+	secrets      map[string]map[string][]byte
+	parsers      map[string]v1alpha1.ParserSpec
+	// nodes holds the labels of every Node by its name.
+	nodes map[string]map[string]string
+	// clusterName is set on every record, empty sets none.
+	clusterName string
+	// socketDirs are the directories the sockets of sinks may be in.
+	socketDirs []string
+	// generation counts the changes to the sinks, Secrets, parsers,
+	// Nodes and the cluster name. changed is when the oldest change not
+	// yet written was made, zero when there is none.
+	generation uint64
+	changed    time.Time
+
+	// writeMu serializes the writes of the rendered config. written is the
+	// generation of the last one, older renders are not written after it.
+	// applied holds the ConfigMap patches of the last successful write,
+	// resized the memory in MB the fluent-bit container was set to,
+	// mounted the socket directories mounted into it and image the image
+	// it was set to. writeErr is the error of the last write that got past
+	// the validator.
+	writeMu  sync.Mutex
+	written  uint64
+	writeErr error
+	applied  []patch
+	resized  int
+	mounted  []string
+	image    string
+	// rejected holds why fluent-bit rejected the sinks of the last config
+	// that was validated, it was not written.
+	rejected map[string]string
+	// longLines are the warnings about sinks truncating lines longer than
+	// fluent-bit reads that were logged last.
+	longLines []string
+	// events holds the last Event recorded on each sink and conflicts the
+	// last conflict Event of the sinks still in conflict.
+	events    map[string]sinkEvent
+	conflicts map[string]sinkEvent
 }
 
 func NewConfig() *Config {
 	return &Config{
 		sinks:        make(map[string]*v1alpha1.LogSink),
 		clusterSinks: make(map[string]*v1alpha1.ClusterLogSink),
+		secrets:      make(map[string]map[string][]byte),
+		parsers:      make(map[string]v1alpha1.ParserSpec),
+		nodes:        make(map[string]map[string]string),
+		events:       make(map[string]sinkEvent),
+		conflicts:    make(map[string]sinkEvent),
 	}
 }
 
-// TODO: Refactor
 func (sc *Config) String() string {
+	return sc.logRender().conf
+}
+
+// Parsers returns the multiline and time parsers used by the sinks'
+// filters. The fluent-bit service loads them from a parsers file of their
+// own.
+func (sc *Config) Parsers() string {
+	return sc.logRender().parsers
+}
+
+// logRender renders the config, logging the sinks that cannot be rendered.
+func (sc *Config) logRender() rendered {
+	_, r := sc.generationRender()
+	return r
+}
+
+// generationRender is logRender that also returns the generation of the
+// changes the render includes.
+func (sc *Config) generationRender() (uint64, rendered) {
+	sc.mu.Lock()
+	gen := sc.generation
+	r := sc.render()
+	sc.mu.Unlock()
+	for _, err := range r.errs {
+		log.Print(err)
+	}
+	return gen, r
+}
+
+// changeRender is generationRender that also takes when the oldest change
+// the render includes was made, the next change is timed from when it is
+// made.
+func (sc *Config) changeRender() (uint64, time.Time, rendered) {
+	sc.mu.Lock()
+	changed := sc.changed
+	sc.changed = time.Time{}
+	sc.mu.Unlock()
+	gen, r := sc.generationRender()
+	return gen, changed, r
+}
+
+// markChanged notes a change made at now, unless an older one is not
+// written yet.
+func (sc *Config) markChanged(now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.changed.IsZero() {
+		sc.changed = now
+	}
+}
+
+// unwritten notes that the change made at changed was not written after
+// all.
+func (sc *Config) unwritten(changed time.Time) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
+	if !changed.IsZero() && (sc.changed.IsZero() || changed.Before(sc.changed)) {
+		sc.changed = changed
+	}
+}
+
+// instances returns the sinks each fluent-bit output and filter instance
+// belongs to. Instances are named the way fluent-bit names them in its
+// metrics: the plugin name followed by the index among instances of the same
+// plugin.
+func (sc *Config) instances() (outputs, filters map[string][]entry, disabled []entry, missing []renderError) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	r := sc.render()
+	return r.outputs, r.filters, r.disabled, r.missing
+}
+
+// counts returns the number of LogSinks and ClusterLogSinks in the config.
+func (sc *Config) counts() (sinks, clusterSinks int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return len(sc.sinks), len(sc.clusterSinks)
+}
+
+// RenderConfig returns the outputs config the sink-controller would write for
+// the given sinks without applying it. Sinks that cannot be rendered are left
+// out of the config the same way the controller leaves them out and are
+// reported in the returned error.
+func RenderConfig(sinks []v1alpha1.LogSink, clusterSinks []v1alpha1.ClusterLogSink) (string, error) {
+	sc := NewConfig()
+	for i := range sinks {
+		sc.UpsertSink(&sinks[i])
+	}
+	for i := range clusterSinks {
+		sc.UpsertClusterSink(&clusterSinks[i])
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	r := sc.render()
+	if len(r.errs) != 0 {
+		msgs := make([]string, 0, len(r.errs))
+		for _, err := range r.errs {
+			msgs = append(msgs, err.Error())
+		}
+		return r.conf, errors.New(strings.Join(msgs, "; "))
+	}
+	return r.conf, nil
+}
+
+// renderError is why a sink, or one of its destinations, was left out of
+// the config.
+type renderError struct {
+	sink entry
+	err  error
+}
+
+func (e renderError) Error() string {
+	return e.err.Error()
+}
+
+type rendered struct {
+	conf    string
+	parsers string
+	// certs are the client certificate files of the rendered sinks, the
+	// shared credentials file of their AWS access keys and the
+	// credentials file of the variables their outputs reference.
+	certs   map[string][]byte
+	outputs map[string][]entry
+	filters map[string][]entry
+	// sinkConfs are the sections of each rendered sink, by sink.
+	sinkConfs map[string]string
+	// bufferMB is the memory the streams of the rendered sinks may hold.
+	bufferMB int
+	// sockets are the directories of the sockets of the rendered sinks.
+	sockets []string
+	// entries are the rendered sinks.
+	entries []entry
+	// disabled are the sinks left out because they are disabled.
+	disabled []entry
+	// missing are why the sinks referencing a Secret or ClusterLogParser
+	// that does not exist were left out.
+	missing []renderError
+	// conflicts are the rendered sinks whose receivers get records twice.
+	conflicts []conflict
+	errs      []error
+}
+
+// block is a rendered section along with the sinks it delivers to when it is
+// an output.
+type block struct {
+	section
+	sinks []entry
+}
+
+// TODO: Refactor
+func (sc *Config) render() rendered {
 	if len(sc.sinks)+len(sc.clusterSinks) == 0 {
-		return nullConfig
+		return rendered{conf: nullConfig}
 	}
-	sinks := make([]sink, 0, len(sc.sinks))
-	for _, s := range sc.sinks {
-		var tlsConfig *tls
-		if s.Spec.EnableTLS {
-			tlsConfig = &tls{
-				InsecureSkipVerify: s.Spec.InsecureSkipVerify,
+
+	var (
+		entries  []entry
+		routed   bool
+		shared   []entry
+		sinks    = make([]sink, 0, len(sc.sinks))
+		clusters = make([]sink, 0, len(sc.clusterSinks))
+		outputs  []block
+		streams  []block
+		parsers  strings.Builder
+		custom   = make(map[string]bool)
+		certs    = make(map[string][]byte)
+		profiles = make(map[string]string)
+		creds    = make(map[string]string)
+		buffers  int
+		claimed  []string
+		catchAll string
+		disabled []entry
+		missing  []renderError
+		tags     []string
+		inputs   []block
+		errs     []error
+	)
+	// Streams cannot be told apart by a Tag more than one sink uses.
+	duplicates := sharedTags(sc.entries())
+	// LogSinks come before ClusterLogSinks so the namespaces claimed by
+	// exclusive LogSinks are known when the ClusterLogSinks are rendered.
+	for _, e := range sc.entries() {
+		// Disabled sinks claim no namespaces either.
+		if e.spec.Disabled {
+			disabled = append(disabled, e)
+			continue
+		}
+		if err := ValidateMessageTemplate(e.spec.MessageTemplate); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if err := ValidateSyslogFacility(e.spec.SyslogFacility); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if err := ValidateSyslogSeverity(e.spec.DefaultSeverity); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if err := ValidateExclusiveFields(e.spec); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if e.cluster() {
+			e.spec.ExcludeNamespaces = withClaimed(e.spec.ExcludeNamespaces, claimed)
+		}
+		if err := ValidateCompression(e.spec); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if err := ValidateWeights(e.spec); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if err := ValidateTag(e.spec.Tag); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if s, ok := duplicates[e.spec.Tag]; ok {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: tag %s is shared by the sinks %s", e, e.spec.Tag, strings.Join(s, ", "))})
+			continue
+		}
+		if err := ValidateSource(e.spec); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if e.audit() && !e.cluster() {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: source audit is only supported by ClusterLogSinks", e)})
+			continue
+		}
+		if e.spec.CatchAll && !e.cluster() {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: catch_all is only supported by ClusterLogSinks", e)})
+			continue
+		}
+		if e.spec.SystemOnly && !e.cluster() {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: system_only is only supported by ClusterLogSinks", e)})
+			continue
+		}
+		if len(e.spec.NamespaceGlobs) != 0 && !e.cluster() {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: namespace_globs are only supported by ClusterLogSinks", e)})
+			continue
+		}
+		if e.spec.SocketPath != "" {
+			err := ValidateSocketPath(e.spec)
+			if err == nil {
+				err = SocketPathAllowed(e.spec.SocketPath, sc.socketDirs)
+			}
+			if !e.cluster() {
+				err = fmt.Errorf("socket_path is only supported by ClusterLogSinks")
+			}
+			if err != nil {
+				errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+				continue
+			}
+		}
+		if err := validateTLSServerNames(e.destinations); err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+			continue
+		}
+		if len(e.spec.HostPaths) != 0 {
+			err := ValidateHostPaths(e.spec.HostPaths)
+			if !e.cluster() {
+				err = fmt.Errorf("host_paths are only supported by ClusterLogSinks")
+			}
+			if err != nil {
+				errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+				continue
+			}
+		}
+		if e.spec.SecretRef != nil {
+			token, err := sc.token(e)
+			if _, ok := err.(missingSecretError); ok {
+				missing = append(missing, renderError{e, err})
+			}
+			if err == nil {
+				e.destinations, err = withCredentials(e.destinations, token)
+			}
+			if err != nil {
+				errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+				continue
 			}
 		}
-		sinks = append(sinks, sink{
-			Addr:      fmt.Sprintf("%s:%d", s.Spec.Host, s.Spec.Port),
-			Namespace: canonicalNamespace(s.Namespace),
-			TLS:       tlsConfig,
-			name:      s.Name,
+		if e.spec.TLSSecretRef != nil {
+			cert, files, err := sc.clientCert(e)
+			if _, ok := err.(missingSecretError); ok {
+				missing = append(missing, renderError{e, err})
+			}
+			if err != nil {
+				errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+				continue
+			}
+			e.cert = cert
+			for name, data := range files {
+				certs[name] = data
+			}
+		}
+		if len(parserNames(e.spec)) != 0 {
+			ps, err := sc.logParsers(e)
+			if _, ok := err.(missingParserError); ok {
+				missing = append(missing, renderError{e, err})
+			}
+			if err != nil {
+				errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+				continue
+			}
+			e.logParsers = ps
+		}
+		if len(e.spec.NodeSelector) != 0 {
+			e.nodes = sc.selectedNodes(e.spec.NodeSelector)
+		}
+		f, err := sinkFilters(e, sc.clusterName)
+		if err != nil {
+			errs = append(errs, renderError{e, fmt.Errorf("unable to render filters for sink %s: %s", e, err)})
+			continue
+		}
+		e.filters = f
+		if e.spec.CatchAll {
+			if catchAll != "" {
+				errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: sink %s is already the catch-all", e, catchAll)})
+				continue
+			}
+			catchAll = e.String()
+		}
+		entries = append(entries, e)
+		if e.spec.ExclusiveMatch && !e.cluster() {
+			claimed = append(claimed, e.namespace)
+		}
+		routed = routed || e.streamed()
+		if e.spec.Tag != "" {
+			tags = append(tags, e.spec.Tag)
+		}
+	}
+
+	// The catch-all only receives what the other sinks leave, which is
+	// known once all of them are rendered.
+	for i, e := range entries {
+		if !e.spec.CatchAll {
+			continue
+		}
+		if f, ok := catchAllFilter(e, entries); ok {
+			entries[i].filters = append([]section{f}, e.filters...)
+			routed = true
+		}
+	}
+
+	// When any sink has its own stream the outputs that would otherwise
+	// match everything must skip the copies made for those streams.
+	all := matchAll
+	if routed {
+		all = unrouted(tags)
+	}
+
+	for _, e := range entries {
+		if e.streamed() {
+			// Every destination is fed by the same filter chain. Weighted
+			// destinations each get the records they are balanced to, only
+			// the rendered ones are balanced to.
+			var (
+				outs    []block
+				weights []int
+			)
+			for i, d := range e.destinations {
+				m := e.match()
+				if weighted(e.spec) {
+					m = matchTag(e.destinationTag(strconv.Itoa(len(weights))))
+				}
+				o, err := output(d, e, m)
+				if err != nil {
+					errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+					continue
+				}
+				if weighted(e.spec) {
+					weights = append(weights, e.spec.Destinations[i].Weight)
+				}
+				addProfile(profiles, e, d)
+				addCredential(creds, e, d)
+				outs = append(outs, block{section: o, sinks: []entry{e}})
+			}
+			if len(outs) == 0 {
+				continue
+			}
+			if ml := e.spec.Multiline; ml != nil {
+				parsers.WriteString(multilineParser(e.parser(), *ml).String())
+			}
+			if e.spec.TimeKey != "" {
+				parsers.WriteString(timeParser(e.timeParser(), e.spec.TimeFormat).String())
+			}
+			// Sinks sharing a ClusterLogParser share its section.
+			for i, name := range parserNames(e.spec) {
+				if !custom[name] {
+					custom[name] = true
+					parsers.WriteString(customParser(name, e.logParsers[i]).String())
+				}
+			}
+			// The audit log is the only input of audit sinks, they get
+			// no container logs.
+			switch {
+			case e.audit():
+				inputs = append(inputs, block{section: e.auditInput(), sinks: []entry{e}})
+			case len(e.spec.HostPaths) != 0:
+				inputs = append(inputs, block{section: e.hostInput(), sinks: []entry{e}})
+			}
+			if !e.audit() {
+				streams = append(streams, block{section: e.stream(all), sinks: []entry{e}})
+			}
+			for _, f := range e.filters {
+				streams = append(streams, block{section: f, sinks: []entry{e}})
+			}
+			if len(weights) != 0 {
+				for _, f := range e.balanceFilters(weights) {
+					streams = append(streams, block{section: f, sinks: []entry{e}})
+				}
+				// The records are buffered again after they are balanced.
+				buffers += e.bufferMB()
+			}
+			streams = append(streams, outs...)
+			buffers += e.bufferMB()
+			continue
+		}
+
+		inShared := false
+		for _, d := range e.destinations {
+			switch {
+			case ownOutput(d):
+				o, err := output(d, e, e.scope(all))
+				if err != nil {
+					errs = append(errs, renderError{e, fmt.Errorf("unable to render sink %s: %s", e, err)})
+					continue
+				}
+				addProfile(profiles, e, d)
+				addCredential(creds, e, d)
+				outputs = append(outputs, block{section: o, sinks: []entry{e}})
+			case e.cluster():
+				clusters = append(clusters, newSink(d, e.cert))
+				inShared = true
+			default:
+				ns := newSink(d, e.cert)
+				ns.Namespace = e.namespace
+				sinks = append(sinks, ns)
+				inShared = true
+			}
+		}
+		if inShared {
+			shared = append(shared, e)
+		}
+	}
+
+	if len(profiles) != 0 {
+		certs[awsCredentialsFile] = awsCredentials(profiles)
+	}
+	if len(creds) != 0 {
+		certs[credentialsFile] = credentialsConf(creds)
+	}
+
+	var blocks []block
+	if len(shared) != 0 {
+		blocks = append(blocks, block{
+			section: syslogOutput(all, sinks, clusters),
+			sinks:   shared,
 		})
 	}
-	sort.Slice(sinks, func(i, j int) bool {
-		if sinks[i].Namespace != sinks[j].Namespace {
-			return sinks[i].Namespace < sinks[j].Namespace
+	blocks = append(blocks, outputs...)
+	blocks = append(blocks, streams...)
+	if len(blocks) == 0 {
+		return rendered{conf: nullConfig, disabled: disabled, missing: missing, errs: errs}
+	}
+	// The cluster name belongs to no sink, it is set before any of them
+	// sees the records.
+	if sc.clusterName != "" {
+		blocks = append([]block{{section: clusterNameFilter(sc.clusterName)}}, blocks...)
+	}
+
+	var (
+		b         strings.Builder
+		outs      = make(map[string][]entry)
+		filters   = make(map[string][]entry)
+		sinkConfs = make(map[string]string)
+		counts    = map[string]map[string]int{
+			"OUTPUT": make(map[string]int),
+			"FILTER": make(map[string]int),
 		}
-		return sinks[i].name < sinks[j].name
-	})
+	)
+	if len(creds) != 0 {
+		b.WriteString(credentialsInclude())
+	}
+	// The inputs of host paths feed the streams of their sinks.
+	for _, in := range inputs {
+		b.WriteString(in.String())
+		for _, e := range in.sinks {
+			sinkConfs[e.String()] += in.String()
+		}
+	}
+	for _, bl := range blocks {
+		b.WriteString(bl.String())
+		for _, e := range bl.sinks {
+			sinkConfs[e.String()] += bl.String()
+		}
+		name := bl.props[0][1]
+		instance := fmt.Sprintf("%s.%d", name, counts[bl.kind][name])
+		counts[bl.kind][name]++
+		switch bl.kind {
+		case "OUTPUT":
+			outs[instance] = bl.sinks
+		case "FILTER":
+			filters[instance] = bl.sinks
+		}
+	}
+	return rendered{
+		conf:      b.String(),
+		parsers:   parsers.String(),
+		certs:     certs,
+		outputs:   outs,
+		filters:   filters,
+		sinkConfs: sinkConfs,
+		bufferMB:  buffers,
+		sockets:   socketDirs(entries),
+		entries:   entries,
+		disabled:  disabled,
+		missing:   missing,
+		conflicts: destinationConflicts(entries),
+		errs:      errs,
+	}
+}
+
+func syslogOutput(m match, sinks, clusterSinks []sink) section {
 	// TODO: don't return null config yet. just set to empty json
 	sinksJSON, err := json.Marshal(sinks)
 	if err != nil {
 		log.Print("unable to marshal sinks")
 		sinksJSON = []byte("[]")
 	}
-
-	clusterSinks := make([]sink, 0, len(sc.clusterSinks))
-	for _, s := range sc.clusterSinks {
-		var tlsConfig *tls
-		if s.Spec.EnableTLS {
-			tlsConfig = &tls{
-				InsecureSkipVerify: s.Spec.InsecureSkipVerify,
-			}
-		}
-		clusterSinks = append(clusterSinks, sink{
-			Addr: fmt.Sprintf("%s:%d", s.Spec.Host, s.Spec.Port),
-			TLS:  tlsConfig,
-			name: s.Name,
-		})
-	}
-	sort.Slice(clusterSinks, func(i, j int) bool {
-		return clusterSinks[i].name < clusterSinks[j].name
-	})
 	clusterSinksJSON, err := json.Marshal(clusterSinks)
 	if err != nil {
 		log.Print("unable to marshal cluster sinks")
 		clusterSinksJSON = []byte("[]")
 	}
 
-	return fmt.Sprintf(`
-[OUTPUT]
-    Name syslog
-    Match *
-    Sinks %s
-    ClusterSinks %s
-`, sinksJSON, clusterSinksJSON)
+	o := newOutput("syslog", m)
+	o.add("Sinks", string(sinksJSON))
+	o.add("ClusterSinks", string(clusterSinksJSON))
+	return o
+}
+
+// entries returns every sink ordered so the rendered config is stable across
+// reconciles: LogSinks by namespace and then name followed by ClusterLogSinks
+// by name.
+func (sc *Config) entries() []entry {
+	entries := make([]entry, 0, len(sc.sinks)+len(sc.clusterSinks))
+	for _, s := range sortedSinks(sc.sinks) {
+		entries = append(entries, entry{
+			spec:         s.Spec,
+			name:         s.Name,
+			namespace:    canonicalNamespace(s.Namespace),
+			uid:          s.UID,
+			destinations: Destinations(s.Spec),
+			logSink:      s,
+		})
+	}
+	for _, s := range sortedClusterSinks(sc.clusterSinks) {
+		entries = append(entries, entry{
+			spec:           s.Spec,
+			name:           s.Name,
+			uid:            s.UID,
+			destinations:   Destinations(s.Spec),
+			clusterLogSink: s,
+		})
+	}
+	return entries
+}
+
+func sortedSinks(m map[string]*v1alpha1.LogSink) []*v1alpha1.LogSink {
+	sinks := make([]*v1alpha1.LogSink, 0, len(m))
+	for _, s := range m {
+		sinks = append(sinks, s)
+	}
+	sort.Slice(sinks, func(i, j int) bool {
+		ni, nj := canonicalNamespace(sinks[i].Namespace), canonicalNamespace(sinks[j].Namespace)
+		if ni != nj {
+			return ni < nj
+		}
+		return sinks[i].Name < sinks[j].Name
+	})
+	return sinks
+}
+
+func sortedClusterSinks(m map[string]*v1alpha1.ClusterLogSink) []*v1alpha1.ClusterLogSink {
+	sinks := make([]*v1alpha1.ClusterLogSink, 0, len(m))
+	for _, s := range m {
+		sinks = append(sinks, s)
+	}
+	sort.Slice(sinks, func(i, j int) bool {
+		return sinks[i].Name < sinks[j].Name
+	})
+	return sinks
+}
+
+func newSink(spec v1alpha1.SinkSpec, cert *clientCert) sink {
+	var tlsConfig *tls
+	if spec.EnableTLS {
+		tlsConfig = &tls{
+			InsecureSkipVerify: spec.InsecureSkipVerify,
+			ServerName:         spec.TLSServerName,
+		}
+		if cert != nil {
+			tlsConfig.CertFile = cert.certFile
+			tlsConfig.KeyFile = cert.keyFile
+			tlsConfig.CAFile = cert.caFile
+		}
+	}
+	addr, protocol := HostPort(spec.Host, spec.Port), spec.Protocol
+	if spec.SocketPath != "" {
+		addr, protocol = spec.SocketPath, "unix"
+	}
+	return sink{
+		Addr:            addr,
+		Protocol:        protocol,
+		TLS:             tlsConfig,
+		Format:          spec.SyslogFormat,
+		AppName:         spec.AppName,
+		MessageTemplate: spec.MessageTemplate,
+		Facility:        spec.SyslogFacility,
+		Severity:        spec.DefaultSeverity,
+	}
 }
 
 func (sc *Config) UpsertSink(s *v1alpha1.LogSink) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
+	sc.generation++
 	sc.sinks[key(s)] = s
 }
 
 func (sc *Config) UpsertClusterSink(cs *v1alpha1.ClusterLogSink) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
+	sc.generation++
 	sc.clusterSinks[clusterKey(cs)] = cs
 }
 
 func (sc *Config) DeleteSink(s *v1alpha1.LogSink) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
+	sc.generation++
 	delete(sc.sinks, key(s))
 }
 
 func (sc *Config) DeleteClusterSink(s *v1alpha1.ClusterLogSink) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
+	sc.generation++
 	delete(sc.clusterSinks, clusterKey(s))
 }
 
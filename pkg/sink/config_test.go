@@ -17,12 +17,18 @@ package sink_test
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
 	"github.com/knative/observability/pkg/sink"
@@ -758,3 +764,4178 @@ func expectConfig(conf string, compare ConfigComparer, t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "http://example.com/logs",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name http\n    Match kube.*_some-namespace_*\n    Host example.com\n    Port 80\n    URI /logs\n    Format json_lines\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestHTTPSinkOptions(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:               "http",
+			URI:                "https://example.com:8443/logs?source=k8s",
+			Format:             "json_array",
+			EnableTLS:          true,
+			InsecureSkipVerify: true,
+			Headers: map[string]string{
+				"X-Second": "b",
+				"X-First":  "a",
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name http\n    Match *\n    Host example.com\n    Port 8443\n    URI /logs?source=k8s\n    Format json\n    tls On\n    tls.verify Off\n    Header X-First a\n    Header X-Second b\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestHTTPSinkWithSyslogSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-1",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-2",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://example.org",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[OUTPUT]\n    Name http\n    Match kube.*_ns1_*\n    Host example.org\n    Port 443\n    URI /\n    Format json_lines\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidHTTPSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "ftp://example.com",
+		},
+	})
+
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestInvalidHTTPHeaders(t *testing.T) {
+	for _, headers := range []map[string]string{
+		{"": "a"},
+		{"X-Scope tenant": "a"},
+		{"X-Scope\n[OUTPUT]\n    Name stdout\n   ": "a"},
+		{"X-Scope": "tenant\n[OUTPUT]\n    Name stdout\n    Match *"},
+		{"X-Scope": "tenant\r\n[OUTPUT]"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name-1",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "http",
+				URI:  "https://example.org",
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name-2",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:    "http",
+				URI:     "https://example.com",
+				Headers: headers,
+			},
+		})
+
+		expected := "\n[OUTPUT]\n    Name http\n    Match kube.*_ns1_*\n    Host example.org\n    Port 443\n    URI /\n    Format json_lines\n    tls On\n"
+		if sc.String() != expected {
+			t.Errorf("Config not equal for headers %q: Expected: %q Actual: %q", headers, expected, sc.String())
+		}
+	}
+}
+
+func TestNamespaceScoping(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "syslog",
+			Namespace: "team-a",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "http",
+			Namespace: "team-a",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://example.com",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster-http",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://example.org",
+		},
+	})
+
+	teamATag := "kube.var.log.containers.app-7d9f_team-a_app-0123abcd.log"
+	teamBTag := "kube.var.log.containers.app-7d9f_team-b_app-0123abcd.log"
+
+	sections := parseSections(sc.String())
+	if len(sections) != 3 {
+		t.Fatalf("Expected 3 sections, got %d", len(sections))
+	}
+
+	var sinks []NamespaceSink
+	err := json.Unmarshal([]byte(sections[0].get("Sinks")), &sinks)
+	if err != nil {
+		t.Fatalf("Could not Unmarshal namespace sink: %s", err)
+	}
+	if len(sinks) != 1 || sinks[0].Namespace != "team-a" {
+		t.Errorf("Expected syslog sink to be scoped to team-a: %v", sinks)
+	}
+
+	namespaced := sections[1].get("Match")
+	if !tagMatches(namespaced, teamATag) {
+		t.Errorf("Expected %s to match %s", namespaced, teamATag)
+	}
+	if tagMatches(namespaced, teamBTag) {
+		t.Errorf("Expected %s to not match %s", namespaced, teamBTag)
+	}
+
+	cluster := sections[2].get("Match")
+	if !tagMatches(cluster, teamATag) || !tagMatches(cluster, teamBTag) {
+		t.Errorf("Expected cluster sink to match all namespaces: %s", cluster)
+	}
+}
+
+type testSection struct {
+	kind  string
+	props [][2]string
+}
+
+func (s testSection) get(key string) string {
+	for _, p := range s.props {
+		if p[0] == key {
+			return p[1]
+		}
+	}
+	return ""
+}
+
+func parseSections(conf string) []testSection {
+	var sections []testSection
+	for _, line := range strings.Split(conf, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			sections = append(sections, testSection{kind: strings.Trim(line, "[]")})
+			continue
+		}
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) != 2 || len(sections) == 0 {
+			continue
+		}
+		current := &sections[len(sections)-1]
+		current.props = append(current.props, [2]string{kv[0], kv[1]})
+	}
+	return sections
+}
+
+// tagMatches reports whether a fluent-bit wildcard match pattern matches the
+// tag. Tags never contain a path separator so path.Match is equivalent.
+func tagMatches(pattern, tag string) bool {
+	ok, _ := path.Match(pattern, tag)
+	return ok
+}
+
+func TestPodSelectorMatchLabels(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"tier": "web",
+					"app":  "my.app",
+				},
+			},
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_some-namespace_*\n    Rule $log .* sink.ns.some-namespace.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.some-namespace.some-name\n    Regex $kubernetes['labels']['app'] ^my\\.app$\n    Regex $kubernetes['labels']['tier'] ^web$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.some-namespace.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestPodSelectorMatchExpressions(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://example.com",
+			PodSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}},
+					{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"batch"}},
+					{Key: "team", Operator: metav1.LabelSelectorOpExists},
+					{Key: "debug", Operator: metav1.LabelSelectorOpDoesNotExist},
+				},
+			},
+		},
+	})
+
+	sections := parseSections(sc.String())
+	if len(sections) != 3 {
+		t.Fatalf("Expected 3 sections, got %d", len(sections))
+	}
+	if sections[0].get("Match_Regex") != `^(?!sink\.).*` {
+		t.Errorf("Expected cluster stream to skip routed records: %v", sections[0].props)
+	}
+	expected := [][2]string{
+		{"Name", "grep"},
+		{"Match", "sink.cluster.some-name"},
+		{"Regex", "$kubernetes['labels']['env'] ^(prod|staging)$"},
+		{"Exclude", "$kubernetes['labels']['tier'] ^(batch)$"},
+		{"Regex", "$kubernetes['labels']['team'] .*"},
+		{"Exclude", "$kubernetes['labels']['debug'] .*"},
+	}
+	if diff := cmp.Diff(expected, sections[1].props); diff != "" {
+		t.Errorf("As (-want, +got) = %v", diff)
+	}
+	if sections[2].get("Name") != "http" || sections[2].get("Match") != "sink.cluster.some-name" {
+		t.Errorf("Expected http output to match the sink stream: %v", sections[2].props)
+	}
+}
+
+func TestEmptyPodSelector(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "syslog",
+			Host:        "example.com",
+			Port:        12345,
+			PodSelector: &metav1.LabelSelector{},
+		},
+	})
+
+	expectConfig(
+		sc.String(),
+		ConfigComparer{
+			Name:  "syslog",
+			Match: "*",
+			NamespaceSinks: []NamespaceSink{
+				{
+					Addr:      "example.com:12345",
+					Namespace: "some-namespace",
+				},
+			},
+		},
+		t,
+	)
+}
+
+func TestPodSelectorWithSharedSinks(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "selected",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "everything",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 45678,
+		},
+	})
+
+	sections := parseSections(sc.String())
+	if len(sections) != 4 {
+		t.Fatalf("Expected 4 sections, got %d", len(sections))
+	}
+	shared := sections[0]
+	if shared.get("ClusterSinks") != `[{"addr":"example.org:45678"}]` {
+		t.Errorf("Expected cluster sink in the shared output: %v", shared.props)
+	}
+	if shared.get("Match_Regex") != `^(?!sink\.).*` {
+		t.Errorf("Expected shared output to skip routed records: %v", shared.props)
+	}
+}
+
+func TestInvalidPodSelector(t *testing.T) {
+	for _, ls := range []*metav1.LabelSelector{
+		{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Like", Values: []string{"prod"}}}},
+		{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "In"}}},
+		{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Exists", Values: []string{"prod"}}}},
+		{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a']['b", Operator: "Exists"}}},
+		{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "NotIn", Values: []string{"prod\n[OUTPUT]\n    Name stdout"}}}},
+		{MatchLabels: map[string]string{"a']['b": "prod"}},
+		{MatchLabels: map[string]string{"": "prod"}},
+		{MatchLabels: map[string]string{"app": "web\n[OUTPUT]\n    Name stdout\n    Match *"}},
+		{MatchLabels: map[string]string{"app": "web frontend"}},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "syslog",
+				Host:        "example.com",
+				Port:        12345,
+				PodSelector: ls,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for selector %v: Expected: %s Actual: %s", ls, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestRetryLimit(t *testing.T) {
+	var tests = []struct {
+		name     string
+		limit    int
+		expected string
+	}{
+		{"limited", 5, "    Retry_Limit 5\n"},
+		{"unlimited", -1, "    Retry_Limit False\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc := sink.NewConfig()
+			sc.UpsertSink(&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-name-1",
+					Namespace: "ns1",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type: "syslog",
+					Host: "example.com",
+					Port: 12345,
+				},
+			})
+			sc.UpsertSink(&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-name-2",
+					Namespace: "ns1",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type:       "syslog",
+					Host:       "example.org",
+					Port:       12346,
+					RetryLimit: test.limit,
+				},
+			})
+
+			expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+				"\n[OUTPUT]\n    Name syslog\n    Match kube.*_ns1_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n" +
+				test.expected
+			if sc.String() != expected {
+				t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+			}
+		})
+	}
+}
+
+func TestRetryLimitHTTPSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "http",
+			URI:        "http://example.com/logs",
+			RetryLimit: 3,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name http\n    Match *\n    Host example.com\n    Port 80\n    URI /logs\n    Format json_lines\n    Retry_Limit 3\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	var tests = []struct {
+		name     string
+		seconds  int
+		expected string
+	}{
+		{"idle timeout", 15, "    net.keepalive on\n    net.keepalive_idle_timeout 15\n"},
+		{"disabled", 0, "    net.keepalive off\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seconds := test.seconds
+			sc := sink.NewConfig()
+			sc.UpsertSink(&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-name-1",
+					Namespace: "ns1",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type: "syslog",
+					Host: "example.com",
+					Port: 12345,
+				},
+			})
+			sc.UpsertSink(&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-name-2",
+					Namespace: "ns1",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type:             "syslog",
+					Host:             "example.org",
+					Port:             12346,
+					KeepAliveSeconds: &seconds,
+				},
+			})
+
+			// The keepalive applies to the sink's own output only.
+			expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+				"\n[OUTPUT]\n    Name syslog\n    Match kube.*_ns1_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n" +
+				test.expected
+			if sc.String() != expected {
+				t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+			}
+		})
+	}
+}
+
+func TestKeepAliveHTTPSink(t *testing.T) {
+	seconds := 5
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:             "http",
+			URI:              "http://example.com/logs",
+			RetryLimit:       3,
+			KeepAliveSeconds: &seconds,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name http\n    Match *\n    Host example.com\n    Port 80\n    URI /logs\n    Format json_lines\n    Retry_Limit 3\n" +
+		"    net.keepalive on\n    net.keepalive_idle_timeout 5\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidKeepAlive(t *testing.T) {
+	seconds := -1
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:             "http",
+			URI:              "http://example.com/logs",
+			KeepAliveSeconds: &seconds,
+		},
+	})
+
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestTLSServerName(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:          "syslog",
+			Host:          "lb.example.com",
+			Port:          6514,
+			EnableTLS:     true,
+			TLSServerName: "syslog.example.com",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:          "http",
+			URI:           "https://lb.example.com/logs",
+			TLSServerName: "logs.example.com",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"lb.example.com:6514\",\"namespace\":\"ns1\",\"tls\":{\"server_name\":\"syslog.example.com\"}}]\n    ClusterSinks []\n" +
+		"\n[OUTPUT]\n    Name http\n    Match *\n    Host lb.example.com\n    Port 443\n    URI /logs\n    Format json_lines\n    tls On\n    tls.vhost logs.example.com\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidTLSServerName(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 514, TLSServerName: "syslog.example.com"},
+		{Type: "syslog", Host: "example.com", Port: 6514, EnableTLS: true, TLSServerName: "-syslog"},
+		{Type: "otlp", Endpoint: "collector:4318", Insecure: true, TLSServerName: "collector.example.com"},
+		{Type: "kafka", Brokers: []string{"kafka:9093"}, Topic: "logs", EnableTLS: true, TLSServerName: "kafka.example.com"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestTemplatedHost(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "${RECEIVER_HOST}",
+			Port: 514,
+			Destinations: []v1alpha1.Destination{
+				{Type: "forward", Host: "fluentd.${ENVIRONMENT}.example.com"},
+			},
+		},
+	})
+
+	// fluent-bit resolves the references when it loads the config.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"${RECEIVER_HOST}:514\"}]\n" +
+		"\n[OUTPUT]\n    Name forward\n    Match *\n    Host fluentd.${ENVIRONMENT}.example.com\n    Port 24224\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidTemplatedHost(t *testing.T) {
+	for _, host := range []string{"$RECEIVER_HOST", "${RECEIVER_HOST", "${DATADOG_API_KEY_0123456789ABCDEF}"} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "splunk",
+				Host: host,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for host %q: Expected: %s Actual: %s", host, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestFlushTimeout(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:                "syslog",
+			Host:                "example.org",
+			Port:                12346,
+			FlushTimeoutSeconds: 30,
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:                "http",
+			URI:                 "http://example.com/logs",
+			FlushTimeoutSeconds: 5,
+		},
+	})
+
+	// Every sink gets an output of its own timing out on its receiver
+	// alone.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match kube.*_ns1_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n" +
+		"    net.connect_timeout 30\n    net.io_timeout 30\n" +
+		"\n[OUTPUT]\n    Name http\n    Match *\n    Host example.com\n    Port 80\n    URI /logs\n    Format json_lines\n" +
+		"    net.connect_timeout 5\n    net.io_timeout 5\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidFlushTimeout(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:                "http",
+			URI:                 "http://example.com/logs",
+			FlushTimeoutSeconds: -1,
+		},
+	})
+
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestPreserveOrder(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-1",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-2",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:          "syslog",
+			Host:          "example.org",
+			Port:          12346,
+			PreserveOrder: true,
+		},
+	})
+
+	// The sink gets an output of its own flushed by a single worker, the
+	// shared output keeps fluent-bit's default.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match kube.*_ns1_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n    Workers 1\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestPreserveOrderHTTPSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:          "http",
+			URI:           "http://example.com/logs",
+			PreserveOrder: true,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name http\n    Match *\n    Host example.com\n    Port 80\n    URI /logs\n    Format json_lines\n    Workers 1\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestRetryDefaults(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestMemoryBuffer(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-1",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-2",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         12346,
+			BufferSizeMB: 10,
+			BufferType:   "memory",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name-2 true\n    Emitter_Mem_Buf_Limit 10M\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name-2\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestFilesystemBuffer(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "http",
+			URI:          "http://example.com/logs",
+			BufferSizeMB: 64,
+			BufferType:   "filesystem",
+		},
+	})
+
+	sections := parseSections(sc.String())
+	if len(sections) != 2 {
+		t.Fatalf("Expected a stream and an output: %v", sections)
+	}
+	if sections[0].get("Emitter_Storage.type") != "filesystem" {
+		t.Errorf("Expected stream to buffer on the filesystem: %v", sections[0].props)
+	}
+	if sections[0].get("Emitter_Mem_Buf_Limit") != "64M" {
+		t.Errorf("Expected mem_buf_limit of 64M: %v", sections[0].props)
+	}
+	if sections[1].get("Match") != "sink.cluster.some-name" {
+		t.Errorf("Expected output to match the stream: %v", sections[1].props)
+	}
+	if sections[1].get("storage.total_limit_size") != "64M" {
+		t.Errorf("Expected output to limit its filesystem buffer: %v", sections[1].props)
+	}
+}
+
+func TestRenderingIsDeterministic(t *testing.T) {
+	sinks := []*v1alpha1.LogSink{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns2"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns2"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.org", Port: 514},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "http",
+				URI:  "https://example.com/logs",
+				Headers: map[string]string{
+					"X-A": "1",
+					"X-B": "2",
+					"X-C": "3",
+					"X-D": "4",
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "d", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.net",
+				Port: 514,
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "a", "tier": "web", "zone": "z"},
+				},
+			},
+		},
+	}
+	clusterSinks := []*v1alpha1.ClusterLogSink{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "y"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 1514},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "x"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.org", Port: 1514},
+		},
+	}
+
+	sc := sink.NewConfig()
+	for _, s := range sinks {
+		sc.UpsertSink(s)
+	}
+	for _, s := range clusterSinks {
+		sc.UpsertClusterSink(s)
+	}
+	expected := sc.String()
+	if actual := sc.String(); actual != expected {
+		t.Errorf("Rendering the same sinks twice differs: Expected: %q Actual: %q", expected, actual)
+	}
+
+	reversed := sink.NewConfig()
+	for i := len(sinks) - 1; i >= 0; i-- {
+		reversed.UpsertSink(sinks[i])
+	}
+	for i := len(clusterSinks) - 1; i >= 0; i-- {
+		reversed.UpsertClusterSink(clusterSinks[i])
+	}
+	if actual := reversed.String(); actual != expected {
+		t.Errorf("Rendering depends on insertion order: Expected: %q Actual: %q", expected, actual)
+	}
+
+	changed := sinks[1].DeepCopy()
+	changed.Spec.Port = 1514
+	sc.UpsertSink(changed)
+	if sc.String() == expected {
+		t.Errorf("Expected a changed sink to change the config")
+	}
+}
+
+func TestDestinations(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "prod.example.com",
+			Port:      514,
+			EnableTLS: true,
+			Destinations: []v1alpha1.Destination{
+				{Host: "archive.example.com", Port: 6514},
+				{Type: "http", Host: "archive.example.org", Port: 8443},
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"prod.example.com:514\",\"namespace\":\"ns1\",\"tls\":{}},{\"addr\":\"archive.example.com:6514\",\"namespace\":\"ns1\",\"tls\":{}}]\n    ClusterSinks []\n" +
+		"\n[OUTPUT]\n    Name http\n    Match kube.*_ns1_*\n    Host archive.example.org\n    Port 8443\n    URI /\n    Format json_lines\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestIPv6Hosts(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "2001:db8::1",
+			Port: 24903,
+			Destinations: []v1alpha1.Destination{
+				{Host: "[2001:db8::2]", Port: 24903},
+				{Type: "http", Host: "2001:db8::3"},
+				{Type: "otlp", Host: "[2001:db8::4]", Port: 4318},
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"[2001:db8::1]:24903\",\"namespace\":\"ns1\"},{\"addr\":\"[2001:db8::2]:24903\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[OUTPUT]\n    Name http\n    Match kube.*_ns1_*\n    Host 2001:db8::3\n    Port 80\n    URI /\n    Format json_lines\n" +
+		"\n[OUTPUT]\n    Name opentelemetry\n    Match kube.*_ns1_*\n    Host 2001:db8::4\n    Port 4318\n    Logs_uri /v1/logs\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestDestinationsOnly(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			Destinations: []v1alpha1.Destination{
+				{Host: "prod.example.com"},
+				{Host: "archive.example.com", Port: 8080},
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name http\n    Match *\n    Host prod.example.com\n    Port 80\n    URI /\n    Format json_lines\n" +
+		"\n[OUTPUT]\n    Name http\n    Match *\n    Host archive.example.com\n    Port 8080\n    URI /\n    Format json_lines\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestDestinationsShareFilters(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://prod.example.com/logs",
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+			Destinations: []v1alpha1.Destination{
+				{Host: "archive.example.com", Port: 8443},
+				{Type: "syslog", Host: "archive.example.org", Port: 514},
+			},
+		},
+	})
+
+	sections := parseSections(sc.String())
+	var kinds, matches, hosts []string
+	for _, s := range sections {
+		kinds = append(kinds, s.kind+" "+s.get("Name"))
+		matches = append(matches, s.get("Match"))
+		hosts = append(hosts, s.get("Host"))
+	}
+	expectedKinds := []string{"FILTER rewrite_tag", "FILTER grep", "OUTPUT http", "OUTPUT http", "OUTPUT syslog"}
+	if diff := cmp.Diff(expectedKinds, kinds); diff != "" {
+		t.Fatalf("Sections not equal (-want, +got) = %v", diff)
+	}
+	for _, m := range matches[1:] {
+		if m != "sink.ns.ns1.some-name" {
+			t.Errorf("Expected every section after the stream to match its tag, got: %s", m)
+		}
+	}
+	if hosts[2] != "prod.example.com" || hosts[3] != "archive.example.com" {
+		t.Errorf("Unexpected http hosts: %v", hosts[2:4])
+	}
+	if uri := sections[3].get("URI"); uri != "/logs" {
+		t.Errorf("Expected destination to keep the sink's path, got: %s", uri)
+	}
+	if s := sections[4].get("ClusterSinks"); s != `[{"addr":"archive.example.org:514"}]` {
+		t.Errorf("Unexpected syslog destination: %s", s)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-1",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name-2",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.org",
+			Port:      12346,
+			ParseJSON: true,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name-2 true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name-2\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name-2\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestDropPatterns(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         12346,
+			DropPatterns: []string{`\b(?:\d{4}[ -]?){3}\d{4}\b`, `password=\S+`},
+			ParseJSON:    true,
+		},
+	})
+
+	// The patterns see the raw line before it is parsed.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.ns1.some-name\n    Exclude log \\b(?:\\d{4}[ -]?){3}\\d{4}\\b\n    Exclude log password=\\S+\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidDropPatterns(t *testing.T) {
+	for _, p := range []string{"", "(", "a\n[OUTPUT]", " leading", "trailing\t"} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.org",
+				Port:         12346,
+				DropPatterns: []string{"secret", p},
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for pattern %q: Expected: %s Actual: %s", p, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+			RedactPatterns: []v1alpha1.RedactRule{
+				{Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, Replacement: "***"},
+				{Pattern: `discount=\d+`, Replacement: `discount=?%`},
+			},
+			ParseJSON: true,
+		},
+	})
+
+	// The rules run in order on the raw line before it is parsed.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call redact\n" +
+		`    code function redact(tag, timestamp, record) local log = record["log"] if type(log) ~= "string" then return 0, timestamp, record end` +
+		` log = string.gsub(log, "[%%+%-.0-9A-Z_a-z]+@[%-.0-9A-Za-z]+%.[A-Za-z][A-Za-z]+", "***")` +
+		` log = string.gsub(log, "discount=[0-9]+", "discount=?%%")` +
+		` record["log"] = log return 2, timestamp, record end` + "\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestRedactPatternTranslation(t *testing.T) {
+	for pattern, want := range map[string]string{
+		`^user=\w+$`:       `"^user=[0-9A-Z_a-z]+$"`,
+		`[^@\s]+@corp`:     `"[^\009-\010\012-\013 @]+@corp"`,
+		`(?i)key:\s?\S*?;`: `"[kK][eE][yY]:[\009-\010\012-\013 ]?[^\009-\010\012-\013 ]-;"`,
+		`a.b`:              `"a[^\010]b"`,
+		`(?s)(x)+.`:        `"x+."`,
+		`100%\.`:           `"100%%%."`,
+		`say "hi"`:         `"say \"hi\""`,
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.org",
+				Port:           12346,
+				RedactPatterns: []v1alpha1.RedactRule{{Pattern: pattern, Replacement: "x"}},
+			},
+		})
+
+		gsub := fmt.Sprintf(`string.gsub(log, %s, "x")`, want)
+		if code := parseSections(sc.String())[1].get("code"); !strings.Contains(code, gsub) {
+			t.Errorf("Lua pattern for %q not equal: Expected: %s Actual: %s", pattern, gsub, code)
+		}
+	}
+}
+
+func TestInvalidRedactPatterns(t *testing.T) {
+	for _, p := range []string{"", "(", "a*", "cat|dog", `\bword`, "(ab)+", "a??", "x^y", "[é]+"} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.org",
+				Port: 12346,
+				RedactPatterns: []v1alpha1.RedactRule{
+					{Pattern: "secret", Replacement: "***"},
+					{Pattern: p, Replacement: "***"},
+				},
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for pattern %q: Expected: %s Actual: %s", p, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestParseJSONAfterPodSelector(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "http",
+			URI:       "http://example.com",
+			ParseJSON: true,
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+		},
+	})
+
+	sections := parseSections(sc.String())
+	var names []string
+	for _, s := range sections {
+		names = append(names, s.get("Name"))
+	}
+	// Records are only parsed once the selector kept them.
+	if diff := cmp.Diff([]string{"rewrite_tag", "grep", "parser", "http"}, names); diff != "" {
+		t.Errorf("Sections not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestMaxRecordsPerSecond(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:                "syslog",
+			Host:                "example.com",
+			Port:                12345,
+			MaxRecordsPerSecond: 100,
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name throttle\n    Match sink.ns.ns1.some-name\n    Rate 100\n    Window 1\n    Interval 1s\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestUnlimitedRecordsPerSecond(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+
+	if strings.Contains(sc.String(), "throttle") {
+		t.Errorf("Expected no throttle without a limit: %s", sc.String())
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	rate := 0.1
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			Host:       "example.com",
+			Port:       12345,
+			SampleRate: &rate,
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call sample\n" +
+		"    code math.randomseed(os.time()) function sample(tag, timestamp, record) if math.random() < 0.1 then return 0, timestamp, record end return -1, 0, 0 end\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestZeroSampleRate(t *testing.T) {
+	rate := 0.0
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			Host:       "example.com",
+			Port:       12345,
+			SampleRate: &rate,
+		},
+	})
+
+	// An explicit zero forwards nothing, unlike an unset rate.
+	if !strings.Contains(sc.String(), "if math.random() < 0 then") {
+		t.Errorf("Expected every record to be dropped: %s", sc.String())
+	}
+}
+
+func TestFullSampleRate(t *testing.T) {
+	one := 1.0
+	for _, rate := range []*float64{nil, &one} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				Host:       "example.com",
+				Port:       12345,
+				SampleRate: rate,
+			},
+		})
+
+		if strings.Contains(sc.String(), "sample") {
+			t.Errorf("Expected no sampling for rate %v: %s", rate != nil, sc.String())
+		}
+	}
+}
+
+func TestInvalidSampleRate(t *testing.T) {
+	for _, rate := range []float64{-0.5, 1.5, math.NaN()} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				Host:       "example.com",
+				Port:       12345,
+				SampleRate: &rate,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for rate %v: Expected: %s Actual: %s", rate, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestSyslogMessageFormat(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "syslog",
+			Host:            "example.com",
+			Port:            12345,
+			SyslogFormat:    "rfc3164",
+			AppName:         "my-app",
+			MessageTemplate: "{{kubernetes.pod_name}}: {{log}}",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\",\"format\":\"rfc3164\",\"app_name\":\"my-app\",\"message_template\":\"{{kubernetes.pod_name}}: {{log}}\"}]\n    ClusterSinks []\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestSyslogFacilityAndSeverity(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "syslog",
+			Host:            "example.com",
+			Port:            12345,
+			SyslogFacility:  "local4",
+			DefaultSeverity: "notice",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\",\"facility\":\"local4\",\"severity\":\"notice\"}]\n    ClusterSinks []\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidSyslogFacilityAndSeverity(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, SyslogFacility: "local8"},
+		{Type: "syslog", Host: "example.com", Port: 12345, SyslogFacility: "LOCAL4"},
+		{Type: "syslog", Host: "example.com", Port: 12345, DefaultSeverity: "warn"},
+		{Type: "syslog", Host: "example.com", Port: 12345, DefaultSeverity: "verbose"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for facility %q and severity %q: Expected: %s Actual: %s", spec.SyslogFacility, spec.DefaultSeverity, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestInvalidMessageTemplate(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "bad-template",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "syslog",
+			Host:            "example.com",
+			Port:            12345,
+			MessageTemplate: "{{kubernetes.labels.}}",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "good-template",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "syslog",
+			Host:            "example.org",
+			Port:            12345,
+			MessageTemplate: "{{ stream }} {{kubernetes.annotations.team}}",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12345\",\"message_template\":\"{{ stream }} {{kubernetes.annotations.team}}\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestExcludeNamespaces(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:              "syslog",
+			Host:              "example.com",
+			Port:              12345,
+			ExcludeNamespaces: []string{"kube-system", "istio-system"},
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-sink",
+			Namespace: "app",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12345,
+		},
+	})
+
+	// kube-system and istio-system records are dropped from the cluster
+	// sink's stream before its other filters and its output, while the
+	// shared output still receives the app namespace.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"example.org:12345\",\"namespace\":\"app\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Exclude $kubernetes['namespace_name'] ^(kube-system|istio-system)$\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Regex $kubernetes['labels']['app'] ^web$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestNamespaceGlobs(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:              "syslog",
+			Host:              "example.com",
+			Port:              12345,
+			NamespaceGlobs:    []string{"team-*", "app-?-v2"},
+			ExcludeNamespaces: []string{"team-legacy"},
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Regex $kubernetes['namespace_name'] ^(team-[a-z0-9-]*|app-[a-z0-9-]-v2)$\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Exclude $kubernetes['namespace_name'] ^(team-legacy)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Fatalf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	regex := strings.TrimPrefix(parseSections(sc.String())[1].get("Regex"), "$kubernetes['namespace_name'] ")
+	re := regexp.MustCompile(regex)
+	for ns, matches := range map[string]bool{
+		"team-a":      true,
+		"team-b":      true,
+		"app-1-v2":    true,
+		"kube-system": false,
+		"my-team-a":   false,
+		"app-12-v2":   false,
+	} {
+		if re.MatchString(ns) != matches {
+			t.Errorf("Expected namespace %s to match %v", ns, matches)
+		}
+	}
+}
+
+func TestInvalidNamespaceGlobs(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, NamespaceGlobs: []string{"Team-*"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, NamespaceGlobs: []string{"team-[ab]"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, NamespaceGlobs: []string{""}},
+		{Type: "syslog", Host: "example.com", Port: 12345, NamespaceGlobs: []string{"team-*"}, HostPaths: []string{"/data/app/*.log"}},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for globs %q: Expected: %s Actual: %s", spec.NamespaceGlobs, emptyConfig, sc.String())
+		}
+	}
+
+	// LogSinks only receive their own namespace.
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "team-a",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, NamespaceGlobs: []string{"team-*"}},
+	})
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestSystemOnly(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			Host:       "example.com",
+			Port:       12345,
+			SystemOnly: true,
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Regex $kubernetes['namespace_name'] ^(kube-system|kube-public|kube-node-lease|knative-observability)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Fatalf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	// The logs of the system components flow, those of the applications
+	// are dropped.
+	regex := strings.TrimPrefix(parseSections(sc.String())[1].get("Regex"), "$kubernetes['namespace_name'] ")
+	re := regexp.MustCompile(regex)
+	for ns, matches := range map[string]bool{
+		"kube-system":           true,
+		"kube-public":           true,
+		"kube-node-lease":       true,
+		"knative-observability": true,
+		"default":               false,
+		"app":                   false,
+		"my-kube-system":        false,
+		"kube-system-apps":      false,
+	} {
+		if re.MatchString(ns) != matches {
+			t.Errorf("Expected namespace %s to match %v", ns, matches)
+		}
+	}
+}
+
+func TestSystemNamespaces(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:              "syslog",
+			Host:              "example.com",
+			Port:              12345,
+			SystemOnly:        true,
+			SystemNamespaces:  []string{"kube-system", "istio-system"},
+			ExcludeNamespaces: []string{"kube-system"},
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Regex $kubernetes['namespace_name'] ^(kube-system|istio-system)$\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Exclude $kubernetes['namespace_name'] ^(kube-system)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidSystemOnly(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, SystemNamespaces: []string{"kube-system"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, SystemOnly: true, SystemNamespaces: []string{"Kube-System"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, SystemOnly: true, SystemNamespaces: []string{""}},
+		{Type: "syslog", Host: "example.com", Port: 12345, SystemOnly: true, NamespaceGlobs: []string{"team-*"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, SystemOnly: true, HostPaths: []string{"/data/app/*.log"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, SystemOnly: true, Source: "audit"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for spec %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+
+	// LogSinks only receive their own namespace.
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "kube-system",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, SystemOnly: true},
+	})
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestNoExcludedNamespaces(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:              "syslog",
+			Host:              "example.com",
+			Port:              12345,
+			ExcludeNamespaces: []string{},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestStream(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:   "syslog",
+			Host:   "example.com",
+			Port:   12345,
+			Stream: "stdout",
+			Multiline: &v1alpha1.Multiline{
+				StartPattern:   `\d{4}-\d{2}-\d{2}`,
+				FlushTimeoutMs: 1000,
+			},
+		},
+	})
+
+	// stderr is dropped before the lines are joined.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name_$TAG true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.ns1.some-name_*\n    Regex stream ^stdout$\n" +
+		"\n[FILTER]\n    Name multiline\n    Match sink.ns.ns1.some-name_*\n    multiline.key_content log\n    multiline.parser multiline-sink.ns.ns1.some-name\n    flush_ms 1000\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Fatalf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	regex := strings.TrimPrefix(parseSections(sc.String())[1].get("Regex"), "stream ")
+	re := regexp.MustCompile(regex)
+	for stream, matches := range map[string]bool{
+		"stdout": true,
+		"stderr": false,
+	} {
+		if re.MatchString(stream) != matches {
+			t.Errorf("Expected stream %s to match %v", stream, matches)
+		}
+	}
+}
+
+func TestAllStreams(t *testing.T) {
+	for _, stream := range []string{"", "all"} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:   "syslog",
+				Host:   "example.com",
+				Port:   12345,
+				Stream: stream,
+			},
+		})
+
+		expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+		if sc.String() != expected {
+			t.Errorf("Config not equal for stream %q: Expected: %q Actual: %q", stream, expected, sc.String())
+		}
+	}
+}
+
+func TestInvalidStream(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, Stream: "stdin"},
+		{Type: "syslog", Host: "example.com", Port: 12345, Stream: "STDOUT"},
+		{Type: "syslog", Host: "example.com", Port: 12345, Stream: "stderr", Source: "audit"},
+		{Type: "syslog", Host: "example.com", Port: 12345, Stream: "stderr", HostPaths: []string{"/data/app/*.log"}},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for stream %q: Expected: %s Actual: %s", spec.Stream, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestIncludeNodeName(t *testing.T) {
+	for key, field := range map[string]string{
+		"":     "hostname",
+		"node": "node",
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:            "syslog",
+				Host:            "example.com",
+				Port:            12345,
+				IncludeNodeName: true,
+				NodeNameKey:     key,
+			},
+		})
+
+		expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+			"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call node_name\n" +
+			`    code function node_name(tag, timestamp, record) local k = record["kubernetes"] ` +
+			`if type(k) ~= "table" or k["host"] == nil then return 0, timestamp, record end ` +
+			`record["` + field + `"] = k["host"] return 2, timestamp, record end` + "\n" +
+			"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+		if sc.String() != expected {
+			t.Errorf("Config not equal for node name key %q: Expected: %q Actual: %q", key, expected, sc.String())
+		}
+	}
+}
+
+func TestNodeNameNotIncludedByDefault(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+
+	if strings.Contains(sc.String(), "node_name") {
+		t.Errorf("Expected no node name filter: %q", sc.String())
+	}
+}
+
+func TestInvalidNodeNameKey(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{NodeNameKey: "node"},
+		{IncludeNodeName: true, NodeNameKey: "node name"},
+		{IncludeNodeName: true, NodeNameKey: "kubernetes"},
+		{IncludeNodeName: true, Source: "audit"},
+		{IncludeNodeName: true, HostPaths: []string{"/var/log/syslog"}},
+	} {
+		spec.Type = "syslog"
+		spec.Host = "example.com"
+		spec.Port = 12345
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for spec %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestExclusiveMatch(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:              "syslog",
+			Host:              "example.com",
+			Port:              12345,
+			ExcludeNamespaces: []string{"kube-system", "app"},
+		},
+	})
+	for _, name := range []string{"app-sink", "other-app-sink"} {
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "app",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.org",
+				Port:           12345,
+				ExclusiveMatch: true,
+			},
+		})
+	}
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claimed-sink",
+			Namespace: "claimed",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:           "syslog",
+			Host:           "example.net",
+			Port:           12345,
+			ExclusiveMatch: true,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared-sink",
+			Namespace: "shared",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12345,
+		},
+	})
+
+	// A pod in app or claimed matches the LogSinks of its namespace and the
+	// ClusterLogSink, but only the LogSinks forward its records. The shared
+	// namespace has no exclusive sink and is still forwarded by both.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"example.org:12345\",\"namespace\":\"app\"},{\"addr\":\"example.org:12345\",\"namespace\":\"app\"},{\"addr\":\"example.net:12345\",\"namespace\":\"claimed\"},{\"addr\":\"example.org:12345\",\"namespace\":\"shared\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Exclude $kubernetes['namespace_name'] ^(kube-system|app|claimed)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	sc.DeleteSink(&v1alpha1.LogSink{ObjectMeta: metav1.ObjectMeta{Name: "claimed-sink", Namespace: "claimed"}})
+	expected = "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"example.org:12345\",\"namespace\":\"app\"},{\"addr\":\"example.org:12345\",\"namespace\":\"app\"},{\"addr\":\"example.org:12345\",\"namespace\":\"shared\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Exclude $kubernetes['namespace_name'] ^(kube-system|app)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal after the claim was released: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestExclusiveMatchWithPodSelector(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-sink",
+			Namespace: "app",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:           "syslog",
+			Host:           "example.org",
+			Port:           12345,
+			ExclusiveMatch: true,
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+		},
+	})
+
+	// The LogSink is not rendered, so it claims nothing and the
+	// ClusterLogSink keeps forwarding the namespace.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestOTLPSinkTLS(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "otlp",
+			Endpoint: "collector.example.com:4318",
+			Headers: map[string]string{
+				"Authorization": "Bearer token",
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name opentelemetry\n    Match kube.*_some-namespace_*\n    Host collector.example.com\n    Port 4318\n    Logs_uri /v1/logs\n    tls On\n    Header Authorization Bearer token\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestOTLPSinkSkipVerify(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:               "otlp",
+			Endpoint:           "[::1]:4318",
+			InsecureSkipVerify: true,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name opentelemetry\n    Match *\n    Host ::1\n    Port 4318\n    Logs_uri /v1/logs\n    tls On\n    tls.verify Off\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestOTLPSinkInsecure(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "otlp",
+			Endpoint: "otel-collector:4318",
+			Insecure: true,
+			Destinations: []v1alpha1.Destination{
+				{Host: "otel-backup", Port: 4318},
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name opentelemetry\n    Match *\n    Host otel-collector\n    Port 4318\n    Logs_uri /v1/logs\n    tls Off\n" +
+		"\n[OUTPUT]\n    Name opentelemetry\n    Match *\n    Host otel-backup\n    Port 4318\n    Logs_uri /v1/logs\n    tls Off\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidOTLPSink(t *testing.T) {
+	for _, endpoint := range []string{"", "collector", ":4318", "collector:otlp", "collector:70000"} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:     "otlp",
+				Endpoint: endpoint,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for endpoint %q: Expected: %s Actual: %s", endpoint, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestInvalidOTLPHeaders(t *testing.T) {
+	for _, headers := range []map[string]string{
+		{"": "a"},
+		{"X-Scope-OrgID tenant": "a"},
+		{"X-Scope-OrgID": "tenant\n[OUTPUT]\n    Name stdout\n    Match *"},
+		{"X-Scope-OrgID": "tenant\r\n[OUTPUT]"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name-1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:     "otlp",
+				Endpoint: "otel-collector:4318",
+				Insecure: true,
+			},
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name-2",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:     "otlp",
+				Endpoint: "otel-other:4318",
+				Insecure: true,
+				Headers:  headers,
+				Destinations: []v1alpha1.Destination{
+					{Host: "otel-backup", Port: 4318},
+				},
+			},
+		})
+
+		// Neither destination of the sink is rendered.
+		expected := "\n[OUTPUT]\n    Name opentelemetry\n    Match *\n    Host otel-collector\n    Port 4318\n    Logs_uri /v1/logs\n    tls Off\n"
+		if sc.String() != expected {
+			t.Errorf("Config not equal for headers %q: Expected: %q Actual: %q", headers, expected, sc.String())
+		}
+	}
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("some-namespace", "es-credentials", "fluent:s3cr3t"))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:               "elasticsearch",
+			Host:               "es.example.com",
+			Port:               9200,
+			EnableTLS:          true,
+			InsecureSkipVerify: true,
+			Index:              "knative-logs",
+			Pipeline:           "add-cluster",
+			SecretRef:          &v1alpha1.SecretKeyRef{Name: "es-credentials", Key: "token"},
+		},
+	})
+
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name es\n    Match kube.*_some-namespace_*\n    Host es.example.com\n    Port 9200\n    Index knative-logs\n    Pipeline add-cluster\n" +
+		"    Suppress_Type_Name On\n    Replace_Dots On\n    HTTP_User fluent\n    HTTP_Passwd ${ELASTICSEARCH_PASSWORD_B0E35BA9AA9F885D}\n    tls On\n    tls.verify Off\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestElasticsearchDateIndex(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "elasticsearch",
+			Host:            "elasticsearch.logging",
+			Port:            9200,
+			Index:           "logs",
+			IndexDateFormat: "%Y.%m.%d",
+			Destinations: []v1alpha1.Destination{
+				{Host: "elasticsearch.backup", Port: 9200},
+			},
+		},
+	})
+
+	output := "    Port 9200\n    Logstash_Format On\n    Logstash_Prefix logs\n    Logstash_DateFormat %Y.%m.%d\n    Suppress_Type_Name On\n    Replace_Dots On\n"
+	expected := "\n[OUTPUT]\n    Name es\n    Match *\n    Host elasticsearch.logging\n" + output +
+		"\n[OUTPUT]\n    Name es\n    Match *\n    Host elasticsearch.backup\n" + output
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestElasticsearchIndexTemplate(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "elasticsearch",
+			Host:            "elasticsearch.logging",
+			Port:            9200,
+			Index:           "logs-${kubernetes.namespace_name}-${kubernetes.labels.app.kubernetes.io/name}",
+			IndexDateFormat: "%Y.%m.%d",
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.cluster.some-name\n    call es_index\n    code function es_index(tag, timestamp, record) " +
+		`local function get(...) local v = record for _, k in ipairs({...}) do if type(v) ~= "table" then return nil end v = v[k] end ` +
+		`if type(v) == "string" or type(v) == "number" then return tostring(v) end end ` +
+		`local v1 = get("kubernetes", "namespace_name") if v1 == nil then record["es_index"] = nil return 2, timestamp, record end ` +
+		`local v3 = get("kubernetes", "labels", "app.kubernetes.io/name") if v3 == nil then record["es_index"] = nil return 2, timestamp, record end ` +
+		`record["es_index"] = string.lower((string.gsub("logs-" .. v1 .. "-" .. v3, "[%s\\/%*%?\"<>|,#:]", "_"))) return 2, timestamp, record end` + "\n" +
+		"\n[OUTPUT]\n    Name es\n    Match sink.cluster.some-name\n    Host elasticsearch.logging\n    Port 9200\n" +
+		"    Logstash_Format On\n    Logstash_Prefix logs\n    Logstash_Prefix_Key $es_index\n    Logstash_DateFormat %Y.%m.%d\n    Suppress_Type_Name On\n    Replace_Dots On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestElasticsearchIndexTemplateOutputFields(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "elasticsearch",
+			Host:            "elasticsearch.logging",
+			Port:            9200,
+			Index:           "logs-${kubernetes.namespace_name}",
+			IndexDateFormat: "%Y.%m.%d",
+			OutputFields:    []string{"log"},
+		},
+	})
+
+	// The index of the record is kept along with the listed fields.
+	expected := "\n[FILTER]\n    Name record_modifier\n    Match sink.cluster.some-name\n    Whitelist_key log\n    Whitelist_key es_index\n"
+	if conf := sc.String(); !strings.Contains(conf, expected) {
+		t.Errorf("Expected the config to contain %q: %q", expected, conf)
+	}
+}
+
+func TestInvalidElasticsearchSink(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Index: ""},
+		{Index: "Logs"},
+		{Index: "_logs"},
+		{Index: "logs/app"},
+		{Index: ".."},
+		{Index: "logs-${kubernetes.namespace_name}"},
+		{Index: "${kubernetes.namespace_name}", IndexDateFormat: "%Y.%m.%d"},
+		{Index: "-${kubernetes.namespace_name}", IndexDateFormat: "%Y.%m.%d"},
+		{Index: "logs-${kubernetes.namespace}", IndexDateFormat: "%Y.%m.%d"},
+		{Index: "logs-${kubernetes.namespace_name", IndexDateFormat: "%Y.%m.%d"},
+		{Index: "Logs-${kubernetes.namespace_name}", IndexDateFormat: "%Y.%m.%d"},
+		{Index: "logs", IndexDateFormat: "%b-%d"},
+		{Index: "logs", IndexDateFormat: "%Y %m"},
+		{Index: "logs", IndexDateFormat: "%Y%"},
+		{Index: "logs", Pipeline: "add cluster"},
+		{Index: "logs", SecretRef: &v1alpha1.SecretKeyRef{Name: "es-credentials", Key: "token"}},
+	} {
+		spec.Type = "elasticsearch"
+		spec.Host = "es.example.com"
+		spec.Port = 9200
+		sc := sink.NewConfig()
+		sc.UpsertSecret(secret("some-namespace", "es-credentials", "no-password"))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for spec %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:    "kafka",
+			Brokers: []string{"kafka-0.kafka:9092", "kafka-1.kafka:9092", "[fd00::3]:9092"},
+			Topic:   "knative-logs",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name kafka\n    Match *\n    Brokers kafka-0.kafka:9092,kafka-1.kafka:9092,[fd00::3]:9092\n    Topics knative-logs\n    Format json\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestKafkaSinkSASL(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("some-namespace", "kafka-credentials", "fluent:s3cr3t\n"))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:               "kafka",
+			Brokers:            []string{"kafka.example.com:9093"},
+			Topic:              "logs.some-namespace",
+			EnableTLS:          true,
+			InsecureSkipVerify: true,
+			SecretRef:          &v1alpha1.SecretKeyRef{Name: "kafka-credentials", Key: "token"},
+			Destinations: []v1alpha1.Destination{
+				{Host: "kafka-backup.example.com", Port: 9093},
+			},
+		},
+	})
+
+	auth := "    Topics logs.some-namespace\n    Format json\n    rdkafka.security.protocol SASL_SSL\n    rdkafka.sasl.mechanism PLAIN\n" +
+		"    rdkafka.sasl.username fluent\n    rdkafka.sasl.password ${KAFKA_SASL_PASSWORD_B0E35BA9AA9F885D}\n    rdkafka.enable.ssl.certificate.verification false\n"
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name kafka\n    Match kube.*_some-namespace_*\n    Brokers kafka.example.com:9093\n" + auth +
+		"\n[OUTPUT]\n    Name kafka\n    Match kube.*_some-namespace_*\n    Brokers kafka-backup.example.com:9093\n" + auth
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidKafkaSink(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Topic: "logs"},
+		{Brokers: []string{"kafka:9092"}},
+		{Brokers: []string{"kafka"}, Topic: "logs"},
+		{Brokers: []string{"kafka:0"}, Topic: "logs"},
+		{Brokers: []string{"kafka:9092,other:9092"}, Topic: "logs"},
+		{Brokers: []string{"kafka:9092"}, Topic: "knative logs"},
+		{Brokers: []string{"kafka:9092"}, Topic: "logs", SecretRef: &v1alpha1.SecretKeyRef{Name: "kafka-credentials", Key: "token"}},
+	} {
+		spec.Type = "kafka"
+		sc := sink.NewConfig()
+		sc.UpsertSecret(secret("some-namespace", "kafka-credentials", "no-password"))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for spec %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "otlp",
+			Endpoint:    "collector.example.com:4318",
+			Compression: "gzip",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "http",
+			URI:         "http://example.com/logs",
+			Compression: "gzip",
+			Destinations: []v1alpha1.Destination{
+				{Host: "backup.example.com"},
+			},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "uncompressed",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "http",
+			URI:         "http://example.org/logs",
+			Compression: "none",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name opentelemetry\n    Match kube.*_some-namespace_*\n    Host collector.example.com\n    Port 4318\n    Logs_uri /v1/logs\n    tls On\n    compress gzip\n" +
+		"\n[OUTPUT]\n    Name http\n    Match *\n    Host example.com\n    Port 80\n    URI /logs\n    Format json_lines\n    compress gzip\n" +
+		"\n[OUTPUT]\n    Name http\n    Match *\n    Host backup.example.com\n    Port 80\n    URI /logs\n    Format json_lines\n    compress gzip\n" +
+		"\n[OUTPUT]\n    Name http\n    Match *\n    Host example.org\n    Port 80\n    URI /logs\n    Format json_lines\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidCompression(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 514, Compression: "gzip"},
+		{Type: "syslog", Host: "example.com", Port: 514, Compression: "none"},
+		{Type: "http", URI: "http://example.com/logs", Compression: "zstd"},
+		{
+			Type:         "http",
+			URI:          "http://example.com/logs",
+			Compression:  "gzip",
+			Destinations: []v1alpha1.Destination{{Type: "syslog", Host: "example.com", Port: 514}},
+		},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestMinSeverity(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "syslog",
+			Host:        "example.com",
+			Port:        12345,
+			ParseJSON:   true,
+			MinSeverity: "warn",
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.ns1.some-name\n" +
+		"    Exclude level ^(?i)(notice|info|informational|debug|trace)$\n" +
+		"    Exclude severity ^(?i)(notice|info|informational|debug|trace)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Fatalf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	regex := strings.TrimPrefix(parseSections(sc.String())[2].get("Exclude"), "level ")
+	re := regexp.MustCompile(regex)
+	for level, dropped := range map[string]bool{
+		"INFO":    true,
+		"info":    true,
+		"Debug":   true,
+		"notice":  true,
+		"WARN":    false,
+		"warning": false,
+		"ERROR":   false,
+		"fatal":   false,
+		"info2":   false,
+	} {
+		if re.MatchString(level) != dropped {
+			t.Errorf("Expected level %s to be dropped %v", level, dropped)
+		}
+	}
+}
+
+func TestMinSeverityTrace(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "syslog",
+			Host:        "example.com",
+			Port:        12345,
+			ParseJSON:   true,
+			MinSeverity: "trace",
+		},
+	})
+
+	// The least severe level forwards every record.
+	if strings.Contains(sc.String(), "Name grep") {
+		t.Errorf("Expected no grep filter: %s", sc.String())
+	}
+}
+
+func TestInvalidMinSeverity(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, ParseJSON: true, MinSeverity: "WARN"},
+		{Type: "syslog", Host: "example.com", Port: 12345, ParseJSON: true, MinSeverity: "severe"},
+		{Type: "syslog", Host: "example.com", Port: 12345, MinSeverity: "warn"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for min severity %q: Expected: %s Actual: %s", spec.MinSeverity, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.SetSocketDirs([]string{"/var/run/collector"})
+	// Only ClusterLogSinks may forward to a socket.
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			SocketPath: "/var/run/collector/syslog.sock",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-cluster-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			SocketPath: "/var/run/collector/syslog.sock",
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n" +
+		"    ClusterSinks [{\"addr\":\"/var/run/collector/syslog.sock\",\"protocol\":\"unix\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidSocketPath(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", SocketPath: "var/run/collector/syslog.sock"},
+		{Type: "syslog", SocketPath: "/var/run/../collector/syslog.sock"},
+		{Type: "syslog", SocketPath: "/syslog.sock"},
+		{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock", Host: "example.com", Port: 12345},
+		{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock", Protocol: "udp"},
+		{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock", EnableTLS: true},
+		{Type: "http", SocketPath: "/var/run/collector/http.sock"},
+		{Type: "syslog", SocketPath: "/var/run/agent/syslog.sock"},
+		{Type: "syslog", SocketPath: "/var/log/syslog.sock"},
+	} {
+		sc := sink.NewConfig()
+		sc.SetSocketDirs([]string{"/var/run/collector", "/var/log"})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for spec %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			ParseJSON: true,
+			Labels: map[string]string{
+				"environment":  "production",
+				"cluster_name": "us east 1",
+			},
+		},
+	})
+
+	// Labels are set after parsing so a field of the same name in the log
+	// line does not replace them.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.ns.ns1.some-name\n    Set cluster_name us east 1\n    Set environment production\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestClusterName(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.SetClusterName("us-east-1")
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:   "syslog",
+			Host:   "example.com",
+			Port:   12346,
+			Labels: map[string]string{"environment": "production"},
+		},
+	})
+
+	// The cluster is set on every record ahead of the filters of the
+	// sinks.
+	expected := "\n[FILTER]\n    Name modify\n    Match *\n    Set cluster us-east-1\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.cluster.some-name\n    Set environment production\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestClusterNameOutputFields(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.SetClusterName("us-east-1")
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12345,
+			OutputFields: []string{"log", "cluster"},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "other-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12346,
+			OutputFields: []string{"log"},
+		},
+	})
+
+	// The cluster is kept whether or not the sink lists it.
+	for _, name := range []string{"other-name", "some-name"} {
+		expected := "\n[FILTER]\n    Name record_modifier\n    Match sink.cluster." + name + "\n    Whitelist_key log\n    Whitelist_key cluster\n\n"
+		if conf := sc.String(); !strings.Contains(conf, expected) {
+			t.Errorf("Expected the config to contain %q: %q", expected, conf)
+		}
+	}
+
+	sc.SetClusterName("")
+	expected := "\n[FILTER]\n    Name record_modifier\n    Match sink.cluster.other-name\n    Whitelist_key log\n\n"
+	if conf := sc.String(); !strings.Contains(conf, expected) {
+		t.Errorf("Expected no cluster to be kept without a cluster name, the config to contain %q: %q", expected, conf)
+	}
+}
+
+func TestNoClusterName(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.SetClusterName("us-east-1")
+	if sc.String() != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, sc.String())
+	}
+
+	sc.SetClusterName("")
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+	if strings.Contains(sc.String(), "Set cluster") {
+		t.Errorf("Expected no cluster name: %s", sc.String())
+	}
+}
+
+func TestInvalidClusterName(t *testing.T) {
+	for _, name := range []string{"us east 1", "us-east-1\n[OUTPUT]", "-us-east-1", strings.Repeat("a", 64)} {
+		if sink.ValidateClusterName(name) == nil {
+			t.Errorf("Expected cluster name %q to be invalid", name)
+		}
+	}
+	for _, name := range []string{"us-east-1", "prod.eu_west"} {
+		if err := sink.ValidateClusterName(name); err != nil {
+			t.Errorf("Expected cluster name %q to be valid: %s", name, err)
+		}
+	}
+}
+
+func TestInvalidLabels(t *testing.T) {
+	for _, labels := range []map[string]string{
+		{"": "production"},
+		{"cluster name": "us-east-1"},
+		{"kubernetes": "replaced"},
+		{"log": "replaced"},
+		{"environment": ""},
+		{"environment": "production\n[OUTPUT]"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:   "syslog",
+				Host:   "example.com",
+				Port:   12345,
+				Labels: labels,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for labels %v: Expected: %s Actual: %s", labels, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestRenameKeys(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			ParseJSON: true,
+			RenameKeys: map[string]string{
+				"log":   "message",
+				"level": "severity",
+			},
+			Labels: map[string]string{
+				"environment": "production",
+			},
+		},
+	})
+
+	// Fields parsed from the line are renamed too, the labels are set
+	// after the renames.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.ns.ns1.some-name\n    Rename level severity\n    Rename log message\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.ns.ns1.some-name\n    Set environment production\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidRenameKeys(t *testing.T) {
+	for _, renames := range []map[string]string{
+		{"": "message"},
+		{"log": ""},
+		{"log message": "message"},
+		{"log": "log\n[OUTPUT]"},
+		{"log": "message", "msg": "message"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				Host:       "example.com",
+				Port:       12345,
+				RenameKeys: renames,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for renames %v: Expected: %s Actual: %s", renames, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestOutputFields(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12345,
+			ParseJSON:    true,
+			RenameKeys:   map[string]string{"msg": "message"},
+			Labels:       map[string]string{"environment": "production"},
+			OutputFields: []string{"message", "level", "environment"},
+			Encoding:     "us-ascii",
+		},
+	})
+
+	// Only the listed fields are left once the fields are renamed and the
+	// labels set, the encoding then only converts them.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.ns.ns1.some-name\n    Rename msg message\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.ns.ns1.some-name\n    Set environment production\n" +
+		"\n[FILTER]\n    Name record_modifier\n    Match sink.ns.ns1.some-name\n    Whitelist_key message\n    Whitelist_key level\n    Whitelist_key environment\n"
+	conf := sc.String()
+	if !strings.HasPrefix(conf, expected) {
+		t.Errorf("Config does not start with the filters: Expected: %q Actual: %q", expected, conf)
+	}
+	if strings.Contains(conf, "Whitelist_key kubernetes") || strings.Contains(conf, "Whitelist_key log\n") {
+		t.Errorf("Expected only the listed fields to be kept: %q", conf)
+	}
+}
+
+func TestNoOutputFieldsForwardTheWholeRecord(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12345,
+			OutputFields: []string{},
+		},
+	})
+
+	if strings.Contains(sc.String(), "record_modifier") {
+		t.Errorf("Expected the whole record to be forwarded: %q", sc.String())
+	}
+}
+
+func TestInvalidOutputFields(t *testing.T) {
+	for _, fields := range [][]string{
+		{""},
+		{"log message"},
+		{"log\n[OUTPUT]"},
+		{"log", "kubernetes", "log"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         12345,
+				OutputFields: fields,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for fields %q: Expected: %s Actual: %s", fields, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestMatchExpr(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "alerts",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "alerts.example.com",
+			Port:      12345,
+			MatchExpr: &v1alpha1.MatchExpr{Key: "log", Regex: "panic"},
+		},
+	})
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "archive",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "archive.example.com",
+			Port: 12345,
+		},
+	})
+
+	// Only the records of the alerts sink are matched against the
+	// expression, the archive receives every line.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks [{\"addr\":\"archive.example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.alerts true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.ns1.alerts\n    Regex log panic\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.alerts\n    Sinks []\n    ClusterSinks [{\"addr\":\"alerts.example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidMatchExpr(t *testing.T) {
+	for _, e := range []v1alpha1.MatchExpr{
+		{Key: "", Regex: "panic"},
+		{Key: "log line", Regex: "panic"},
+		{Key: "log", Regex: ""},
+		{Key: "log", Regex: "("},
+		{Key: "log", Regex: "panic\n[OUTPUT]"},
+		{Key: "log", Regex: " panic"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:      "syslog",
+				Host:      "example.com",
+				Port:      12345,
+				MatchExpr: &e,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for expression %+v: Expected: %s Actual: %s", e, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestSecretRef(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("some-namespace", "log-service", "abc123\n"))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://logs.example.com/ingest",
+			Headers: map[string]string{
+				"X-Source": "knative",
+			},
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+		},
+	})
+
+	// The token stays out of the config, which only references its variable.
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name http\n    Match kube.*_some-namespace_*\n    Host logs.example.com\n    Port 443\n    URI /ingest\n    Format json_lines\n    tls On\n" +
+		"    Header Authorization Bearer ${HTTP_BEARER_TOKEN_B0E35BA9AA9F885D}\n    Header X-Source knative\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestClusterSinkSecretRef(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("knative-observability", "otel-collector", "abc123"))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "otlp",
+			Endpoint: "otel-collector:4318",
+			Destinations: []v1alpha1.Destination{
+				{Host: "otel-backup", Port: 4318},
+			},
+			SecretRef: &v1alpha1.SecretKeyRef{
+				Namespace: "knative-observability",
+				Name:      "otel-collector",
+				Key:       "token",
+			},
+		},
+	})
+
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name opentelemetry\n    Match *\n    Host otel-collector\n    Port 4318\n    Logs_uri /v1/logs\n    tls On\n    Header Authorization Bearer ${OTLP_BEARER_TOKEN_895B2398F1889D85}\n" +
+		"\n[OUTPUT]\n    Name opentelemetry\n    Match *\n    Host otel-backup\n    Port 4318\n    Logs_uri /v1/logs\n    tls On\n    Header Authorization Bearer ${OTLP_BEARER_TOKEN_895B2398F1889D85}\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestUnresolvableSecretRef(t *testing.T) {
+	for _, s := range []*coreV1.Secret{
+		nil,
+		secret("other-namespace", "log-service", "abc123"),
+		secret("some-namespace", "other-service", "abc123"),
+		secret("some-namespace", "log-service", ""),
+		secret("some-namespace", "log-service", "abc 123"),
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "log-service", Namespace: "some-namespace"},
+			Data:       map[string][]byte{"password": []byte("abc123")},
+		},
+	} {
+		sc := sink.NewConfig()
+		if s != nil {
+			sc.UpsertSecret(s)
+		}
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:      "http",
+				URI:       "https://logs.example.com/ingest",
+				SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for secret %v: Expected: %s Actual: %s", s, emptyConfig, sc.String())
+		}
+	}
+}
+
+func secret(namespace, name, token string) *coreV1.Secret {
+	return &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"token": []byte(token),
+		},
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+			Multiline: &v1alpha1.Multiline{
+				StartPattern:   `\d{4}-\d{2}-\d{2}`,
+				FlushTimeoutMs: 1000,
+			},
+			ParseJSON: true,
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name_$TAG true\n" +
+		"\n[FILTER]\n    Name multiline\n    Match sink.ns.ns1.some-name_*\n    multiline.key_content log\n    multiline.parser multiline-sink.ns.ns1.some-name\n    flush_ms 1000\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name_*\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	expectedParsers := "\n[MULTILINE_PARSER]\n    name multiline-sink.ns.ns1.some-name\n    type regex\n    flush_timeout 1000\n" +
+		"    rule \"start_state\" \"/^(?:\\d{4}-\\d{2}-\\d{2})/\" \"cont\"\n" +
+		"    rule \"cont\" \"/^(?!(?:\\d{4}-\\d{2}-\\d{2}))/\" \"cont\"\n"
+	if sc.Parsers() != expectedParsers {
+		t.Errorf("Parsers not equal: Expected: %q Actual: %q", expectedParsers, sc.Parsers())
+	}
+}
+
+func TestMultilineMaxLines(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+			Multiline: &v1alpha1.Multiline{
+				StartPattern: `\S`,
+				MaxLines:     3,
+			},
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name_$TAG true\n" +
+		"\n[FILTER]\n    Name multiline\n    Match sink.cluster.some-name_*\n    multiline.key_content log\n    multiline.parser multiline-sink.cluster.some-name\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name_*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	expectedParsers := "\n[MULTILINE_PARSER]\n    name multiline-sink.cluster.some-name\n    type regex\n" +
+		"    rule \"start_state\" \"/^(?:\\S)/\" \"cont_2\"\n" +
+		"    rule \"cont_2\" \"/^(?!(?:\\S))/\" \"cont_3\"\n" +
+		"    rule \"cont_3\" \"/^(?!(?:\\S))/\" \"cont_4\"\n" +
+		"    rule \"cont_4\" \"/(?!)/\" \"cont_4\"\n"
+	if sc.Parsers() != expectedParsers {
+		t.Errorf("Parsers not equal: Expected: %q Actual: %q", expectedParsers, sc.Parsers())
+	}
+}
+
+func TestInvalidMultiline(t *testing.T) {
+	for _, ml := range []v1alpha1.Multiline{
+		{},
+		{StartPattern: "("},
+		{StartPattern: `"quoted"`},
+		{StartPattern: `\S`, MaxLines: -1},
+		{StartPattern: `\S`, MaxLines: 501},
+		{StartPattern: `\S`, FlushTimeoutMs: -1},
+	} {
+		ml := ml
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:      "syslog",
+				Host:      "example.com",
+				Port:      12345,
+				Multiline: &ml,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for multiline %+v: Expected: %s Actual: %s", ml, emptyConfig, sc.String())
+		}
+		if sc.Parsers() != "" {
+			t.Errorf("Expected no parsers for multiline %+v: %s", ml, sc.Parsers())
+		}
+	}
+}
+
+func TestTimestampNormalization(t *testing.T) {
+	for _, format := range []string{
+		// 10/Oct/2026:13:55:36 -0700
+		"%d/%b/%Y:%H:%M:%S %z",
+		// 2026-10-14 09:21:07,123
+		"%Y-%m-%d %H:%M:%S,%L",
+		// 1791970867
+		"%s",
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "http",
+				URI:        "https://logs.example.com/ingest",
+				ParseJSON:  true,
+				TimeKey:    "ts",
+				TimeFormat: format,
+			},
+		})
+
+		// The time key is parsed from the line before it is normalized.
+		expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+			"\n[FILTER]\n    Name parser\n    Match sink.cluster.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+			"\n[FILTER]\n    Name parser\n    Match sink.cluster.some-name\n    Key_Name ts\n    Parser time-sink.cluster.some-name\n    Reserve_Data On\n" +
+			"\n[FILTER]\n    Name lua\n    Match sink.cluster.some-name\n    call normalize_time\n" +
+			`    code function normalize_time(tag, timestamp, record) local seconds = math.floor(timestamp) ` +
+			`record["ts"] = os.date("!%Y-%m-%dT%H:%M:%S", seconds) .. string.format(".%03dZ", math.floor((timestamp - seconds) * 1000)) ` +
+			`return 2, timestamp, record end` + "\n" +
+			"\n[OUTPUT]\n    Name http\n    Match sink.cluster.some-name\n    Host logs.example.com\n    Port 443\n    URI /ingest\n    Format json_lines\n    tls On\n"
+		if sc.String() != expected {
+			t.Errorf("Config not equal for format %q: Expected: %q Actual: %q", format, expected, sc.String())
+		}
+
+		expectedParsers := "\n[PARSER]\n    Name time-sink.cluster.some-name\n    Format regex\n    Regex ^(?<time>.+)$\n    Time_Key time\n    Time_Format " + format + "\n"
+		if sc.Parsers() != expectedParsers {
+			t.Errorf("Parsers not equal for format %q: Expected: %q Actual: %q", format, expectedParsers, sc.Parsers())
+		}
+	}
+}
+
+func TestNoTimestampNormalization(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+		},
+	})
+
+	if strings.Contains(sc.String(), "[FILTER]") || sc.Parsers() != "" {
+		t.Errorf("Expected no filters or parsers: Config: %q Parsers: %q", sc.String(), sc.Parsers())
+	}
+}
+
+func TestInvalidTimestampNormalization(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{TimeKey: "ts"},
+		{TimeFormat: "%s"},
+		{TimeKey: "log", TimeFormat: "%s"},
+		{TimeKey: "my ts", TimeFormat: "%s"},
+		{TimeKey: "ts", TimeFormat: "seconds"},
+		{TimeKey: "ts", TimeFormat: "%s\n[OUTPUT]"},
+	} {
+		spec.Type = "syslog"
+		spec.Host = "example.org"
+		spec.Port = 12346
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for spec %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestMetadataFields(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+			},
+			MetadataFields: []string{"pod_name", "namespace_name"},
+		},
+	})
+
+	// The pod selector still sees the labels that are dropped afterwards.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.ns1.some-name\n    Regex $kubernetes['labels']['app'] ^web$\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call keep_metadata\n" +
+		`    code function keep_metadata(tag, timestamp, record) local metadata = record["kubernetes"] ` +
+		`if type(metadata) ~= "table" then return 0, timestamp, record end local kept = {} ` +
+		`for _, field in ipairs({"pod_name", "namespace_name"}) do kept[field] = metadata[field] end ` +
+		`record["kubernetes"] = kept return 2, timestamp, record end` + "\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestNoMetadataFields(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:           "syslog",
+			Host:           "example.org",
+			Port:           12346,
+			MetadataFields: []string{},
+		},
+	})
+
+	if strings.Contains(sc.String(), "keep_metadata") {
+		t.Errorf("Expected all metadata to be kept: %q", sc.String())
+	}
+}
+
+func TestInvalidMetadataFields(t *testing.T) {
+	for _, fields := range [][]string{
+		{"pod"},
+		{"pod_name", "labels.app"},
+		{"pod_name", `"}) os.exit() --`},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.org",
+				Port:           12346,
+				MetadataFields: fields,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for fields %q: Expected: %s Actual: %s", fields, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestLokiSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "loki",
+			URL:       "https://loki.example.com",
+			TenantID:  "team-a",
+			LabelKeys: []string{"kubernetes.pod_name", "stream"},
+			Headers: map[string]string{
+				"Authorization": "Bearer token",
+			},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name loki\n    Match kube.*_some-namespace_*\n    host loki.example.com\n    port 443\n    uri /loki/api/v1/push\n    tls On\n    tenant_id team-a\n    labels job=fluent-bit\n    label_keys $kubernetes['namespace_name'],$kubernetes['pod_name'],$stream\n    line_format json\n    bearer_token token\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestLokiSinkSecretRef(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("some-namespace", "loki-token", "abc123"))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "loki",
+			URL:       "https://loki.example.com",
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "loki-token", Key: "token"},
+		},
+	})
+
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name loki\n    Match kube.*_some-namespace_*\n    host loki.example.com\n    port 443\n    uri /loki/api/v1/push\n    tls On\n" +
+		"    labels job=fluent-bit\n    label_keys $kubernetes['namespace_name']\n    line_format json\n    bearer_token ${LOKI_BEARER_TOKEN_B0E35BA9AA9F885D}\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestLokiDefaultLabelsNotDuplicated(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "loki",
+			URL:       "http://loki.logging:3100/custom/push",
+			LabelKeys: []string{"kubernetes.namespace_name", "kubernetes.container_name", "kubernetes.container_name"},
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name loki\n    Match *\n    host loki.logging\n    port 3100\n    uri /custom/push\n    labels job=fluent-bit\n    label_keys $kubernetes['namespace_name'],$kubernetes['container_name']\n    line_format json\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidLokiSink(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "loki"},
+		{Type: "loki", URL: "loki:3100"},
+		{Type: "loki", URL: "ftp://loki:3100"},
+		{Type: "loki", URL: "http://:3100"},
+		{Type: "loki", URL: "http://loki:3100", TenantID: ".."},
+		{Type: "loki", URL: "http://loki:3100", TenantID: "team/a"},
+		{Type: "loki", URL: "http://loki:3100", LabelKeys: []string{"kubernetes..pod_name"}},
+		{Type: "loki", URL: "http://loki:3100", LabelKeys: []string{"kubernetes.labels.app-name"}},
+		{Type: "loki", URL: "http://loki:3100", LabelKeys: []string{"job"}},
+		{Type: "loki", URL: "http://loki:3100", LabelKeys: []string{"namespace_name"}},
+		{Type: "loki", URL: "http://loki:3100", Headers: map[string]string{"Authorization": "Basic Zm9v"}},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestGeneratedNamesGetDistinctStreams(t *testing.T) {
+	sc := sink.NewConfig()
+	for _, s := range []struct {
+		name string
+		uid  types.UID
+		port int
+	}{
+		{"app-4xk2p", "5bb7d9c6-0cd4-4c1b-a5d4-1b8e5d1c2f01", 12345},
+		{"app-4xk2pz", "0f3c1a52-7d5e-4b61-9c3a-8e2f4d6b7a10", 12346},
+	} {
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "app-",
+				Name:         s.name,
+				Namespace:    "ns1",
+				UID:          s.uid,
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.org",
+				Port:         s.port,
+				BufferSizeMB: 5,
+			},
+		})
+	}
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.app-4xk2p.5bb7d9c6-0cd4-4c1b-a5d4-1b8e5d1c2f01 true\n    Emitter_Mem_Buf_Limit 5M\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.app-4xk2p.5bb7d9c6-0cd4-4c1b-a5d4-1b8e5d1c2f01\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12345\"}]\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.app-4xk2pz.0f3c1a52-7d5e-4b61-9c3a-8e2f4d6b7a10 true\n    Emitter_Mem_Buf_Limit 5M\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.app-4xk2pz.0f3c1a52-7d5e-4b61-9c3a-8e2f4d6b7a10\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestRecreatedSinkGetsNewStream(t *testing.T) {
+	sc := sink.NewConfig()
+	s := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+			UID:  "5bb7d9c6-0cd4-4c1b-a5d4-1b8e5d1c2f01",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         12345,
+			BufferType:   "filesystem",
+			BufferSizeMB: 5,
+		},
+	}
+	sc.UpsertClusterSink(s)
+	old := sc.String()
+
+	sc.DeleteClusterSink(s)
+	recreated := s.DeepCopy()
+	recreated.UID = "0f3c1a52-7d5e-4b61-9c3a-8e2f4d6b7a10"
+	sc.UpsertClusterSink(recreated)
+
+	if strings.Contains(sc.String(), "sink.cluster.some-name.5bb7d9c6-0cd4-4c1b-a5d4-1b8e5d1c2f01") {
+		t.Errorf("Expected the recreated sink to not use the stream of %q: %q", old, sc.String())
+	}
+	if !strings.Contains(sc.String(), "Match sink.cluster.some-name.0f3c1a52-7d5e-4b61-9c3a-8e2f4d6b7a10\n") {
+		t.Errorf("Expected the output to match the stream of the recreated sink: %q", sc.String())
+	}
+}
+
+func TestS3Sink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:                 "s3",
+			Bucket:               "cluster-logs",
+			Region:               "eu-west-1",
+			TotalFileSizeMB:      50,
+			UploadTimeoutSeconds: 300,
+		},
+	})
+
+	// Without a SecretRef the sink uses the IAM role of the service account.
+	expected := "\n[OUTPUT]\n    Name s3\n    Match *\n    bucket cluster-logs\n    region eu-west-1\n    total_file_size 50M\n    upload_timeout 300s\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestS3SinkSmallFiles(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "s3",
+			Bucket:          "team-logs",
+			Region:          "us-east-1",
+			TotalFileSizeMB: 1,
+		},
+	})
+
+	// Objects below the smallest part of a multipart upload are put whole.
+	expected := "\n[OUTPUT]\n    Name s3\n    Match kube.*_some-namespace_*\n    bucket team-logs\n    region us-east-1\n    total_file_size 1M\n    use_put_object On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidS3Sink(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "s3"},
+		{Type: "s3", Bucket: "team-logs"},
+		{Type: "s3", Region: "us-east-1"},
+		{Type: "s3", Bucket: "Team-Logs", Region: "us-east-1"},
+		{Type: "s3", Bucket: "ab", Region: "us-east-1"},
+		{Type: "s3", Bucket: "team..logs", Region: "us-east-1"},
+		{Type: "s3", Bucket: "logs-${kubernetes.namespace_name}", Region: "us-east-1"},
+		{Type: "s3", Bucket: "192.168.1.1", Region: "us-east-1"},
+		{Type: "s3", Bucket: "team-logs", Region: "US East"},
+		{Type: "s3", Bucket: "team-logs", Region: "us-east-1", TotalFileSizeMB: -1},
+		{Type: "s3", Bucket: "team-logs", Region: "us-east-1", TotalFileSizeMB: 51201},
+		{Type: "s3", Bucket: "team-logs", Region: "us-east-1", UploadTimeoutSeconds: -1},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestSplunkSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("logging", "hec", "0a1b2c3d-4e5f-6789-abcd-ef0123456789"))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "splunk",
+			Host:       "hec.example.com",
+			EnableTLS:  true,
+			Index:      "k8s_logs",
+			SourceType: "kube:container",
+			SecretRef:  &v1alpha1.SecretKeyRef{Namespace: "logging", Name: "hec", Key: "token"},
+			Destinations: []v1alpha1.Destination{
+				{Host: "hec-backup.example.com", Port: 443},
+			},
+		},
+	})
+
+	// The port defaults to that of the HTTP Event Collector and the token
+	// is set from the included file.
+	output := "    Splunk_Token ${SPLUNK_TOKEN_895B2398F1889D85}\n    Event_Index k8s_logs\n    Event_Sourcetype kube:container\n    tls On\n"
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name splunk\n    Match *\n    Host hec.example.com\n    Port 8088\n" + output +
+		"\n[OUTPUT]\n    Name splunk\n    Match *\n    Host hec-backup.example.com\n    Port 443\n" + output
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidSplunkSink(t *testing.T) {
+	ref := &v1alpha1.SecretKeyRef{Name: "hec", Key: "token"}
+	for _, test := range []struct {
+		spec  v1alpha1.SinkSpec
+		token string
+	}{
+		{v1alpha1.SinkSpec{Host: "hec.example.com"}, ""},
+		{v1alpha1.SinkSpec{SecretRef: ref}, "some-token"},
+		{v1alpha1.SinkSpec{Host: "hec.example.com", SecretRef: ref}, "some token"},
+		{v1alpha1.SinkSpec{Host: "hec.example.com", SecretRef: ref, Index: "_internal"}, "some-token"},
+		{v1alpha1.SinkSpec{Host: "hec.example.com", SecretRef: ref, Index: "K8s"}, "some-token"},
+		{v1alpha1.SinkSpec{Host: "hec.example.com", SecretRef: ref, Index: "kvstore-logs"}, "some-token"},
+		{v1alpha1.SinkSpec{Host: "hec.example.com", SecretRef: ref, SourceType: "kube container"}, "some-token"},
+	} {
+		spec := test.spec
+		spec.Type = "splunk"
+		sc := sink.NewConfig()
+		sc.UpsertSecret(secret("some-namespace", "hec", test.token))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestCustomTag(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+			UID:       "some-uid",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+			Tag:  "team-a.audit",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12345,
+		},
+	})
+
+	// The shared output skips the custom stream like the derived ones.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.|(?:team-a\\.audit)(?:_|$)).*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12345\"}]\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* team-a.audit true\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match team-a.audit\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidTag(t *testing.T) {
+	for _, tag := range []string{
+		"team a",
+		"team_a",
+		"team.*",
+		".team",
+		"team..a",
+		"kube.team-a",
+		"sink.ns.ns1.some-name",
+		strings.Repeat("a", 129),
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, Tag: tag},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for tag %q: Expected: %s Actual: %s", tag, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestSharedTag(t *testing.T) {
+	sc := sink.NewConfig()
+	for _, ns := range []string{"ns1", "ns2"} {
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: ns,
+			},
+			Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, Tag: "audit"},
+		})
+	}
+
+	// Neither sink would only receive its own records.
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestHostPaths(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+			UID:  "some-uid",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			HostPaths: []string{"/data/app/*.log", "/var/log/audit/audit.log"},
+		},
+	})
+
+	// The container logs and the host paths feed the same stream.
+	expected := "\n[INPUT]\n    Name tail\n    Tag sink.cluster.some-name.some-uid\n    Path /data/app/*.log,/var/log/audit/audit.log\n    Path_Key log_path\n" +
+		"    DB /var/fluent-bit/storage/sink.cluster.some-name.some-uid.db\n    Mem_Buf_Limit 5MB\n    Skip_Long_Lines On\n    Refresh_Interval 10\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name.some-uid true\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name.some-uid\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestHostPathsMultiline(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			HostPaths: []string{"/data/app/*.log"},
+			Multiline: &v1alpha1.Multiline{StartPattern: `^\d{4}-`},
+		},
+	})
+
+	// Every file gets a stream of its own like every container.
+	if !strings.Contains(sc.String(), "\n[INPUT]\n    Name tail\n    Tag sink.cluster.some-name_*\n") {
+		t.Errorf("Expected the input to tag the lines of every file apart: %q", sc.String())
+	}
+}
+
+func TestInvalidHostPaths(t *testing.T) {
+	for _, paths := range [][]string{
+		{"data/app/*.log"},
+		{"/data/app/../secrets/*"},
+		{"/data/app/a.log,/data/app/b.log"},
+		{"/data/app/[.log"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, HostPaths: paths},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %q: Expected: %s Actual: %s", paths, emptyConfig, sc.String())
+		}
+	}
+
+	// LogSinks must not read the files of the nodes.
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, HostPaths: []string{"/data/app/*.log"}},
+	})
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestAuditSource(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "security",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:   "http",
+			URI:    "https://siem.example.com/audit",
+			Source: "audit",
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:   "syslog",
+			Host:   "example.com",
+			Port:   12345,
+			Source: "containers",
+		},
+	})
+
+	// The audit sink only gets the audit log, the container logs skip
+	// its stream.
+	expected := "\n[INPUT]\n    Name tail\n    Tag sink.cluster.security\n    Path /var/log/kubernetes/audit/audit.log\n    Parser sink-json\n" +
+		"    DB /var/fluent-bit/storage/sink.cluster.security_audit.db\n    Mem_Buf_Limit 5MB\n    Skip_Long_Lines On\n    Refresh_Interval 10\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n" +
+		"\n[OUTPUT]\n    Name http\n    Match sink.cluster.security\n    Host siem.example.com\n    Port 443\n    URI /audit\n    Format json_lines\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestAuditSourceHostPaths(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "security",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			Source:    "audit",
+			HostPaths: []string{"/var/log/kube-apiserver/audit.log"},
+			DropPatterns: []string{
+				`"verb":"watch"`,
+			},
+		},
+	})
+
+	input := parseSections(sc.String())[0]
+	if input.kind != "INPUT" || input.get("Path") != "/var/log/kube-apiserver/audit.log" {
+		t.Errorf("Expected the audit input to tail the host paths, got:\n%s", sc.String())
+	}
+	if strings.Contains(sc.String(), "rewrite_tag") || !strings.Contains(sc.String(), "Match sink.cluster.security\n    Exclude") {
+		t.Errorf("Expected the filters to apply to the audit log only, got:\n%s", sc.String())
+	}
+}
+
+func TestInvalidAuditSource(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{Type: "syslog", Host: "example.com", Port: 12345, Source: "journald"},
+		{Type: "syslog", Host: "example.com", Port: 12345, Source: "audit", NamespaceGlobs: []string{"team-*"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, Source: "audit", Multiline: &v1alpha1.Multiline{StartPattern: "^{"}},
+		{Type: "syslog", Host: "example.com", Port: 12345, Source: "audit", PodSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "web"},
+		}},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: spec,
+		})
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for source %q: Expected: %s Actual: %s", spec.Source, emptyConfig, sc.String())
+		}
+	}
+
+	// LogSinks cannot read the audit log of the cluster.
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, Source: "audit"},
+	})
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
+
+func TestDatadogSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("logging", "datadog", "0123456789abcdef0123456789abcdef"))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "datadog",
+			Site:      "datadoghq.eu",
+			Service:   "checkout",
+			DDSource:  "kubernetes",
+			Tags:      []string{"env:prod", "team:payments", "pci"},
+			SecretRef: &v1alpha1.SecretKeyRef{Namespace: "logging", Name: "datadog", Key: "token"},
+		},
+	})
+
+	// The tags are joined in their order and the API key is set from the
+	// included file.
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name datadog\n    Match *\n    Host http-intake.logs.datadoghq.eu\n    TLS On\n    compress gzip\n" +
+		"    apikey ${DATADOG_API_KEY_895B2398F1889D85}\n    dd_service checkout\n    dd_source kubernetes\n    dd_tags env:prod,team:payments,pci\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestDatadogDefaultSite(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("some-namespace", "datadog", "0123456789abcdef0123456789abcdef"))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "datadog",
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "datadog", Key: "token"},
+		},
+	})
+
+	if !strings.Contains(sc.String(), "    Host http-intake.logs.datadoghq.com\n") {
+		t.Errorf("Expected the intake of datadoghq.com: %q", sc.String())
+	}
+	if strings.Contains(sc.String(), "dd_tags") {
+		t.Errorf("Expected no tags: %q", sc.String())
+	}
+}
+
+func TestInvalidDatadogSink(t *testing.T) {
+	ref := &v1alpha1.SecretKeyRef{Name: "datadog", Key: "token"}
+	for _, test := range []struct {
+		spec v1alpha1.SinkSpec
+		key  string
+	}{
+		{v1alpha1.SinkSpec{}, ""},
+		{v1alpha1.SinkSpec{SecretRef: ref}, "some key"},
+		{v1alpha1.SinkSpec{SecretRef: ref, Site: "datadoghq.io"}, "some-key"},
+		{v1alpha1.SinkSpec{SecretRef: ref, Service: "check out"}, "some-key"},
+		{v1alpha1.SinkSpec{SecretRef: ref, DDSource: "kube rnetes"}, "some-key"},
+		{v1alpha1.SinkSpec{SecretRef: ref, Tags: []string{"env:prod,team:payments"}}, "some-key"},
+		{v1alpha1.SinkSpec{SecretRef: ref, Tags: []string{"1env:prod"}}, "some-key"},
+	} {
+		spec := test.spec
+		spec.Type = "datadog"
+		sc := sink.NewConfig()
+		sc.UpsertSecret(secret("some-namespace", "datadog", test.key))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestForwardSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "forward",
+			Host: "fluentd.logging",
+			Destinations: []v1alpha1.Destination{
+				{Host: "fluentd-backup.logging", Port: 24225},
+			},
+		},
+	})
+
+	// Anonymous sinks send without a shared key and the port defaults to
+	// that of the forward input.
+	expected := "\n[OUTPUT]\n    Name forward\n    Match kube.*_some-namespace_*\n    Host fluentd.logging\n    Port 24224\n" +
+		"\n[OUTPUT]\n    Name forward\n    Match kube.*_some-namespace_*\n    Host fluentd-backup.logging\n    Port 24225\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestForwardSinkWithSharedKey(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSecret(secret("logging", "fluentd", "some-shared-key"))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "forward",
+			Host:         "fluentd.logging",
+			Port:         24224,
+			EnableTLS:    true,
+			SelfHostname: "node-agent.example.com",
+			SecretRef:    &v1alpha1.SecretKeyRef{Namespace: "logging", Name: "fluentd", Key: "token"},
+		},
+	})
+
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name forward\n    Match *\n    Host fluentd.logging\n    Port 24224\n" +
+		"    Shared_Key ${FORWARD_SHARED_KEY_895B2398F1889D85}\n    Self_Hostname node-agent.example.com\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidForwardSink(t *testing.T) {
+	ref := &v1alpha1.SecretKeyRef{Name: "fluentd", Key: "token"}
+	for _, test := range []struct {
+		spec v1alpha1.SinkSpec
+		key  string
+	}{
+		{v1alpha1.SinkSpec{}, ""},
+		{v1alpha1.SinkSpec{Host: "fluentd.logging", SecretRef: ref}, ""},
+		{v1alpha1.SinkSpec{Host: "fluentd.logging", SecretRef: ref}, "some key"},
+		{v1alpha1.SinkSpec{Host: "fluentd.logging", SelfHostname: "-node"}, ""},
+		{v1alpha1.SinkSpec{Host: "fluentd.logging", SelfHostname: "node agent"}, ""},
+	} {
+		spec := test.spec
+		spec.Type = "forward"
+		sc := sink.NewConfig()
+		sc.UpsertSecret(secret("some-namespace", "fluentd", test.key))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestGELFSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "gelf",
+			Host: "graylog.logging",
+		},
+	})
+
+	// The log line is the short_message and the node of the pod the host,
+	// the port and mode default to those of the GELF UDP input.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_some-namespace_*\n    Rule $log .* sink.ns.some-namespace.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.some-namespace.some-name\n    call gelf_host\n" +
+		`    code function gelf_host(tag, timestamp, record) local k = record["kubernetes"] ` +
+		`if record["host"] ~= nil or type(k) ~= "table" or k["host"] == nil then return 0, timestamp, record end ` +
+		`record["host"] = k["host"] return 2, timestamp, record end` + "\n" +
+		"\n[OUTPUT]\n    Name gelf\n    Match sink.ns.some-namespace.some-name\n    Host graylog.logging\n    Port 12201\n    Mode udp\n" +
+		"    Gelf_Short_Message_Key log\n    Gelf_Host_Key host\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestGELFSinkWithKeys(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:          "gelf",
+			Host:          "lb.logging",
+			Port:          12202,
+			Mode:          "tls",
+			TLSServerName: "graylog.example.com",
+			ParseJSON:     true,
+			GELFKeys: &v1alpha1.GELFKeys{
+				ShortMessage: "msg",
+				FullMessage:  "stack",
+				Host:         "hostname",
+				Level:        "severity",
+				Timestamp:    "ts",
+			},
+		},
+	})
+
+	// A mapped host is taken from the record as it is.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.cluster.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name gelf\n    Match sink.cluster.some-name\n    Host lb.logging\n    Port 12202\n    Mode tls\n" +
+		"    Gelf_Short_Message_Key msg\n    Gelf_Host_Key hostname\n    Gelf_Full_Message_Key stack\n" +
+		"    Gelf_Level_Key severity\n    Gelf_Timestamp_Key ts\n    tls On\n    tls.vhost graylog.example.com\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidGELFSink(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{},
+		{Host: "graylog.logging", Mode: "http"},
+		{Host: "graylog.logging", Mode: "tcp", EnableTLS: true},
+		{Host: "graylog.logging", TLSServerName: "graylog.example.com"},
+		{Host: "graylog.logging", GELFKeys: &v1alpha1.GELFKeys{Host: "kubernetes.host"}},
+		{Host: "graylog.logging", GELFKeys: &v1alpha1.GELFKeys{ShortMessage: "short message"}},
+	} {
+		spec.Type = "gelf"
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "some-namespace",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %+v: Expected: %s Actual: %s", spec, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestDisabledSink(t *testing.T) {
+	sc := sink.NewConfig()
+	s := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			ParseJSON: true,
+			Disabled:  true,
+		},
+	}
+	sc.UpsertSink(s)
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-cluster-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+		},
+	})
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	// Enabling the sink again restores its stream and output.
+	enabled := s.DeepCopy()
+	enabled.Spec.Disabled = false
+	sc.UpsertSink(enabled)
+
+	expected = "\n[OUTPUT]\n    Name syslog\n    Match_Regex ^(?!sink\\.).*\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestDisabledExclusiveSink(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:           "syslog",
+			Host:           "example.com",
+			Port:           12345,
+			ExclusiveMatch: true,
+			Disabled:       true,
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-cluster-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.org",
+			Port: 12346,
+		},
+	})
+
+	// The namespace of a disabled sink is not taken from the ClusterLogSinks.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:12346\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
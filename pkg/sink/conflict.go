@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// conflict is why a sink's receiver gets some of its records twice.
+type conflict struct {
+	sink    entry
+	message string
+}
+
+// destinationConflicts returns a conflict for every LogSink sending records
+// of the same pods to the same receiver as another LogSink of its
+// namespace. Selectors are taken to overlap unless they cannot select the
+// same pods.
+func destinationConflicts(entries []entry) []conflict {
+	var sinks []entry
+	for _, e := range entries {
+		if !e.cluster() {
+			sinks = append(sinks, e)
+		}
+	}
+
+	others := make(map[string]map[string]bool)
+	for i, a := range sinks {
+		for _, b := range sinks[i+1:] {
+			if a.namespace != b.namespace || !overlap(a.spec.PodSelector, b.spec.PodSelector) {
+				continue
+			}
+			for _, r := range sharedReceivers(a, b) {
+				for _, p := range [][2]entry{{a, b}, {b, a}} {
+					k := p[0].String()
+					if others[k] == nil {
+						others[k] = make(map[string]bool)
+					}
+					others[k][fmt.Sprintf("%s with LogSink %s", r, p[1].name)] = true
+				}
+			}
+		}
+	}
+
+	var conflicts []conflict
+	for _, e := range sinks {
+		set := others[e.String()]
+		if len(set) == 0 {
+			continue
+		}
+		descs := make([]string, 0, len(set))
+		for d := range set {
+			descs = append(descs, d)
+		}
+		sort.Strings(descs)
+		conflicts = append(conflicts, conflict{
+			sink:    e,
+			message: fmt.Sprintf("sends the same records to %s, the receiver gets them twice", strings.Join(descs, " and ")),
+		})
+	}
+	return conflicts
+}
+
+// sharedReceivers returns the receivers both sinks send to.
+func sharedReceivers(a, b entry) []string {
+	seen := make(map[string]bool)
+	for _, d := range a.destinations {
+		seen[receiver(d)] = true
+	}
+	var shared []string
+	for _, d := range b.destinations {
+		if r := receiver(d); seen[r] {
+			shared = append(shared, r)
+			delete(seen, r)
+		}
+	}
+	return shared
+}
+
+// receiver returns the address the destination delivers to, along with
+// what tells its records apart at the address.
+func receiver(d v1alpha1.SinkSpec) string {
+	switch d.Type {
+	case v1alpha1.SinkTypeHTTP:
+		return d.URI
+	case v1alpha1.SinkTypeLoki:
+		return d.URL
+	case v1alpha1.SinkTypeOTLP:
+		return d.Endpoint
+	case v1alpha1.SinkTypeKafka:
+		return fmt.Sprintf("topic %s of %s", d.Topic, strings.Join(d.Brokers, ","))
+	case v1alpha1.SinkTypeElasticsearch:
+		return fmt.Sprintf("index %s of %s", d.Index, HostPort(d.Host, d.Port))
+	case v1alpha1.SinkTypeS3:
+		return "s3://" + d.Bucket
+	case v1alpha1.SinkTypeSplunk:
+		return fmt.Sprintf("index %s of %s", d.Index, HostPort(d.Host, SplunkPort(d.Port)))
+	case v1alpha1.SinkTypeDatadog:
+		return DatadogHost(d.Site)
+	case v1alpha1.SinkTypeForward:
+		return HostPort(d.Host, ForwardPort(d.Port))
+	case v1alpha1.SinkTypeGELF:
+		return HostPort(d.Host, GELFPort(d.Port))
+	default:
+		return HostPort(d.Host, d.Port)
+	}
+}
+
+// overlap reports whether the selectors may select the same pods. It only
+// tells selectors apart when requirements of the same label rule each
+// other out.
+func overlap(a, b *metav1.LabelSelector) bool {
+	for _, ra := range requirements(a) {
+		for _, rb := range requirements(b) {
+			if ra.Key == rb.Key && (excludes(ra, rb) || excludes(rb, ra)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// excludes reports whether no labels satisfy both requirements of the
+// same key.
+func excludes(a, b metav1.LabelSelectorRequirement) bool {
+	switch a.Operator {
+	case metav1.LabelSelectorOpIn:
+		switch b.Operator {
+		case metav1.LabelSelectorOpIn:
+			return !intersect(a.Values, b.Values)
+		case metav1.LabelSelectorOpNotIn:
+			return subset(a.Values, b.Values)
+		case metav1.LabelSelectorOpDoesNotExist:
+			return true
+		}
+	case metav1.LabelSelectorOpExists:
+		return b.Operator == metav1.LabelSelectorOpDoesNotExist
+	}
+	return false
+}
+
+// requirements returns the requirements of the selector, its match labels
+// as In requirements of a single value.
+func requirements(ls *metav1.LabelSelector) []metav1.LabelSelectorRequirement {
+	if ls == nil {
+		return nil
+	}
+	reqs := make([]metav1.LabelSelectorRequirement, 0, len(ls.MatchLabels)+len(ls.MatchExpressions))
+	for k, v := range ls.MatchLabels {
+		reqs = append(reqs, metav1.LabelSelectorRequirement{
+			Key:      k,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   []string{v},
+		})
+	}
+	return append(reqs, ls.MatchExpressions...)
+}
+
+func intersect(a, b []string) bool {
+	for _, v := range a {
+		for _, w := range b {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func subset(a, b []string) bool {
+	for _, v := range a {
+		if !intersect([]string{v}, b) {
+			return false
+		}
+	}
+	return true
+}
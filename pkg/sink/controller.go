@@ -19,42 +19,65 @@ import (
 	"encoding/json"
 	"log"
 	"reflect"
+	"time"
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 type Controller struct {
-	cmp ConfigMapPatcher
-	dsp DaemonSetPodDeleter
-	sc  *Config
+	*reconciler
 }
 
-func NewController(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config) *Controller {
+func NewController(cmp ConfigMapPatcher, r Reloader, sc *Config, opts ...Option) *Controller {
 	return &Controller{
-		cmp: cmp,
-		dsp: dsp,
-		sc:  sc,
+		reconciler: newReconciler(cmp, r, sc, opts),
 	}
 }
 
+// WithNamespaces has the controller only reconcile the LogSinks of the
+// allowed namespaces, all of them when allowed is empty, that are not
+// denied. LogSinks of other namespaces are logged and ignored.
+// ClusterLogSinks are not affected.
+func WithNamespaces(allowed, denied []string) Option {
+	return func(rc *reconciler) {
+		rc.allowed = allowed
+		rc.denied = denied
+	}
+}
+
+// namespaceAllowed reports whether the LogSinks of the namespace are
+// reconciled.
+func (rc *reconciler) namespaceAllowed(namespace string) bool {
+	for _, ns := range rc.denied {
+		if ns == namespace {
+			return false
+		}
+	}
+	if len(rc.allowed) == 0 {
+		return true
+	}
+	for _, ns := range rc.allowed {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Controller) OnAdd(o interface{}) {
 	d, ok := o.(*v1alpha1.LogSink)
 	if !ok {
 		return
 	}
+	if !c.namespaceAllowed(canonicalNamespace(d.Namespace)) {
+		log.Printf("ignoring sink %s/%s, its namespace is not allowed", d.Namespace, d.Name)
+		return
+	}
 
 	c.sc.UpsertSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
-	}
-	patchConfig(patches, c.cmp, c.dsp)
+	c.reconcile()
 }
 
 func (c *Controller) OnDelete(o interface{}) {
@@ -62,44 +85,111 @@ func (c *Controller) OnDelete(o interface{}) {
 	if !ok {
 		return
 	}
+	if !c.namespaceAllowed(canonicalNamespace(d.Namespace)) {
+		return
+	}
 
 	c.sc.DeleteSink(d)
 
-	patches := []patch{
+	c.reconcile()
+}
+
+// configPatches replaces the outputs and the sinks' parsers in the
+// fluent-bit ConfigMap with the rendered ones.
+func configPatches(r rendered) []patch {
+	return []patch{
 		{
 			Op:    "replace",
 			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
+			Value: r.conf,
+		},
+		{
+			Op:    "replace",
+			Path:  "/data/multiline-parsers.conf",
+			Value: r.parsers,
 		},
 	}
-	patchConfig(patches, c.cmp, c.dsp)
 }
 
-func patchConfig(patches []patch, cmp ConfigMapPatcher, dsp DaemonSetPodDeleter) {
-	data, err := json.Marshal(patches)
-	if err != nil {
-		log.Println(err.Error())
+// certPatches replaces the client certificates in the fluent-bit-tls
+// Secret with the rendered ones, which drops those of removed sinks.
+func certPatches(r rendered) []secretPatch {
+	certs := r.certs
+	if certs == nil {
+		certs = map[string][]byte{}
 	}
+	return []secretPatch{
+		{
+			Op:    "add",
+			Path:  "/data",
+			Value: certs,
+		},
+	}
+}
 
-	_, err = cmp.Patch(ConfigMapName, types.JSONPatchType, data)
-	if err != nil {
+// patchConfig applies the patches and reloads fluent-bit, recording a
+// reconcile that started at start and the propagation of the change made at
+// changed. The certificates are written first and the config is left as it
+// is when they cannot be, so it never references files fluent-bit does not
+// have. It returns the first error it ran into.
+func patchConfig(start, changed time.Time, patches []patch, cmp ConfigMapPatcher, certs []secretPatch, sp SecretPatcher, r Reloader, sc *Config) error {
+	var failure error
+	fail := func(reason string, err error) {
 		log.Println(err.Error())
+		if failure == nil {
+			failure = err
+			recordApplyFailure(reason)
+		}
 	}
 
-	err = dsp.DeleteCollection(
-		nil,
-		metav1.ListOptions{
-			LabelSelector: "app=fluent-bit-ds",
-		},
-	)
-	if err != nil {
-		log.Println(err.Error())
+	if sp != nil {
+		data, err := json.Marshal(certs)
+		if err == nil {
+			_, err = sp.Patch(TLSSecretName, types.JSONPatchType, data)
+		}
+		if err != nil {
+			fail(applyReasonSecret, err)
+		}
+	}
+
+	if failure == nil {
+		data, err := json.Marshal(patches)
+		if err != nil {
+			fail(applyReasonConfigMap, err)
+		}
+
+		_, err = cmp.Patch(ConfigMapName, types.JSONPatchType, data)
+		if err != nil {
+			fail(applyReasonConfigMap, err)
+		} else {
+			recordPropagation(changed)
+		}
+
+		err = r.Reload()
+		if err != nil {
+			fail(applyReasonReload, err)
+		}
 	}
 
+	sinks, clusterSinks := sc.counts()
+	recordReconcile(start, failure != nil, sinks, clusterSinks)
+	return failure
 }
 
 func (c *Controller) OnUpdate(old, new interface{}) {
-	if !reflect.DeepEqual(old, new) {
-		c.OnAdd(new)
+	o, _ := old.(*v1alpha1.LogSink)
+	n, ok := new.(*v1alpha1.LogSink)
+	if !ok {
+		return
+	}
+	// Status updates from the health reporter only need the stored sink
+	// refreshed, reloading fluent-bit for them would reset its metrics.
+	// Ignored sinks are only logged when their spec changes.
+	if o != nil && reflect.DeepEqual(o.Spec, n.Spec) {
+		if c.namespaceAllowed(canonicalNamespace(n.Namespace)) {
+			c.sc.UpsertSink(n)
+		}
+		return
 	}
+	c.OnAdd(n)
 }
@@ -17,6 +17,7 @@ package sink_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -67,6 +68,26 @@ func TestSinkModification(t *testing.T) {
 				"\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"test-ns\",\"tls\":{\"insecure_skip_verify\":true}}]\n    ClusterSinks []\n",
 			},
 		},
+		{
+			"Add a single sink with skip verify set but TLS disabled",
+			[]string{"add"},
+			[]v1alpha1.SinkSpec{
+				{Type: "syslog", Host: "example.com", Port: 12345, InsecureSkipVerify: true},
+			},
+			[]string{
+				"\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"test-ns\"}]\n    ClusterSinks []\n",
+			},
+		},
+		{
+			"Add a single udp sink",
+			[]string{"add"},
+			[]v1alpha1.SinkSpec{
+				{Type: "syslog", Host: "example.com", Port: 12345, Protocol: "udp"},
+			},
+			[]string{
+				"\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"test-ns\",\"protocol\":\"udp\"}]\n    ClusterSinks []\n",
+			},
+		},
 		{
 			"Add multiple sinks",
 			[]string{"add", "add"},
@@ -107,10 +128,10 @@ func TestSinkModification(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			spyConfigMapPatcher := &spyConfigMapPatcher{}
-			spyDaemonSetPodDeleter := &spyDaemonSetPodDeleter{}
+			spyReloader := &spyReloader{}
 			c := sink.NewController(
 				spyConfigMapPatcher,
-				spyDaemonSetPodDeleter,
+				spyReloader,
 				sink.NewConfig(),
 			)
 			for i, spec := range test.specs {
@@ -130,19 +151,51 @@ func TestSinkModification(t *testing.T) {
 				}
 			}
 			spyConfigMapPatcher.expectPatches(test.patches, t)
-			if spyDaemonSetPodDeleter.Selector != "app=fluent-bit-ds" {
-				t.Errorf("DaemonSet PodDeleter not equal: Expected: %s, Actual: %s", spyDaemonSetPodDeleter.Selector, "app=fluent-bit-ds")
+			if spyReloader.reloads != len(test.patches) {
+				t.Errorf("Reloads not equal: Expected: %d, Actual: %d", len(test.patches), spyReloader.reloads)
 			}
 		})
 	}
 }
 
+func TestMultilineParsersPatch(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	sc := sink.NewConfig()
+	c := sink.NewController(spyPatcher, &spyReloader{}, sc)
+
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      12345,
+			Multiline: &v1alpha1.Multiline{StartPattern: `\S`},
+		},
+	})
+
+	var jp []jsonPatch
+	err := json.Unmarshal(spyPatcher.patches[0].data, &jp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []jsonPatch{
+		{Op: "replace", Path: "/data/outputs.conf", Value: sc.String()},
+		{Op: "replace", Path: "/data/multiline-parsers.conf", Value: sc.Parsers()},
+	}
+	if diff := cmp.Diff(expected, jp); diff != "" {
+		t.Errorf("Patches not equal (-want, +got) = %v", diff)
+	}
+	if !strings.Contains(sc.Parsers(), "name multiline-sink.ns.test-ns.sink") {
+		t.Errorf("Expected the sink's multiline parser: %s", sc.Parsers())
+	}
+}
+
 func TestNoChanges(t *testing.T) {
 	spyPatcher := &spyConfigMapPatcher{}
-	spyDeleter := &spyDaemonSetPodDeleter{}
+	spyReloader := &spyReloader{}
 	c := sink.NewController(
 		spyPatcher,
-		spyDeleter,
+		spyReloader,
 		sink.NewConfig(),
 	)
 
@@ -165,15 +218,51 @@ func TestNoChanges(t *testing.T) {
 	if spyPatcher.patchCalled {
 		t.Errorf("Expected patch to not be called")
 	}
-	if spyDeleter.deleteCollectionCalled {
-		t.Errorf("Expected delete to not be called")
+	if spyReloader.reloads != 0 {
+		t.Errorf("Expected reload to not be called")
+	}
+}
+
+func TestStatusOnlyChange(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	c := sink.NewController(
+		spyPatcher,
+		spyReloader,
+		sink.NewConfig(),
+	)
+
+	s1 := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "some-name",
+			Namespace:       "test-ns",
+			ResourceVersion: "1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	}
+	s2 := s1.DeepCopy()
+	s2.ResourceVersion = "2"
+	s2.Status.Conditions = []v1alpha1.Condition{
+		{Type: v1alpha1.SinkConditionReady, Status: coreV1.ConditionTrue},
+	}
+	c.OnUpdate(s1, s2)
+
+	if spyPatcher.patchCalled {
+		t.Errorf("Expected patch to not be called")
+	}
+	if spyReloader.reloads != 0 {
+		t.Errorf("Expected reload to not be called")
 	}
 }
 
 func TestNotASink(t *testing.T) {
 	c := sink.NewController(
 		&spyConfigMapPatcher{},
-		&spyDaemonSetPodDeleter{},
+		&spyReloader{},
 		sink.NewConfig(),
 	)
 
@@ -187,8 +276,77 @@ func TestNoNamespace(t *testing.T) {
 	spyPatcher := &spyConfigMapPatcher{}
 	c := sink.NewController(
 		spyPatcher,
-		&spyDaemonSetPodDeleter{},
+		&spyReloader{},
+		sink.NewConfig(),
+	)
+	s1 := &v1alpha1.LogSink{
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	}
+
+	c.OnAdd(s1)
+
+	spyPatcher.expectPatches([]string{
+		"\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"default\"}]\n    ClusterSinks []\n",
+	}, t)
+}
+
+func TestDeniedNamespace(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		allowed, denied []string
+	}{
+		{"denied", nil, []string{"other-ns", "test-ns"}},
+		{"not allowed", []string{"other-ns"}, nil},
+		{"allowed and denied", []string{"test-ns"}, []string{"test-ns"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			spyPatcher := &spyConfigMapPatcher{}
+			spyReloader := &spyReloader{}
+			sc := sink.NewConfig()
+			c := sink.NewController(spyPatcher, spyReloader, sc, sink.WithNamespaces(test.allowed, test.denied))
+
+			s1 := &v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-name",
+					Namespace: "test-ns",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type: "syslog",
+					Host: "example.com",
+					Port: 12345,
+				},
+			}
+			s2 := s1.DeepCopy()
+			s2.Spec.Port = 12346
+			c.OnAdd(s1)
+			c.OnUpdate(s1, s2)
+			c.OnUpdate(s2, s2.DeepCopy())
+			c.OnDelete(s2)
+
+			if spyPatcher.patchCalled {
+				t.Errorf("Expected patch to not be called")
+			}
+			if spyReloader.reloads != 0 {
+				t.Errorf("Expected reload to not be called")
+			}
+			if sc.String() != emptyConfig {
+				t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, sc.String())
+			}
+		})
+	}
+}
+
+func TestAllowedNamespace(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	c := sink.NewController(
+		spyPatcher,
+		&spyReloader{},
 		sink.NewConfig(),
+		sink.WithNamespaces([]string{"default", "test-ns"}, []string{"other-ns"}),
 	)
 	s1 := &v1alpha1.LogSink{
 		Spec: v1alpha1.SinkSpec{
@@ -253,6 +411,11 @@ func (s *spyConfigMapPatcher) expectPatches(patches []string, t *testing.T) {
 				Path:  "/data/outputs.conf",
 				Value: p,
 			},
+			{
+				Op:    "replace",
+				Path:  "/data/multiline-parsers.conf",
+				Value: "",
+			},
 		}
 		var jpActual []jsonPatch
 		err := json.Unmarshal(s.patches[i].data, &jpActual)
@@ -266,16 +429,12 @@ func (s *spyConfigMapPatcher) expectPatches(patches []string, t *testing.T) {
 	}
 }
 
-type spyDaemonSetPodDeleter struct {
-	deleteCollectionCalled bool
-	Selector               string
+type spyReloader struct {
+	reloads int
+	err     error
 }
 
-func (s *spyDaemonSetPodDeleter) DeleteCollection(
-	options *metav1.DeleteOptions,
-	listOptions metav1.ListOptions,
-) error {
-	s.deleteCollectionCalled = true
-	s.Selector = listOptions.LabelSelector
-	return nil
+func (s *spyReloader) Reload() error {
+	s.reloads++
+	return s.err
 }
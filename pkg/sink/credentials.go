@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// credentialsFile holds the credentials of the sinks in the fluent-bit-tls
+// Secret. The outputs config includes it first, its variables are then set
+// for the outputs referencing them.
+const credentialsFile = "credentials.conf"
+
+// secretCredential is how a sink type keeps the credential withCredentials
+// sets from the SecretRef out of the config.
+type secretCredential struct {
+	// prefix is the one of the variables holding the credentials.
+	prefix string
+	// value returns the credential of the Authorization header.
+	value func(header string) (string, error)
+}
+
+// secretCredentials are the sink types whose outputs reference their
+// credential as a variable of the credentials file.
+var secretCredentials = map[string]secretCredential{
+	v1alpha1.SinkTypeSplunk:        {"SPLUNK_TOKEN_", schemeCredential("Splunk", "HEC token")},
+	v1alpha1.SinkTypeDatadog:       {"DATADOG_API_KEY_", schemeCredential("Datadog", "API key")},
+	v1alpha1.SinkTypeForward:       {"FORWARD_SHARED_KEY_", schemeCredential("SharedKey", "shared key")},
+	v1alpha1.SinkTypeHTTP:          {"HTTP_BEARER_TOKEN_", schemeCredential("Bearer", "bearer token")},
+	v1alpha1.SinkTypeOTLP:          {"OTLP_BEARER_TOKEN_", schemeCredential("Bearer", "bearer token")},
+	v1alpha1.SinkTypeElasticsearch: {"ELASTICSEARCH_PASSWORD_", basicPassword},
+	v1alpha1.SinkTypeKafka:         {"KAFKA_SASL_PASSWORD_", basicPassword},
+	v1alpha1.SinkTypeLoki:          {"LOKI_BEARER_TOKEN_", schemeCredential("Bearer", "bearer token")},
+}
+
+// schemeCredential returns the value of a secretCredential sent as the
+// Authorization header of the scheme, name is what it is called in errors.
+func schemeCredential(scheme, name string) func(string) (string, error) {
+	prefix := scheme + " "
+	return func(header string) (string, error) {
+		v := strings.TrimPrefix(header, prefix)
+		if !strings.HasPrefix(header, prefix) || v == "" || strings.ContainsAny(v, " \t\r\n") {
+			return "", fmt.Errorf("%s must not be empty or contain whitespace", name)
+		}
+		return v, nil
+	}
+}
+
+// basicPassword returns the password of a basic Authorization header, the
+// user is rendered as it is.
+func basicPassword(header string) (string, error) {
+	_, password, err := basicAuth(header)
+	return password, err
+}
+
+// credential returns the credential of the Authorization header
+// withCredentials sets from the SecretRef of a destination of the type.
+func credential(typ, header string) (string, error) {
+	return secretCredentials[typ].value(header)
+}
+
+// credentialVariable returns the name of the variable holding the credential
+// of the sink with the tag. Sink names may only differ in dots and hyphens,
+// so the name is derived from a hash of the tag.
+func credentialVariable(typ, tag string) string {
+	sum := sha256.Sum256([]byte(tag))
+	return fmt.Sprintf("%s%X", secretCredentials[typ].prefix, sum[:8])
+}
+
+// credentialRef returns the reference to the variable the output of the
+// sink with the tag renders in place of its credential.
+func credentialRef(typ, tag string) string {
+	return "${" + credentialVariable(typ, tag) + "}"
+}
+
+// addCredential adds the credential a destination has from its SecretRef to
+// the credentials under the name of the variable its output references.
+func addCredential(creds map[string]string, e entry, d v1alpha1.SinkSpec) {
+	if _, ok := secretCredentials[d.Type]; !ok || d.SecretRef == nil {
+		return
+	}
+	auth, ok := d.Headers["Authorization"]
+	if !ok {
+		return
+	}
+	v, err := credential(d.Type, auth)
+	if err != nil {
+		return
+	}
+	creds[credentialVariable(d.Type, e.tag())] = v
+}
+
+// credentialsConf returns the file setting the variables of the
+// credentials.
+func credentialsConf(creds map[string]string) []byte {
+	names := make([]string, 0, len(creds))
+	for name := range creds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "@SET %s=%s\n", name, creds[name])
+	}
+	return []byte(b.String())
+}
+
+// credentialsInclude returns the line including the credentials file into
+// the outputs config.
+func credentialsInclude() string {
+	return fmt.Sprintf("\n@INCLUDE %s/%s\n", tlsDirectory, credentialsFile)
+}
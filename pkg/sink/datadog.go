@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// DefaultDatadogSite is the site sinks of type datadog ship to when they
+// leave theirs unset.
+const DefaultDatadogSite = "datadoghq.com"
+
+// DatadogSites are the sites of the Datadog regions.
+var DatadogSites = []string{
+	"datadoghq.com",
+	"us3.datadoghq.com",
+	"us5.datadoghq.com",
+	"datadoghq.eu",
+	"ap1.datadoghq.com",
+	"ddog-gov.com",
+}
+
+var datadogTag = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.:/-]*$`)
+
+// datadogOutput returns a datadog output shipping the records to the logs
+// intake of the site. The API key stays out of the config, the output
+// references its variable in the credentials file.
+func datadogOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	if err := ValidateDatadogSite(spec.Site); err != nil {
+		return section{}, err
+	}
+	if err := ValidateDatadogAttribute(spec.Service); err != nil {
+		return section{}, err
+	}
+	if err := ValidateDatadogAttribute(spec.DDSource); err != nil {
+		return section{}, err
+	}
+	for _, t := range spec.Tags {
+		if err := ValidateDatadogTag(t); err != nil {
+			return section{}, err
+		}
+	}
+	auth, ok := spec.Headers["Authorization"]
+	if !ok {
+		return section{}, fmt.Errorf("datadog sinks need the API key of spec.secret_ref")
+	}
+	if _, err := credential(v1alpha1.SinkTypeDatadog, auth); err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("datadog", m)
+	o.add("Host", DatadogHost(spec.Site))
+	o.add("TLS", "On")
+	o.add("compress", "gzip")
+	o.add("apikey", credentialRef(v1alpha1.SinkTypeDatadog, tag))
+	if spec.Service != "" {
+		o.add("dd_service", spec.Service)
+	}
+	if spec.DDSource != "" {
+		o.add("dd_source", spec.DDSource)
+	}
+	if len(spec.Tags) != 0 {
+		o.add("dd_tags", strings.Join(spec.Tags, ","))
+	}
+	return o, nil
+}
+
+// DatadogHost returns the host of the logs intake of the site.
+func DatadogHost(site string) string {
+	if site == "" {
+		site = DefaultDatadogSite
+	}
+	return "http-intake.logs." + site
+}
+
+// ValidateDatadogSite returns why the site is not one of DatadogSites or
+// nil if it is. An empty site is DefaultDatadogSite.
+func ValidateDatadogSite(site string) error {
+	if site == "" {
+		return nil
+	}
+	for _, s := range DatadogSites {
+		if site == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown site %q, must be one of %s", site, strings.Join(DatadogSites, ", "))
+}
+
+// ValidateDatadogAttribute returns why the service or source cannot be
+// rendered or nil if it can. Empty leaves it to the pipelines of the
+// Datadog account.
+func ValidateDatadogAttribute(attr string) error {
+	if strings.ContainsAny(attr, " \t\r\n") {
+		return fmt.Errorf("%q must not contain whitespace", attr)
+	}
+	return nil
+}
+
+// ValidateDatadogTag returns why the tag cannot be added to the logs or nil
+// if it can. Tags are a key or key:value starting with a letter, the
+// commas joining them in the output cannot be part of a tag.
+func ValidateDatadogTag(tag string) error {
+	switch {
+	case len(tag) > 200:
+		return fmt.Errorf("tag %q is longer than 200 characters", tag)
+	case !datadogTag.MatchString(tag):
+		return fmt.Errorf("tag %q must start with a letter and may only contain alphanumerics, underscores, hyphens, colons, periods and slashes", tag)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+// debugSink is a sink in the config as NewDebugHandler serves it.
+type debugSink struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Disabled  bool   `json:"disabled,omitempty"`
+}
+
+// NewDebugHandler returns a read-only handler serving the outputs config
+// last written to the fluent-bit ConfigMap at /config and the sinks in the
+// config as JSON at /sinks. The config holds the credentials of the sinks,
+// the handler is meant to be served on a local address only.
+func NewDebugHandler(sc *Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		conf, ok := sc.appliedConfig()
+		if !ok {
+			http.Error(w, "no config was written yet", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err := io.WriteString(w, conf)
+		if err != nil {
+			log.Printf("unable to write debug config: %s", err)
+		}
+	})
+	mux.HandleFunc("/sinks", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(sc.debugSinks())
+		if err != nil {
+			log.Printf("unable to write debug sinks: %s", err)
+		}
+	})
+	return mux
+}
+
+// appliedConfig returns the outputs config of the last successful write. It
+// reports false when no config was written yet.
+func (sc *Config) appliedConfig() (string, bool) {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	for _, p := range sc.applied {
+		if p.Path == "/data/outputs.conf" {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// debugSinks returns the LogSinks followed by the ClusterLogSinks in the
+// config, each sorted by namespace and name.
+func (sc *Config) debugSinks() []debugSink {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sinks := []debugSink{}
+	for _, e := range sc.entries() {
+		kind := "LogSink"
+		if e.clusterLogSink != nil {
+			kind = "ClusterLogSink"
+		}
+		sinks = append(sinks, debugSink{
+			Kind:      kind,
+			Namespace: e.namespace,
+			Name:      e.name,
+			Type:      e.spec.Type,
+			Disabled:  e.spec.Disabled,
+		})
+	}
+	return sinks
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestDebugHandler(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	sc := sink.NewConfig()
+	h := sink.NewDebugHandler(sc)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d before the first write, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "cluster.example.com",
+			Port: 12345,
+		},
+	})
+	c := sink.NewController(spyPatcher, &spyReloader{}, sc)
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "test-ns"},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "syslog",
+			Host:     "example.com",
+			Port:     12345,
+			Disabled: true,
+		},
+	})
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-name", Namespace: "test-ns"},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "other.example.com",
+			Port: 12345,
+		},
+	})
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if rec.Body.String() != sc.String() {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", sc.String(), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sinks", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+	expected := `[{"kind":"LogSink","namespace":"test-ns","name":"other-name","type":"syslog"},` +
+		`{"kind":"LogSink","namespace":"test-ns","name":"some-name","type":"syslog","disabled":true},` +
+		`{"kind":"ClusterLogSink","name":"cluster-sink","type":"syslog"}]` + "\n"
+	if diff := cmp.Diff(expected, rec.Body.String()); diff != "" {
+		t.Errorf("Sinks not equal (-want, +got): %s", diff)
+	}
+}
+
+func TestDebugHandlerIsReadOnly(t *testing.T) {
+	h := sink.NewDebugHandler(sink.NewConfig())
+	for _, path := range []string{"/config", "/sinks"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status %d for POST %s, got %d", http.StatusMethodNotAllowed, path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+// DedupMaxRecords is the number of recent records the deduplication of a
+// sink remembers. The oldest records are forgotten first once it is
+// reached, so a sink logging more distinct lines than it within its window
+// forwards some duplicates.
+const DedupMaxRecords = 10000
+
+// ValidateDedupWindow returns why a sink cannot drop the duplicate records
+// it received within n seconds or nil if it can. Zero keeps the duplicates.
+func ValidateDedupWindow(n int) error {
+	if n < 0 {
+		return fmt.Errorf("must not be negative, got %d", n)
+	}
+	return nil
+}
+
+// dedupFilter returns a lua filter dropping the records whose log line the
+// same container logged within the last window seconds. Every fluent-bit
+// pod remembers the records it read itself and forgets them when it
+// restarts, so duplicates are only dropped on a best-effort basis. Records
+// without a log line pass through as they are.
+func dedupFilter(window int, m match) section {
+	f := newFilter("lua", m)
+	f.add("call", "dedup")
+	f.add("code", fmt.Sprintf(
+		`local seen, keys, times, first, last = {}, {}, {}, 1, 0 `+
+			`function dedup(tag, timestamp, record) local line = record["log"] `+
+			`if type(line) ~= "string" then return 0, timestamp, record end `+
+			`local k = record["kubernetes"] if type(k) == "table" then `+
+			`line = tostring(k["namespace_name"]) .. "/" .. tostring(k["pod_name"]) .. "/" .. tostring(k["container_name"]) .. "\n" .. line end `+
+			`local now = os.time() `+
+			`while first <= last and (now - times[first] >= %d or last - first >= %d) do `+
+			`seen[keys[first]] = nil keys[first] = nil times[first] = nil first = first + 1 end `+
+			`if seen[line] then return -1, 0, 0 end `+
+			`seen[line] = true last = last + 1 keys[last] = line times[last] = now return 0, timestamp, record end`,
+		window,
+		DedupMaxRecords-1,
+	))
+	return f
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestDedupWindow(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:               "syslog",
+			Host:               "example.com",
+			Port:               12345,
+			DedupWindowSeconds: 60,
+			RedactPatterns:     []v1alpha1.RedactRule{{Pattern: `password=\S+`, Replacement: "password=x"}},
+		},
+	})
+
+	// Duplicates are dropped before the lines are redacted.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call dedup\n" +
+		`    code local seen, keys, times, first, last = {}, {}, {}, 1, 0 ` +
+		`function dedup(tag, timestamp, record) local line = record["log"] ` +
+		`if type(line) ~= "string" then return 0, timestamp, record end ` +
+		`local k = record["kubernetes"] if type(k) == "table" then ` +
+		`line = tostring(k["namespace_name"]) .. "/" .. tostring(k["pod_name"]) .. "/" .. tostring(k["container_name"]) .. "\n" .. line end ` +
+		`local now = os.time() ` +
+		`while first <= last and (now - times[first] >= 60 or last - first >= 9999) do ` +
+		`seen[keys[first]] = nil keys[first] = nil times[first] = nil first = first + 1 end ` +
+		`if seen[line] then return -1, 0, 0 end ` +
+		`seen[line] = true last = last + 1 keys[last] = line times[last] = now return 0, timestamp, record end` + "\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call redact\n"
+	if !strings.HasPrefix(sc.String(), expected) {
+		t.Errorf("Config does not start with the dedup filter: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestNoDedupWindow(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+
+	if strings.Contains(sc.String(), "dedup") {
+		t.Errorf("Expected no dedup filter without a window: %s", sc.String())
+	}
+}
+
+func TestInvalidDedupWindow(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:               "syslog",
+			Host:               "example.com",
+			Port:               12345,
+			DedupWindowSeconds: -1,
+		},
+	})
+
+	if sc.String() != emptyConfig {
+		t.Errorf("Empty Config not equal: Expected: %s Actual: %s", emptyConfig, sc.String())
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// Destinations returns a spec for every receiver of the sink. They carry
+// the sink's settings with the destination's type and address.
+func Destinations(spec v1alpha1.SinkSpec) []v1alpha1.SinkSpec {
+	base := spec
+	base.Destinations = nil
+
+	var specs []v1alpha1.SinkSpec
+	if implicitDestination(spec) {
+		specs = append(specs, base)
+	}
+	for _, d := range spec.Destinations {
+		specs = append(specs, withDestination(base, d))
+	}
+	return specs
+}
+
+// implicitDestination reports whether the sink's own address is its first
+// destination.
+func implicitDestination(spec v1alpha1.SinkSpec) bool {
+	return spec.Host != "" ||
+		spec.URI != "" ||
+		spec.Endpoint != "" ||
+		len(spec.Brokers) != 0 ||
+		spec.URL != "" ||
+		spec.Bucket != "" ||
+		spec.Site != "" ||
+		spec.SocketPath != "" ||
+		len(spec.Destinations) == 0
+}
+
+func withDestination(spec v1alpha1.SinkSpec, d v1alpha1.Destination) v1alpha1.SinkSpec {
+	if d.Type != "" {
+		spec.Type = d.Type
+	}
+	spec.Host = d.Host
+	spec.Port = d.Port
+	switch spec.Type {
+	case v1alpha1.SinkTypeHTTP:
+		spec.URI = destinationURI(spec.URI, spec.EnableTLS, d)
+	case v1alpha1.SinkTypeLoki:
+		spec.URL = destinationURI(spec.URL, spec.EnableTLS, d)
+	case v1alpha1.SinkTypeOTLP:
+		spec.Endpoint = HostPort(d.Host, d.Port)
+	case v1alpha1.SinkTypeKafka:
+		spec.Brokers = []string{HostPort(d.Host, d.Port)}
+	}
+	return spec
+}
+
+// destinationURI points the sink's URI or URL at the destination, keeping
+// its scheme and path. Without one the scheme follows EnableTLS.
+func destinationURI(uri string, enableTLS bool, d v1alpha1.Destination) string {
+	u := &url.URL{Scheme: "http", Path: "/"}
+	if enableTLS {
+		u.Scheme = "https"
+	}
+	if uri != "" {
+		parsed, err := url.Parse(uri)
+		if err == nil {
+			u = parsed
+		}
+	}
+	u.Host = d.Host
+	if d.Port != 0 {
+		u.Host = HostPort(d.Host, d.Port)
+	} else if ipv6(d.Host) {
+		u.Host = "[" + strings.Trim(d.Host, "[]") + "]"
+	}
+	return fmt.Sprint(u)
+}
+
+// HostPort joins the host and port into an address. IPv6 hosts, with or
+// without brackets, are wrapped in brackets.
+func HostPort(host string, port int) string {
+	return net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
+
+// hostVariable matches a reference to an environment variable of the
+// fluent-bit container, which fluent-bit replaces with its value when it
+// loads the config.
+var hostVariable = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// TemplatedHost reports whether the host references environment variables
+// and so is only known to the fluent-bit pods.
+func TemplatedHost(host string) bool {
+	return hostVariable.MatchString(host)
+}
+
+// ValidateHost returns why the host is not a host name or IP address or nil
+// if it is. Brackets are only allowed around IPv6 addresses. The host may
+// reference environment variables of the fluent-bit container as ${NAME},
+// the rest of it must still be a host name. The credentials of other sinks
+// cannot be referenced.
+func ValidateHost(host string) error {
+	for _, m := range hostVariable.FindAllStringSubmatch(host, -1) {
+		for _, c := range secretCredentials {
+			if strings.HasPrefix(m[1], c.prefix) {
+				return fmt.Errorf("%q must not reference the variable %s of a sink", host, m[1])
+			}
+		}
+	}
+	switch resolved := hostVariable.ReplaceAllString(host, "x"); {
+	case host == "":
+		return fmt.Errorf("must not be empty")
+	case strings.Contains(resolved, "$"):
+		return fmt.Errorf("%q must only use $ in ${NAME} references to environment variables", host)
+	case strings.HasPrefix(host, "[") || strings.HasSuffix(host, "]"):
+		if !strings.HasPrefix(host, "[") || !strings.HasSuffix(host, "]") || !ipv6(host) {
+			return fmt.Errorf("%q is not a bracketed IPv6 address", host)
+		}
+	case strings.Contains(host, ":") && !ipv6(host):
+		return fmt.Errorf("%q is not an IPv6 address", host)
+	}
+	return nil
+}
+
+func ipv6(host string) bool {
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.To4() == nil
+}
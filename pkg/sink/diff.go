@@ -0,0 +1,214 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes of a
+// hunk.
+const diffContext = 3
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// DiffConfig returns the unified diff turning the old rendered config into
+// the new one, such as the config fluent-bit runs and the one a change
+// would render, or an empty string when they are equal. Both must be
+// configs of fluent-bit sections.
+func DiffConfig(old, new string) (string, error) {
+	a, err := configLines("old", old)
+	if err != nil {
+		return "", err
+	}
+	b, err := configLines("new", new)
+	if err != nil {
+		return "", err
+	}
+	lines := diffLines(a, b)
+
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			// Hunks closer than twice the context are merged.
+			run := end
+			for run < len(lines) && lines[run].op == ' ' {
+				run++
+			}
+			if run == len(lines) || run-end > 2*diffContext {
+				if run-end < diffContext {
+					end = run
+				} else {
+					end += diffContext
+				}
+				break
+			}
+			end = run
+		}
+		if out.Len() == 0 {
+			out.WriteString("--- old\n+++ new\n")
+		}
+		writeHunk(&out, lines, start, end)
+		i = end
+	}
+	return out.String(), nil
+}
+
+// configLines splits the config into its lines, each ending in a newline
+// but a last one without it.
+func configLines(name, conf string) ([]string, error) {
+	lines := strings.SplitAfter(conf, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		switch t := strings.TrimSuffix(l, "\n"); {
+		case t == "",
+			strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]"),
+			strings.HasPrefix(t, "@INCLUDE "),
+			strings.HasPrefix(t, "    "):
+		default:
+			return nil, fmt.Errorf("line %d of the %s config is not part of a section: %q", i+1, name, t)
+		}
+	}
+	return lines, nil
+}
+
+// diffLines returns the shortest edit script turning a into b, see
+// Myers' "An O(ND) Difference Algorithm and Its Variations".
+func diffLines(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// Step d only reads the diagonals -d to d, the trace keeps those so
+	// it grows with the square of the edits rather than the lines.
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the trace of diffLines back from the end of a and b,
+// trace[d][d+k] is the furthest x on diagonal k before step d.
+func backtrack(a, b []string, trace [][]int) []diffLine {
+	var lines []diffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prev := k - 1
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prev = k + 1
+		}
+		px := v[d+prev]
+		py := px - prev
+		for x > px && y > py {
+			x--
+			y--
+			lines = append(lines, diffLine{' ', a[x]})
+		}
+		if x == px {
+			y--
+			lines = append(lines, diffLine{'+', b[y]})
+		} else {
+			x--
+			lines = append(lines, diffLine{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		lines = append(lines, diffLine{' ', a[x]})
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+func writeHunk(out *strings.Builder, lines []diffLine, start, end int) {
+	var oldStart, newStart, oldCount, newCount int
+	for _, l := range lines[:start] {
+		if l.op != '+' {
+			oldStart++
+		}
+		if l.op != '-' {
+			newStart++
+		}
+	}
+	for _, l := range lines[start:end] {
+		if l.op != '+' {
+			oldCount++
+		}
+		if l.op != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, l := range lines[start:end] {
+		out.WriteByte(l.op)
+		out.WriteString(l.text)
+		if !strings.HasSuffix(l.text, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange returns the range of a hunk's lines in a file, which starts
+// after the first lines. An empty range names the line it follows.
+func hunkRange(first, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", first)
+	case 1:
+		return fmt.Sprintf("%d", first+1)
+	}
+	return fmt.Sprintf("%d,%d", first+1, count)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestDiffConfig(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(diffSink("sink-a", 443))
+	sc.UpsertSink(diffSink("sink-b", 443))
+	before := sc.String()
+
+	cases := []struct {
+		name     string
+		change   func(*sink.Config)
+		expected string
+	}{
+		{
+			"added sink",
+			func(sc *sink.Config) { sc.UpsertSink(diffSink("sink-c", 443)) },
+			"--- old\n+++ new\n@@ -16,3 +16,12 @@\n" +
+				"     URI /ingest\n     Format json_lines\n     tls On\n" +
+				"+\n+[OUTPUT]\n+    Name http\n+    Match kube.*_ns1_*\n+    Host logs.example.com\n+    Port 443\n" +
+				"+    URI /ingest\n+    Format json_lines\n+    tls On\n",
+		},
+		{
+			"removed sink",
+			func(sc *sink.Config) { sc.DeleteSink(diffSink("sink-a", 443)) },
+			"--- old\n+++ new\n@@ -7,12 +7,3 @@\n" +
+				"     URI /ingest\n     Format json_lines\n     tls On\n" +
+				"-\n-[OUTPUT]\n-    Name http\n-    Match kube.*_ns1_*\n-    Host logs.example.com\n-    Port 443\n" +
+				"-    URI /ingest\n-    Format json_lines\n-    tls On\n",
+		},
+		{
+			"changed sink",
+			func(sc *sink.Config) { sc.UpsertSink(diffSink("sink-b", 6443)) },
+			"--- old\n+++ new\n@@ -12,7 +12,7 @@\n" +
+				"     Name http\n     Match kube.*_ns1_*\n     Host logs.example.com\n" +
+				"-    Port 443\n+    Port 6443\n" +
+				"     URI /ingest\n     Format json_lines\n     tls On\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sc := sink.NewConfig()
+			sc.UpsertSink(diffSink("sink-a", 443))
+			sc.UpsertSink(diffSink("sink-b", 443))
+			tc.change(sc)
+
+			diff, err := sink.DiffConfig(before, sc.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != tc.expected {
+				t.Errorf("Diff not equal: Expected: %q Actual: %q", tc.expected, diff)
+			}
+		})
+	}
+}
+
+func TestDiffConfigUnchanged(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(diffSink("sink-a", 443))
+
+	diff, err := sink.DiffConfig(sc.String(), sc.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != "" {
+		t.Errorf("Expected no diff, got %q", diff)
+	}
+}
+
+func TestDiffConfigNotRendered(t *testing.T) {
+	_, err := sink.DiffConfig("\n[OUTPUT]\n    Name null\n", "not a fluent-bit config\n")
+	if err == nil {
+		t.Error("Expected an error for a config that is not made of sections")
+	}
+}
+
+func diffSink(name string, port int) *v1alpha1.LogSink {
+	return &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  fmt.Sprintf("https://logs.example.com:%d/ingest", port),
+		},
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"reflect"
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DriftController writes the config again when the fluent-bit ConfigMap or
+// DaemonSet is modified out-of-band rather than leaving them until a sink
+// changes. Only the objects named and labeled as managed by the
+// sink-controller are reconciled, and nothing is restored before the config
+// was written once.
+type DriftController struct {
+	*reconciler
+}
+
+func NewDriftController(cmp ConfigMapPatcher, r Reloader, sc *Config, opts ...Option) *DriftController {
+	return &DriftController{
+		reconciler: newReconciler(cmp, r, sc, opts),
+	}
+}
+
+func (c *DriftController) OnAdd(o interface{}) {
+	switch o := o.(type) {
+	case *coreV1.ConfigMap:
+		if managed(o.ObjectMeta, ConfigMapName) && c.sc.drifted(o.Data) {
+			c.repair()
+		}
+	case *extensionsV1beta1.DaemonSet:
+		// A recreated DaemonSet may run pods that never saw the config.
+		if managed(o.ObjectMeta, DaemonSetName) {
+			c.imageDrifted(o)
+			c.repair()
+		}
+	}
+}
+
+func (c *DriftController) OnUpdate(old, new interface{}) {
+	switch n := new.(type) {
+	case *coreV1.ConfigMap:
+		// The controller's own patches and the resyncs leave the data as
+		// it was written.
+		if managed(n.ObjectMeta, ConfigMapName) && c.sc.drifted(n.Data) {
+			c.repair()
+		}
+	case *extensionsV1beta1.DaemonSet:
+		o, _ := old.(*extensionsV1beta1.DaemonSet)
+		if managed(n.ObjectMeta, DaemonSetName) && (c.imageDrifted(n) || o == nil || c.templateChanged(o, n)) {
+			c.repair()
+		}
+	}
+}
+
+// OnDelete leaves deleted objects alone, patches cannot recreate them.
+func (c *DriftController) OnDelete(o interface{}) {}
+
+func (c *DriftController) repair() {
+	if c.sc.invalidate() {
+		c.reconcile()
+	}
+}
+
+func managed(m metav1.ObjectMeta, name string) bool {
+	return m.Name == name && m.Labels[ManagedByLabel] == ManagedBy
+}
+
+// templateChanged reports whether the pod template of the DaemonSet changed
+// other than by the sink-controller restarting it, sizing its memory,
+// mounting the sink sockets or setting its image.
+func (c *DriftController) templateChanged(old, new *extensionsV1beta1.DaemonSet) bool {
+	o, n := old.Spec.Template.DeepCopy(), new.Spec.Template.DeepCopy()
+	for _, t := range []*coreV1.PodTemplateSpec{o, n} {
+		delete(t.Annotations, RestartedAtAnnotation)
+		if len(t.Annotations) == 0 {
+			t.Annotations = nil
+		}
+		if c.socketDS != nil {
+			withoutSocketVolumes(t)
+		}
+		if c.imageDS != nil {
+			for i := range t.Spec.Containers {
+				if t.Spec.Containers[i].Name == fluentBitContainer {
+					t.Spec.Containers[i].Image = ""
+				}
+			}
+		}
+		if c.ds == nil {
+			continue
+		}
+		for i := range t.Spec.Containers {
+			if t.Spec.Containers[i].Name != fluentBitContainer {
+				continue
+			}
+			r := &t.Spec.Containers[i].Resources
+			delete(r.Requests, coreV1.ResourceMemory)
+			delete(r.Limits, coreV1.ResourceMemory)
+			if len(r.Requests) == 0 {
+				r.Requests = nil
+			}
+			if len(r.Limits) == 0 {
+				r.Limits = nil
+			}
+		}
+	}
+	return !reflect.DeepEqual(o, n)
+}
+
+// drifted reports whether the data of the fluent-bit ConfigMap differs
+// from the config written last.
+func (sc *Config) drifted(data map[string]string) bool {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	for _, p := range sc.applied {
+		if data[strings.TrimPrefix(p.Path, "/data/")] != p.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidate has the next write apply the config although no sink changed.
+// It reports false when no config was written yet.
+func (sc *Config) invalidate() bool {
+	sc.writeMu.Lock()
+	applied := sc.applied != nil
+	sc.writeMu.Unlock()
+	if !applied {
+		return false
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	return true
+}
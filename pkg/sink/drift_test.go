@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestDriftedConfigMapRestored(t *testing.T) {
+	sc := sink.NewConfig()
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	sink.NewController(spyPatcher, spyReloader, sc).OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	written := lastConfig(t, spyPatcher)
+
+	c := sink.NewDriftController(spyPatcher, spyReloader, sc)
+	cm := fluentBitConfigMap(written, sc.Parsers())
+	// The controller's own patch and resyncs leave the ConfigMap alone.
+	c.OnAdd(cm)
+	c.OnUpdate(cm, cm)
+	if len(spyPatcher.patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %d", len(spyPatcher.patches))
+	}
+
+	edited := fluentBitConfigMap("\n[OUTPUT]\n    Name null\n    Match *\n", sc.Parsers())
+	c.OnUpdate(cm, edited)
+	if len(spyPatcher.patches) != 2 {
+		t.Fatalf("Expected 2 patches, got %d", len(spyPatcher.patches))
+	}
+	if conf := lastConfig(t, spyPatcher); conf != written {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", written, conf)
+	}
+	if spyReloader.reloads != 2 {
+		t.Errorf("Reloads not equal: Expected: 2, Actual: %d", spyReloader.reloads)
+	}
+}
+
+func TestUnmanagedConfigMapNotRestored(t *testing.T) {
+	sc := sink.NewConfig()
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	c := sink.NewDriftController(spyPatcher, spyReloader, sc)
+
+	// Nothing is restored before the config was written.
+	c.OnAdd(fluentBitConfigMap("edited", ""))
+
+	sink.NewController(spyPatcher, spyReloader, sc).OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+
+	unlabeled := fluentBitConfigMap("edited", "")
+	unlabeled.Labels = nil
+	c.OnUpdate(unlabeled, unlabeled)
+
+	other := fluentBitConfigMap("edited", "")
+	other.Name = "metric-agent"
+	c.OnUpdate(other, other)
+
+	if len(spyPatcher.patches) != 1 {
+		t.Errorf("Expected 1 patch, got %d", len(spyPatcher.patches))
+	}
+}
+
+func TestDaemonSetDrift(t *testing.T) {
+	sc := sink.NewConfig()
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	sink.NewController(spyPatcher, spyReloader, sc).OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 12345,
+		},
+	})
+	c := sink.NewDriftController(spyPatcher, spyReloader, sc)
+
+	ds := fluentBitDaemonSet("oratos/fluent-bit-out-syslog:v0.9")
+	restarted := ds.DeepCopy()
+	restarted.Spec.Template.Annotations = map[string]string{
+		sink.RestartedAtAnnotation: "2018-11-01T00:00:00Z",
+	}
+	// Restarts by the controller are not drift.
+	c.OnUpdate(ds, restarted)
+	if len(spyPatcher.patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %d", len(spyPatcher.patches))
+	}
+
+	c.OnUpdate(restarted, fluentBitDaemonSet("fluent/fluent-bit:1.0"))
+	if len(spyPatcher.patches) != 2 {
+		t.Fatalf("Expected 2 patches, got %d", len(spyPatcher.patches))
+	}
+	if spyReloader.reloads != 2 {
+		t.Errorf("Reloads not equal: Expected: 2, Actual: %d", spyReloader.reloads)
+	}
+}
+
+func fluentBitConfigMap(outputs, parsers string) *coreV1.ConfigMap {
+	return &coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   sink.ConfigMapName,
+			Labels: map[string]string{sink.ManagedByLabel: sink.ManagedBy},
+		},
+		Data: map[string]string{
+			"fluent-bit.conf":        "@INCLUDE outputs.conf",
+			"outputs.conf":           outputs,
+			"multiline-parsers.conf": parsers,
+		},
+	}
+}
+
+func fluentBitDaemonSet(image string) *extensionsV1beta1.DaemonSet {
+	return &extensionsV1beta1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   sink.DaemonSetName,
+			Labels: map[string]string{sink.ManagedByLabel: sink.ManagedBy},
+		},
+		Spec: extensionsV1beta1.DaemonSetSpec{
+			Template: coreV1.PodTemplateSpec{
+				Spec: coreV1.PodSpec{
+					Containers: []coreV1.Container{
+						{Name: "fluent-bit", Image: image},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// esOutput returns an es output indexing the records into Elasticsearch.
+// With an IndexDateFormat every record goes to the index named after the
+// Index and the date of the record, e.g. logs-2026.10.14, which fluent-bit
+// calls the logstash format. A templated Index names the index after the
+// es_index field indexFilter sets instead, falling back to its leading
+// literal. Elasticsearch rejects field names with dots clashing with
+// objects, as pod labels often do, so dots are replaced. The password of the
+// SecretRef stays out of the config, the output references its variable in
+// the credentials file.
+func esOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	if err := ValidateIndex(spec.Index); err != nil {
+		return section{}, err
+	}
+	if err := ValidateIndexDateFormat(spec.IndexDateFormat); err != nil {
+		return section{}, err
+	}
+	if TemplatedIndex(spec.Index) && spec.IndexDateFormat == "" {
+		return section{}, fmt.Errorf("index template %q requires an index date format", spec.Index)
+	}
+	if err := ValidatePipeline(spec.Pipeline); err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("es", m)
+	o.add("Host", spec.Host)
+	o.add("Port", strconv.Itoa(spec.Port))
+	if spec.IndexDateFormat != "" {
+		o.add("Logstash_Format", "On")
+		if TemplatedIndex(spec.Index) {
+			parts, _ := parseIndexTemplate(spec.Index)
+			o.add("Logstash_Prefix", indexFallback(parts))
+			o.add("Logstash_Prefix_Key", "$"+indexKey)
+		} else {
+			o.add("Logstash_Prefix", spec.Index)
+		}
+		o.add("Logstash_DateFormat", spec.IndexDateFormat)
+	} else {
+		o.add("Index", spec.Index)
+	}
+	if spec.Pipeline != "" {
+		o.add("Pipeline", spec.Pipeline)
+	}
+	// Mapping types are gone since Elasticsearch 8.
+	o.add("Suppress_Type_Name", "On")
+	o.add("Replace_Dots", "On")
+	if auth, ok := spec.Headers["Authorization"]; ok {
+		user, password, err := basicAuth(auth)
+		if err != nil {
+			return section{}, err
+		}
+		if spec.SecretRef != nil {
+			password = credentialRef(spec.Type, tag)
+		}
+		o.add("HTTP_User", user)
+		o.add("HTTP_Passwd", password)
+	}
+	if spec.EnableTLS {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	return o, nil
+}
+
+// ValidateIndex returns why records cannot be indexed into the index or nil
+// if they can. A template must start with a literal, the index of the
+// records missing one of its keys, and only reference keys every record
+// has.
+func ValidateIndex(index string) error {
+	if index == "" {
+		return fmt.Errorf("index must not be empty")
+	}
+	if !TemplatedIndex(index) {
+		return validIndexName(index)
+	}
+	parts, err := parseIndexTemplate(index)
+	if err != nil {
+		return err
+	}
+	if indexFallback(parts) == "" {
+		return fmt.Errorf("index template %q must start with a literal prefix", index)
+	}
+	var literal strings.Builder
+	for _, p := range parts {
+		literal.WriteString(p.literal)
+	}
+	return validIndexName(literal.String())
+}
+
+// TemplatedIndex reports whether the index references record keys, such as
+// logs-${kubernetes.namespace_name}.
+func TemplatedIndex(index string) bool {
+	return strings.Contains(index, "${")
+}
+
+// indexKey is the field indexFilter sets to the expanded index template.
+// Elasticsearch reserves _index, so it is indexed along with the record.
+const indexKey = "es_index"
+
+// indexPart is a literal of an index template or, with a key, one of its
+// placeholders.
+type indexPart struct {
+	literal string
+	key     string
+}
+
+// parseIndexTemplate splits the index template into its literals and
+// placeholders.
+func parseIndexTemplate(index string) ([]indexPart, error) {
+	var parts []indexPart
+	for {
+		start := strings.Index(index, "${")
+		if start == -1 {
+			if index != "" {
+				parts = append(parts, indexPart{literal: index})
+			}
+			return parts, nil
+		}
+		if start > 0 {
+			parts = append(parts, indexPart{literal: index[:start]})
+		}
+		end := strings.IndexByte(index[start:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated placeholder in index template at %q", index[start:])
+		}
+		key := index[start+2 : start+end]
+		if !knownTemplateKey(key) {
+			return nil, fmt.Errorf("unknown placeholder ${%s} in index template", key)
+		}
+		parts = append(parts, indexPart{key: key})
+		index = index[start+end+1:]
+	}
+}
+
+// indexFallback returns the leading literal of the template without the
+// separators before the first placeholder, e.g. logs of logs-${...}.
+func indexFallback(parts []indexPart) string {
+	if len(parts) == 0 || parts[0].key != "" {
+		return ""
+	}
+	return strings.TrimRight(parts[0].literal, "-_.")
+}
+
+// indexForbiddenPattern is the lua pattern of indexForbidden, the characters
+// replaced in the values of the placeholders.
+const indexForbiddenPattern = `[%s\/%*%?"<>|,#:]`
+
+// indexFilter returns a lua filter setting the es_index field of the
+// records to the index template expanded with their values of its keys,
+// lowercased as Elasticsearch requires. Records missing one of the keys
+// have the field removed, so one the record came with does not replace
+// the fallback index.
+func indexFilter(index string, m match) (section, error) {
+	if err := ValidateIndex(index); err != nil {
+		return section{}, err
+	}
+	parts, _ := parseIndexTemplate(index)
+
+	var (
+		b      strings.Builder
+		concat []string
+	)
+	for i, p := range parts {
+		if p.key == "" {
+			concat = append(concat, luaString(p.literal))
+			continue
+		}
+		path := templateKeyPath(p.key)
+		for j, k := range path {
+			path[j] = luaString(k)
+		}
+		v := fmt.Sprintf("v%d", i)
+		fmt.Fprintf(&b, "local %s = get(%s) if %s == nil then record[%s] = nil return 2, timestamp, record end ", v, strings.Join(path, ", "), v, luaString(indexKey))
+		concat = append(concat, v)
+	}
+
+	f := newFilter("lua", m)
+	f.add("call", "es_index")
+	f.add("code",
+		`function es_index(tag, timestamp, record) `+
+			`local function get(...) local v = record for _, k in ipairs({...}) do `+
+			`if type(v) ~= "table" then return nil end v = v[k] end `+
+			`if type(v) == "string" or type(v) == "number" then return tostring(v) end end `+
+			b.String()+
+			fmt.Sprintf(`record[%s] = string.lower((string.gsub(%s, %s, "_"))) `, luaString(indexKey), strings.Join(concat, " .. "), luaString(indexForbiddenPattern))+
+			`return 2, timestamp, record end`,
+	)
+	return f, nil
+}
+
+// esDestination reports whether any of the destinations is of type
+// elasticsearch.
+func esDestination(destinations []v1alpha1.SinkSpec) bool {
+	for _, d := range destinations {
+		if d.Type == v1alpha1.SinkTypeElasticsearch {
+			return true
+		}
+	}
+	return false
+}
+
+// validIndexName checks the name against the rules Elasticsearch applies to
+// index names.
+func validIndexName(index string) error {
+	switch {
+	case index == "." || index == "..":
+		return fmt.Errorf("index must not be %q", index)
+	case strings.IndexAny(index[:1], "-_+") == 0:
+		return fmt.Errorf("index %q must not start with -, _ or +", index)
+	case strings.ToLower(index) != index:
+		return fmt.Errorf("index %q must be lowercase", index)
+	case strings.ContainsAny(index, indexForbidden):
+		return fmt.Errorf("index %q must not contain any of %q", index, indexForbidden)
+	case len(index) > 255:
+		return fmt.Errorf("index %q is longer than 255 bytes", index)
+	}
+	return nil
+}
+
+// indexForbidden are the characters Elasticsearch does not allow in index
+// names, along with the colon it deprecated.
+const indexForbidden = ` \/*?"<>|,#:` + "\t\r\n"
+
+// indexDateConversions are the strftime conversions producing digits only,
+// which keeps the indices named after the date legal.
+var indexDateConversions = map[byte]bool{
+	'Y': true,
+	'y': true,
+	'G': true,
+	'g': true,
+	'm': true,
+	'd': true,
+	'j': true,
+	'H': true,
+	'U': true,
+	'W': true,
+	'V': true,
+	'u': true,
+	'w': true,
+}
+
+// ValidateIndexDateFormat returns why the indices named after the date
+// format would not be legal or nil if they would. An empty format is none.
+func ValidateIndexDateFormat(format string) error {
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			if strings.ToLower(string(c)) != string(c) || strings.IndexByte(indexForbidden, c) >= 0 {
+				return fmt.Errorf("index date format %q must not contain %q", format, c)
+			}
+			continue
+		}
+		if i+1 == len(format) || !indexDateConversions[format[i+1]] {
+			return fmt.Errorf("index date format %q may only use the conversions %%Y, %%y, %%G, %%g, %%m, %%d, %%j, %%H, %%U, %%W, %%V, %%u and %%w", format)
+		}
+		i++
+	}
+	return nil
+}
+
+// ValidatePipeline returns why the ingest pipeline cannot be rendered or nil
+// if it can. An empty pipeline is none.
+func ValidatePipeline(pipeline string) error {
+	if strings.ContainsAny(pipeline, " \t\r\n") {
+		return fmt.Errorf("pipeline %q must not contain whitespace", pipeline)
+	}
+	return nil
+}
+
+// basicAuthHeader returns the Authorization header of the credentials of an
+// elasticsearch sink, which its Secret holds as user:password.
+func basicAuthHeader(credentials string) (string, error) {
+	if i := strings.IndexByte(credentials, ':'); i < 1 {
+		return "", fmt.Errorf("credentials are not of the form user:password")
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+}
+
+// basicAuth returns the user and password of a basic Authorization header.
+func basicAuth(header string) (string, string, error) {
+	const prefix = "Basic "
+	if !strings.HasPrefix(header, prefix) {
+		return "", "", fmt.Errorf("elasticsearch sinks only support basic authorization")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid basic authorization: %s", err)
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("credentials are not of the form user:password")
+	}
+	return parts[0], parts[1], nil
+}
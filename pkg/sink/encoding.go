@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultEncoding is the charset of the records fluent-bit reads, sinks
+// with it forward them without conversion.
+const DefaultEncoding = "utf-8"
+
+// encodings are the charsets sinks can convert their records to along with
+// the first code point they cannot represent.
+var encodings = map[string]int{
+	DefaultEncoding: 0,
+	"iso-8859-1":    256,
+	"us-ascii":      128,
+}
+
+// ValidateEncoding returns why the records of a sink cannot be converted to
+// the charset or nil if they can. Empty is the DefaultEncoding.
+func ValidateEncoding(encoding string) error {
+	if encoding == "" {
+		return nil
+	}
+	if _, ok := encodings[encoding]; !ok {
+		names := make([]string, 0, len(encodings))
+		for name := range encodings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("encoding %q must be one of %s", encoding, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// converted reports whether the records of a sink with the encoding are
+// converted before they are forwarded.
+func converted(encoding string) bool {
+	return encoding != "" && encoding != DefaultEncoding
+}
+
+// encodingFilter returns a lua filter converting the UTF-8 strings of the
+// records, nested ones included, to the encoding. Characters the encoding
+// cannot represent and invalid UTF-8 sequences are replaced by a question
+// mark.
+func encodingFilter(encoding string, m match) section {
+	f := newFilter("lua", m)
+	f.add("call", "encode")
+	f.add("code", fmt.Sprintf(
+		`local function char(c) local b1, b2 = c:byte(1, 2) `+
+			`if #c == 2 and b1 >= 194 and b1 < 224 then local cp = (b1 - 192) * 64 + b2 - 128 `+
+			`if cp < %d then return string.char(cp) end end return "?" end `+
+			`local function recode(v) if type(v) == "string" then return (v:gsub("[\128-\255][\128-\191]*", char)) end `+
+			`if type(v) == "table" then for k, x in pairs(v) do v[k] = recode(x) end end return v end `+
+			`function encode(tag, timestamp, record) return 2, timestamp, recode(record) end`,
+		encodings[encoding],
+	))
+	return f
+}
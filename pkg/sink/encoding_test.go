@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestEncoding(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:                "syslog",
+			Host:                "example.com",
+			Port:                12345,
+			Encoding:            "iso-8859-1",
+			Labels:              map[string]string{"cluster_name": "café"},
+			MaxRecordsPerSecond: 100,
+		},
+	})
+
+	// The labels are converted as well, the records are throttled after.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name modify\n    Match sink.ns.ns1.some-name\n    Set cluster_name café\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call encode\n" +
+		`    code local function char(c) local b1, b2 = c:byte(1, 2) ` +
+		`if #c == 2 and b1 >= 194 and b1 < 224 then local cp = (b1 - 192) * 64 + b2 - 128 ` +
+		`if cp < 256 then return string.char(cp) end end return "?" end ` +
+		`local function recode(v) if type(v) == "string" then return (v:gsub("[\128-\255][\128-\191]*", char)) end ` +
+		`if type(v) == "table" then for k, x in pairs(v) do v[k] = recode(x) end end return v end ` +
+		`function encode(tag, timestamp, record) return 2, timestamp, recode(record) end` + "\n" +
+		"\n[FILTER]\n    Name throttle\n    Match sink.ns.ns1.some-name\n    Rate 100\n    Window 1\n    Interval 1s\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestEncodingASCII(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "http",
+			URI:      "https://logs.example.com/ingest",
+			Encoding: "us-ascii",
+		},
+	})
+
+	if conf := sc.String(); !strings.Contains(conf, "\n    call encode\n") || !strings.Contains(conf, "if cp < 128 then") {
+		t.Errorf("Expected the records to be converted to us-ascii, got:\n%s", conf)
+	}
+}
+
+func TestDefaultEncoding(t *testing.T) {
+	for _, encoding := range []string{"", sink.DefaultEncoding} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:     "syslog",
+				Host:     "example.com",
+				Port:     12345,
+				Encoding: encoding,
+			},
+		})
+
+		expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:12345\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n"
+		if sc.String() != expected {
+			t.Errorf("Config not equal for encoding %q: Expected: %q Actual: %q", encoding, expected, sc.String())
+		}
+	}
+}
+
+func TestInvalidEncoding(t *testing.T) {
+	for _, encoding := range []string{"latin1", "UTF-8", "utf-16"} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:     "syslog",
+				Host:     "example.com",
+				Port:     12345,
+				Encoding: encoding,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for encoding %q: Expected: %s Actual: %s", encoding, emptyConfig, sc.String())
+		}
+		if err := sink.ValidateEncoding(encoding); err == nil {
+			t.Errorf("Expected encoding %q to be invalid", encoding)
+		}
+	}
+}
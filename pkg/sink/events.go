@@ -0,0 +1,256 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/knative/observability/pkg/client/clientset/versioned/scheme"
+)
+
+// Reasons of the Events recorded on sinks when the config is written.
+const (
+	// EventReasonApplied is a Normal Event for a sink in a config that was
+	// written and reloaded.
+	EventReasonApplied = "Applied"
+	// EventReasonRenderFailed is a Warning Event for a sink left out of the
+	// config, e.g. because its Secret does not exist.
+	EventReasonRenderFailed = "RenderFailed"
+	// EventReasonApplyFailed is a Warning Event for a sink in a config that
+	// could not be written or reloaded.
+	EventReasonApplyFailed = "ApplyFailed"
+	// EventReasonDestinationConflict is a Warning Event for a LogSink
+	// sending records of the same pods to the same receiver as another
+	// LogSink of its namespace. Both sinks are still applied.
+	EventReasonDestinationConflict = "DestinationConflict"
+	// EventReasonDisabled is a Normal Event for a sink left out of the
+	// config because it is disabled.
+	EventReasonDisabled = "Disabled"
+	// EventReasonInvalidConfig is a Warning Event for a sink whose sections
+	// fluent-bit rejected. The config holding them was not written.
+	EventReasonInvalidConfig = "InvalidConfig"
+)
+
+// EventRecorder records an Event on an object. A record.EventRecorder
+// satisfies it.
+type EventRecorder interface {
+	Event(object runtime.Object, eventtype, reason, message string)
+}
+
+// WithEventRecorder has the controller record Events on the sinks when it
+// writes the config. A sink only gets an Event when its outcome or its spec
+// changed since the previous one.
+func WithEventRecorder(rec EventRecorder) Option {
+	return func(rc *reconciler) {
+		rc.recorder = rec
+	}
+}
+
+type eventRecorder struct {
+	events typedCoreV1.EventsGetter
+	now    func() time.Time
+}
+
+// NewEventRecorder returns an EventRecorder creating the Events with the
+// client. Events on ClusterLogSinks are created in the default namespace.
+func NewEventRecorder(events typedCoreV1.EventsGetter) EventRecorder {
+	return &eventRecorder{
+		events: events,
+		now:    time.Now,
+	}
+}
+
+func (r *eventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	ref, err := objectReference(object)
+	if err != nil {
+		log.Printf("unable to reference %T for event %s: %s", object, reason, err)
+		return
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	t := metav1.NewTime(r.now())
+	_, err = r.events.Events(namespace).Create(&coreV1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", ref.Name, t.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        message,
+		Source:         coreV1.EventSource{Component: "sink-controller"},
+		FirstTimestamp: t,
+		LastTimestamp:  t,
+		Count:          1,
+		Type:           eventtype,
+	})
+	if err != nil {
+		log.Printf("unable to record event %s on %s/%s: %s", reason, ref.Namespace, ref.Name, err)
+	}
+}
+
+// objectReference references an object of a kind in the clientset's scheme.
+// Unlike reference.GetReference it does not need objects from informers to
+// have their kind or selfLink set.
+func objectReference(object runtime.Object) (coreV1.ObjectReference, error) {
+	gvks, _, err := scheme.Scheme.ObjectKinds(object)
+	if err != nil {
+		return coreV1.ObjectReference{}, err
+	}
+	m, err := meta.Accessor(object)
+	if err != nil {
+		return coreV1.ObjectReference{}, err
+	}
+	return coreV1.ObjectReference{
+		Kind:            gvks[0].Kind,
+		APIVersion:      gvks[0].GroupVersion().String(),
+		Name:            m.GetName(),
+		Namespace:       m.GetNamespace(),
+		UID:             m.GetUID(),
+		ResourceVersion: m.GetResourceVersion(),
+	}, nil
+}
+
+// sinkEvent is the last Event recorded on a sink.
+type sinkEvent struct {
+	generation int64
+	eventtype  string
+	reason     string
+	message    string
+}
+
+// sinkObject is a LogSink or a ClusterLogSink.
+type sinkObject interface {
+	runtime.Object
+	metav1.Object
+}
+
+func (e entry) object() sinkObject {
+	if e.cluster() {
+		return e.clusterLogSink
+	}
+	return e.logSink
+}
+
+// recordEvents records the outcome of writing the render on every sink
+// whose outcome changed. It is called with the Config's writeMu held.
+func (rc *reconciler) recordEvents(r rendered, applyErr error) {
+	if rc.recorder == nil {
+		return
+	}
+	var (
+		sinks   = make(map[string]entry)
+		current = make(map[string]sinkEvent)
+	)
+	for _, err := range r.errs {
+		re, ok := err.(renderError)
+		if !ok {
+			continue
+		}
+		k := re.sink.String()
+		if _, seen := current[k]; seen {
+			continue
+		}
+		sinks[k] = re.sink
+		current[k] = sinkEvent{
+			eventtype: coreV1.EventTypeWarning,
+			reason:    EventReasonRenderFailed,
+			message:   re.Error(),
+		}
+	}
+	for _, e := range r.disabled {
+		k := e.String()
+		sinks[k] = e
+		current[k] = sinkEvent{
+			eventtype: coreV1.EventTypeNormal,
+			reason:    EventReasonDisabled,
+			message:   "left out of the fluent-bit config while disabled",
+		}
+	}
+	for _, entries := range r.outputs {
+		for _, e := range entries {
+			k := e.String()
+			if _, seen := current[k]; seen {
+				continue
+			}
+			sinks[k] = e
+			current[k] = sinkEvent{
+				eventtype: coreV1.EventTypeNormal,
+				reason:    EventReasonApplied,
+				message:   "applied to the fluent-bit config",
+			}
+			if applyErr != nil {
+				current[k] = sinkEvent{
+					eventtype: coreV1.EventTypeWarning,
+					reason:    EventReasonApplyFailed,
+					message:   fmt.Sprintf("unable to apply the fluent-bit config: %s", applyErr),
+				}
+			}
+			if msg, ok := rc.sc.rejected[k]; ok {
+				current[k] = sinkEvent{
+					eventtype: coreV1.EventTypeWarning,
+					reason:    EventReasonInvalidConfig,
+					message:   msg,
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(current))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e := sinks[k]
+		ev := current[k]
+		ev.generation = e.object().GetGeneration()
+		current[k] = ev
+		if rc.sc.events[k] == ev {
+			continue
+		}
+		rc.recorder.Event(e.object(), ev.eventtype, ev.reason, ev.message)
+	}
+	// Sinks that were deleted get an Event again if they are recreated.
+	rc.sc.events = current
+
+	conflicts := make(map[string]sinkEvent, len(r.conflicts))
+	for _, c := range r.conflicts {
+		k := c.sink.String()
+		ev := sinkEvent{
+			generation: c.sink.object().GetGeneration(),
+			eventtype:  coreV1.EventTypeWarning,
+			reason:     EventReasonDestinationConflict,
+			message:    c.message,
+		}
+		conflicts[k] = ev
+		if rc.sc.conflicts[k] == ev {
+			continue
+		}
+		rc.recorder.Event(c.sink.object(), ev.eventtype, ev.reason, ev.message)
+	}
+	rc.sc.conflicts = conflicts
+}
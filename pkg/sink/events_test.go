@@ -0,0 +1,335 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestEventsForMissingSecret(t *testing.T) {
+	rec := &spyEventRecorder{}
+	sc := sink.NewConfig()
+	c := sink.NewController(&spyConfigMapPatcher{}, &spyReloader{}, sc, sink.WithEventRecorder(rec))
+	sec := sink.NewSecretController(&spyConfigMapPatcher{}, &spyReloader{}, sc, sink.WithEventRecorder(rec))
+
+	s := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "http",
+			URI:       "https://logs.example.com/ingest",
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+		},
+	}
+	c.OnAdd(s)
+
+	if len(rec.events) != 1 {
+		t.Fatalf("Expected 1 event, got: %v", rec.events)
+	}
+	ev := rec.events[0]
+	if ev.object != s || ev.eventtype != coreV1.EventTypeWarning || ev.reason != sink.EventReasonRenderFailed {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	if !strings.Contains(ev.message, "secret some-namespace/log-service") {
+		t.Errorf("Expected the message to name the secret: %s", ev.message)
+	}
+
+	// The failure is only reported once.
+	c.OnAdd(s)
+	if len(rec.events) != 1 {
+		t.Fatalf("Expected 1 event, got: %v", rec.events)
+	}
+
+	sec.OnAdd(secret("some-namespace", "log-service", "abc123"))
+	expected := []recordedEvent{
+		ev,
+		{object: s, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: "applied to the fluent-bit config"},
+	}
+	if diff := cmp.Diff(expected, rec.events, cmp.AllowUnexported(recordedEvent{})); diff != "" {
+		t.Errorf("Events not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestEventsOnApply(t *testing.T) {
+	rec := &spyEventRecorder{}
+	reloader := &spyReloader{}
+	sc := sink.NewConfig()
+	c := sink.NewController(&spyConfigMapPatcher{}, reloader, sc, sink.WithEventRecorder(rec))
+	cc := sink.NewClusterController(&spyConfigMapPatcher{}, reloader, sc, sink.WithEventRecorder(rec))
+
+	s := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "some-name",
+			Namespace:  "some-namespace",
+			Generation: 1,
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 514,
+		},
+	}
+	cs := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "some-name",
+			Generation: 1,
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "https://logs.example.com/ingest",
+		},
+	}
+	c.OnAdd(s)
+	// Adding a second sink leaves the applied one alone.
+	cc.OnAdd(cs)
+
+	reloader.err = errors.New("some error")
+	s2 := s.DeepCopy()
+	s2.Generation = 2
+	s2.Spec.Port = 601
+	c.OnUpdate(s, s2)
+
+	// Status updates do not write the config, the next write recovers.
+	reloader.err = nil
+	c.OnUpdate(s2, s2.DeepCopy())
+	cc.OnDelete(cs)
+	c.OnDelete(s2)
+	c.OnAdd(s2)
+
+	applied := "applied to the fluent-bit config"
+	failed := "unable to apply the fluent-bit config: some error"
+	expected := []recordedEvent{
+		{object: s, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+		{object: cs, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+		{object: cs, eventtype: coreV1.EventTypeWarning, reason: sink.EventReasonApplyFailed, message: failed},
+		{object: s2, eventtype: coreV1.EventTypeWarning, reason: sink.EventReasonApplyFailed, message: failed},
+		{object: s2, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+		{object: s2, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+	}
+	if diff := cmp.Diff(expected, rec.events, cmp.AllowUnexported(recordedEvent{})); diff != "" {
+		t.Errorf("Events not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestEventsForDisabledSink(t *testing.T) {
+	rec := &spyEventRecorder{}
+	c := sink.NewController(&spyConfigMapPatcher{}, &spyReloader{}, sink.NewConfig(), sink.WithEventRecorder(rec))
+
+	s := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "some-name",
+			Namespace:  "some-namespace",
+			Generation: 1,
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "syslog",
+			Host:     "example.com",
+			Port:     514,
+			Disabled: true,
+		},
+	}
+	c.OnAdd(s)
+	enabled := s.DeepCopy()
+	enabled.Generation = 2
+	enabled.Spec.Disabled = false
+	c.OnUpdate(s, enabled)
+
+	expected := []recordedEvent{
+		{object: s, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonDisabled, message: "left out of the fluent-bit config while disabled"},
+		{object: enabled, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: "applied to the fluent-bit config"},
+	}
+	if diff := cmp.Diff(expected, rec.events, cmp.AllowUnexported(recordedEvent{})); diff != "" {
+		t.Errorf("Events not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestEventsForConflictingSinks(t *testing.T) {
+	rec := &spyEventRecorder{}
+	c := sink.NewController(&spyConfigMapPatcher{}, &spyReloader{}, sink.NewConfig(), sink.WithEventRecorder(rec))
+
+	spec := v1alpha1.SinkSpec{
+		Type: "syslog",
+		Host: "example.com",
+		Port: 514,
+		PodSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "web"},
+		},
+	}
+	a := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink-a", Namespace: "some-namespace"},
+		Spec:       spec,
+	}
+	b := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink-b", Namespace: "some-namespace"},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 514,
+			Destinations: []v1alpha1.Destination{
+				{Host: "other.example.com", Port: 514},
+			},
+		},
+	}
+	// Sinks of other namespaces never receive the same records.
+	other := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink-a", Namespace: "other-namespace"},
+		Spec:       spec,
+	}
+	c.OnAdd(a)
+	c.OnAdd(other)
+	c.OnAdd(b)
+
+	applied := "applied to the fluent-bit config"
+	expected := []recordedEvent{
+		{object: a, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+		{object: other, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+		{object: b, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: applied},
+		{
+			object:    a,
+			eventtype: coreV1.EventTypeWarning,
+			reason:    sink.EventReasonDestinationConflict,
+			message:   "sends the same records to example.com:514 with LogSink sink-b, the receiver gets them twice",
+		},
+		{
+			object:    b,
+			eventtype: coreV1.EventTypeWarning,
+			reason:    sink.EventReasonDestinationConflict,
+			message:   "sends the same records to example.com:514 with LogSink sink-a, the receiver gets them twice",
+		},
+	}
+	if diff := cmp.Diff(expected, rec.events, cmp.AllowUnexported(recordedEvent{})); diff != "" {
+		t.Errorf("Events not equal (-want, +got) = %v", diff)
+	}
+
+	// The conflict is only reported once and is gone with a selector
+	// ruling out the pods of the other sink.
+	c.OnUpdate(a, a)
+	disjoint := b.DeepCopy()
+	disjoint.Spec.PodSelector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"web"}},
+		},
+	}
+	c.OnUpdate(b, disjoint)
+	if len(rec.events) != len(expected) {
+		t.Fatalf("Expected no more events, got: %v", rec.events[len(expected):])
+	}
+
+	// Conflicts that come back are reported again.
+	c.OnUpdate(disjoint, b)
+	expected = append(expected, expected[3], expected[4])
+	if diff := cmp.Diff(expected, rec.events, cmp.AllowUnexported(recordedEvent{})); diff != "" {
+		t.Errorf("Events not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	events := &stubEvents{}
+	rec := sink.NewEventRecorder(events)
+
+	rec.Event(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+			UID:       "some-uid",
+		},
+	}, coreV1.EventTypeWarning, sink.EventReasonRenderFailed, "some message")
+	rec.Event(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-cluster-name",
+		},
+	}, coreV1.EventTypeNormal, sink.EventReasonApplied, "some other message")
+
+	if len(events.created) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events.created))
+	}
+	e := events.created[0]
+	if e.Namespace != "some-namespace" || !strings.HasPrefix(e.Name, "some-name.") {
+		t.Errorf("Unexpected event name: %s/%s", e.Namespace, e.Name)
+	}
+	expectedRef := coreV1.ObjectReference{
+		Kind:       "LogSink",
+		APIVersion: "observability.knative.dev/v1alpha1",
+		Name:       "some-name",
+		Namespace:  "some-namespace",
+		UID:        "some-uid",
+	}
+	if diff := cmp.Diff(expectedRef, e.InvolvedObject); diff != "" {
+		t.Errorf("Involved object not equal (-want, +got) = %v", diff)
+	}
+	if e.Type != coreV1.EventTypeWarning || e.Reason != sink.EventReasonRenderFailed || e.Message != "some message" {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+	if e.Source.Component != "sink-controller" || e.Count != 1 || e.FirstTimestamp.IsZero() {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+
+	e = events.created[1]
+	if e.Namespace != metav1.NamespaceDefault || e.InvolvedObject.Kind != "ClusterLogSink" {
+		t.Errorf("Unexpected event for the ClusterLogSink: %+v", e)
+	}
+}
+
+type recordedEvent struct {
+	object    runtime.Object
+	eventtype string
+	reason    string
+	message   string
+}
+
+type spyEventRecorder struct {
+	events []recordedEvent
+}
+
+func (s *spyEventRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	s.events = append(s.events, recordedEvent{
+		object:    object,
+		eventtype: eventtype,
+		reason:    reason,
+		message:   message,
+	})
+}
+
+type stubEvents struct {
+	created []*coreV1.Event
+}
+
+func (s *stubEvents) Events(namespace string) typedCoreV1.EventInterface {
+	return &stubEventInterface{stub: s}
+}
+
+type stubEventInterface struct {
+	typedCoreV1.EventInterface
+	stub *stubEvents
+}
+
+func (s *stubEventInterface) Create(e *coreV1.Event) (*coreV1.Event, error) {
+	s.stub.created = append(s.stub.created, e)
+	return e, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// specFields report whether a spec sets the field they are named after.
+var specFields = map[string]func(v1alpha1.SinkSpec) bool{
+	"socket_path":            func(s v1alpha1.SinkSpec) bool { return s.SocketPath != "" },
+	"host":                   func(s v1alpha1.SinkSpec) bool { return s.Host != "" },
+	"port":                   func(s v1alpha1.SinkSpec) bool { return s.Port != 0 },
+	"protocol":               func(s v1alpha1.SinkSpec) bool { return s.Protocol != "" },
+	"enable_tls":             func(s v1alpha1.SinkSpec) bool { return s.EnableTLS },
+	"destinations":           func(s v1alpha1.SinkSpec) bool { return len(s.Destinations) != 0 },
+	"sample_rate":            func(s v1alpha1.SinkSpec) bool { return s.SampleRate != nil },
+	"max_records_per_second": func(s v1alpha1.SinkSpec) bool { return s.MaxRecordsPerSecond != 0 },
+	"parser_name":            func(s v1alpha1.SinkSpec) bool { return s.ParserName != "" },
+	"parser_names":           func(s v1alpha1.SinkSpec) bool { return len(s.ParserNames) != 0 },
+	"parse_json":             func(s v1alpha1.SinkSpec) bool { return s.ParseJSON },
+	"exclusive_match":        func(s v1alpha1.SinkSpec) bool { return s.ExclusiveMatch },
+	"pod_selector":           func(s v1alpha1.SinkSpec) bool { return Selects(s.PodSelector) },
+	"host_paths":             func(s v1alpha1.SinkSpec) bool { return len(s.HostPaths) != 0 },
+	"namespace_globs":        func(s v1alpha1.SinkSpec) bool { return len(s.NamespaceGlobs) != 0 },
+	"system_only":            func(s v1alpha1.SinkSpec) bool { return s.SystemOnly },
+	"insecure":               func(s v1alpha1.SinkSpec) bool { return s.Insecure },
+	"tls_secret_ref":         func(s v1alpha1.SinkSpec) bool { return s.TLSSecretRef != nil },
+	"stream":                 func(s v1alpha1.SinkSpec) bool { return s.Stream != "" && s.Stream != v1alpha1.StreamAll },
+	"catch_all":              func(s v1alpha1.SinkSpec) bool { return s.CatchAll },
+	"include_node_name":      func(s v1alpha1.SinkSpec) bool { return s.IncludeNodeName },
+}
+
+// exclusiveFields are the pairs of fields a sink cannot set together, one
+// of them would be ignored or contradict the other. The first field of a
+// pair is the one reported.
+var exclusiveFields = [][2]string{
+	// The socket replaces the address of the sink.
+	{"socket_path", "host"},
+	{"socket_path", "port"},
+	{"socket_path", "protocol"},
+	{"socket_path", "enable_tls"},
+	{"socket_path", "destinations"},
+	// Both bound the volume of a sink, together the sample rate no longer
+	// says what share of the records is forwarded.
+	{"sample_rate", "max_records_per_second"},
+	{"parser_name", "parse_json"},
+	{"parser_names", "parser_name"},
+	{"parser_names", "parse_json"},
+	// An exclusive sink claims its whole namespace.
+	{"exclusive_match", "pod_selector"},
+	{"host_paths", "namespace_globs"},
+	{"host_paths", "system_only"},
+	// Both select the namespaces of the sink.
+	{"system_only", "namespace_globs"},
+	// Files on the host are not written by containers and have no stream
+	// or kubernetes metadata.
+	{"host_paths", "stream"},
+	{"host_paths", "catch_all"},
+	{"host_paths", "include_node_name"},
+	{"insecure", "tls_secret_ref"},
+}
+
+// ExclusiveFields returns the pairs of mutually exclusive fields the spec
+// sets together, in the order of exclusiveFields.
+func ExclusiveFields(spec v1alpha1.SinkSpec) [][2]string {
+	var set [][2]string
+	for _, f := range exclusiveFields {
+		if specFields[f[0]](spec) && specFields[f[1]](spec) {
+			set = append(set, f)
+		}
+	}
+	return set
+}
+
+// ValidateExclusiveFields returns why the spec sets mutually exclusive
+// fields or nil if it does not.
+func ValidateExclusiveFields(spec v1alpha1.SinkSpec) error {
+	set := ExclusiveFields(spec)
+	if len(set) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s cannot be combined with %s", set[0][0], set[0][1])
+}
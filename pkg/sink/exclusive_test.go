@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestExclusiveFields(t *testing.T) {
+	socket := "/var/run/collector/syslog.sock"
+	rate := 0.5
+	tests := []struct {
+		spec     v1alpha1.SinkSpec
+		expected string
+	}{
+		{
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", SocketPath: socket},
+			"socket_path cannot be combined with host",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", Port: 514, SocketPath: socket},
+			"socket_path cannot be combined with port",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", Protocol: "udp", SocketPath: socket},
+			"socket_path cannot be combined with protocol",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", EnableTLS: true, SocketPath: socket},
+			"socket_path cannot be combined with enable_tls",
+		},
+		{
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				SocketPath:   socket,
+				Destinations: []v1alpha1.Destination{{Host: "example.com", Port: 514}},
+			},
+			"socket_path cannot be combined with destinations",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SampleRate: &rate, MaxRecordsPerSecond: 100},
+			"sample_rate cannot be combined with max_records_per_second",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx", ParseJSON: true},
+			"parser_name cannot be combined with parse_json",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", HostPaths: []string{"/var/log/syslog"}, IncludeNodeName: true},
+			"host_paths cannot be combined with include_node_name",
+		},
+		{
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx", ParserNames: []string{"json", "nginx"}},
+			"parser_names cannot be combined with parser_name",
+		},
+		{
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				ExclusiveMatch: true,
+				PodSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			"exclusive_match cannot be combined with pod_selector",
+		},
+		{
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				HostPaths:      []string{"/var/log/app.log"},
+				NamespaceGlobs: []string{"team-*"},
+			},
+			"host_paths cannot be combined with namespace_globs",
+		},
+		{
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				SystemOnly:     true,
+				NamespaceGlobs: []string{"team-*"},
+			},
+			"system_only cannot be combined with namespace_globs",
+		},
+		{
+			v1alpha1.SinkSpec{
+				Type:      "syslog",
+				Host:      "example.com",
+				Port:      514,
+				HostPaths: []string{"/var/log/app.log"},
+				Stream:    "stderr",
+			},
+			"host_paths cannot be combined with stream",
+		},
+		{
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         6514,
+				EnableTLS:    true,
+				Insecure:     true,
+				TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+			},
+			"insecure cannot be combined with tls_secret_ref",
+		},
+	}
+
+	for _, test := range tests {
+		err := sink.ValidateExclusiveFields(test.spec)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("Error not equal: Expected: %q Actual: %v", test.expected, err)
+		}
+
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: test.spec,
+		})
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for %q: Expected: %s Actual: %s", test.expected, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestNoExclusiveFields(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:                "syslog",
+		Host:                "example.com",
+		Port:                514,
+		ParseJSON:           true,
+		MaxRecordsPerSecond: 100,
+	}
+	if err := sink.ValidateExclusiveFields(spec); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// syslogFacilities are the names of the syslog facilities in the order of
+// their codes.
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// ValidateSyslogFacility returns why the facility of syslog messages is not
+// one of the syslog facility names or nil if it is. An empty facility
+// leaves it to the syslog output.
+func ValidateSyslogFacility(facility string) error {
+	if facility == "" {
+		return nil
+	}
+	for _, f := range syslogFacilities {
+		if facility == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("facility %q must be one of %s", facility, strings.Join(syslogFacilities, ", "))
+}
@@ -0,0 +1,745 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// sinkFilters returns the filters applied to the records in the sink's
+// stream before they reach its output, the cluster name is the one set on
+// every record. Sinks without filters share the main stream.
+func sinkFilters(e entry, clusterName string) ([]section, error) {
+	var (
+		spec    = e.spec
+		m       = e.match()
+		filters []section
+	)
+	// The other stream is dropped before its lines are joined.
+	if spec.Stream != "" {
+		if err := ValidateStream(spec.Stream); err != nil {
+			return nil, err
+		}
+		if spec.Stream != v1alpha1.StreamAll {
+			filters = append(filters, streamFilter(spec.Stream, m))
+		}
+	}
+	// Lines are joined first so the other filters see whole records.
+	if spec.Multiline != nil {
+		if err := ValidateMultiline(*spec.Multiline); err != nil {
+			return nil, err
+		}
+		filters = append(filters, multilineFilter(e.parser(), *spec.Multiline, m))
+	}
+	// Sampling the whole records early spares the other filters the
+	// records it drops.
+	if spec.SampleRate != nil {
+		if err := ValidateSampleRate(*spec.SampleRate); err != nil {
+			return nil, err
+		}
+		if *spec.SampleRate < 1 {
+			filters = append(filters, sampleFilter(*spec.SampleRate, m))
+		}
+	}
+	if len(spec.NamespaceGlobs) != 0 {
+		f, err := namespaceGlobsFilter(spec.NamespaceGlobs, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if err := ValidateSystemNamespaces(spec); err != nil {
+		return nil, err
+	}
+	if spec.SystemOnly {
+		filters = append(filters, systemNamespacesFilter(SystemNamespaces(spec), m))
+	}
+	if len(spec.ExcludeNamespaces) != 0 {
+		filters = append(filters, excludeNamespacesFilter(spec.ExcludeNamespaces, m))
+	}
+	if spec.PodSelector != nil {
+		f, err := podSelectorFilter(spec.PodSelector, m)
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			filters = append(filters, *f)
+		}
+	}
+	if len(spec.NodeSelector) != 0 {
+		if err := ValidateNodeSelector(spec.NodeSelector); err != nil {
+			return nil, err
+		}
+		filters = append(filters, nodeSelectorFilter(e.nodes, m))
+	}
+	if len(spec.DropPatterns) != 0 {
+		f, err := dropPatternsFilter(spec.DropPatterns, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	// Duplicates are told apart by the lines as they were logged, before
+	// redaction makes distinct lines equal.
+	if spec.DedupWindowSeconds != 0 {
+		if err := ValidateDedupWindow(spec.DedupWindowSeconds); err != nil {
+			return nil, err
+		}
+		filters = append(filters, dedupFilter(spec.DedupWindowSeconds, m))
+	}
+	if len(spec.RedactPatterns) != 0 {
+		f, err := redactFilter(spec.RedactPatterns, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	// Lines are truncated once redacted so no part of a secret is left
+	// past the cut, and before they are parsed into fields.
+	if spec.MaxMessageBytes != 0 {
+		if err := ValidateMaxMessageBytes(spec.MaxMessageBytes); err != nil {
+			return nil, err
+		}
+		filters = append(filters, truncateFilter(spec.MaxMessageBytes, m))
+	}
+	switch {
+	case spec.ParseJSON:
+		filters = append(filters, parseJSONFilter(m))
+	case len(e.logParsers) != 0:
+		for i, name := range parserNames(spec) {
+			filters = append(filters, customParserFilter(name, e.logParsers[i].KeyName, m))
+		}
+	}
+	// The time key may be one of the fields parsed from the line.
+	if spec.TimeKey != "" || spec.TimeFormat != "" {
+		if err := ValidateTimeKey(spec.TimeKey); err != nil {
+			return nil, err
+		}
+		if err := ValidateTimeFormat(spec.TimeFormat); err != nil {
+			return nil, err
+		}
+		filters = append(filters, timestampFilters(e.timeParser(), spec.TimeKey, m)...)
+	}
+	// The severity is one of the fields parsed from the line.
+	if spec.MinSeverity != "" {
+		if err := ValidateSeverity(spec.MinSeverity); err != nil {
+			return nil, err
+		}
+		if !spec.ParseJSON && len(e.logParsers) == 0 {
+			return nil, fmt.Errorf("min_severity requires parse_json, a parser_name or parser_names")
+		}
+		if f := severityFilter(spec.MinSeverity, m); f != nil {
+			filters = append(filters, *f)
+		}
+	}
+	// The expression may match a field parsed from the line.
+	if spec.MatchExpr != nil {
+		if err := ValidateMatchExpr(*spec.MatchExpr); err != nil {
+			return nil, err
+		}
+		filters = append(filters, matchExprFilter(*spec.MatchExpr, m))
+	}
+	// The node is copied out of the metadata before it is trimmed.
+	if spec.IncludeNodeName || spec.NodeNameKey != "" {
+		if err := ValidateNodeNameKey(spec); err != nil {
+			return nil, err
+		}
+		filters = append(filters, nodeNameFilter(spec.NodeNameKey, m))
+	}
+	if gelfDestination(e.destinations) && (spec.GELFKeys == nil || spec.GELFKeys.Host == "") {
+		filters = append(filters, gelfHostFilter(m))
+	}
+	// The index is expanded before the metadata it names is trimmed.
+	if TemplatedIndex(spec.Index) && esDestination(e.destinations) {
+		f, err := indexFilter(spec.Index, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	// The metadata is trimmed once the filters selecting on it ran.
+	if len(spec.MetadataFields) != 0 {
+		f, err := metadataFilter(spec.MetadataFields, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(spec.RenameKeys) != 0 {
+		f, err := renameKeysFilter(spec.RenameKeys, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(spec.Labels) != 0 {
+		f, err := labelsFilter(spec.Labels, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	// The record is projected once no other filter adds fields to it, the
+	// fields naming its cluster and index are kept.
+	if len(spec.OutputFields) != 0 {
+		var keep []string
+		if clusterName != "" {
+			keep = append(keep, ClusterNameKey)
+		}
+		if TemplatedIndex(spec.Index) && esDestination(e.destinations) {
+			keep = append(keep, indexKey)
+		}
+		f, err := outputFieldsFilter(spec.OutputFields, keep, m)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	// The strings are converted once no other filter changes them.
+	if converted(spec.Encoding) {
+		if err := ValidateEncoding(spec.Encoding); err != nil {
+			return nil, err
+		}
+		filters = append(filters, encodingFilter(spec.Encoding, m))
+	}
+	// Throttling last only counts the records the sink actually sends.
+	if spec.MaxRecordsPerSecond > 0 {
+		filters = append(filters, throttleFilter(spec.MaxRecordsPerSecond, m))
+	}
+	return filters, nil
+}
+
+// throttleFilter returns a throttle filter dropping the records above rate
+// per second. With a window of a single interval the limit applies to every
+// second rather than to an average that would let bursts through.
+func throttleFilter(rate int, m match) section {
+	f := newFilter("throttle", m)
+	f.add("Rate", strconv.Itoa(rate))
+	f.add("Window", "1")
+	f.add("Interval", "1s")
+	return f
+}
+
+// ValidateSampleRate returns why the fraction of records cannot be sampled
+// or nil if it can.
+func ValidateSampleRate(rate float64) error {
+	if math.IsNaN(rate) || rate < 0 || rate > 1 {
+		return fmt.Errorf("sample rate must be between 0 and 1, got %v", rate)
+	}
+	return nil
+}
+
+// sampleFilter returns a lua filter keeping every record with the
+// probability of rate and dropping it otherwise. The generator is seeded
+// when fluent-bit loads the filter so restarted pods do not repeat the
+// same choices.
+func sampleFilter(rate float64, m match) section {
+	f := newFilter("lua", m)
+	f.add("call", "sample")
+	f.add("code", fmt.Sprintf(
+		`math.randomseed(os.time()) function sample(tag, timestamp, record) `+
+			`if math.random() < %s then return 0, timestamp, record end return -1, 0, 0 end`,
+		strconv.FormatFloat(rate, 'g', -1, 64),
+	))
+	return f
+}
+
+// parseJSONFilter returns a parser filter replacing the log line of a record
+// with the fields it holds when it is JSON. The parser filter passes records
+// it cannot parse through as they are and Reserve_Data keeps the kubernetes
+// metadata next to the parsed fields.
+func parseJSONFilter(m match) section {
+	f := newFilter("parser", m)
+	f.add("Key_Name", "log")
+	f.add("Parser", "sink-json")
+	f.add("Reserve_Data", "On")
+	return f
+}
+
+// reservedRecordKeys are the record fields set by the tail input and the
+// kubernetes filter.
+var reservedRecordKeys = map[string]bool{
+	"log":        true,
+	"stream":     true,
+	"time":       true,
+	"kubernetes": true,
+}
+
+// ReservedRecordKey reports whether a sink label would replace a record
+// field set by fluent-bit.
+func ReservedRecordKey(k string) bool {
+	return reservedRecordKeys[k]
+}
+
+// labelsFilter returns a modify filter setting every label as a record
+// field. Set rather than Add replaces fields of the same name so records
+// cannot carry different values.
+func labelsFilter(labels map[string]string, m match) (section, error) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	f := newFilter("modify", m)
+	for _, k := range keys {
+		v := labels[k]
+		switch {
+		case k == "" || strings.ContainsAny(k, " \t\r\n"):
+			return section{}, fmt.Errorf("invalid label key %q", k)
+		case ReservedRecordKey(k):
+			return section{}, fmt.Errorf("label %q would replace a field set by fluent-bit", k)
+		case strings.TrimSpace(v) == "" || strings.ContainsAny(v, "\r\n"):
+			return section{}, fmt.Errorf("invalid value %q for label %q", v, k)
+		}
+		f.add("Set", fmt.Sprintf("%s %s", k, v))
+	}
+	return f, nil
+}
+
+// ValidateRenameKey returns why the record field name cannot be renamed
+// from or to by a modify filter or nil if it can.
+func ValidateRenameKey(k string) error {
+	switch {
+	case k == "":
+		return fmt.Errorf("key must not be empty")
+	case strings.ContainsAny(k, " \t\r\n"):
+		return fmt.Errorf("key %q must not contain whitespace", k)
+	}
+	return nil
+}
+
+// renameKeysFilter returns a modify filter renaming the record fields in
+// the keys of renames to their values. The rules are sorted so the config
+// does not change between renders.
+func renameKeysFilter(renames map[string]string, m match) (section, error) {
+	keys := make([]string, 0, len(renames))
+	for k := range renames {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	f := newFilter("modify", m)
+	targets := make(map[string]string, len(renames))
+	for _, k := range keys {
+		v := renames[k]
+		if err := ValidateRenameKey(k); err != nil {
+			return section{}, err
+		}
+		if err := ValidateRenameKey(v); err != nil {
+			return section{}, fmt.Errorf("invalid rename of %q: %s", k, err)
+		}
+		if prev, ok := targets[v]; ok {
+			return section{}, fmt.Errorf("%q and %q are both renamed to %q", prev, k, v)
+		}
+		targets[v] = k
+		f.add("Rename", fmt.Sprintf("%s %s", k, v))
+	}
+	return f, nil
+}
+
+// ValidateOutputField returns why the record field cannot be kept by a
+// record_modifier filter or nil if it can.
+func ValidateOutputField(field string) error {
+	switch {
+	case field == "":
+		return fmt.Errorf("field must not be empty")
+	case strings.ContainsAny(field, " \t\r\n"):
+		return fmt.Errorf("field %q must not contain whitespace", field)
+	}
+	return nil
+}
+
+// outputFieldsFilter returns a record_modifier filter removing every field
+// of a record but the listed ones and those the output needs.
+func outputFieldsFilter(fields, keep []string, m match) (section, error) {
+	f := newFilter("record_modifier", m)
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if err := ValidateOutputField(field); err != nil {
+			return section{}, err
+		}
+		if seen[field] {
+			return section{}, fmt.Errorf("field %q is listed more than once", field)
+		}
+		seen[field] = true
+		f.add("Whitelist_key", field)
+	}
+	for _, field := range keep {
+		if !seen[field] {
+			seen[field] = true
+			f.add("Whitelist_key", field)
+		}
+	}
+	return f, nil
+}
+
+// ValidateDropPattern returns why the pattern cannot be rendered into a
+// grep filter or nil if it can. fluent-bit trims property values, so
+// surrounding whitespace would silently change the pattern.
+func ValidateDropPattern(p string) error {
+	switch {
+	case p == "":
+		return fmt.Errorf("drop pattern must not be empty")
+	case strings.ContainsAny(p, "\r\n"):
+		return fmt.Errorf("drop pattern %q must be a single line", p)
+	case strings.TrimSpace(p) != p:
+		return fmt.Errorf("drop pattern %q must not start or end with whitespace", p)
+	}
+	if _, err := regexp.Compile(p); err != nil {
+		return fmt.Errorf("invalid drop pattern %q: %s", p, err)
+	}
+	return nil
+}
+
+// dropPatternsFilter returns a grep filter dropping the records whose log
+// line matches any of the patterns.
+func dropPatternsFilter(patterns []string, m match) (section, error) {
+	f := newFilter("grep", m)
+	for _, p := range patterns {
+		if err := ValidateDropPattern(p); err != nil {
+			return section{}, err
+		}
+		f.add("Exclude", "log "+p)
+	}
+	return f, nil
+}
+
+// ValidateMatchExpr returns why the expression cannot be rendered into a
+// grep filter or nil if it can. The key is separated from the regex by
+// whitespace and fluent-bit trims property values, so neither may hold
+// whitespace where it would change the expression.
+func ValidateMatchExpr(e v1alpha1.MatchExpr) error {
+	switch {
+	case e.Key == "":
+		return fmt.Errorf("key must not be empty")
+	case strings.ContainsAny(e.Key, " \t\r\n"):
+		return fmt.Errorf("key %q must not contain whitespace", e.Key)
+	case e.Regex == "":
+		return fmt.Errorf("regex must not be empty")
+	case strings.ContainsAny(e.Regex, "\r\n"):
+		return fmt.Errorf("regex %q must be a single line", e.Regex)
+	case strings.TrimSpace(e.Regex) != e.Regex:
+		return fmt.Errorf("regex %q must not start or end with whitespace", e.Regex)
+	}
+	if _, err := regexp.Compile(e.Regex); err != nil {
+		return fmt.Errorf("invalid regex %q: %s", e.Regex, err)
+	}
+	return nil
+}
+
+// matchExprFilter returns a grep filter dropping the records whose field
+// does not match the expression.
+func matchExprFilter(e v1alpha1.MatchExpr, m match) section {
+	f := newFilter("grep", m)
+	f.add("Regex", e.Key+" "+e.Regex)
+	return f
+}
+
+// metadataFields are the fields the kubernetes filter adds to the
+// kubernetes metadata of a record.
+var metadataFields = []string{
+	"pod_name",
+	"namespace_name",
+	"pod_id",
+	"labels",
+	"annotations",
+	"host",
+	"container_name",
+	"docker_id",
+	"container_hash",
+	"container_image",
+}
+
+// ValidateMetadataField returns why the field cannot be kept or nil if it
+// can.
+func ValidateMetadataField(field string) error {
+	for _, f := range metadataFields {
+		if field == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown metadata field %q, must be one of %s", field, strings.Join(metadataFields, ", "))
+}
+
+// metadataFilter returns a lua filter replacing the kubernetes metadata of
+// a record with the allowed fields of it. The nested fields are out of
+// reach of the record_modifier and modify filters, which only see the top
+// level of a record.
+func metadataFilter(fields []string, m match) (section, error) {
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if err := ValidateMetadataField(field); err != nil {
+			return section{}, err
+		}
+		quoted = append(quoted, luaString(field))
+	}
+
+	f := newFilter("lua", m)
+	f.add("call", "keep_metadata")
+	f.add("code", fmt.Sprintf(
+		`function keep_metadata(tag, timestamp, record) local metadata = record["kubernetes"] `+
+			`if type(metadata) ~= "table" then return 0, timestamp, record end local kept = {} `+
+			`for _, field in ipairs({%s}) do kept[field] = metadata[field] end `+
+			`record["kubernetes"] = kept return 2, timestamp, record end`,
+		strings.Join(quoted, ", "),
+	))
+	return f, nil
+}
+
+// ValidateStream returns why the sink cannot select the container stream or
+// nil if it can. An empty stream is all of them.
+func ValidateStream(stream string) error {
+	switch stream {
+	case "", v1alpha1.StreamAll, v1alpha1.StreamStdout, v1alpha1.StreamStderr:
+		return nil
+	}
+	return fmt.Errorf(
+		"unknown stream %q, must be one of %s, %s, %s",
+		stream,
+		v1alpha1.StreamAll,
+		v1alpha1.StreamStdout,
+		v1alpha1.StreamStderr,
+	)
+}
+
+// streamFilter returns a grep filter keeping only the lines the containers
+// wrote to the stream, which the tail input's parser sets.
+func streamFilter(stream string, m match) section {
+	f := newFilter("grep", m)
+	f.add("Regex", fmt.Sprintf("stream ^%s$", stream))
+	return f
+}
+
+// excludeNamespacesFilter returns a grep filter dropping records from pods in
+// any of the namespaces.
+func excludeNamespacesFilter(namespaces []string, m match) section {
+	f := newFilter("grep", m)
+	f.add("Exclude", fmt.Sprintf("$kubernetes['namespace_name'] %s", anyOf(namespaces)))
+	return f
+}
+
+// namespaceGlobsFilter returns a grep filter keeping only records from pods
+// in namespaces matching any of the globs.
+func namespaceGlobsFilter(globs []string, m match) (section, error) {
+	patterns := make([]string, len(globs))
+	for i, g := range globs {
+		if err := ValidateNamespaceGlob(g); err != nil {
+			return section{}, err
+		}
+		patterns[i] = globPattern(g)
+	}
+	f := newFilter("grep", m)
+	f.add("Regex", fmt.Sprintf("$kubernetes['namespace_name'] ^(%s)$", strings.Join(patterns, "|")))
+	return f, nil
+}
+
+// DefaultSystemNamespaces are the namespaces of the system components a
+// sink with system_only forwards when it does not set its own.
+var DefaultSystemNamespaces = []string{
+	"kube-system",
+	"kube-public",
+	"kube-node-lease",
+	"knative-observability",
+}
+
+// SystemNamespaces returns the namespaces a sink with system_only forwards.
+func SystemNamespaces(spec v1alpha1.SinkSpec) []string {
+	if len(spec.SystemNamespaces) == 0 {
+		return DefaultSystemNamespaces
+	}
+	return spec.SystemNamespaces
+}
+
+// ValidateSystemNamespaces returns why the system namespaces of the spec
+// cannot be forwarded or nil if they can. They only apply to system_only
+// and must be namespace names.
+func ValidateSystemNamespaces(spec v1alpha1.SinkSpec) error {
+	if len(spec.SystemNamespaces) != 0 && !spec.SystemOnly {
+		return fmt.Errorf("system_namespaces require system_only")
+	}
+	for _, ns := range spec.SystemNamespaces {
+		if errs := validation.IsDNS1123Label(ns); len(errs) != 0 {
+			return fmt.Errorf("invalid system namespace %q: %s", ns, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// systemNamespacesFilter returns a grep filter keeping only records from
+// pods in any of the system namespaces.
+func systemNamespacesFilter(namespaces []string, m match) section {
+	f := newFilter("grep", m)
+	f.add("Regex", fmt.Sprintf("$kubernetes['namespace_name'] %s", anyOf(namespaces)))
+	return f
+}
+
+var namespaceGlob = regexp.MustCompile(`^[a-z0-9*?-]+$`)
+
+// ValidateNamespaceGlob returns why the glob cannot select namespaces or
+// nil if it can. It is at most as long as a namespace name and only holds
+// the characters of one besides the wildcards * and ?.
+func ValidateNamespaceGlob(glob string) error {
+	switch {
+	case len(glob) > 63:
+		return fmt.Errorf("namespace glob %q is longer than 63 characters", glob)
+	case !namespaceGlob.MatchString(glob):
+		return fmt.Errorf("namespace glob %q must only contain lowercase alphanumerics, hyphens, * and ?", glob)
+	}
+	return nil
+}
+
+// globPattern returns the regular expression of a namespace glob, the
+// wildcards only match the characters of a namespace name.
+func globPattern(glob string) string {
+	var b strings.Builder
+	for _, c := range glob {
+		switch c {
+		case '*':
+			b.WriteString("[a-z0-9-]*")
+		case '?':
+			b.WriteString("[a-z0-9-]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// podSelectorFilter returns a grep filter keeping only records from pods
+// whose labels satisfy the selector. An empty selector matches everything
+// and returns no filter.
+func podSelectorFilter(ls *metav1.LabelSelector, m match) (*section, error) {
+	if !Selects(ls) {
+		return nil, nil
+	}
+	if err := ValidatePodSelector(ls); err != nil {
+		return nil, err
+	}
+	f := newFilter("grep", m)
+	keys := make([]string, 0, len(ls.MatchLabels))
+	for k := range ls.MatchLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.add("Regex", fmt.Sprintf("%s ^%s$", labelKey(k), regexp.QuoteMeta(ls.MatchLabels[k])))
+	}
+	for _, r := range ls.MatchExpressions {
+		key := labelKey(r.Key)
+		switch r.Operator {
+		case metav1.LabelSelectorOpIn:
+			f.add("Regex", fmt.Sprintf("%s %s", key, anyOf(r.Values)))
+		case metav1.LabelSelectorOpNotIn:
+			f.add("Exclude", fmt.Sprintf("%s %s", key, anyOf(r.Values)))
+		case metav1.LabelSelectorOpExists:
+			f.add("Regex", fmt.Sprintf("%s .*", key))
+		case metav1.LabelSelectorOpDoesNotExist:
+			f.add("Exclude", fmt.Sprintf("%s .*", key))
+		}
+	}
+	return &f, nil
+}
+
+// Selects reports whether the selector leaves out any pods.
+func Selects(ls *metav1.LabelSelector) bool {
+	return ls != nil && len(ls.MatchLabels)+len(ls.MatchExpressions) != 0
+}
+
+// ValidatePodSelector returns why the selector cannot match the labels of
+// pods or nil if it can. Keys and values are rendered into the regexes of
+// the grep filter, restricting them to valid label keys and values keeps
+// them within the quoting of the record accessor and on a single line.
+func ValidatePodSelector(ls *metav1.LabelSelector) error {
+	if ls == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(ls.MatchLabels))
+	for k := range ls.MatchLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(ls.MatchLabels[k]); len(errs) != 0 {
+			return fmt.Errorf("invalid value %q of label %s: %s", ls.MatchLabels[k], k, strings.Join(errs, ", "))
+		}
+	}
+	for _, r := range ls.MatchExpressions {
+		if errs := validation.IsQualifiedName(r.Key); len(errs) != 0 {
+			return fmt.Errorf("invalid label key %q: %s", r.Key, strings.Join(errs, ", "))
+		}
+		switch r.Operator {
+		case metav1.LabelSelectorOpIn, metav1.LabelSelectorOpNotIn:
+			if len(r.Values) == 0 {
+				return fmt.Errorf("operator %s of label %s needs values", r.Operator, r.Key)
+			}
+		case metav1.LabelSelectorOpExists, metav1.LabelSelectorOpDoesNotExist:
+			if len(r.Values) != 0 {
+				return fmt.Errorf("operator %s of label %s takes no values", r.Operator, r.Key)
+			}
+		default:
+			return fmt.Errorf("invalid label selector operator %q", r.Operator)
+		}
+		for _, v := range r.Values {
+			if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+				return fmt.Errorf("invalid value %q of label %s: %s", v, r.Key, strings.Join(errs, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// withClaimed returns the namespaces a ClusterLogSink excludes along with
+// the namespaces claimed by exclusive LogSinks.
+func withClaimed(excluded, claimed []string) []string {
+	if len(claimed) == 0 {
+		return excluded
+	}
+	seen := make(map[string]bool, len(excluded)+len(claimed))
+	namespaces := make([]string, 0, len(excluded)+len(claimed))
+	for _, ns := range append(append([]string{}, excluded...), claimed...) {
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+func labelKey(k string) string {
+	return fmt.Sprintf("$kubernetes['labels']['%s']", k)
+}
+
+func anyOf(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	return fmt.Sprintf("^(%s)$", strings.Join(quoted, "|"))
+}
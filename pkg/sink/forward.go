@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// DefaultForwardPort is the port of the forward input sinks of type forward
+// send to when they leave theirs unset.
+const DefaultForwardPort = 24224
+
+var selfHostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
+// forwardOutput returns a forward output sending the records to the
+// aggregator with the forward protocol. Sinks with a SecretRef
+// authenticate with its shared key, which stays out of the config, the
+// output references its variable in the credentials file.
+func forwardOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	if err := ValidateHost(spec.Host); err != nil {
+		return section{}, err
+	}
+	if err := ValidateSelfHostname(spec.SelfHostname); err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("forward", m)
+	o.add("Host", spec.Host)
+	o.add("Port", strconv.Itoa(ForwardPort(spec.Port)))
+	if auth, ok := spec.Headers["Authorization"]; ok {
+		if _, err := credential(v1alpha1.SinkTypeForward, auth); err != nil {
+			return section{}, err
+		}
+		o.add("Shared_Key", credentialRef(v1alpha1.SinkTypeForward, tag))
+	}
+	if spec.SelfHostname != "" {
+		o.add("Self_Hostname", spec.SelfHostname)
+	}
+	if spec.EnableTLS {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	return o, nil
+}
+
+// ForwardPort returns the port a forward destination sends to.
+func ForwardPort(port int) int {
+	if port == 0 {
+		return DefaultForwardPort
+	}
+	return port
+}
+
+// ValidateSelfHostname returns why the hostname cannot be sent in the
+// handshake of the shared key or nil if it can. An empty hostname is
+// fluent-bit's localhost.
+func ValidateSelfHostname(hostname string) error {
+	if hostname != "" && !selfHostname.MatchString(hostname) {
+		return fmt.Errorf("self hostname %q may only contain alphanumerics, hyphens and periods and must start and end with an alphanumeric", hostname)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// DefaultGELFPort is the port of the GELF input sinks of type gelf send to
+// when they leave theirs unset.
+const DefaultGELFPort = 12201
+
+// GELF modes are the transports sinks of type gelf send over.
+const (
+	GELFModeUDP = "udp"
+	GELFModeTCP = "tcp"
+	GELFModeTLS = "tls"
+)
+
+// DefaultGELFMode is the mode of sinks of type gelf that leave theirs
+// unset.
+const DefaultGELFMode = GELFModeUDP
+
+// gelfHostKey is the record field the host of the GELF messages is taken
+// from unless the sink maps another one, gelfHostFilter sets it to the node
+// of the pod.
+const gelfHostKey = "host"
+
+// gelfOutput returns a gelf output sending the records to the Graylog GELF
+// input. The log line is the short_message unless GELFKeys maps another
+// field.
+func gelfOutput(spec v1alpha1.SinkSpec, m match) (section, error) {
+	if err := ValidateHost(spec.Host); err != nil {
+		return section{}, err
+	}
+	if err := ValidateGELFMode(spec.Mode); err != nil {
+		return section{}, err
+	}
+	mode := GELFMode(spec.Mode)
+	if spec.EnableTLS && mode != GELFModeTLS {
+		return section{}, fmt.Errorf("enable_tls requires mode %s", GELFModeTLS)
+	}
+	keys := GELFKeys(spec.GELFKeys)
+	if err := ValidateGELFKeys(keys); err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("gelf", m)
+	o.add("Host", spec.Host)
+	o.add("Port", strconv.Itoa(GELFPort(spec.Port)))
+	o.add("Mode", mode)
+	o.add("Gelf_Short_Message_Key", keys.ShortMessage)
+	o.add("Gelf_Host_Key", keys.Host)
+	if keys.FullMessage != "" {
+		o.add("Gelf_Full_Message_Key", keys.FullMessage)
+	}
+	if keys.Level != "" {
+		o.add("Gelf_Level_Key", keys.Level)
+	}
+	if keys.Timestamp != "" {
+		o.add("Gelf_Timestamp_Key", keys.Timestamp)
+	}
+	if mode == GELFModeTLS {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	return o, nil
+}
+
+// GELFPort returns the port a gelf destination sends to.
+func GELFPort(port int) int {
+	if port == 0 {
+		return DefaultGELFPort
+	}
+	return port
+}
+
+// GELFMode returns the mode a gelf destination sends over.
+func GELFMode(mode string) string {
+	if mode == "" {
+		return DefaultGELFMode
+	}
+	return mode
+}
+
+// GELFKeys returns the record fields of the GELF fields with the defaults
+// of the unset ones.
+func GELFKeys(keys *v1alpha1.GELFKeys) v1alpha1.GELFKeys {
+	var k v1alpha1.GELFKeys
+	if keys != nil {
+		k = *keys
+	}
+	if k.ShortMessage == "" {
+		k.ShortMessage = "log"
+	}
+	if k.Host == "" {
+		k.Host = gelfHostKey
+	}
+	return k
+}
+
+// ValidateGELFMode returns why the mode is not one of udp, tcp and tls or
+// nil if it is. An empty mode is DefaultGELFMode.
+func ValidateGELFMode(mode string) error {
+	switch mode {
+	case "", GELFModeUDP, GELFModeTCP, GELFModeTLS:
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q, must be one of %s, %s, %s", mode, GELFModeUDP, GELFModeTCP, GELFModeTLS)
+}
+
+// ValidateGELFKeys returns why the GELF fields cannot be taken from the
+// record fields or nil if they can. The gelf output only looks up fields at
+// the top level of a record.
+func ValidateGELFKeys(keys v1alpha1.GELFKeys) error {
+	for _, k := range []string{keys.ShortMessage, keys.FullMessage, keys.Host, keys.Level, keys.Timestamp} {
+		switch {
+		case strings.ContainsAny(k, " \t\r\n"):
+			return fmt.Errorf("gelf key %q must not contain whitespace", k)
+		case strings.Contains(k, "."):
+			return fmt.Errorf("gelf key %q must be a top-level record field", k)
+		}
+	}
+	return nil
+}
+
+// gelfDestination reports whether any of the destinations is of type gelf.
+func gelfDestination(destinations []v1alpha1.SinkSpec) bool {
+	for _, d := range destinations {
+		if d.Type == v1alpha1.SinkTypeGELF {
+			return true
+		}
+	}
+	return false
+}
+
+// gelfHostFilter returns a lua filter setting the host field of the records
+// to the node of their pod, the gelf output cannot take it from the
+// kubernetes metadata. Records with a host field keep theirs.
+func gelfHostFilter(m match) section {
+	f := newFilter("lua", m)
+	f.add("call", "gelf_host")
+	f.add("code",
+		`function gelf_host(tag, timestamp, record) local k = record["kubernetes"] `+
+			`if record["host"] ~= nil or type(k) ~= "table" or k["host"] == nil then return 0, timestamp, record end `+
+			`record["host"] = k["host"] return 2, timestamp, record end`,
+	)
+	return f
+}
@@ -0,0 +1,421 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ThrottleDroppedRecords holds the records each sink's throttle dropped, as
+// reported by the running fluent-bit pods, keyed by namespace/name for
+// LogSinks and name for ClusterLogSinks.
+var ThrottleDroppedRecords = expvar.NewMap("sinkcontroller_throttle_dropped_records")
+
+// ReasonConnectionRefused is the reason set on the Ready condition of sinks
+// whose fluent-bit output reported errors, fluent-bit counts a receiver
+// refusing or dropping the connection as one.
+const ReasonConnectionRefused = "ConnectionRefused"
+
+// ReasonDisabled is the reason set on the Ready condition of sinks that are
+// disabled.
+const ReasonDisabled = "Disabled"
+
+// ReasonSecretMissing is the reason set on the Ready condition of sinks left
+// out of the config because a Secret they reference does not exist.
+const ReasonSecretMissing = "SecretMissing"
+
+// ReasonParserMissing is the reason set on the Ready condition of sinks left
+// out of the config because a ClusterLogParser they reference does not
+// exist.
+const ReasonParserMissing = "ParserMissing"
+
+// OutputMetrics are the counters fluent-bit reports for an output instance.
+type OutputMetrics struct {
+	ProcRecords   uint64 `json:"proc_records"`
+	ProcBytes     uint64 `json:"proc_bytes"`
+	Errors        uint64 `json:"errors"`
+	Retries       uint64 `json:"retries"`
+	RetriesFailed uint64 `json:"retries_failed"`
+}
+
+func (m OutputMetrics) failures() uint64 {
+	return m.Errors + m.RetriesFailed
+}
+
+// FilterMetrics are the counters fluent-bit reports for a filter instance.
+type FilterMetrics struct {
+	DropRecords uint64 `json:"drop_records"`
+	AddRecords  uint64 `json:"add_records"`
+}
+
+// Metrics are the metrics of every output and filter instance and the
+// buffers of the input instances keyed by instance name.
+type Metrics struct {
+	Outputs map[string]OutputMetrics `json:"output"`
+	Filters map[string]FilterMetrics `json:"filter"`
+	Storage map[string]InputStorage  `json:"-"`
+}
+
+type MetricsGetter interface {
+	// Metrics returns the instance metrics summed across the fluent-bit
+	// pods.
+	Metrics() (Metrics, error)
+}
+
+type PodLister interface {
+	List(opts metav1.ListOptions) (*coreV1.PodList, error)
+}
+
+type fluentBitMetrics struct {
+	pods   PodLister
+	port   int
+	client *http.Client
+}
+
+// NewFluentBitMetrics returns a MetricsGetter that scrapes the built in HTTP
+// server of every running fluent-bit pod.
+func NewFluentBitMetrics(pods PodLister, port int) MetricsGetter {
+	return &fluentBitMetrics{
+		pods: pods,
+		port: port,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+func (f *fluentBitMetrics) Metrics() (Metrics, error) {
+	pods, err := f.pods.List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit-ds",
+	})
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	total := Metrics{
+		Outputs: make(map[string]OutputMetrics),
+		Filters: make(map[string]FilterMetrics),
+		Storage: make(map[string]InputStorage),
+	}
+	for _, p := range pods.Items {
+		if p.Status.Phase != coreV1.PodRunning || p.Status.PodIP == "" {
+			continue
+		}
+		m, err := f.podMetrics(p.Status.PodIP)
+		if err != nil {
+			log.Printf("unable to get metrics from fluent-bit pod %s: %s", p.Name, err)
+			continue
+		}
+		for name, om := range m.Outputs {
+			t := total.Outputs[name]
+			t.ProcRecords += om.ProcRecords
+			t.ProcBytes += om.ProcBytes
+			t.Errors += om.Errors
+			t.Retries += om.Retries
+			t.RetriesFailed += om.RetriesFailed
+			total.Outputs[name] = t
+		}
+		for name, fm := range m.Filters {
+			t := total.Filters[name]
+			t.DropRecords += fm.DropRecords
+			t.AddRecords += fm.AddRecords
+			total.Filters[name] = t
+		}
+		storage, err := f.podStorage(p.Status.PodIP)
+		if err != nil {
+			log.Printf("unable to get storage metrics from fluent-bit pod %s: %s", p.Name, err)
+			continue
+		}
+		for name, in := range storage {
+			t := total.Storage[name]
+			t.Chunks += in.Chunks
+			t.MemBytes += in.MemBytes
+			t.Overlimit = t.Overlimit || in.Overlimit
+			total.Storage[name] = t
+		}
+	}
+	return total, nil
+}
+
+func (f *fluentBitMetrics) podMetrics(ip string) (Metrics, error) {
+	u := fmt.Sprintf("http://%s/api/v1/metrics", net.JoinHostPort(ip, strconv.Itoa(f.port)))
+	resp, err := f.client.Get(u)
+	if err != nil {
+		return Metrics{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Metrics{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var m Metrics
+	err = json.NewDecoder(resp.Body).Decode(&m)
+	if err != nil {
+		return Metrics{}, err
+	}
+	return m, nil
+}
+
+// HealthReporter periodically sets the Ready condition of every sink based
+// on the metrics of the fluent-bit output delivering to it, along with the
+// buffer of its stream.
+type HealthReporter struct {
+	sc        *Config
+	metrics   MetricsGetter
+	updater   SinkStatusUpdater
+	threshold uint64
+	last      map[string]OutputMetrics
+}
+
+// HealthOption configures a HealthReporter.
+type HealthOption func(*HealthReporter)
+
+// WithBackpressureThreshold sets the bytes the stream of a sink may buffer
+// before it is Backpressured. Zero only sets the condition when the stream
+// reached its memory limit.
+func WithBackpressureThreshold(bytes uint64) HealthOption {
+	return func(r *HealthReporter) {
+		r.threshold = bytes
+	}
+}
+
+func NewHealthReporter(sc *Config, m MetricsGetter, u SinkStatusUpdater, opts ...HealthOption) *HealthReporter {
+	r := &HealthReporter{
+		sc:      sc,
+		metrics: m,
+		updater: u,
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Run reconciles sink health every interval until stopCh is closed.
+func (r *HealthReporter) Run(interval time.Duration, stopCh <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.Reconcile()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Reconcile marks sinks as not ready when any of their outputs reported
+// errors since the previous call and as ready otherwise. Outputs that do not
+// show up in the metrics yet, e.g. because fluent-bit has not picked up the
+// latest config, are left out, and so are the outputs seen for the first
+// time: their counters hold the errors of their whole lifetime and only
+// become the baseline of the next call. Disabled sinks are not ready
+// regardless of the metrics and so are sinks whose Secret or
+// ClusterLogParser is missing and the sinks that broke a config fluent-bit
+// rejected. The sinks with a stream of their own
+// get its buffer and their Backpressured condition along with their Ready
+// condition. It also records the throughput of each sink and publishes the
+// records dropped by its throttle.
+func (r *HealthReporter) Reconcile() {
+	outputs, filters, disabled, missing := r.sc.instances()
+	for _, e := range disabled {
+		r.setCondition(e, v1alpha1.Condition{
+			Type:    v1alpha1.SinkConditionReady,
+			Status:  coreV1.ConditionFalse,
+			Reason:  ReasonDisabled,
+			Message: "the sink is disabled, fluent-bit does not forward its logs",
+		})
+	}
+	for _, re := range missing {
+		reason := ReasonSecretMissing
+		if _, ok := re.err.(missingParserError); ok {
+			reason = ReasonParserMissing
+		}
+		r.setCondition(re.sink, v1alpha1.Condition{
+			Type:    v1alpha1.SinkConditionReady,
+			Status:  coreV1.ConditionFalse,
+			Reason:  reason,
+			Message: fmt.Sprintf("%s, fluent-bit does not forward the sink's logs until it exists", re.err),
+		})
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The outputs of rejected sinks are not the ones fluent-bit runs.
+	rejected := r.sc.rejections()
+	for _, name := range names {
+		for _, e := range outputs[name] {
+			msg, ok := rejected[e.String()]
+			if !ok {
+				continue
+			}
+			r.setCondition(e, v1alpha1.Condition{
+				Type:    v1alpha1.SinkConditionReady,
+				Status:  coreV1.ConditionFalse,
+				Reason:  ReasonInvalidConfig,
+				Message: msg,
+			})
+		}
+	}
+
+	metrics, err := r.metrics.Metrics()
+	if err != nil {
+		log.Printf("unable to get fluent-bit metrics: %s", err)
+		return
+	}
+
+	var (
+		order []string
+		sinks = make(map[string]entry)
+		conds = make(map[string]v1alpha1.Condition)
+	)
+	for _, name := range names {
+		current, ok := metrics.Outputs[name]
+		if !ok {
+			continue
+		}
+		if _, ok := r.last[name]; !ok {
+			continue
+		}
+		cond := v1alpha1.Condition{
+			Type:   v1alpha1.SinkConditionReady,
+			Status: coreV1.ConditionTrue,
+		}
+		// Counters reset when fluent-bit restarts so only an increase
+		// means new failures.
+		if failed := current.failures(); failed > r.last[name].failures() {
+			cond.Status = coreV1.ConditionFalse
+			cond.Reason = ReasonConnectionRefused
+			cond.Message = fmt.Sprintf(
+				"fluent-bit output %s reported %d errors",
+				name,
+				failed-r.last[name].failures(),
+			)
+		}
+		// A sink with several destinations is only ready when all of
+		// them are.
+		for _, e := range outputs[name] {
+			k := e.String()
+			if _, ok := rejected[k]; ok {
+				continue
+			}
+			prev, seen := conds[k]
+			if !seen {
+				order = append(order, k)
+				sinks[k] = e
+			}
+			if !seen || prev.Status == coreV1.ConditionTrue {
+				conds[k] = cond
+			}
+		}
+	}
+	buffers := emitterBuffers(filters, metrics.Storage)
+	for _, k := range order {
+		cond := conds[k]
+		b, buffered := buffers[k]
+		r.updateStatus(sinks[k], func(s *v1alpha1.SinkStatus) bool {
+			changed := s.SetCondition(cond)
+			if !buffered {
+				return changed
+			}
+			chunks, bytes := int64(b.Chunks), int64(b.MemBytes)
+			if s.BufferedChunks != chunks || s.BufferedBytes != bytes {
+				s.BufferedChunks, s.BufferedBytes = chunks, bytes
+				changed = true
+			}
+			return s.SetCondition(backpressured(b, r.threshold)) || changed
+		})
+	}
+	recordForwarded(outputs, metrics.Outputs, r.last)
+	r.last = metrics.Outputs
+
+	recordThrottled(filters, metrics.Filters)
+}
+
+func (r *HealthReporter) setCondition(e entry, c v1alpha1.Condition) {
+	r.updateStatus(e, func(s *v1alpha1.SinkStatus) bool {
+		return s.SetCondition(c)
+	})
+}
+
+// updateStatus updates the status of the sink unless update reports that
+// it left it unchanged.
+func (r *HealthReporter) updateStatus(e entry, update func(*v1alpha1.SinkStatus) bool) {
+	var err error
+	if e.cluster() {
+		s := e.clusterLogSink.DeepCopy()
+		if !update(&s.Status) {
+			return
+		}
+		_, err = r.updater.ClusterLogSinks("").UpdateStatus(s)
+	} else {
+		s := e.logSink.DeepCopy()
+		if !update(&s.Status) {
+			return
+		}
+		_, err = r.updater.LogSinks(s.Namespace).UpdateStatus(s)
+	}
+	if err != nil {
+		log.Printf("unable to update status of sink %s: %s", e, err)
+	}
+}
+
+func recordThrottled(filters map[string][]entry, metrics map[string]FilterMetrics) {
+	current := make(map[string]bool)
+	for name, entries := range filters {
+		if !strings.HasPrefix(name, "throttle.") {
+			continue
+		}
+		fm, ok := metrics[name]
+		if !ok {
+			continue
+		}
+		for _, e := range entries {
+			dropped := new(expvar.Int)
+			dropped.Set(int64(fm.DropRecords))
+			ThrottleDroppedRecords.Set(e.String(), dropped)
+			current[e.String()] = true
+		}
+	}
+
+	var stale []string
+	ThrottleDroppedRecords.Do(func(kv expvar.KeyValue) {
+		if !current[kv.Key] {
+			stale = append(stale, kv.Key)
+		}
+	})
+	for _, k := range stale {
+		ThrottleDroppedRecords.Delete(k)
+	}
+}
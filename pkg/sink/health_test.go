@@ -0,0 +1,530 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestHealthReporterReady(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	}
+	cls := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+		Spec:       v1alpha1.SinkSpec{Type: "http", URI: "http://example.com/logs"},
+	}
+	client := fake.NewSimpleClientset(ls, cls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	sc.UpsertClusterSink(cls)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"syslog.0": {ProcRecords: 10},
+				"http.0":   {ProcRecords: 10},
+			},
+		},
+	}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+
+	// The first scrape is only the baseline.
+	ls = getLogSink(t, client, "test-ns", "sink")
+	if len(ls.Status.Conditions) != 0 {
+		t.Errorf("Expected no conditions, got: %v", ls.Status.Conditions)
+	}
+	r.Reconcile()
+
+	expectCondition(t, getLogSink(t, client, "test-ns", "sink"), coreV1.ConditionTrue, "")
+	expectCondition(t, getClusterLogSink(t, client, "cluster-sink"), coreV1.ConditionTrue, "")
+}
+
+func TestHealthReporterFailingOutput(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"syslog.0": {Errors: 4, RetriesFailed: 1},
+			},
+		},
+	}
+
+	// Errors from before the first scrape do not count.
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+	metrics.metrics.Outputs = map[string]sink.OutputMetrics{
+		"syslog.0": {Errors: 6, RetriesFailed: 2},
+	}
+	r.Reconcile()
+
+	s := getLogSink(t, client, "test-ns", "sink")
+	expectCondition(t, s, coreV1.ConditionFalse, sink.ReasonConnectionRefused)
+	if msg := s.Status.GetCondition(v1alpha1.SinkConditionReady).Message; msg != "fluent-bit output syslog.0 reported 3 errors" {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+
+	// Without new failures the sink recovers.
+	sc.UpsertSink(s)
+	r.Reconcile()
+
+	expectCondition(t, getLogSink(t, client, "test-ns", "sink"), coreV1.ConditionTrue, "")
+}
+
+func TestHealthReporterDisabled(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, Disabled: true},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	metrics := &stubMetricsGetter{err: errors.New("no fluent-bit pods")}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+
+	expectCondition(t, getLogSink(t, client, "test-ns", "sink"), coreV1.ConditionFalse, sink.ReasonDisabled)
+}
+
+func TestHealthReporterSecretMissing(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "http",
+			URI:       "https://logs.example.com/ingest",
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+		},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	p := &spyConfigMapPatcher{}
+	c := sink.NewSecretController(p, &spyReloader{}, sc)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"http.0": {ProcRecords: 10},
+			},
+		},
+	}
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+
+	s := secret("test-ns", "log-service", "abc123")
+	c.OnAdd(s)
+	r.Reconcile()
+	r.Reconcile()
+	expectCondition(t, getLogSink(t, client, "test-ns", "sink"), coreV1.ConditionTrue, "")
+
+	// The sink's output is left out while its Secret is deleted.
+	c.OnDelete(s)
+	if conf := lastConfig(t, p); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+	metrics.metrics.Outputs = map[string]sink.OutputMetrics{}
+	r.Reconcile()
+	missing := getLogSink(t, client, "test-ns", "sink")
+	expectCondition(t, missing, coreV1.ConditionFalse, sink.ReasonSecretMissing)
+	expected := "secret test-ns/log-service not found, fluent-bit does not forward the sink's logs until it exists"
+	if msg := missing.Status.GetCondition(v1alpha1.SinkConditionReady).Message; msg != expected {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+
+	// Recreating the Secret renders the sink again, it is ready once
+	// fluent-bit reported its output twice.
+	c.OnAdd(secret("test-ns", "log-service", "def456"))
+	if conf := lastConfig(t, p); !strings.Contains(conf, "Name http") {
+		t.Errorf("Expected the sink to be rendered again, got %q", conf)
+	}
+	metrics.metrics.Outputs = map[string]sink.OutputMetrics{
+		"http.0": {ProcRecords: 5},
+	}
+	r.Reconcile()
+	r.Reconcile()
+	expectCondition(t, getLogSink(t, client, "test-ns", "sink"), coreV1.ConditionTrue, "")
+}
+
+func TestHealthReporterParserMissing(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, ParserName: "nginx"},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	metrics := &stubMetricsGetter{err: errors.New("no fluent-bit pods")}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+
+	missing := getLogSink(t, client, "test-ns", "sink")
+	expectCondition(t, missing, coreV1.ConditionFalse, sink.ReasonParserMissing)
+	expected := "parser nginx not found, fluent-bit does not forward the sink's logs until it exists"
+	if msg := missing.Status.GetCondition(v1alpha1.SinkConditionReady).Message; msg != expected {
+		t.Errorf("Unexpected message: %s", msg)
+	}
+}
+
+func TestHealthReporterMissingOutput(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+
+	r := sink.NewHealthReporter(
+		sc,
+		&stubMetricsGetter{err: errors.New("unavailable")},
+		client.ObservabilityV1alpha1(),
+	)
+	r.Reconcile()
+	r = sink.NewHealthReporter(
+		sc,
+		&stubMetricsGetter{},
+		client.ObservabilityV1alpha1(),
+	)
+	r.Reconcile()
+
+	s := getLogSink(t, client, "test-ns", "sink")
+	if len(s.Status.Conditions) != 0 {
+		t.Errorf("Expected no conditions, got: %v", s.Status.Conditions)
+	}
+}
+
+func TestHealthReporterFailingDestination(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "http://example.com",
+			Destinations: []v1alpha1.Destination{
+				{Host: "example.org"},
+				{Host: "example.net"},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"http.0": {ProcRecords: 10},
+				"http.1": {},
+				"http.2": {ProcRecords: 10},
+			},
+		},
+	}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+	metrics.metrics.Outputs = map[string]sink.OutputMetrics{
+		"http.0": {ProcRecords: 10},
+		"http.1": {Errors: 1},
+		"http.2": {ProcRecords: 10},
+	}
+	r.Reconcile()
+
+	expectCondition(t, getLogSink(t, client, "test-ns", "sink"), coreV1.ConditionFalse, sink.ReasonConnectionRefused)
+}
+
+func TestHealthReporterThrottleDrops(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "throttled", Namespace: "test-ns"},
+		Spec: v1alpha1.SinkSpec{
+			Type:                "syslog",
+			Host:                "example.com",
+			Port:                12345,
+			MaxRecordsPerSecond: 10,
+		},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{},
+			Filters: map[string]sink.FilterMetrics{
+				"rewrite_tag.0": {AddRecords: 100},
+				"throttle.0":    {DropRecords: 42},
+			},
+		},
+	}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+
+	v := sink.ThrottleDroppedRecords.Get("test-ns/throttled")
+	if v == nil || v.String() != "42" {
+		t.Errorf("Expected 42 dropped records, got: %v", v)
+	}
+
+	sc.DeleteSink(ls)
+	r.Reconcile()
+
+	if v := sink.ThrottleDroppedRecords.Get("test-ns/throttled"); v != nil {
+		t.Errorf("Expected removed sink to be dropped from the metric, got: %v", v)
+	}
+}
+
+func TestHealthReporterBackpressure(t *testing.T) {
+	var sinks []runtime.Object
+	sc := sink.NewConfig()
+	for _, name := range []string{"fast", "paused", "slow"} {
+		ls := &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345, BufferType: "memory"},
+		}
+		sinks = append(sinks, ls)
+		sc.UpsertSink(ls)
+	}
+	client := fake.NewSimpleClientset(sinks...)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"syslog.0": {ProcRecords: 10},
+				"syslog.1": {ProcRecords: 10},
+				"syslog.2": {ProcRecords: 10},
+			},
+			Storage: map[string]sink.InputStorage{
+				"emitter_for_rewrite_tag.0": {Chunks: 1, MemBytes: 512 << 10},
+				"emitter_for_rewrite_tag.1": {Chunks: 5, MemBytes: 10 << 20, Overlimit: true},
+				"emitter_for_rewrite_tag.2": {Chunks: 2, MemBytes: 2 << 20},
+			},
+		},
+	}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1(), sink.WithBackpressureThreshold(1<<20))
+	r.Reconcile()
+	r.Reconcile()
+
+	for _, tc := range []struct {
+		name   string
+		chunks int64
+		bytes  int64
+		status coreV1.ConditionStatus
+		reason string
+	}{
+		{"fast", 1, 512 << 10, coreV1.ConditionFalse, ""},
+		{"paused", 5, 10 << 20, coreV1.ConditionTrue, sink.ReasonBufferOverLimit},
+		{"slow", 2, 2 << 20, coreV1.ConditionTrue, sink.ReasonBufferAboveThreshold},
+	} {
+		s := getLogSink(t, client, "test-ns", tc.name)
+		expectCondition(t, s, coreV1.ConditionTrue, "")
+		if s.Status.BufferedChunks != tc.chunks || s.Status.BufferedBytes != tc.bytes {
+			t.Errorf("Buffer of %s not equal: Expected: %d chunks, %d bytes Actual: %d chunks, %d bytes", tc.name, tc.chunks, tc.bytes, s.Status.BufferedChunks, s.Status.BufferedBytes)
+		}
+		c := s.Status.GetCondition(v1alpha1.SinkConditionBackpressured)
+		if c == nil {
+			t.Fatalf("Expected Backpressured condition on %s", tc.name)
+		}
+		if c.Status != tc.status || c.Reason != tc.reason {
+			t.Errorf("Backpressured of %s not equal: Expected: %s %s Actual: %s %s", tc.name, tc.status, tc.reason, c.Status, c.Reason)
+		}
+	}
+
+	// The receiver caught up.
+	metrics.metrics.Storage["emitter_for_rewrite_tag.2"] = sink.InputStorage{}
+	sc.UpsertSink(getLogSink(t, client, "test-ns", "slow"))
+	r.Reconcile()
+
+	s := getLogSink(t, client, "test-ns", "slow")
+	if s.Status.BufferedChunks != 0 || s.Status.BufferedBytes != 0 {
+		t.Errorf("Expected an empty buffer, got %d chunks, %d bytes", s.Status.BufferedChunks, s.Status.BufferedBytes)
+	}
+	if c := s.Status.GetCondition(v1alpha1.SinkConditionBackpressured); c == nil || c.Status != coreV1.ConditionFalse {
+		t.Errorf("Expected the sink to no longer be Backpressured: %v", c)
+	}
+}
+
+func TestHealthReporterSharedStreamHasNoBuffer(t *testing.T) {
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	}
+	client := fake.NewSimpleClientset(ls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{"syslog.0": {ProcRecords: 10}},
+			Storage: map[string]sink.InputStorage{"tail.0": {Chunks: 5, MemBytes: 10 << 20, Overlimit: true}},
+		},
+	}
+
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1(), sink.WithBackpressureThreshold(1<<20))
+	r.Reconcile()
+	r.Reconcile()
+
+	s := getLogSink(t, client, "test-ns", "sink")
+	expectCondition(t, s, coreV1.ConditionTrue, "")
+	if c := s.Status.GetCondition(v1alpha1.SinkConditionBackpressured); c != nil {
+		t.Errorf("Expected no Backpressured condition, got: %v", c)
+	}
+}
+
+func TestFluentBitMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/metrics":
+			fmt.Fprint(w, `{"input":{},"filter":{"throttle.0":{"drop_records":4,"add_records":0}},"output":{"syslog.0":{"proc_records":5,"errors":1,"retries_failed":2},"http.0":{"proc_records":3}}}`)
+		case "/api/v1/storage":
+			fmt.Fprint(w, `{"storage_layer":{"chunks":{"total_chunks":3}},"input_chunks":{"tail.0":{"status":{"overlimit":false,"mem_size":"0b","mem_limit":"0b"},"chunks":{"total":0,"up":0,"down":0,"busy":0,"busy_size":"0b"}},"emitter_for_rewrite_tag.0":{"status":{"overlimit":true,"mem_size":"1.5M","mem_limit":"10.0M"},"chunks":{"total":3,"up":3,"down":0,"busy":2,"busy_size":"1.0M"}}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods := &stubPodLister{
+		pods: []coreV1.Pod{
+			runningPod("fluent-bit-1", host),
+			runningPod("fluent-bit-2", host),
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit-3"},
+				Status:     coreV1.PodStatus{Phase: coreV1.PodPending},
+			},
+		},
+	}
+
+	metrics, err := sink.NewFluentBitMetrics(pods, p).Metrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pods.selector != "app=fluent-bit-ds" {
+		t.Errorf("Unexpected selector: %s", pods.selector)
+	}
+	expected := sink.Metrics{
+		Outputs: map[string]sink.OutputMetrics{
+			"syslog.0": {ProcRecords: 10, Errors: 2, RetriesFailed: 4},
+			"http.0":   {ProcRecords: 6},
+		},
+		Filters: map[string]sink.FilterMetrics{
+			"throttle.0": {DropRecords: 8},
+		},
+		Storage: map[string]sink.InputStorage{
+			"tail.0":                    {},
+			"emitter_for_rewrite_tag.0": {Chunks: 6, MemBytes: 3 << 20, Overlimit: true},
+		},
+	}
+	if diff := cmp.Diff(expected, metrics); diff != "" {
+		t.Errorf("Metrics not equal (-want, +got) = %v", diff)
+	}
+}
+
+func expectCondition(t *testing.T, o interface{}, status coreV1.ConditionStatus, reason string) {
+	t.Helper()
+	var c *v1alpha1.Condition
+	switch s := o.(type) {
+	case *v1alpha1.LogSink:
+		c = s.Status.GetCondition(v1alpha1.SinkConditionReady)
+	case *v1alpha1.ClusterLogSink:
+		c = s.Status.GetCondition(v1alpha1.SinkConditionReady)
+	}
+	if c == nil {
+		t.Fatalf("Expected Ready condition")
+	}
+	if c.Status != status {
+		t.Errorf("Ready status not equal. Expected: %s, Actual: %s", status, c.Status)
+	}
+	if c.Reason != reason {
+		t.Errorf("Ready reason not equal. Expected: %s, Actual: %s", reason, c.Reason)
+	}
+}
+
+func getLogSink(t *testing.T, c *fake.Clientset, ns, name string) *v1alpha1.LogSink {
+	t.Helper()
+	s, err := c.ObservabilityV1alpha1().LogSinks(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func getClusterLogSink(t *testing.T, c *fake.Clientset, name string) *v1alpha1.ClusterLogSink {
+	t.Helper()
+	s, err := c.ObservabilityV1alpha1().ClusterLogSinks("").Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func runningPod(name, ip string) coreV1.Pod {
+	return coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: coreV1.PodStatus{
+			Phase: coreV1.PodRunning,
+			PodIP: ip,
+		},
+	}
+}
+
+type stubMetricsGetter struct {
+	metrics sink.Metrics
+	err     error
+}
+
+func (s *stubMetricsGetter) Metrics() (sink.Metrics, error) {
+	return s.metrics, s.err
+}
+
+type stubPodLister struct {
+	pods          []coreV1.Pod
+	selector      string
+	fieldSelector string
+}
+
+func (s *stubPodLister) List(opts metav1.ListOptions) (*coreV1.PodList, error) {
+	s.selector = opts.LabelSelector
+	s.fieldSelector = opts.FieldSelector
+	return &coreV1.PodList{Items: s.pods}, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// httpFormats maps the SinkSpec format names to the fluent-bit http output
+// formats.
+var httpFormats = map[string]string{
+	"":                       "json_lines",
+	v1alpha1.FormatJSONLines: "json_lines",
+	v1alpha1.FormatJSONArray: "json",
+}
+
+// httpOutput returns an http output posting the records to the URI. The
+// bearer token of the SecretRef stays out of the config, the Authorization
+// header references its variable in the credentials file.
+func httpOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	u, err := url.Parse(spec.URI)
+	if err != nil {
+		return section{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return section{}, fmt.Errorf("unsupported uri scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return section{}, fmt.Errorf("uri %q has no host", spec.URI)
+	}
+	format, ok := httpFormats[spec.Format]
+	if !ok {
+		return section{}, fmt.Errorf("unsupported format %q", spec.Format)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	path := u.RequestURI()
+
+	o := newOutput("http", m)
+	o.add("Host", u.Hostname())
+	o.add("Port", port)
+	o.add("URI", path)
+	o.add("Format", format)
+	if u.Scheme == "https" || spec.EnableTLS {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	if err := addHeaders(&o, spec, tag); err != nil {
+		return section{}, err
+	}
+	addCompression(&o, spec.Compression)
+	return o, nil
+}
+
+// addHeaders adds a Header property for every header of the destination
+// sorted by name. The bearer token withCredentials sets from the SecretRef
+// is replaced with the reference to its variable in the credentials file,
+// headers spelled out in the spec are rendered as they are once
+// ValidateHeader accepts them.
+func addHeaders(o *section, spec v1alpha1.SinkSpec, tag string) error {
+	keys := make([]string, 0, len(spec.Headers))
+	for k := range spec.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := spec.Headers[k]
+		if k == "Authorization" && spec.SecretRef != nil {
+			if _, err := credential(spec.Type, v); err != nil {
+				return err
+			}
+			v = "Bearer " + credentialRef(spec.Type, tag)
+		}
+		if err := ValidateHeader(k, v); err != nil {
+			return err
+		}
+		o.add("Header", fmt.Sprintf("%s %s", k, v))
+	}
+	return nil
+}
+
+// ValidateHeader returns an error when the header cannot be rendered as a
+// Header property. The name ends at the first whitespace and a line break
+// in the value would start a new property or section of the config.
+func ValidateHeader(k, v string) error {
+	switch {
+	case k == "":
+		return fmt.Errorf("header names must not be empty")
+	case strings.ContainsAny(k, " \t\r\n"):
+		return fmt.Errorf("invalid header name %q", k)
+	case strings.ContainsAny(v, "\r\n"):
+		return fmt.Errorf("invalid value %q for header %s", v, k)
+	}
+	return nil
+}
+
+// ValidateCompression returns an error when the compression is unknown or
+// set on a sink with a destination whose output cannot compress.
+func ValidateCompression(spec v1alpha1.SinkSpec) error {
+	switch spec.Compression {
+	case "":
+		return nil
+	case v1alpha1.CompressionNone, v1alpha1.CompressionGzip:
+	default:
+		return fmt.Errorf(
+			"unknown compression %q, must be one of %s, %s",
+			spec.Compression,
+			v1alpha1.CompressionNone,
+			v1alpha1.CompressionGzip,
+		)
+	}
+	for _, d := range Destinations(spec) {
+		if d.Type != v1alpha1.SinkTypeHTTP && d.Type != v1alpha1.SinkTypeOTLP {
+			return fmt.Errorf(
+				"only sinks of type %s and %s support compression",
+				v1alpha1.SinkTypeHTTP,
+				v1alpha1.SinkTypeOTLP,
+			)
+		}
+	}
+	return nil
+}
+
+// addCompression sets the compress property of http and opentelemetry
+// outputs, which send uncompressed payloads without it.
+func addCompression(o *section, compression string) {
+	if compression == v1alpha1.CompressionGzip {
+		o.add("compress", "gzip")
+	}
+}
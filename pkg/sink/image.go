@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultFluentBitImage is the image of the fluent-bit container the
+// DaemonSet is deployed with.
+const DefaultFluentBitImage = "oratos/fluent-bit-out-syslog:v0.9"
+
+// hostLabel matches a label of a registry host name.
+const hostLabel = `[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?`
+
+// imageReference matches an image reference of an optional registry, a
+// path of lowercase components and an optional tag and digest. Like docker,
+// only a first component with a dot or port or of localhost is a registry.
+var imageReference = regexp.MustCompile(
+	`^(?:(?:(?:localhost|` + hostLabel + `(?:\.` + hostLabel + `)+)(?::[0-9]+)?|` + hostLabel + `:[0-9]+)/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`,
+)
+
+// ValidateImage returns why the image cannot be run by the fluent-bit
+// container or nil if it can.
+func ValidateImage(image string) error {
+	if len(image) > 255 || !imageReference.MatchString(image) {
+		return fmt.Errorf("invalid image reference %q", image)
+	}
+	return nil
+}
+
+// WithImage has the controller set the image of the fluent-bit container
+// of the DaemonSet. It is set with the first write and again when the
+// DaemonSet is found running another image, changing it rolls the
+// fluent-bit pods.
+func WithImage(ds DaemonSetPatcher, image string) Option {
+	return func(rc *reconciler) {
+		rc.imageDS = ds
+		rc.image = image
+	}
+}
+
+// setImage patches the image of the fluent-bit container unless it was set
+// already. It is called with the Config's writeMu held. A failed patch is
+// retried with the next write.
+func (rc *reconciler) setImage() {
+	if rc.imageDS == nil || rc.image == rc.sc.image {
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{
+							"name":  fluentBitContainer,
+							"image": rc.image,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err == nil {
+		_, err = rc.imageDS.Patch(DaemonSetName, types.StrategicMergePatchType, data)
+	}
+	if err != nil {
+		log.Printf("unable to set the image of %s to %s: %s", DaemonSetName, rc.image, err)
+		return
+	}
+	rc.sc.image = rc.image
+}
+
+// imageDrifted reports whether the fluent-bit container of the DaemonSet
+// runs another image than the one the controller sets, and has the next
+// write set it again if so.
+func (rc *reconciler) imageDrifted(ds *extensionsV1beta1.DaemonSet) bool {
+	if rc.imageDS == nil {
+		return false
+	}
+	for _, c := range ds.Spec.Template.Spec.Containers {
+		if c.Name == fluentBitContainer && c.Image != rc.image {
+			rc.sc.writeMu.Lock()
+			rc.sc.image = ""
+			rc.sc.writeMu.Unlock()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestImageIsSet(t *testing.T) {
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		sink.NewConfig(),
+		sink.WithImage(spyDaemonSet, "registry.example.com:5000/hardened/fluent-bit:1.9.3"),
+	)
+
+	for _, name := range []string{"sink-1", "sink-2"} {
+		c.OnAdd(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "some-namespace"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+		})
+	}
+
+	if len(spyDaemonSet.patches) != 1 {
+		t.Fatalf("Expected the image to be set once, got %d patches", len(spyDaemonSet.patches))
+	}
+	if image := patchedImage(t, spyDaemonSet.patches[0]); image != "registry.example.com:5000/hardened/fluent-bit:1.9.3" {
+		t.Errorf("Image not equal: Expected: registry.example.com:5000/hardened/fluent-bit:1.9.3 Actual: %s", image)
+	}
+}
+
+func TestDriftedImageIsSetAgain(t *testing.T) {
+	sc := sink.NewConfig()
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	image := "registry.example.com/hardened/fluent-bit:1.9.3"
+	sink.NewController(spyPatcher, &spyReloader{}, sc, sink.WithImage(spyDaemonSet, image)).OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+	c := sink.NewDriftController(spyPatcher, &spyReloader{}, sc, sink.WithImage(spyDaemonSet, image))
+
+	// The controller's own patch is not drift.
+	ds := fluentBitDaemonSet(sink.DefaultFluentBitImage)
+	c.OnUpdate(ds, fluentBitDaemonSet(image))
+	if len(spyDaemonSet.patches) != 1 || len(spyPatcher.patches) != 1 {
+		t.Fatalf("Expected nothing to be patched again, got %d DaemonSet and %d ConfigMap patches", len(spyDaemonSet.patches), len(spyPatcher.patches))
+	}
+
+	c.OnUpdate(fluentBitDaemonSet(image), ds)
+	if len(spyDaemonSet.patches) != 2 {
+		t.Fatalf("Expected the image to be set again, got %d patches", len(spyDaemonSet.patches))
+	}
+	if got := patchedImage(t, spyDaemonSet.patches[1]); got != image {
+		t.Errorf("Image not equal: Expected: %s Actual: %s", image, got)
+	}
+}
+
+func TestValidateImage(t *testing.T) {
+	for _, image := range []string{
+		sink.DefaultFluentBitImage,
+		"fluent-bit",
+		"fluent/fluent-bit:1.9.3",
+		"registry.example.com:5000/hardened/fluent-bit:1.9.3",
+		"localhost/fluent_bit",
+		"fluent/fluent-bit@sha256:" + sha256Digest,
+		"fluent/fluent-bit:1.9.3@sha256:" + sha256Digest,
+	} {
+		if err := sink.ValidateImage(image); err != nil {
+			t.Errorf("Expected %s to be valid: %s", image, err)
+		}
+	}
+
+	for _, image := range []string{
+		"",
+		"Fluent/fluent-bit",
+		"fluent/fluent-bit:",
+		"fluent/fluent-bit:-1.9",
+		"fluent//fluent-bit",
+		"fluent/fluent-bit@sha256:abc",
+		"fluent/fluent-bit 1.9",
+	} {
+		if err := sink.ValidateImage(image); err == nil {
+			t.Errorf("Expected %q to be invalid", image)
+		}
+	}
+}
+
+const sha256Digest = "4bc453b53cb3d914b45f4b250294236adba2c0e09ff6f03793949e7e39fd4cc1"
+
+func patchedImage(t *testing.T, p patch) string {
+	t.Helper()
+	if p.name != sink.DaemonSetName || p.pt != types.StrategicMergePatchType {
+		t.Fatalf("Unexpected patch of %s with %s", p.name, p.pt)
+	}
+	var ds extensionsV1beta1.DaemonSet
+	if err := json.Unmarshal(p.data, &ds); err != nil {
+		t.Fatal(err)
+	}
+	containers := ds.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Name != "fluent-bit" {
+		t.Fatalf("Expected the fluent-bit container to be patched: %s", p.data)
+	}
+	return containers[0].Image
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// hostPathKey is the record key holding the file a line of a host path was
+// read from.
+const hostPathKey = "log_path"
+
+// DefaultAuditLogPath is where audit sinks without host paths read the API
+// audit log from, the --audit-log-path kubeadm sets up.
+const DefaultAuditLogPath = "/var/log/kubernetes/audit/audit.log"
+
+// storageDirectory is where fluent-bit keeps the buffers and tail offsets
+// that outlive its pods.
+const storageDirectory = "/var/fluent-bit/storage"
+
+// hostInput returns the tail input reading the sink's host paths into its
+// stream. With multiline the path of each file follows the underscore the
+// way a container's tag does, so the lines of every file are joined apart.
+func (e entry) hostInput() section {
+	tag := e.tag()
+	if e.spec.Multiline != nil {
+		tag += "_*"
+	}
+	in := section{
+		kind: "INPUT",
+		props: [][2]string{
+			{"Name", "tail"},
+			{"Tag", tag},
+		},
+	}
+	in.add("Path", strings.Join(e.spec.HostPaths, ","))
+	in.add("Path_Key", hostPathKey)
+	in.add("DB", fmt.Sprintf("%s/%s.db", storageDirectory, e.tag()))
+	in.add("Mem_Buf_Limit", "5MB")
+	in.add("Skip_Long_Lines", "On")
+	in.add("Refresh_Interval", "10")
+	return in
+}
+
+// ValidateHostPaths returns why the globs cannot be tailed or nil if they
+// can. The tail input takes the globs comma separated.
+func ValidateHostPaths(paths []string) error {
+	for _, p := range paths {
+		switch {
+		case !filepath.IsAbs(p):
+			return fmt.Errorf("host path %q must be absolute", p)
+		case filepath.Clean(p) != p:
+			return fmt.Errorf("host path %q must be clean, e.g. %s", p, filepath.Clean(p))
+		case strings.ContainsAny(p, ",\r\n"):
+			return fmt.Errorf("host path %q must not contain commas or line breaks", p)
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("host path %q is not a valid glob: %s", p, err)
+		}
+	}
+	return nil
+}
+
+// audit reports whether the sink forwards the API audit log rather than
+// container logs.
+func (e entry) audit() bool {
+	return e.spec.Source == v1alpha1.LogSourceAudit
+}
+
+// auditInput returns the tail input reading the API audit log into the
+// sink's stream. Nodes without the log, i.e. those that are not part of the
+// control plane, have nothing to tail. Every line is an audit event in
+// JSON.
+func (e entry) auditInput() section {
+	paths := e.spec.HostPaths
+	if len(paths) == 0 {
+		paths = []string{DefaultAuditLogPath}
+	}
+	in := section{
+		kind: "INPUT",
+		props: [][2]string{
+			{"Name", "tail"},
+			{"Tag", e.tag()},
+		},
+	}
+	in.add("Path", strings.Join(paths, ","))
+	in.add("Parser", "sink-json")
+	in.add("DB", fmt.Sprintf("%s/%s_audit.db", storageDirectory, e.tag()))
+	in.add("Mem_Buf_Limit", "5MB")
+	in.add("Skip_Long_Lines", "On")
+	in.add("Refresh_Interval", "10")
+	return in
+}
+
+// ValidateSource returns why the sink cannot forward the logs of its source
+// or nil if it can. The audit log has no pods, namespaces, nodes, streams
+// or multi-line messages.
+func ValidateSource(spec v1alpha1.SinkSpec) error {
+	switch spec.Source {
+	case "", v1alpha1.LogSourceContainers:
+		return nil
+	case v1alpha1.LogSourceAudit:
+	default:
+		return fmt.Errorf("source %q must be %s or %s", spec.Source, v1alpha1.LogSourceContainers, v1alpha1.LogSourceAudit)
+	}
+	switch {
+	case spec.PodSelector != nil:
+		return fmt.Errorf("source audit cannot be combined with pod_selector")
+	case len(spec.NodeSelector) != 0:
+		return fmt.Errorf("source audit cannot be combined with node_selector")
+	case spec.Stream != "" && spec.Stream != v1alpha1.StreamAll:
+		return fmt.Errorf("source audit cannot be combined with stream %s", spec.Stream)
+	case spec.CatchAll:
+		return fmt.Errorf("source audit cannot be combined with catch_all")
+	case len(spec.NamespaceGlobs) != 0:
+		return fmt.Errorf("source audit cannot be combined with namespace_globs")
+	case spec.SystemOnly:
+		return fmt.Errorf("source audit cannot be combined with system_only")
+	case spec.Multiline != nil:
+		return fmt.Errorf("source audit cannot be combined with multiline")
+	case spec.IncludeNodeName:
+		return fmt.Errorf("source audit cannot be combined with include_node_name")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// kafkaOutput returns a kafka output producing the records as JSON to the
+// topic. fluent-bit passes the rdkafka properties to librdkafka, which
+// handles the TLS and SASL connections to the brokers. The SASL password of
+// the SecretRef stays out of the config like the one of elasticsearch
+// outputs.
+func kafkaOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	if err := ValidateBrokers(spec.Brokers); err != nil {
+		return section{}, err
+	}
+	if err := ValidateTopic(spec.Topic); err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("kafka", m)
+	o.add("Brokers", strings.Join(spec.Brokers, ","))
+	o.add("Topics", spec.Topic)
+	o.add("Format", "json")
+
+	_, sasl := spec.Headers["Authorization"]
+	switch {
+	case sasl && spec.EnableTLS:
+		o.add("rdkafka.security.protocol", "SASL_SSL")
+	case sasl:
+		o.add("rdkafka.security.protocol", "SASL_PLAINTEXT")
+	case spec.EnableTLS:
+		o.add("rdkafka.security.protocol", "SSL")
+	}
+	if sasl {
+		user, password, err := basicAuth(spec.Headers["Authorization"])
+		if err != nil {
+			return section{}, err
+		}
+		if spec.SecretRef != nil {
+			password = credentialRef(spec.Type, tag)
+		}
+		o.add("rdkafka.sasl.mechanism", "PLAIN")
+		o.add("rdkafka.sasl.username", user)
+		o.add("rdkafka.sasl.password", password)
+	}
+	if spec.EnableTLS && spec.InsecureSkipVerify {
+		o.add("rdkafka.enable.ssl.certificate.verification", "false")
+	}
+	return o, nil
+}
+
+// ValidateBrokers returns why the brokers cannot be rendered or nil if they
+// can. At least one broker of the form host:port is required.
+func ValidateBrokers(brokers []string) error {
+	if len(brokers) == 0 {
+		return fmt.Errorf("brokers must not be empty")
+	}
+	for _, b := range brokers {
+		host, p, err := net.SplitHostPort(b)
+		if err != nil || host == "" || strings.ContainsAny(b, ", \t\r\n") {
+			return fmt.Errorf("broker %q is not of the form host:port", b)
+		}
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("broker %q has an invalid port", b)
+		}
+	}
+	return nil
+}
+
+// topicName matches the characters Kafka allows in topic names.
+var topicName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// ValidateTopic returns why records cannot be produced to the topic or nil
+// if they can.
+func ValidateTopic(topic string) error {
+	switch {
+	case topic == "":
+		return fmt.Errorf("topic must not be empty")
+	case topic == "." || topic == "..":
+		return fmt.Errorf("topic must not be %q", topic)
+	case len(topic) > 249:
+		return fmt.Errorf("topic %q is longer than 249 characters", topic)
+	case !topicName.MatchString(topic):
+		return fmt.Errorf("topic %q may only contain ASCII letters, digits, ., _ and -", topic)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// lokiPushPath is where Loki receives the pushed logs when the URL of a
+// sink has no path.
+const lokiPushPath = "/loki/api/v1/push"
+
+// lokiDefaultLabelKeys are the record fields every stream a loki sink pushes
+// is labeled with, next to the static job label.
+var lokiDefaultLabelKeys = []string{"kubernetes.namespace_name"}
+
+var (
+	lokiLabelName   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	lokiKeySegment  = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	lokiTenantChars = regexp.MustCompile(`^[a-zA-Z0-9!._*'()-]+$`)
+)
+
+// lokiOutput returns a loki output pushing the records to the URL. The
+// streams are labeled job=fluent-bit and with the namespace of the pods,
+// the LabelKeys add labels named after the last part of the record field
+// they are taken from, e.g. pod_name for kubernetes.pod_name. The records
+// are pushed as JSON lines so the fields that are not labels are kept. The
+// bearer token of the SecretRef stays out of the config like the one of
+// http outputs.
+func lokiOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	u, err := parseLokiURL(spec.URL)
+	if err != nil {
+		return section{}, err
+	}
+	if err := ValidateTenantID(spec.TenantID); err != nil {
+		return section{}, err
+	}
+	keys, err := lokiLabelKeys(spec.LabelKeys)
+	if err != nil {
+		return section{}, err
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	path := u.RequestURI()
+	if u.Path == "" || u.Path == "/" {
+		path = lokiPushPath
+	}
+
+	o := newOutput("loki", m)
+	o.add("host", u.Hostname())
+	o.add("port", port)
+	o.add("uri", path)
+	if u.Scheme == "https" || spec.EnableTLS {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	if spec.TenantID != "" {
+		o.add("tenant_id", spec.TenantID)
+	}
+	o.add("labels", "job=fluent-bit")
+	o.add("label_keys", strings.Join(keys, ","))
+	o.add("line_format", "json")
+	if auth, ok := spec.Headers["Authorization"]; ok {
+		const prefix = "Bearer "
+		if !strings.HasPrefix(auth, prefix) {
+			return section{}, fmt.Errorf("loki sinks only support bearer tokens")
+		}
+		token := strings.TrimPrefix(auth, prefix)
+		if spec.SecretRef != nil {
+			token = credentialRef(spec.Type, tag)
+		}
+		o.add("bearer_token", token)
+	}
+	return o, nil
+}
+
+// ValidateLokiURL returns why logs cannot be pushed to the URL or nil if
+// they can.
+func ValidateLokiURL(raw string) error {
+	_, err := parseLokiURL(raw)
+	return err
+}
+
+func parseLokiURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("url must not be empty")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	case u.Hostname() == "":
+		return nil, fmt.Errorf("url %q has no host", raw)
+	case strings.ContainsAny(raw, " \t\r\n"):
+		return nil, fmt.Errorf("url %q must not contain whitespace", raw)
+	}
+	return u, nil
+}
+
+// ValidateTenantID returns why the tenant cannot be sent as the
+// X-Scope-OrgID of the pushes or nil if it can. Empty is no tenant.
+func ValidateTenantID(tenant string) error {
+	switch {
+	case tenant == "":
+	case len(tenant) > 150:
+		return fmt.Errorf("tenant id %q is longer than 150 bytes", tenant)
+	case tenant == "." || tenant == "..":
+		return fmt.Errorf("tenant id must not be %q", tenant)
+	case !lokiTenantChars.MatchString(tenant):
+		return fmt.Errorf("tenant id %q may only contain alphanumerics and !-_.*'()", tenant)
+	}
+	return nil
+}
+
+// ValidateLabelKey returns why the record field cannot label the streams
+// or nil if it can. Nested fields are joined by dots and the last part
+// names the label.
+func ValidateLabelKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("label key must not be empty")
+	}
+	segments := strings.Split(key, ".")
+	for _, s := range segments {
+		if !lokiKeySegment.MatchString(s) {
+			return fmt.Errorf("label key %q must be record fields joined by dots", key)
+		}
+	}
+	if name := segments[len(segments)-1]; !lokiLabelName.MatchString(name) {
+		return fmt.Errorf("label key %q does not end in a valid label name", key)
+	}
+	return nil
+}
+
+// ValidateLabelKeys returns why the record fields cannot label the streams
+// along with the default label keys or nil if they can.
+func ValidateLabelKeys(keys []string) error {
+	_, err := lokiLabelKeys(keys)
+	return err
+}
+
+// lokiLabelKeys returns the record accessors of the default label keys
+// followed by those of the keys. Keys that are already labels are left out
+// so no label is sent twice, and keys naming the same label as another
+// field are rejected.
+func lokiLabelKeys(keys []string) ([]string, error) {
+	var (
+		accessors []string
+		seen      = make(map[string]bool)
+		labels    = map[string]string{"job": ""}
+	)
+	for _, key := range append(append([]string{}, lokiDefaultLabelKeys...), keys...) {
+		if err := ValidateLabelKey(key); err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		segments := strings.Split(key, ".")
+		name := segments[len(segments)-1]
+		if other, ok := labels[name]; ok {
+			if other == "" {
+				return nil, fmt.Errorf("label key %q collides with the static %s label", key, name)
+			}
+			return nil, fmt.Errorf("label keys %q and %q both name the %s label", other, key, name)
+		}
+		labels[name] = key
+
+		accessor := "$" + segments[0]
+		for _, s := range segments[1:] {
+			accessor += fmt.Sprintf("['%s']", s)
+		}
+		accessors = append(accessors, accessor)
+	}
+	return accessors, nil
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/exporter/prometheus"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+var (
+	reconcileCount = stats.Int64(
+		"reconcile_count",
+		"Number of times the fluent-bit config was reconciled",
+		stats.UnitDimensionless,
+	)
+	reconcileErrorCount = stats.Int64(
+		"reconcile_error_count",
+		"Number of reconciles that failed to patch the config or reload fluent-bit",
+		stats.UnitDimensionless,
+	)
+	reconcileLatency = stats.Float64(
+		"reconcile_latency",
+		"Time taken to patch the config and reload fluent-bit",
+		stats.UnitMilliseconds,
+	)
+	propagationLatency = stats.Float64(
+		"config_propagation_latency",
+		"Time from a change of the sinks to the fluent-bit ConfigMap holding it",
+		stats.UnitMilliseconds,
+	)
+	applyFailureCount = stats.Int64(
+		"config_apply_failure_count",
+		"Number of writes of the fluent-bit config that failed, by the step that failed",
+		stats.UnitDimensionless,
+	)
+	managedSinks = stats.Int64(
+		"managed_sinks",
+		"Number of sinks in the fluent-bit config",
+		stats.UnitDimensionless,
+	)
+	forwardedRecords = stats.Int64(
+		"sink_forwarded_records",
+		"Number of records the fluent-bit outputs of a sink forwarded",
+		stats.UnitDimensionless,
+	)
+	forwardedBytes = stats.Int64(
+		"sink_forwarded_bytes",
+		"Number of bytes the fluent-bit outputs of a sink forwarded",
+		stats.UnitBytes,
+	)
+
+	kindKey   = mustNewKey("kind")
+	sinkKey   = mustNewKey("sink")
+	reasonKey = mustNewKey("reason")
+)
+
+// The reasons of failed writes of the config: the validator rejected it, or
+// the fluent-bit-tls Secret or ConfigMap could not be patched, or fluent-bit
+// could not be reloaded.
+const (
+	applyReasonValidation = "validation"
+	applyReasonSecret     = "secret"
+	applyReasonConfigMap  = "configmap"
+	applyReasonReload     = "reload"
+)
+
+// Views are the reconcile metrics of the sink-controller and the throughput
+// of every sink. The propagation latency is timed from the oldest change a
+// write of the config includes, failed writes are tagged with their reason.
+// Managed sinks are tagged with their kind. Forwarded records and bytes are
+// tagged with the kind of their sink and the sink, which is namespace/name
+// for LogSinks and name for ClusterLogSinks. They are summed across the
+// fluent-bit pods and the destinations of the sink. Sinks sharing the
+// syslog output with other sinks are not counted, fluent-bit only counts
+// the output as a whole.
+var Views = []*view.View{
+	{
+		Measure:     reconcileCount,
+		Description: reconcileCount.Description(),
+		Aggregation: view.Count(),
+	},
+	{
+		Measure:     reconcileErrorCount,
+		Description: reconcileErrorCount.Description(),
+		Aggregation: view.Count(),
+	},
+	{
+		Measure:     reconcileLatency,
+		Description: reconcileLatency.Description(),
+		Aggregation: view.Distribution(5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+	},
+	{
+		Measure:     propagationLatency,
+		Description: propagationLatency.Description(),
+		Aggregation: view.Distribution(10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000),
+	},
+	{
+		Measure:     applyFailureCount,
+		Description: applyFailureCount.Description(),
+		TagKeys:     []tag.Key{reasonKey},
+		Aggregation: view.Count(),
+	},
+	{
+		Measure:     managedSinks,
+		Description: managedSinks.Description(),
+		TagKeys:     []tag.Key{kindKey},
+		Aggregation: view.LastValue(),
+	},
+	{
+		Measure:     forwardedRecords,
+		Description: forwardedRecords.Description(),
+		TagKeys:     []tag.Key{kindKey, sinkKey},
+		Aggregation: view.Sum(),
+	},
+	{
+		Measure:     forwardedBytes,
+		Description: forwardedBytes.Description(),
+		TagKeys:     []tag.Key{kindKey, sinkKey},
+		Aggregation: view.Sum(),
+	},
+}
+
+// NewMetricsHandler registers the Views and returns a handler serving them
+// in the Prometheus text format. Metric names are prefixed with
+// sinkcontroller_.
+func NewMetricsHandler() (http.Handler, error) {
+	err := view.Register(Views...)
+	if err != nil {
+		return nil, err
+	}
+	e, err := prometheus.NewExporter(prometheus.Options{
+		Namespace: "sinkcontroller",
+	})
+	if err != nil {
+		return nil, err
+	}
+	view.RegisterExporter(e)
+	return e, nil
+}
+
+func mustNewKey(name string) tag.Key {
+	k, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// recordReconcile records a reconcile that started at start and the number
+// of sinks the config holds afterwards.
+func recordReconcile(start time.Time, failed bool, sinks, clusterSinks int) {
+	ms := []stats.Measurement{
+		reconcileCount.M(1),
+		reconcileLatency.M(float64(time.Since(start)) / float64(time.Millisecond)),
+	}
+	if failed {
+		ms = append(ms, reconcileErrorCount.M(1))
+	}
+	stats.Record(context.Background(), ms...)
+
+	for kind, n := range map[string]int{"LogSink": sinks, "ClusterLogSink": clusterSinks} {
+		ctx, err := tag.New(context.Background(), tag.Insert(kindKey, kind))
+		if err != nil {
+			log.Printf("unable to tag managed sinks: %s", err)
+			continue
+		}
+		stats.Record(ctx, managedSinks.M(int64(n)))
+	}
+}
+
+// recordPropagation records the time since the change the ConfigMap now
+// holds was made, zero is no change.
+func recordPropagation(changed time.Time) {
+	if changed.IsZero() {
+		return
+	}
+	stats.Record(context.Background(), propagationLatency.M(float64(time.Since(changed))/float64(time.Millisecond)))
+}
+
+// recordApplyFailure records a write of the config that failed for the
+// reason.
+func recordApplyFailure(reason string) {
+	ctx, err := tag.New(context.Background(), tag.Insert(reasonKey, reason))
+	if err != nil {
+		log.Printf("unable to tag failed write: %s", err)
+		return
+	}
+	stats.Record(ctx, applyFailureCount.M(1))
+}
+
+// recordForwarded records the records and bytes every output of a single
+// sink forwarded since the last metrics. A counter below its last value was
+// reset by a restart of fluent-bit and counts from zero.
+func recordForwarded(outputs map[string][]entry, current, last map[string]OutputMetrics) {
+	for name, entries := range outputs {
+		m, ok := current[name]
+		if !ok || len(entries) != 1 {
+			continue
+		}
+		e := entries[0]
+		kind := "LogSink"
+		if e.cluster() {
+			kind = "ClusterLogSink"
+		}
+		ctx, err := tag.New(
+			context.Background(),
+			tag.Insert(kindKey, kind),
+			tag.Insert(sinkKey, e.String()),
+		)
+		if err != nil {
+			log.Printf("unable to tag forwarded records of sink %s: %s", e, err)
+			continue
+		}
+		stats.Record(
+			ctx,
+			forwardedRecords.M(int64(increase(m.ProcRecords, last[name].ProcRecords))),
+			forwardedBytes.M(int64(increase(m.ProcBytes, last[name].ProcBytes))),
+		)
+	}
+}
+
+func increase(current, last uint64) uint64 {
+	if current < last {
+		return current
+	}
+	return current - last
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	h, err := sink.NewMetricsHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(sink.Views...)
+	view.SetReportingPeriod(10 * time.Millisecond)
+	defer view.SetReportingPeriod(time.Minute)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	c := sink.NewController(
+		&spyConfigMapPatcher{},
+		&spyReloader{err: errors.New("unavailable")},
+		sink.NewConfig(),
+	)
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+
+	expected := []string{
+		"sinkcontroller_reconcile_count 1",
+		"sinkcontroller_reconcile_error_count 1",
+		"sinkcontroller_reconcile_latency_bucket",
+		`sinkcontroller_config_apply_failure_count{reason="reload"} 1`,
+		`sinkcontroller_managed_sinks{kind="LogSink"} 1`,
+		`sinkcontroller_managed_sinks{kind="ClusterLogSink"} 0`,
+	}
+	var body string
+	for i := 0; i < 100; i++ {
+		body = scrape(t, server.URL)
+		if containsAll(body, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected metrics to contain %q:\n%s", e, body)
+		}
+	}
+}
+
+func TestPropagationMetrics(t *testing.T) {
+	h, err := sink.NewMetricsHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(sink.Views...)
+	view.SetReportingPeriod(10 * time.Millisecond)
+	defer view.SetReportingPeriod(time.Minute)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	c := sink.NewController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		sink.NewConfig(),
+		sink.WithValidator(stubValidator(func(conf string) bool {
+			return strings.Contains(conf, "broken.example.com")
+		})),
+	)
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "broken.example.com", Port: 12345},
+	})
+
+	// The rejected config never reached the ConfigMap.
+	expected := []string{
+		"sinkcontroller_config_propagation_latency_bucket",
+		"sinkcontroller_config_propagation_latency_count 1",
+		`sinkcontroller_config_apply_failure_count{reason="validation"} 1`,
+	}
+	var body string
+	for i := 0; i < 100; i++ {
+		body = scrape(t, server.URL)
+		if containsAll(body, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected metrics to contain %q:\n%s", e, body)
+		}
+	}
+}
+
+func TestPropagationOfRejectedChange(t *testing.T) {
+	h, err := sink.NewMetricsHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(sink.Views...)
+	view.SetReportingPeriod(10 * time.Millisecond)
+	defer view.SetReportingPeriod(time.Minute)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	c := sink.NewController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		sink.NewConfig(),
+		sink.WithValidator(stubValidator(func(conf string) bool {
+			return strings.Contains(conf, "broken.example.com")
+		})),
+	)
+	broken := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "broken.example.com", Port: 12345},
+	}
+	c.OnAdd(broken)
+	time.Sleep(60 * time.Millisecond)
+	c.OnDelete(broken)
+
+	// The write of the deletion is timed from the rejected change.
+	expected := []string{
+		`sinkcontroller_config_propagation_latency_bucket{le="50"} 0`,
+		"sinkcontroller_config_propagation_latency_count 1",
+	}
+	var body string
+	for i := 0; i < 100; i++ {
+		body = scrape(t, server.URL)
+		if containsAll(body, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected metrics to contain %q:\n%s", e, body)
+		}
+	}
+}
+
+func TestForwardedMetrics(t *testing.T) {
+	h, err := sink.NewMetricsHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(sink.Views...)
+	view.SetReportingPeriod(10 * time.Millisecond)
+	defer view.SetReportingPeriod(time.Minute)
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	ls := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "http", URI: "http://example.com/logs"},
+	}
+	cls := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+		Spec: v1alpha1.SinkSpec{
+			Type: "http",
+			URI:  "http://example.com/cluster",
+			Destinations: []v1alpha1.Destination{
+				{Host: "backup.example.com", Port: 8080},
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(ls, cls)
+	sc := sink.NewConfig()
+	sc.UpsertSink(ls)
+	sc.UpsertClusterSink(cls)
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"http.0": {ProcRecords: 10, ProcBytes: 1000},
+				"http.1": {ProcRecords: 20, ProcBytes: 2000},
+				"http.2": {ProcRecords: 20, ProcBytes: 2000},
+			},
+		},
+	}
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+
+	// The counters only add the increase, a restart of fluent-bit counts
+	// from zero again.
+	metrics.metrics.Outputs = map[string]sink.OutputMetrics{
+		"http.0": {ProcRecords: 15, ProcBytes: 1500},
+		"http.1": {ProcRecords: 5, ProcBytes: 500},
+		"http.2": {ProcRecords: 25, ProcBytes: 2500},
+	}
+	r.Reconcile()
+
+	expected := []string{
+		`sinkcontroller_sink_forwarded_records{kind="LogSink",sink="test-ns/sink"} 15`,
+		`sinkcontroller_sink_forwarded_bytes{kind="LogSink",sink="test-ns/sink"} 1500`,
+		`sinkcontroller_sink_forwarded_records{kind="ClusterLogSink",sink="cluster-sink"} 50`,
+		`sinkcontroller_sink_forwarded_bytes{kind="ClusterLogSink",sink="cluster-sink"} 5000`,
+	}
+	var body string
+	for i := 0; i < 100; i++ {
+		body = scrape(t, server.URL)
+		if containsAll(body, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected metrics to contain %q:\n%s", e, body)
+		}
+	}
+}
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status code: %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func containsAll(s string, subs []string) bool {
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
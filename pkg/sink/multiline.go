@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// MaxMultilineLines bounds MaxLines, every line is a state of the rendered
+// parser.
+const MaxMultilineLines = 500
+
+// ValidateMultiline returns why the multiline settings cannot be rendered
+// or nil if they can.
+func ValidateMultiline(ml v1alpha1.Multiline) error {
+	if ml.StartPattern == "" {
+		return fmt.Errorf("start pattern must not be empty")
+	}
+	// The pattern is quoted in the parser's rules.
+	if strings.ContainsAny(ml.StartPattern, "\"\r\n") {
+		return fmt.Errorf("start pattern must be a single line without double quotes")
+	}
+	if _, err := regexp.Compile(ml.StartPattern); err != nil {
+		return fmt.Errorf("invalid start pattern: %s", err)
+	}
+	if ml.MaxLines < 0 || ml.MaxLines > MaxMultilineLines {
+		return fmt.Errorf("max lines must be between 0 and %d, got %d", MaxMultilineLines, ml.MaxLines)
+	}
+	if ml.FlushTimeoutMs < 0 {
+		return fmt.Errorf("flush timeout must not be negative, got %d", ml.FlushTimeoutMs)
+	}
+	return nil
+}
+
+// multilineParser returns the multiline parser joining the lines of a
+// record. A line matching the start pattern starts a record and every
+// following line that does not match is appended to it. With MaxLines
+// the parser counts the lines in its states: line i moves it from cont_i
+// to cont_i+1 and the last state only has a rule that never matches, so
+// the next line ends the record.
+func multilineParser(name string, ml v1alpha1.Multiline) section {
+	start := fmt.Sprintf("^(?:%s)", ml.StartPattern)
+	cont := fmt.Sprintf("^(?!(?:%s))", ml.StartPattern)
+
+	p := section{kind: "MULTILINE_PARSER"}
+	p.add("name", name)
+	p.add("type", "regex")
+	if ml.FlushTimeoutMs != 0 {
+		p.add("flush_timeout", strconv.Itoa(ml.FlushTimeoutMs))
+	}
+	if ml.MaxLines == 0 {
+		p.add("rule", rule("start_state", start, "cont"))
+		p.add("rule", rule("cont", cont, "cont"))
+		return p
+	}
+	p.add("rule", rule("start_state", start, "cont_2"))
+	for i := 2; i <= ml.MaxLines; i++ {
+		p.add("rule", rule(fmt.Sprintf("cont_%d", i), cont, fmt.Sprintf("cont_%d", i+1)))
+	}
+	last := fmt.Sprintf("cont_%d", ml.MaxLines+1)
+	p.add("rule", rule(last, "(?!)", last))
+	return p
+}
+
+func rule(state, pattern, next string) string {
+	return fmt.Sprintf(`"%s" "/%s/" "%s"`, state, pattern, next)
+}
+
+// multilineFilter returns the filter joining the lines of the records in
+// the sink's stream with the parser. fluent-bit keeps the lines of every
+// tag apart, which is why sinks with multiline get a stream per container.
+func multilineFilter(parser string, ml v1alpha1.Multiline, m match) section {
+	f := newFilter("multiline", m)
+	f.add("multiline.key_content", "log")
+	f.add("multiline.parser", parser)
+	if ml.FlushTimeoutMs != 0 {
+		f.add("flush_ms", strconv.Itoa(ml.FlushTimeoutMs))
+	}
+	return f
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// NodeController keeps the labels of the Nodes in the config and re-renders
+// it when the nodes a NodeSelector selects may have changed.
+type NodeController struct {
+	*reconciler
+}
+
+func NewNodeController(cmp ConfigMapPatcher, r Reloader, sc *Config, opts ...Option) *NodeController {
+	return &NodeController{
+		reconciler: newReconciler(cmp, r, sc, opts),
+	}
+}
+
+func (c *NodeController) OnAdd(o interface{}) {
+	n, ok := o.(*coreV1.Node)
+	if !ok {
+		return
+	}
+
+	if !c.sc.UpsertNode(n) {
+		return
+	}
+	c.reconcile()
+}
+
+func (c *NodeController) OnDelete(o interface{}) {
+	n, ok := o.(*coreV1.Node)
+	if !ok {
+		return
+	}
+
+	if !c.sc.DeleteNode(n) {
+		return
+	}
+	c.reconcile()
+}
+
+func (c *NodeController) OnUpdate(old, new interface{}) {
+	o, _ := old.(*coreV1.Node)
+	n, ok := new.(*coreV1.Node)
+	if !ok {
+		return
+	}
+	// Nodes are updated by their status every few seconds, only changes
+	// to their labels select them differently.
+	if o != nil && reflect.DeepEqual(o.Labels, n.Labels) {
+		return
+	}
+	c.OnAdd(n)
+}
+
+// UpsertNode stores the labels of the node and reports whether any sink
+// selects nodes.
+func (sc *Config) UpsertNode(n *coreV1.Node) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.nodes[n.Name] = n.Labels
+	return sc.nodesSelected()
+}
+
+// DeleteNode removes the node and reports whether any sink selects nodes.
+func (sc *Config) DeleteNode(n *coreV1.Node) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	delete(sc.nodes, n.Name)
+	return sc.nodesSelected()
+}
+
+func (sc *Config) nodesSelected() bool {
+	for _, e := range sc.entries() {
+		if len(e.spec.NodeSelector) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedNodes returns the sorted names of the nodes whose labels match
+// the selector.
+func (sc *Config) selectedNodes(selector map[string]string) []string {
+	var names []string
+	for name, labels := range sc.nodes {
+		if nodeSelected(selector, labels) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func nodeSelected(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateNodeSelector returns why the selector cannot match the labels of
+// nodes or nil if it can.
+func ValidateNodeSelector(selector map[string]string) error {
+	keys := make([]string, 0, len(selector))
+	for k := range selector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(selector[k]); len(errs) != 0 {
+			return fmt.Errorf("invalid value %q of label %s: %s", selector[k], k, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// nodeSelectorFilter returns a grep filter keeping only records from the
+// nodes whose names the kubernetes filter set as the host of the record.
+// Without any selected node every record is dropped, no host is empty.
+func nodeSelectorFilter(nodes []string, m match) section {
+	f := newFilter("grep", m)
+	if len(nodes) == 0 {
+		f.add("Regex", "$kubernetes['host'] ^$")
+		return f
+	}
+	f.add("Regex", fmt.Sprintf("$kubernetes['host'] %s", anyOf(nodes)))
+	return f
+}
+
+// defaultNodeNameKey is the field include_node_name sets when it has no
+// node_name_key.
+const defaultNodeNameKey = "hostname"
+
+// ValidateNodeNameKey returns why the node name cannot be set as the field
+// or nil if it can. An empty key is hostname.
+func ValidateNodeNameKey(spec v1alpha1.SinkSpec) error {
+	key := spec.NodeNameKey
+	switch {
+	case key == "":
+	case !spec.IncludeNodeName:
+		return fmt.Errorf("node_name_key requires include_node_name")
+	case strings.ContainsAny(key, " \t\r\n\"\\"):
+		return fmt.Errorf("node name key %q must not contain whitespace, quotes or backslashes", key)
+	case ReservedRecordKey(key):
+		return fmt.Errorf("node name key %q collides with a field set by fluent-bit", key)
+	}
+	return nil
+}
+
+// nodeNameFilter returns a lua filter setting the field of the records to
+// the node of their pod, like gelfHostFilter but replacing the field.
+func nodeNameFilter(key string, m match) section {
+	if key == "" {
+		key = defaultNodeNameKey
+	}
+	f := newFilter("lua", m)
+	f.add("call", "node_name")
+	f.add("code",
+		`function node_name(tag, timestamp, record) local k = record["kubernetes"] `+
+			`if type(k) ~= "table" or k["host"] == nil then return 0, timestamp, record end `+
+			fmt.Sprintf(`record[%s] = k["host"] return 2, timestamp, record end`, luaString(key)),
+	)
+	return f
+}
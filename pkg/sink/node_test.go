@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestNodeSelector(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertNode(node("gpu-node-2", map[string]string{"accelerator": "gpu"}))
+	sc.UpsertNode(node("cpu-node-1", map[string]string{"accelerator": "none"}))
+	sc.UpsertNode(node("gpu-node-1", map[string]string{"accelerator": "gpu", "zone": "a"}))
+	sc.UpsertNode(node("unlabeled-node", nil))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12345,
+			NodeSelector: map[string]string{"accelerator": "gpu"},
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match_Regex ^(?!sink\\.).*\n    Rule $log .* sink.cluster.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.cluster.some-name\n    Regex $kubernetes['host'] ^(gpu-node-1|gpu-node-2)$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.cluster.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestNodeSelectorWithoutNodes(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertNode(node("cpu-node-1", map[string]string{"accelerator": "none"}))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12345,
+			NodeSelector: map[string]string{"accelerator": "gpu"},
+		},
+	})
+
+	// No record has an empty host.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name grep\n    Match sink.ns.ns1.some-name\n    Regex $kubernetes['host'] ^$\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidNodeSelector(t *testing.T) {
+	for _, selector := range []map[string]string{
+		{"": "gpu"},
+		{"accelerator/": "gpu"},
+		{"accelerator": "gpu nodes"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         12345,
+				NodeSelector: selector,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Expected node selector %v to be rejected: %q", selector, sc.String())
+		}
+	}
+}
+
+func TestNodeController(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         12345,
+			NodeSelector: map[string]string{"accelerator": "gpu"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	c := sink.NewNodeController(spyPatcher, spyReloader, sc)
+
+	n1 := node("some-node", map[string]string{"accelerator": "gpu"})
+	c.OnAdd(n1)
+	if conf := lastConfig(t, spyPatcher); !strings.Contains(conf, "^(some-node)$") {
+		t.Errorf("Expected the node to be selected: %q", conf)
+	}
+
+	// Status updates of a node leave fluent-bit alone.
+	updated := node("some-node", map[string]string{"accelerator": "gpu"})
+	updated.Status.Phase = coreV1.NodeRunning
+	c.OnUpdate(n1, updated)
+	if spyReloader.reloads != 1 {
+		t.Fatalf("Reloads not equal: Expected: 1, Actual: %d", spyReloader.reloads)
+	}
+
+	relabeled := node("some-node", map[string]string{"accelerator": "none"})
+	c.OnUpdate(updated, relabeled)
+	if conf := lastConfig(t, spyPatcher); strings.Contains(conf, "some-node") {
+		t.Errorf("Expected the relabeled node to be left out: %q", conf)
+	}
+
+	c.OnDelete(relabeled)
+	if spyReloader.reloads != 3 {
+		t.Errorf("Reloads not equal: Expected: 3, Actual: %d", spyReloader.reloads)
+	}
+}
+
+func TestNodeControllerWithoutNodeSelectors(t *testing.T) {
+	spyReloader := &spyReloader{}
+	c := sink.NewNodeController(&spyConfigMapPatcher{}, spyReloader, sink.NewConfig())
+
+	c.OnAdd(node("some-node", map[string]string{"accelerator": "gpu"}))
+	c.OnDelete(node("some-node", map[string]string{"accelerator": "gpu"}))
+	if spyReloader.reloads != 0 {
+		t.Errorf("Reloads not equal: Expected: 0, Actual: %d", spyReloader.reloads)
+	}
+}
+
+func node(name string, labels map[string]string) *coreV1.Node {
+	return &coreV1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// otlpOutput returns an opentelemetry output shipping the records as OTLP
+// logs. fluent-bit's opentelemetry output speaks OTLP over HTTP, so the
+// endpoint is the collector's OTLP/HTTP receiver, usually on port 4318.
+// Receivers that only accept OTLP over gRPC need an OpenTelemetry Collector
+// in front of them. The bearer token of the SecretRef stays out of the
+// config like the one of http outputs.
+func otlpOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	host, port, err := SplitEndpoint(spec.Endpoint)
+	if err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("opentelemetry", m)
+	o.add("Host", host)
+	o.add("Port", strconv.Itoa(port))
+	o.add("Logs_uri", "/v1/logs")
+	if spec.Insecure {
+		o.add("tls", "Off")
+	} else {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	if err := addHeaders(&o, spec, tag); err != nil {
+		return section{}, err
+	}
+	addCompression(&o, spec.Compression)
+	return o, nil
+}
+
+// SplitEndpoint splits an OTLP endpoint of the form host:port.
+func SplitEndpoint(endpoint string) (string, int, error) {
+	host, p, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", 0, fmt.Errorf("endpoint %q is not of the form host:port", endpoint)
+	}
+	if host == "" {
+		return "", 0, fmt.Errorf("endpoint %q has no host", endpoint)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("endpoint %q has an invalid port", endpoint)
+	}
+	return host, port, nil
+}
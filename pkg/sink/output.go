@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// section is a single fluent-bit config section such as an [OUTPUT] or
+// [FILTER] block. Properties are rendered in the order they were added.
+type section struct {
+	kind  string
+	props [][2]string
+}
+
+// match selects the records a section applies to. It is either a Match
+// wildcard or a Match_Regex pattern.
+type match [2]string
+
+var (
+	matchAll = match{"Match", "*"}
+	// matchUnrouted matches every record except the copies made for sinks
+	// with their own stream and a derived tag.
+	matchUnrouted = match{"Match_Regex", `^(?!sink\.).*`}
+)
+
+// unrouted returns the match of every record except the copies made for
+// sinks with their own stream, tagged with the sinks' Tags or derived tags.
+func unrouted(tags []string) match {
+	if len(tags) == 0 {
+		return matchUnrouted
+	}
+	quoted := make([]string, 0, len(tags))
+	for _, t := range tags {
+		quoted = append(quoted, regexp.QuoteMeta(t))
+	}
+	return match{"Match_Regex", fmt.Sprintf(`^(?!sink\.|(?:%s)(?:_|$)).*`, strings.Join(quoted, "|"))}
+}
+
+func matchTag(pattern string) match {
+	return match{"Match", pattern}
+}
+
+func newOutput(name string, m match) section {
+	return newSection("OUTPUT", name, m)
+}
+
+func newFilter(name string, m match) section {
+	return newSection("FILTER", name, m)
+}
+
+func newSection(kind, name string, m match) section {
+	return section{
+		kind: kind,
+		props: [][2]string{
+			{"Name", name},
+			m,
+		},
+	}
+}
+
+func (s *section) add(key, value string) {
+	s.props = append(s.props, [2]string{key, value})
+}
+
+func (s section) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n[%s]\n", s.kind)
+	for _, p := range s.props {
+		fmt.Fprintf(&b, "    %s %s\n", p[0], p[1])
+	}
+	return b.String()
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// ParserController keeps the ClusterLogParsers in the config and re-renders
+// it when a parser sinks reference changes.
+type ParserController struct {
+	*reconciler
+}
+
+func NewParserController(cmp ConfigMapPatcher, r Reloader, sc *Config, opts ...Option) *ParserController {
+	return &ParserController{
+		reconciler: newReconciler(cmp, r, sc, opts),
+	}
+}
+
+func (c *ParserController) OnAdd(o interface{}) {
+	p, ok := o.(*v1alpha1.ClusterLogParser)
+	if !ok {
+		return
+	}
+
+	if !c.sc.UpsertParser(p) {
+		return
+	}
+	c.reconcile()
+}
+
+func (c *ParserController) OnDelete(o interface{}) {
+	p, ok := o.(*v1alpha1.ClusterLogParser)
+	if !ok {
+		return
+	}
+
+	if !c.sc.DeleteParser(p) {
+		return
+	}
+	c.reconcile()
+}
+
+func (c *ParserController) OnUpdate(old, new interface{}) {
+	o, _ := old.(*v1alpha1.ClusterLogParser)
+	n, ok := new.(*v1alpha1.ClusterLogParser)
+	if !ok {
+		return
+	}
+	// Resyncs of an unchanged parser leave the config alone.
+	if o != nil && reflect.DeepEqual(o.Spec, n.Spec) {
+		c.sc.UpsertParser(n)
+		return
+	}
+	c.OnAdd(n)
+}
+
+// UpsertParser stores the parser and reports whether any sink references
+// it.
+func (sc *Config) UpsertParser(p *v1alpha1.ClusterLogParser) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.parsers[p.Name] = p.Spec
+	return sc.parserReferenced(p.Name)
+}
+
+// DeleteParser removes the parser and reports whether any sink references
+// it.
+func (sc *Config) DeleteParser(p *v1alpha1.ClusterLogParser) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	delete(sc.parsers, p.Name)
+	return sc.parserReferenced(p.Name)
+}
+
+func (sc *Config) parserReferenced(name string) bool {
+	for _, e := range sc.entries() {
+		for _, n := range parserNames(e.spec) {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parserNames returns the ClusterLogParsers the sink parses its records
+// with, in order.
+func parserNames(spec v1alpha1.SinkSpec) []string {
+	if spec.ParserName != "" {
+		return []string{spec.ParserName}
+	}
+	return spec.ParserNames
+}
+
+// ValidateParserNames returns why the parsers cannot be chained or nil if
+// they can. A parser listed twice would parse the fields it promoted.
+func ValidateParserNames(names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("parser names must not be empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("parser %s is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// logParsers returns the valid ClusterLogParsers the sink references, in
+// the order of parserNames.
+func (sc *Config) logParsers(e entry) ([]v1alpha1.ParserSpec, error) {
+	names := parserNames(e.spec)
+	if err := ValidateParserNames(names); err != nil {
+		return nil, err
+	}
+	ps := make([]v1alpha1.ParserSpec, len(names))
+	for i, name := range names {
+		p, ok := sc.parsers[name]
+		if !ok {
+			return nil, missingParserError(name)
+		}
+		if err := ValidateParser(p); err != nil {
+			return nil, fmt.Errorf("invalid parser %s: %s", name, err)
+		}
+		ps[i] = p
+	}
+	return ps, nil
+}
+
+// missingParserError is why a sink referencing a ClusterLogParser that does
+// not exist is left out of the config.
+type missingParserError string
+
+func (e missingParserError) Error() string {
+	return fmt.Sprintf("parser %s not found", string(e))
+}
+
+// ValidateParser returns why the spec cannot be rendered into a parser or
+// nil if it can.
+func ValidateParser(p v1alpha1.ParserSpec) error {
+	switch p.Format {
+	case v1alpha1.ParserFormatRegex:
+		if err := ValidateParserRegex(p.Regex); err != nil {
+			return err
+		}
+	case v1alpha1.ParserFormatJSON:
+		if p.Regex != "" {
+			return fmt.Errorf("regex is only supported by parsers of format %s", v1alpha1.ParserFormatRegex)
+		}
+	default:
+		return fmt.Errorf(
+			"unknown parser format %q, must be one of %s, %s",
+			p.Format,
+			v1alpha1.ParserFormatRegex,
+			v1alpha1.ParserFormatJSON,
+		)
+	}
+	if err := ValidateParserKeyName(p.KeyName); err != nil {
+		return err
+	}
+	if p.TimeKey != "" || p.TimeFormat != "" {
+		if err := ValidateTimeKey(p.TimeKey); err != nil {
+			return err
+		}
+		if err := ValidateTimeFormat(p.TimeFormat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateParserKeyName returns why the parser cannot parse the field or nil
+// if it can. An empty key is the log line.
+func ValidateParserKeyName(key string) error {
+	if strings.ContainsAny(key, " \t\r\n\"\\") {
+		return fmt.Errorf("key name %q must not contain whitespace, quotes or backslashes", key)
+	}
+	return nil
+}
+
+// ValidateParserRegex returns why the regular expression cannot parse the
+// log lines into fields or nil if it can. fluent-bit trims property values
+// and only keeps the named groups.
+func ValidateParserRegex(regex string) error {
+	switch {
+	case regex == "":
+		return fmt.Errorf("regex must not be empty")
+	case strings.ContainsAny(regex, "\r\n"):
+		return fmt.Errorf("regex must be a single line")
+	case strings.TrimSpace(regex) != regex:
+		return fmt.Errorf("regex must not start or end with whitespace")
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return fmt.Errorf("invalid regex: %s", err)
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("regex %q has no named groups, e.g. (?<level>[A-Z]+)", regex)
+}
+
+// customParserName is the name the ClusterLogParser is rendered under in
+// the parsers file, apart from the parsers the controller generates.
+func customParserName(name string) string {
+	return "custom-" + name
+}
+
+// customParser returns the parser section of the ClusterLogParser.
+func customParser(name string, p v1alpha1.ParserSpec) section {
+	s := section{kind: "PARSER"}
+	s.add("Name", customParserName(name))
+	s.add("Format", p.Format)
+	if p.Regex != "" {
+		s.add("Regex", p.Regex)
+	}
+	if p.TimeKey != "" {
+		s.add("Time_Key", p.TimeKey)
+		s.add("Time_Format", p.TimeFormat)
+	}
+	return s
+}
+
+// customParserFilter returns a parser filter replacing the field of a
+// record, the log line unless the ClusterLogParser has a KeyName, with the
+// fields the parser finds in it, like parseJSONFilter.
+func customParserFilter(name, key string, m match) section {
+	if key == "" {
+		key = "log"
+	}
+	f := newFilter("parser", m)
+	f.add("Key_Name", key)
+	f.add("Parser", customParserName(name))
+	f.add("Reserve_Data", "On")
+	return f
+}
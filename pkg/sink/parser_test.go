@@ -0,0 +1,232 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestCustomParser(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertParser(clusterLogParser("nginx-access", v1alpha1.ParserSpec{
+		Format:     "regex",
+		Regex:      `^(?<remote>[^ ]*) (?<method>\S+) (?<path>[^ ]*)$`,
+		TimeKey:    "time",
+		TimeFormat: "%d/%b/%Y:%H:%M:%S %z",
+	}))
+	for _, name := range []string{"some-name-1", "some-name-2"} {
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "http",
+				URI:        "https://logs.example.com/ingest",
+				ParserName: "nginx-access",
+			},
+		})
+	}
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name-1 true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name-1\n    Key_Name log\n    Parser custom-nginx-access\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name http\n    Match sink.ns.ns1.some-name-1\n    Host logs.example.com\n    Port 443\n    URI /ingest\n    Format json_lines\n    tls On\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name-2 true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name-2\n    Key_Name log\n    Parser custom-nginx-access\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name http\n    Match sink.ns.ns1.some-name-2\n    Host logs.example.com\n    Port 443\n    URI /ingest\n    Format json_lines\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	// Sinks sharing a parser share its section.
+	expectedParsers := "\n[PARSER]\n    Name custom-nginx-access\n    Format regex\n" +
+		"    Regex ^(?<remote>[^ ]*) (?<method>\\S+) (?<path>[^ ]*)$\n" +
+		"    Time_Key time\n    Time_Format %d/%b/%Y:%H:%M:%S %z\n"
+	if sc.Parsers() != expectedParsers {
+		t.Errorf("Parsers not equal: Expected: %q Actual: %q", expectedParsers, sc.Parsers())
+	}
+}
+
+func TestChainedParsers(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertParser(clusterLogParser("access", v1alpha1.ParserSpec{
+		Format:  "regex",
+		Regex:   `^(?<method>\S+) (?<path>[^ ]*)$`,
+		KeyName: "message",
+	}))
+	sc.UpsertParser(clusterLogParser("json-wrapper", v1alpha1.ParserSpec{Format: "json"}))
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:        "http",
+			URI:         "https://logs.example.com/ingest",
+			ParserNames: []string{"json-wrapper", "access"},
+		},
+	})
+
+	// The wrapped message is promoted before it is parsed.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser custom-json-wrapper\n    Reserve_Data On\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name message\n    Parser custom-access\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name http\n    Match sink.ns.ns1.some-name\n    Host logs.example.com\n    Port 443\n    URI /ingest\n    Format json_lines\n    tls On\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+
+	expectedParsers := "\n[PARSER]\n    Name custom-json-wrapper\n    Format json\n" +
+		"\n[PARSER]\n    Name custom-access\n    Format regex\n    Regex ^(?<method>\\S+) (?<path>[^ ]*)$\n"
+	if sc.Parsers() != expectedParsers {
+		t.Errorf("Parsers not equal: Expected: %q Actual: %q", expectedParsers, sc.Parsers())
+	}
+}
+
+func TestInvalidChainedParsers(t *testing.T) {
+	for _, names := range [][]string{
+		{"json-wrapper", "access"},
+		{"json-wrapper", "json-wrapper"},
+		{"json-wrapper", ""},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertParser(clusterLogParser("json-wrapper", v1alpha1.ParserSpec{Format: "json"}))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "syslog",
+				Host:        "example.org",
+				Port:        12346,
+				ParserNames: names,
+			},
+		})
+
+		if sc.String() != emptyConfig || sc.Parsers() != "" {
+			t.Errorf("Expected parsers %v to be rejected: Config: %q Parsers: %q", names, sc.String(), sc.Parsers())
+		}
+	}
+}
+
+func TestMissingCustomParser(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			Host:       "example.org",
+			Port:       12346,
+			ParserName: "nginx-access",
+		},
+	})
+
+	if sc.String() != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, sc.String())
+	}
+}
+
+func TestInvalidCustomParser(t *testing.T) {
+	for _, spec := range []v1alpha1.ParserSpec{
+		{Format: "ltsv"},
+		{Format: "regex"},
+		{Format: "regex", Regex: "^(.*)$"},
+		{Format: "regex", Regex: "^(?<level>[A-Z]+"},
+		{Format: "regex", Regex: " ^(?<level>[A-Z]+)$"},
+		{Format: "json", Regex: "^(?<level>[A-Z]+)$"},
+		{Format: "json", TimeKey: "time"},
+		{Format: "json", KeyName: "wrapped message"},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertParser(clusterLogParser("some-parser", spec))
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-name",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				Host:       "example.org",
+				Port:       12346,
+				ParserName: "some-parser",
+			},
+		})
+
+		if sc.String() != emptyConfig || sc.Parsers() != "" {
+			t.Errorf("Expected parser %+v to be rejected: Config: %q Parsers: %q", spec, sc.String(), sc.Parsers())
+		}
+	}
+}
+
+func TestParserController(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "http",
+			URI:        "https://logs.example.com/ingest",
+			ParserName: "some-parser",
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	c := sink.NewParserController(spyPatcher, spyReloader, sc)
+
+	p1 := clusterLogParser("some-parser", v1alpha1.ParserSpec{Format: "json"})
+	c.OnAdd(p1)
+	if conf := lastConfig(t, spyPatcher); conf == emptyConfig {
+		t.Errorf("Expected the sink once its parser exists")
+	}
+
+	// Resyncs of an unchanged parser leave fluent-bit alone.
+	c.OnUpdate(p1, clusterLogParser("some-parser", v1alpha1.ParserSpec{Format: "json"}))
+	if spyReloader.reloads != 1 {
+		t.Fatalf("Reloads not equal: Expected: 1, Actual: %d", spyReloader.reloads)
+	}
+
+	c.OnDelete(p1)
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+
+	// Parsers no sink references are not rendered.
+	c.OnAdd(clusterLogParser("other-parser", v1alpha1.ParserSpec{Format: "json"}))
+	c.OnDelete(clusterLogParser("other-parser", v1alpha1.ParserSpec{Format: "json"}))
+	c.OnAdd(&v1alpha1.ClusterLogSink{})
+	if spyReloader.reloads != 2 {
+		t.Errorf("Reloads not equal: Expected: 2, Actual: %d", spyReloader.reloads)
+	}
+}
+
+func clusterLogParser(name string, spec v1alpha1.ParserSpec) *v1alpha1.ClusterLogParser {
+	return &v1alpha1.ClusterLogParser{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: spec,
+	}
+}
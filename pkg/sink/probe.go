@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// Probe tells the kubelet whether the sink-controller is alive and ready.
+// It is ready once the informers it waits for synced and the last write of
+// the config succeeded. Until it waits for informers it is ready, a
+// standby only waits for the lease and has nothing to sync.
+type Probe struct {
+	sc *Config
+
+	mu     sync.Mutex
+	synced []cache.InformerSynced
+}
+
+// NewProbe returns a Probe reporting on the writes of the Config.
+func NewProbe(sc *Config) *Probe {
+	return &Probe{sc: sc}
+}
+
+// WaitFor gates the readiness on the informers having synced.
+func (p *Probe) WaitFor(synced ...cache.InformerSynced) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.synced = append(p.synced, synced...)
+}
+
+// Handler returns a handler serving the liveness at /healthz and the
+// readiness at /readyz. They answer 200 or, when not ready, 503 with the
+// reason.
+func (p *Probe) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeProbe(w, http.StatusOK, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if reason := p.notReady(); reason != "" {
+			writeProbe(w, http.StatusServiceUnavailable, reason)
+			return
+		}
+		writeProbe(w, http.StatusOK, "ok")
+	})
+	return mux
+}
+
+// notReady returns why the sink-controller is not ready or an empty string
+// if it is. Sinks rejected by the validator are reported in their status
+// and keep the previous config running, they do not fail the readiness or
+// a single broken sink would hold up every rollout.
+func (p *Probe) notReady() string {
+	p.mu.Lock()
+	synced := p.synced
+	p.mu.Unlock()
+	for _, s := range synced {
+		if !s() {
+			return "informer caches have not synced"
+		}
+	}
+	if err := p.sc.writeError(); err != nil {
+		return "last write of the fluent-bit config failed: " + err.Error()
+	}
+	return ""
+}
+
+func writeProbe(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := io.WriteString(w, msg+"\n"); err != nil {
+		log.Printf("unable to write probe: %s", err)
+	}
+}
+
+// writeError returns the error of the last write of the config, nil when
+// it succeeded or nothing was written yet.
+func (sc *Config) writeError() error {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	return sc.writeErr
+}
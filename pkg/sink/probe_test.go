@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestProbeHealthz(t *testing.T) {
+	p := sink.NewProbe(sink.NewConfig())
+	p.WaitFor(func() bool { return false })
+
+	// Liveness does not wait for the informers.
+	code, _ := probe(t, p, "/healthz")
+	if code != http.StatusOK {
+		t.Errorf("Expected /healthz to be OK before the informers synced, got %d", code)
+	}
+}
+
+func TestProbeReadyzUnsynced(t *testing.T) {
+	p := sink.NewProbe(sink.NewConfig())
+	synced := false
+	p.WaitFor(func() bool { return true }, func() bool { return synced })
+
+	code, body := probe(t, p, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to be unavailable before the informers synced, got %d", code)
+	}
+	if !strings.Contains(body, "informer caches have not synced") {
+		t.Errorf("Expected the reason in the body, got %q", body)
+	}
+
+	synced = true
+	if code, body := probe(t, p, "/readyz"); code != http.StatusOK {
+		t.Errorf("Expected /readyz to be OK once the informers synced, got %d: %s", code, body)
+	}
+}
+
+func TestProbeReadyzStandby(t *testing.T) {
+	p := sink.NewProbe(sink.NewConfig())
+
+	if code, body := probe(t, p, "/readyz"); code != http.StatusOK {
+		t.Errorf("Expected /readyz to be OK without informers to wait for, got %d: %s", code, body)
+	}
+}
+
+func TestProbeReadyzFailedWrite(t *testing.T) {
+	sc := sink.NewConfig()
+	patcher := &flakyConfigMapPatcher{errs: []error{errors.New("connection refused")}}
+	c := sink.NewController(patcher, &spyReloader{}, sc)
+	p := sink.NewProbe(sc)
+	p.WaitFor(func() bool { return true })
+
+	c.OnAdd(benchmarkSink(0))
+	code, body := probe(t, p, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to be unavailable after a failed write, got %d", code)
+	}
+	if !strings.Contains(body, "connection refused") {
+		t.Errorf("Expected the error in the body, got %q", body)
+	}
+
+	// The next write succeeds.
+	c.OnAdd(benchmarkSink(1))
+	if code, body := probe(t, p, "/readyz"); code != http.StatusOK {
+		t.Errorf("Expected /readyz to be OK after a successful write, got %d: %s", code, body)
+	}
+}
+
+func TestProbeReadyzRejectedSink(t *testing.T) {
+	sc := sink.NewConfig()
+	v := stubValidator(func(string) bool { return true })
+	c := sink.NewController(&flakyConfigMapPatcher{}, &spyReloader{}, sc, sink.WithValidator(v))
+	p := sink.NewProbe(sc)
+
+	// The sinks are rejected, the controller itself is fine.
+	c.OnAdd(benchmarkSink(0))
+	if code, body := probe(t, p, "/readyz"); code != http.StatusOK {
+		t.Errorf("Expected /readyz to be OK when the validator rejects the sinks, got %d: %s", code, body)
+	}
+}
+
+func probe(t *testing.T, p *sink.Probe, path string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code, rec.Body.String()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"log"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// QuiesceAnnotation set to true on a Node stops the fluent-bit pod running
+// there before the node is drained, so no logs are lost:
+//
+//	kubectl annotate node <node> observability.knative.dev/quiesce=true
+//
+// Removing it runs fluent-bit on the node again.
+const QuiesceAnnotation = "observability.knative.dev/quiesce"
+
+// QuiesceStatusAnnotation reports the progress of quiescing a Node, it is
+// Quiescing while a fluent-bit pod still runs there and Quiesced once
+// none does and the node can be drained.
+const QuiesceStatusAnnotation = "observability.knative.dev/quiesce-status"
+
+const (
+	QuiesceStatusQuiescing = "Quiescing"
+	QuiesceStatusQuiesced  = "Quiesced"
+)
+
+// QuiescedLabel keeps the fluent-bit DaemonSet off the Node, whose node
+// affinity excludes it. The DaemonSet controller deletes the pod running
+// there, on SIGTERM fluent-bit pauses its inputs and flushes the records it
+// buffered within its Grace period before it exits.
+const QuiescedLabel = "observability.knative.dev/fluent-bit-quiesced"
+
+type NodePatcher interface {
+	Patch(
+		name string,
+		pt types.PatchType,
+		data []byte,
+		subresources ...string,
+	) (*coreV1.Node, error)
+}
+
+// QuiesceController labels the Nodes with the QuiesceAnnotation and reports
+// whether fluent-bit stopped on them. Nodes are updated by their status
+// every few seconds, which is when a quiescing node is checked again.
+type QuiesceController struct {
+	nodes NodePatcher
+	pods  PodLister
+}
+
+func NewQuiesceController(nodes NodePatcher, pods PodLister) *QuiesceController {
+	return &QuiesceController{
+		nodes: nodes,
+		pods:  pods,
+	}
+}
+
+func (c *QuiesceController) OnAdd(o interface{}) {
+	n, ok := o.(*coreV1.Node)
+	if !ok {
+		return
+	}
+
+	if err := c.sync(n); err != nil {
+		log.Printf("unable to quiesce fluent-bit on node %s: %s", n.Name, err)
+	}
+}
+
+func (c *QuiesceController) OnUpdate(_, new interface{}) {
+	c.OnAdd(new)
+}
+
+func (c *QuiesceController) OnDelete(interface{}) {}
+
+func (c *QuiesceController) sync(n *coreV1.Node) error {
+	if n.Annotations[QuiesceAnnotation] != "true" {
+		_, labeled := n.Labels[QuiescedLabel]
+		_, reported := n.Annotations[QuiesceStatusAnnotation]
+		if !labeled && !reported {
+			return nil
+		}
+		return c.patch(n.Name, nil, nil)
+	}
+
+	running, err := c.running(n.Name)
+	if err != nil {
+		return err
+	}
+	status := QuiesceStatusQuiesced
+	if running {
+		status = QuiesceStatusQuiescing
+	}
+	if n.Labels[QuiescedLabel] == "true" && n.Annotations[QuiesceStatusAnnotation] == status {
+		return nil
+	}
+	return c.patch(n.Name, "true", status)
+}
+
+// running reports whether a fluent-bit pod runs on the node, pods being
+// deleted still do until fluent-bit exited.
+func (c *QuiesceController) running(node string) (bool, error) {
+	pods, err := c.pods.List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit-ds",
+		FieldSelector: "spec.nodeName=" + node,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pods.Items {
+		if p.Status.Phase != coreV1.PodSucceeded && p.Status.Phase != coreV1.PodFailed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// patch sets the QuiescedLabel and QuiesceStatusAnnotation of the node, nil
+// removes them.
+func (c *QuiesceController) patch(node string, label, status interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				QuiescedLabel: label,
+			},
+			"annotations": map[string]interface{}{
+				QuiesceStatusAnnotation: status,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.nodes.Patch(node, types.MergePatchType, data)
+	return err
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestQuiesceLabelsNodeWhileFluentBitRuns(t *testing.T) {
+	nodes := &spyNodePatcher{}
+	pods := &stubPodLister{
+		pods: []coreV1.Pod{runningPod("fluent-bit-1", "10.0.0.1")},
+	}
+	c := sink.NewQuiesceController(nodes, pods)
+
+	c.OnAdd(quiesceNode("node-1", map[string]string{sink.QuiesceAnnotation: "true"}, nil))
+
+	if pods.selector != "app=fluent-bit-ds" || pods.fieldSelector != "spec.nodeName=node-1" {
+		t.Errorf("Unexpected selectors: %s %s", pods.selector, pods.fieldSelector)
+	}
+	nodes.expectPatch(t, "node-1", "true", sink.QuiesceStatusQuiescing)
+}
+
+func TestQuiesceReportsQuiescedOnceFluentBitStopped(t *testing.T) {
+	for _, p := range [][]coreV1.Pod{
+		nil,
+		{{Status: coreV1.PodStatus{Phase: coreV1.PodSucceeded}}},
+	} {
+		nodes := &spyNodePatcher{}
+		c := sink.NewQuiesceController(nodes, &stubPodLister{pods: p})
+
+		c.OnUpdate(nil, quiesceNode(
+			"node-1",
+			map[string]string{
+				sink.QuiesceAnnotation:       "true",
+				sink.QuiesceStatusAnnotation: sink.QuiesceStatusQuiescing,
+			},
+			map[string]string{sink.QuiescedLabel: "true"},
+		))
+
+		nodes.expectPatch(t, "node-1", "true", sink.QuiesceStatusQuiesced)
+	}
+}
+
+func TestQuiesceDoesNotPatchUnchangedStatus(t *testing.T) {
+	nodes := &spyNodePatcher{}
+	c := sink.NewQuiesceController(nodes, &stubPodLister{})
+
+	c.OnUpdate(nil, quiesceNode(
+		"node-1",
+		map[string]string{
+			sink.QuiesceAnnotation:       "true",
+			sink.QuiesceStatusAnnotation: sink.QuiesceStatusQuiesced,
+		},
+		map[string]string{sink.QuiescedLabel: "true"},
+	))
+	c.OnUpdate(nil, quiesceNode("node-2", nil, nil))
+
+	if len(nodes.patches) != 0 {
+		t.Errorf("Expected no patches, got %d", len(nodes.patches))
+	}
+}
+
+func TestQuiesceResumesWhenAnnotationRemoved(t *testing.T) {
+	nodes := &spyNodePatcher{}
+	c := sink.NewQuiesceController(nodes, &stubPodLister{})
+
+	c.OnUpdate(nil, quiesceNode(
+		"node-1",
+		map[string]string{sink.QuiesceStatusAnnotation: sink.QuiesceStatusQuiesced},
+		map[string]string{sink.QuiescedLabel: "true"},
+	))
+
+	nodes.expectPatch(t, "node-1", nil, nil)
+}
+
+func TestQuiesceDoesNotLabelNodeWhenPodsCannotBeListed(t *testing.T) {
+	nodes := &spyNodePatcher{}
+	c := sink.NewQuiesceController(nodes, &failingPodLister{})
+
+	c.OnAdd(quiesceNode("node-1", map[string]string{sink.QuiesceAnnotation: "true"}, nil))
+
+	if len(nodes.patches) != 0 {
+		t.Errorf("Expected no patches, got %d", len(nodes.patches))
+	}
+}
+
+func TestFluentBitFlushesWithinTerminationGracePeriod(t *testing.T) {
+	var cm coreV1.ConfigMap
+	readManifest(t, "300-fluent-bit-config.yaml", &cm)
+	var ds appsV1.DaemonSet
+	readManifest(t, "500-fluent-bit-daemon.yaml", &ds)
+
+	grace := -1
+	for _, line := range strings.Split(cm.Data["fluent-bit.conf"], "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "Grace" {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil {
+				t.Fatalf("Grace is not a number of seconds: %s", fields[1])
+			}
+			grace = n
+		}
+	}
+	if grace < 0 {
+		t.Fatal("Expected the [SERVICE] section to set the Grace of fluent-bit")
+	}
+	period := ds.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if period == nil || int64(grace) >= *period {
+		t.Errorf("Expected the Grace of %ds to be below the terminationGracePeriodSeconds of the DaemonSet, got %v", grace, period)
+	}
+}
+
+// readManifest decodes the manifest in the file of the config directory.
+func readManifest(t *testing.T, file string, o interface{}) {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("..", "..", "config", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(b, o); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func quiesceNode(name string, annotations, labels map[string]string) *coreV1.Node {
+	return &coreV1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: annotations,
+			Labels:      labels,
+		},
+	}
+}
+
+type spyNodePatcher struct {
+	patches []patch
+}
+
+func (s *spyNodePatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*coreV1.Node, error) {
+	s.patches = append(s.patches, patch{
+		name: name,
+		pt:   pt,
+		data: data,
+	})
+	return nil, nil
+}
+
+// expectPatch checks the node was patched once with the label and status,
+// nil ones being removed.
+func (s *spyNodePatcher) expectPatch(t *testing.T, name string, label, status interface{}) {
+	t.Helper()
+	if len(s.patches) != 1 {
+		t.Fatalf("Expected the node to be patched once, got %d", len(s.patches))
+	}
+	p := s.patches[0]
+	if p.name != name || p.pt != types.MergePatchType {
+		t.Errorf("Unexpected patch of %s with type %s", p.name, p.pt)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(p.data, &actual); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      map[string]interface{}{sink.QuiescedLabel: label},
+			"annotations": map[string]interface{}{sink.QuiesceStatusAnnotation: status},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Patch not equal: Expected: %v Actual: %v", expected, actual)
+	}
+}
+
+type failingPodLister struct{}
+
+func (failingPodLister) List(metav1.ListOptions) (*coreV1.PodList, error) {
+	return nil, errors.New("unavailable")
+}
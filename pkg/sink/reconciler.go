@@ -0,0 +1,194 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Option configures a controller.
+type Option func(*reconciler)
+
+// WithWorkers has n workers write the config once the controller is Run
+// rather than writing it from the informer's event handlers. A change made
+// while a write is pending is included in that write, so a burst of changes
+// such as the initial list of a large cluster results in a few writes. Zero
+// writes from the event handlers.
+func WithWorkers(n int) Option {
+	return func(rc *reconciler) {
+		rc.workers = n
+	}
+}
+
+// WithTLSSecret has the controller write the client certificates of the
+// sinks with a TLSSecretRef to the fluent-bit-tls Secret before it writes
+// the config referencing them.
+func WithTLSSecret(sp SecretPatcher) Option {
+	return func(rc *reconciler) {
+		rc.sp = sp
+	}
+}
+
+// reconciler writes the rendered config to the fluent-bit ConfigMap and
+// reloads fluent-bit. Workers render concurrently but their writes are
+// serialized by the Config, which drops a render older than the one written
+// last.
+type reconciler struct {
+	cmp           ConfigMapPatcher
+	sp            SecretPatcher
+	ds            DaemonSetPatcher
+	baseMB        int
+	socketDS      DaemonSetPatcher
+	imageDS       DaemonSetPatcher
+	image         string
+	bufferMaxSize int
+	allowed       []string
+	denied        []string
+	r             Reloader
+	sc            *Config
+	workers       int
+	recorder      EventRecorder
+	validator     ConfigValidator
+	pending       chan struct{}
+	// backoff delays the retries of transiently failed writes up to
+	// maxDelay, retrying is set while one is scheduled. Both are guarded
+	// by the Config's writeMu.
+	backoff  *flowcontrol.Backoff
+	maxDelay time.Duration
+	retrying bool
+}
+
+func newReconciler(cmp ConfigMapPatcher, r Reloader, sc *Config, opts []Option) *reconciler {
+	rc := &reconciler{
+		cmp:     cmp,
+		r:       r,
+		sc:      sc,
+		pending: make(chan struct{}, 1),
+	}
+	for _, o := range opts {
+		o(rc)
+	}
+	return rc
+}
+
+// Run starts the workers and blocks until stopCh is closed and the writes
+// in flight finished. When a write was still pending once they finished it
+// is made before Run returns, changes a write in flight already rendered
+// are not written again.
+func (rc *reconciler) Run(stopCh <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i := 0; i < rc.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stopCh:
+					return
+				case <-rc.pending:
+					rc.write()
+				}
+			}
+		}()
+	}
+	<-stopCh
+	wg.Wait()
+	if rc.workers > 0 {
+		select {
+		case <-rc.pending:
+			rc.write()
+		default:
+		}
+	}
+}
+
+// reconcile writes the config after a change. With workers it only asks
+// for a write and returns, a write that is already pending renders the
+// change as well.
+func (rc *reconciler) reconcile() {
+	rc.sc.markChanged(time.Now())
+	if rc.workers <= 0 {
+		rc.write()
+		return
+	}
+	select {
+	case rc.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (rc *reconciler) write() {
+	start := time.Now()
+	gen, changed, r := rc.sc.changeRender()
+	if rc.sc.stale(gen) {
+		return
+	}
+	// Another write may start while this one validates, the newer
+	// generation is the one written.
+	rejected, err := rc.validate(r)
+
+	rc.sc.writeMu.Lock()
+	defer rc.sc.writeMu.Unlock()
+	if gen <= rc.sc.written {
+		return
+	}
+	last := rc.sc.written
+	rc.sc.written = gen
+	rc.sc.rejected = rejected
+	patches := configPatches(r)
+	if err != nil {
+		// fluent-bit keeps running the config written last. Its sinks are
+		// rejected until they change, so the write is not retried. The
+		// write that fixes them propagates the change.
+		log.Println(err.Error())
+		rc.sc.unwritten(changed)
+		recordApplyFailure(applyReasonValidation)
+		sinks, clusterSinks := rc.sc.counts()
+		recordReconcile(start, true, sinks, clusterSinks)
+	} else {
+		err = patchConfig(start, changed, patches, rc.cmp, certPatches(r), rc.sp, rc.r, rc.sc)
+		rc.sc.writeErr = err
+		switch {
+		case err == nil:
+			rc.sc.applied = patches
+			rc.resize(r)
+			rc.mountSockets(r)
+			rc.setImage()
+			rc.resetRetry()
+		case transient(err) && rc.backoff != nil:
+			// The retry renders the same generation again, its
+			// propagation is timed from the same change.
+			rc.sc.written = last
+			rc.sc.unwritten(changed)
+			rc.retry()
+		default:
+			rc.sc.unwritten(changed)
+		}
+	}
+	rc.warnLongLines(r)
+	rc.recordEvents(r, err)
+}
+
+// stale reports whether a generation as new as gen was written.
+func (sc *Config) stale(gen uint64) bool {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	return gen <= sc.written
+}
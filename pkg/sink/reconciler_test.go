@@ -0,0 +1,252 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestWorkers(t *testing.T) {
+	p := &slowConfigMapPatcher{delay: time.Millisecond}
+	sc := sink.NewConfig()
+	c := sink.NewController(p, &spyReloader{}, sc, sink.WithWorkers(4))
+	cc := sink.NewClusterController(p, &spyReloader{}, sc, sink.WithWorkers(4))
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, r := range []interface{ Run(<-chan struct{}) }{c, cc} {
+		wg.Add(1)
+		go func(r interface{ Run(<-chan struct{}) }) {
+			defer wg.Done()
+			r.Run(stop)
+		}(r)
+	}
+
+	// The informers of both kinds deliver their events concurrently.
+	var informers sync.WaitGroup
+	informers.Add(2)
+	go func() {
+		defer informers.Done()
+		for i := 0; i < 250; i++ {
+			c.OnAdd(benchmarkSink(i))
+		}
+		c.OnDelete(benchmarkSink(0))
+	}()
+	go func() {
+		defer informers.Done()
+		for i := 0; i < 50; i++ {
+			cc.OnAdd(&v1alpha1.ClusterLogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cluster-sink-%03d", i)},
+				Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+			})
+		}
+	}()
+	informers.Wait()
+
+	waitForConfig(t, p, sc.String())
+	if n := p.count(); n >= 300 {
+		t.Errorf("Expected the writes to be coalesced, got %d writes for 301 changes", n)
+	}
+
+	close(stop)
+	wg.Wait()
+}
+
+func TestWorkersNotRunning(t *testing.T) {
+	p := &slowConfigMapPatcher{}
+	c := sink.NewController(p, &spyReloader{}, sink.NewConfig(), sink.WithWorkers(1))
+
+	c.OnAdd(benchmarkSink(0))
+
+	if n := p.count(); n != 0 {
+		t.Errorf("Expected no writes before the workers run, got %d", n)
+	}
+}
+
+func TestWorkersShutdown(t *testing.T) {
+	p := &blockingConfigMapPatcher{
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	sc := sink.NewConfig()
+	c := sink.NewController(p, &spyReloader{}, sc, sink.WithWorkers(1))
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.Run(stop)
+		close(done)
+	}()
+
+	c.OnAdd(benchmarkSink(0))
+	<-p.started
+	// The second change waits for the write in flight.
+	c.OnAdd(benchmarkSink(1))
+	close(stop)
+
+	select {
+	case <-done:
+		t.Fatal("Expected Run to wait for the write in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(p.release)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for Run to return")
+	}
+
+	if n := len(p.patches); n != 2 {
+		t.Fatalf("Expected the pending change to be written, got %d writes", n)
+	}
+	var jp []jsonPatch
+	if err := json.Unmarshal(p.patches[1], &jp); err != nil {
+		t.Fatal(err)
+	}
+	if jp[0].Value != sc.String() {
+		t.Errorf("Expected the last write to hold both sinks, got %s", jp[0].Value)
+	}
+}
+
+func BenchmarkControllerWorkers(b *testing.B) {
+	const sinks = 500
+	for _, workers := range []int{0, 1, 4, 8} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				// The delay stands in for the round trip to the API
+				// server a real write makes.
+				p := &slowConfigMapPatcher{delay: time.Millisecond}
+				sc := sink.NewConfig()
+				c := sink.NewController(p, &spyReloader{}, sc, sink.WithWorkers(workers))
+				stop := make(chan struct{})
+				done := make(chan struct{})
+				go func() {
+					c.Run(stop)
+					close(done)
+				}()
+
+				for i := 0; i < sinks; i++ {
+					c.OnAdd(benchmarkSink(i))
+				}
+				waitForConfig(b, p, sc.String())
+
+				close(stop)
+				<-done
+			}
+		})
+	}
+}
+
+func benchmarkSink(i int) *v1alpha1.LogSink {
+	return &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sink-%03d", i),
+			Namespace: fmt.Sprintf("ns-%02d", i%50),
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "syslog",
+			Host:      "example.com",
+			Port:      514,
+			ParseJSON: i%2 == 0,
+			Labels:    map[string]string{"cluster": "some-cluster"},
+		},
+	}
+}
+
+// waitForConfig waits until the last write holds the config.
+func waitForConfig(t testing.TB, p *slowConfigMapPatcher, conf string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if data := p.last(); data != nil {
+			var jp []jsonPatch
+			if err := json.Unmarshal(data, &jp); err != nil {
+				t.Fatal(err)
+			}
+			if jp[0].Value == conf {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the config to be written")
+}
+
+// slowConfigMapPatcher is safe for concurrent use and takes delay for
+// every patch.
+type slowConfigMapPatcher struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	patches [][]byte
+}
+
+func (s *slowConfigMapPatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*coreV1.ConfigMap, error) {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patches = append(s.patches, data)
+	return nil, nil
+}
+
+func (s *slowConfigMapPatcher) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.patches)
+}
+
+func (s *slowConfigMapPatcher) last() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.patches) == 0 {
+		return nil
+	}
+	return s.patches[len(s.patches)-1]
+}
+
+// blockingConfigMapPatcher signals started when a patch begins and blocks
+// it until release is closed.
+type blockingConfigMapPatcher struct {
+	started chan struct{}
+	release chan struct{}
+
+	patches [][]byte
+}
+
+func (b *blockingConfigMapPatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*coreV1.ConfigMap, error) {
+	b.started <- struct{}{}
+	<-b.release
+	b.patches = append(b.patches, data)
+	return nil, nil
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// ValidateRedactRule returns why the rule cannot be rendered or nil if it
+// can.
+func ValidateRedactRule(r v1alpha1.RedactRule) error {
+	_, err := luaPattern(r.Pattern)
+	return err
+}
+
+// redactFilter returns a lua filter replacing the matches of every rule in
+// the log line of a record, one rule after the other. fluent-bit has no
+// filter replacing part of a field, so the patterns are translated to Lua
+// patterns for string.gsub.
+func redactFilter(rules []v1alpha1.RedactRule, m match) (section, error) {
+	var b strings.Builder
+	b.WriteString(`function redact(tag, timestamp, record) local log = record["log"] if type(log) ~= "string" then return 0, timestamp, record end`)
+	for _, r := range rules {
+		p, err := luaPattern(r.Pattern)
+		if err != nil {
+			return section{}, err
+		}
+		// % is the escape character of gsub replacements.
+		repl := strings.Replace(r.Replacement, "%", "%%", -1)
+		fmt.Fprintf(&b, " log = string.gsub(log, %s, %s)", luaString(p), luaString(repl))
+	}
+	b.WriteString(` record["log"] = log return 2, timestamp, record end`)
+
+	f := newFilter("lua", m)
+	f.add("call", "redact")
+	f.add("code", b.String())
+	return f, nil
+}
+
+// luaPattern translates a regular expression to a Lua pattern. Lua
+// patterns have no alternation and only repeat single characters, so
+// expressions using either are rejected, as are anchors other than at the
+// start and end and classes of non-ASCII characters.
+func luaPattern(pattern string) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("redact pattern must not be empty")
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("invalid redact pattern %q: %s", pattern, err)
+	}
+	// gsub would insert the replacement between every character.
+	if regexp.MustCompile(pattern).MatchString("") {
+		return "", fmt.Errorf("redact pattern %q must not match an empty string", pattern)
+	}
+	re = re.Simplify()
+
+	var subs []*syntax.Regexp
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	} else {
+		subs = []*syntax.Regexp{re}
+	}
+	var b strings.Builder
+	for i, sub := range subs {
+		switch {
+		case sub.Op == syntax.OpBeginText && i == 0:
+			b.WriteString("^")
+		case sub.Op == syntax.OpEndText && i == len(subs)-1:
+			b.WriteString("$")
+		default:
+			if err := writeLua(&b, sub); err != nil {
+				return "", fmt.Errorf("unsupported redact pattern %q: %s", pattern, err)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+func writeLua(b *strings.Builder, re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			item, err := luaLiteral(r, re.Flags&syntax.FoldCase != 0)
+			if err != nil {
+				return err
+			}
+			b.WriteString(item)
+		}
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		item, err := luaItem(re)
+		if err != nil {
+			return err
+		}
+		b.WriteString(item)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		item, err := luaItem(re.Sub[0])
+		if err != nil {
+			return err
+		}
+		greedy := re.Flags&syntax.NonGreedy == 0
+		switch {
+		case re.Op == syntax.OpStar && greedy:
+			b.WriteString(item + "*")
+		case re.Op == syntax.OpStar:
+			b.WriteString(item + "-")
+		case re.Op == syntax.OpPlus && greedy:
+			b.WriteString(item + "+")
+		case re.Op == syntax.OpPlus:
+			b.WriteString(item + item + "-")
+		case greedy:
+			b.WriteString(item + "?")
+		default:
+			return fmt.Errorf("lazy ? is not supported")
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := writeLua(b, sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpCapture:
+		// The replacement is literal, so groups only group.
+		return writeLua(b, re.Sub[0])
+	case syntax.OpAlternate:
+		return fmt.Errorf("alternation is not supported")
+	case syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine:
+		return fmt.Errorf("anchors are only supported at the start and end")
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return fmt.Errorf("word boundaries are not supported")
+	default:
+		return fmt.Errorf("%s can only repeat single characters", re)
+	}
+	return nil
+}
+
+// luaItem translates an expression matching a single character.
+func luaItem(re *syntax.Regexp) (string, error) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		// Lua would only repeat the last byte of a multi-byte character.
+		if len(re.Rune) == 1 && re.Rune[0] > unicode.MaxASCII {
+			return "", fmt.Errorf("repeating non-ASCII characters is not supported")
+		}
+		if len(re.Rune) == 1 {
+			return luaLiteral(re.Rune[0], re.Flags&syntax.FoldCase != 0)
+		}
+	case syntax.OpAnyChar:
+		return ".", nil
+	case syntax.OpAnyCharNotNL:
+		return "[^\n]", nil
+	case syntax.OpCharClass:
+		return luaClass(re.Rune)
+	case syntax.OpCapture:
+		return luaItem(re.Sub[0])
+	}
+	return "", fmt.Errorf("%s can only repeat single characters", re)
+}
+
+func luaLiteral(r rune, foldCase bool) (string, error) {
+	if r > unicode.MaxASCII {
+		return string(r), nil
+	}
+	if foldCase && unicode.IsLetter(r) {
+		return fmt.Sprintf("[%c%c]", unicode.ToLower(r), unicode.ToUpper(r)), nil
+	}
+	if strings.ContainsRune("^$()%.[]*+-?", r) {
+		return "%" + string(r), nil
+	}
+	return string(r), nil
+}
+
+// luaClass translates the ranges of a character class to a Lua set. A
+// class of every character but some ASCII ones is a negated set.
+func luaClass(ranges []rune) (string, error) {
+	negated := false
+	if len(ranges) > 0 && ranges[len(ranges)-1] == unicode.MaxRune {
+		negated = true
+		ranges = complement(ranges)
+	}
+	var b strings.Builder
+	b.WriteString("[")
+	if negated {
+		b.WriteString("^")
+	}
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if hi > unicode.MaxASCII {
+			return "", fmt.Errorf("classes of non-ASCII characters are not supported")
+		}
+		b.WriteString(luaClassRange(lo, hi))
+	}
+	b.WriteString("]")
+	return b.String(), nil
+}
+
+// luaClassRange translates a range of a class. Lua does not read an escaped
+// character as the end of a range, so those are written on their own.
+func luaClassRange(lo, hi rune) string {
+	special := func(r rune) bool { return strings.ContainsRune("^]-%", r) }
+	var head, tail string
+	for lo <= hi && special(lo) {
+		head += "%" + string(lo)
+		lo++
+	}
+	for hi >= lo && special(hi) {
+		tail = "%" + string(hi) + tail
+		hi--
+	}
+	switch {
+	case lo < hi:
+		return head + string(lo) + "-" + string(hi) + tail
+	case lo == hi:
+		return head + string(lo) + tail
+	}
+	return head + tail
+}
+
+// complement returns the ranges of the characters missing from the sorted
+// ranges.
+func complement(ranges []rune) []rune {
+	var out []rune
+	next := rune(0)
+	for i := 0; i < len(ranges); i += 2 {
+		if ranges[i] > next {
+			out = append(out, next, ranges[i]-1)
+		}
+		next = ranges[i+1] + 1
+	}
+	if next <= unicode.MaxRune {
+		out = append(out, next, unicode.MaxRune)
+	}
+	return out
+}
+
+// luaString quotes s as a Lua string literal on a single line.
+func luaString(s string) string {
+	var b strings.Builder
+	b.WriteString(`"`)
+	for _, c := range []byte(s) {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteString(`\` + string(c))
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, `\%03d`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString(`"`)
+	return b.String()
+}
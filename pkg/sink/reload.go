@@ -0,0 +1,139 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RestartedAtAnnotation is set on the fluent-bit pod template to roll the
+// DaemonSet when its pods cannot reload their config.
+const RestartedAtAnnotation = "observability.knative.dev/restartedAt"
+
+type Reloader interface {
+	// Reload makes the fluent-bit pods pick up the patched ConfigMap.
+	Reload() error
+}
+
+type DaemonSetPatcher interface {
+	Patch(
+		name string,
+		pt types.PatchType,
+		data []byte,
+		subresources ...string,
+	) (*extensionsV1beta1.DaemonSet, error)
+}
+
+type fluentBitReloader struct {
+	pods   PodLister
+	ds     DaemonSetPatcher
+	port   int
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewFluentBitReloader returns a Reloader that leaves fluent-bit pods
+// supporting hot reload running and rolls the DaemonSet otherwise. The
+// config-reloader container next to fluent-bit triggers the reload once the
+// kubelet updated the mounted ConfigMap, which the sink-controller cannot
+// observe itself.
+func NewFluentBitReloader(pods PodLister, ds DaemonSetPatcher, port int) Reloader {
+	return &fluentBitReloader{
+		pods: pods,
+		ds:   ds,
+		port: port,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		now: time.Now,
+	}
+}
+
+func (r *fluentBitReloader) Reload() error {
+	pods, err := r.pods.List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit-ds",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pods.Items {
+		if p.Status.Phase != coreV1.PodRunning || p.Status.PodIP == "" {
+			continue
+		}
+		ok, err := r.hotReload(p.Status.PodIP)
+		if err != nil {
+			log.Printf("unable to check hot reload of fluent-bit pod %s: %s", p.Name, err)
+			continue
+		}
+		if !ok {
+			log.Printf("fluent-bit pod %s does not support hot reload, restarting %s", p.Name, DaemonSetName)
+			return r.restart()
+		}
+	}
+	return nil
+}
+
+// hotReload reports whether the fluent-bit pod serves the reload endpoint.
+// Releases without hot reload, or with it disabled, answer it with a 404.
+func (r *fluentBitReloader) hotReload(ip string) (bool, error) {
+	u := fmt.Sprintf("http://%s/api/v2/reload", net.JoinHostPort(ip, strconv.Itoa(r.port)))
+	resp, err := r.client.Get(u)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// restart rolls the DaemonSet by changing its pod template so pods are
+// replaced one at a time following its update strategy.
+func (r *fluentBitReloader) restart() error {
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						RestartedAtAnnotation: r.now().UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.ds.Patch(DaemonSetName, types.StrategicMergePatchType, data)
+	return err
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestConfigChangeReloadsWithoutRestart(t *testing.T) {
+	server, host, port := fluentBitServer(t, http.StatusOK)
+	defer server.Close()
+	pods := &stubPodLister{
+		pods: []coreV1.Pod{
+			runningPod("fluent-bit-1", host),
+			runningPod("fluent-bit-2", host),
+		},
+	}
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewController(
+		spyPatcher,
+		sink.NewFluentBitReloader(pods, spyDaemonSet, port),
+		sink.NewConfig(),
+	)
+
+	s1 := &v1alpha1.LogSink{
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	}
+	s2 := &v1alpha1.LogSink{
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12346},
+	}
+	c.OnAdd(s1)
+	c.OnUpdate(s1, s2)
+
+	if len(spyPatcher.patches) != 2 {
+		t.Errorf("Expected the config map to be patched twice, got %d", len(spyPatcher.patches))
+	}
+	if pods.selector != "app=fluent-bit-ds" {
+		t.Errorf("Unexpected selector: %s", pods.selector)
+	}
+	if len(spyDaemonSet.patches) != 0 {
+		t.Errorf("Expected the DaemonSet to not be restarted, got %d patches", len(spyDaemonSet.patches))
+	}
+}
+
+func TestReloadUnsupportedRestartsDaemonSet(t *testing.T) {
+	server, host, port := fluentBitServer(t, http.StatusNotFound)
+	defer server.Close()
+	pods := &stubPodLister{
+		pods: []coreV1.Pod{
+			runningPod("fluent-bit-1", host),
+			runningPod("fluent-bit-2", host),
+		},
+	}
+	spyDaemonSet := &spyDaemonSetPatcher{}
+
+	err := sink.NewFluentBitReloader(pods, spyDaemonSet, port).Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spyDaemonSet.patches) != 1 {
+		t.Fatalf("Expected the DaemonSet to be restarted once, got %d patches", len(spyDaemonSet.patches))
+	}
+	p := spyDaemonSet.patches[0]
+	if p.name != sink.DaemonSetName {
+		t.Errorf("DaemonSet name does not equal Got: %s, Expected %s", p.name, sink.DaemonSetName)
+	}
+	if p.pt != types.StrategicMergePatchType {
+		t.Errorf("Patch Type does not equal Got: %s, Expected %s", p.pt, types.StrategicMergePatchType)
+	}
+	var ds extensionsV1beta1.DaemonSet
+	err = json.Unmarshal(p.data, &ds)
+	if err != nil {
+		t.Fatalf("Could not Unmarshal patch: %s", err)
+	}
+	if ds.Spec.Template.Annotations[sink.RestartedAtAnnotation] == "" {
+		t.Errorf("Expected the pod template to be annotated: %s", p.data)
+	}
+}
+
+func TestReloadSkipsUnreachablePods(t *testing.T) {
+	pods := &stubPodLister{
+		pods: []coreV1.Pod{
+			runningPod("fluent-bit-1", "127.0.0.1"),
+		},
+	}
+	spyDaemonSet := &spyDaemonSetPatcher{}
+
+	// Nothing listens on port 1, the pod could be starting up.
+	err := sink.NewFluentBitReloader(pods, spyDaemonSet, 1).Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spyDaemonSet.patches) != 0 {
+		t.Errorf("Expected the DaemonSet to not be restarted, got %d patches", len(spyDaemonSet.patches))
+	}
+}
+
+func fluentBitServer(t *testing.T, reloadStatus int) (*httptest.Server, string, int) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/reload" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(reloadStatus)
+	}))
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, host, p
+}
+
+type spyDaemonSetPatcher struct {
+	patches []patch
+}
+
+func (s *spyDaemonSetPatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*extensionsV1beta1.DaemonSet, error) {
+	s.patches = append(s.patches, patch{
+		name: name,
+		pt:   pt,
+		data: data,
+	})
+	return nil, nil
+}
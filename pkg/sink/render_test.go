@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func TestRenderConfig(t *testing.T) {
+	var tests = []struct {
+		name         string
+		sinks        []v1alpha1.LogSink
+		clusterSinks []v1alpha1.ClusterLogSink
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "syslog",
+			sinks: []v1alpha1.LogSink{
+				logSink("ns2", "sink-b", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+				logSink("ns1", "sink-a", v1alpha1.SinkSpec{Type: "syslog", Host: "example.org", Port: 6514, EnableTLS: true}),
+				logSink("", "sink-c", v1alpha1.SinkSpec{Type: "syslog", Host: "example.net", Port: 514, Protocol: "udp"}),
+			},
+			clusterSinks: []v1alpha1.ClusterLogSink{
+				clusterLogSink("cluster-b", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 1514}),
+				clusterLogSink("cluster-a", v1alpha1.SinkSpec{Type: "syslog", Host: "example.org", Port: 1514}),
+			},
+		},
+		{
+			name: "mixed",
+			sinks: []v1alpha1.LogSink{
+				logSink("ns1", "syslog", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+				logSink("ns1", "http", v1alpha1.SinkSpec{
+					Type:    "http",
+					URI:     "https://example.com/logs",
+					Headers: map[string]string{"Authorization": "Bearer token"},
+				}),
+				logSink("ns2", "web", v1alpha1.SinkSpec{
+					Type: "syslog",
+					Host: "example.org",
+					Port: 514,
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"tier": "web"},
+					},
+					BufferSizeMB: 10,
+				}),
+			},
+			clusterSinks: []v1alpha1.ClusterLogSink{
+				clusterLogSink("cluster", v1alpha1.SinkSpec{Type: "http", URI: "http://example.net", RetryLimit: -1}),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := sink.RenderConfig(test.sinks, test.clusterSinks)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", test.name+".golden")
+			if *update {
+				err = ioutil.WriteFile(golden, []byte(conf), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if conf != string(expected) {
+				t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+			}
+
+			reversed, err := sink.RenderConfig(reverseSinks(test.sinks), reverseClusterSinks(test.clusterSinks))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reversed != conf {
+				t.Errorf("Config depends on sink order: Expected: %q Actual: %q", conf, reversed)
+			}
+		})
+	}
+}
+
+func TestRenderConfigInvalidSink(t *testing.T) {
+	conf, err := sink.RenderConfig(
+		[]v1alpha1.LogSink{
+			logSink("ns1", "valid", v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}),
+			logSink("ns1", "invalid", v1alpha1.SinkSpec{Type: "http", URI: "ftp://example.com"}),
+		},
+		nil,
+	)
+	if err == nil {
+		t.Fatal("Expected an error for the invalid sink")
+	}
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks [{\"addr\":\"example.com:514\",\"namespace\":\"ns1\"}]\n    ClusterSinks []\n"
+	if conf != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+	}
+}
+
+func logSink(ns, name string, spec v1alpha1.SinkSpec) v1alpha1.LogSink {
+	return v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Spec: spec,
+	}
+}
+
+func clusterLogSink(name string, spec v1alpha1.SinkSpec) v1alpha1.ClusterLogSink {
+	return v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: spec,
+	}
+}
+
+func reverseSinks(s []v1alpha1.LogSink) []v1alpha1.LogSink {
+	r := make([]v1alpha1.LogSink, 0, len(s))
+	for i := len(s) - 1; i >= 0; i-- {
+		r = append(r, s[i])
+	}
+	return r
+}
+
+func reverseClusterSinks(s []v1alpha1.ClusterLogSink) []v1alpha1.ClusterLogSink {
+	r := make([]v1alpha1.ClusterLogSink, 0, len(s))
+	for i := len(s) - 1; i >= 0; i-- {
+		r = append(r, s[i])
+	}
+	return r
+}
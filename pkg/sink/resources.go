@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fluentBitContainer is the container of the fluent-bit DaemonSet whose
+// memory is sized to the buffers of the sinks.
+const fluentBitContainer = "fluent-bit"
+
+// emitterBufferMB is the memory limit fluent-bit gives the emitter of a
+// stream without an Emitter_Mem_Buf_Limit.
+const emitterBufferMB = 10
+
+// WithResources has the controller set the memory request and limit of the
+// fluent-bit container to baseMB along with the buffers of the sinks'
+// streams. The DaemonSet is only patched when the total changes since
+// changing its pod template rolls the fluent-bit pods.
+func WithResources(ds DaemonSetPatcher, baseMB int) Option {
+	return func(rc *reconciler) {
+		rc.ds = ds
+		rc.baseMB = baseMB
+	}
+}
+
+// bufferMB returns the memory the sink's stream may hold. Streams buffered
+// on the filesystem keep the chunks they write and the ones their outputs
+// read back up in memory, which is twice the limit.
+func (e entry) bufferMB() int {
+	mb := e.spec.BufferSizeMB
+	if mb == 0 {
+		mb = emitterBufferMB
+	}
+	if e.spec.BufferType == v1alpha1.BufferTypeFilesystem {
+		return 2 * mb
+	}
+	return mb
+}
+
+// resize patches the memory of the fluent-bit container when the buffers of
+// the rendered sinks changed it. It is called with the Config's writeMu
+// held. A failed patch is retried with the next write.
+func (rc *reconciler) resize(r rendered) {
+	if rc.ds == nil {
+		return
+	}
+	mb := rc.baseMB + r.bufferMB
+	if mb == rc.sc.resized {
+		return
+	}
+
+	memory := fmt.Sprintf("%dMi", mb)
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{
+							"name": fluentBitContainer,
+							"resources": map[string]interface{}{
+								"requests": map[coreV1.ResourceName]string{coreV1.ResourceMemory: memory},
+								"limits":   map[coreV1.ResourceName]string{coreV1.ResourceMemory: memory},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	if err == nil {
+		_, err = rc.ds.Patch(DaemonSetName, types.StrategicMergePatchType, data)
+	}
+	if err != nil {
+		log.Printf("unable to set the memory of %s to %s: %s", DaemonSetName, memory, err)
+		return
+	}
+	rc.sc.resized = mb
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestMemoryScalesWithFilesystemBufferedSinks(t *testing.T) {
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		sink.NewConfig(),
+		sink.WithResources(spyDaemonSet, 100),
+	)
+
+	for i := 1; i <= 3; i++ {
+		c.OnAdd(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("sink-%d", i),
+				Namespace: "some-namespace",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         12345,
+				BufferType:   "filesystem",
+				BufferSizeMB: 50,
+			},
+		})
+
+		expected := fmt.Sprintf("%dMi", 100+i*100)
+		if memory := lastMemory(t, spyDaemonSet); memory != expected {
+			t.Errorf("Memory not equal with %d sinks: Expected: %s Actual: %s", i, expected, memory)
+		}
+	}
+	if len(spyDaemonSet.patches) != 3 {
+		t.Errorf("Expected 3 patches, got %d", len(spyDaemonSet.patches))
+	}
+}
+
+func TestMemoryOfMemoryBufferedSinks(t *testing.T) {
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewClusterController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		sink.NewConfig(),
+		sink.WithResources(spyDaemonSet, 100),
+	)
+
+	// Sinks in the shared stream do not add to the memory.
+	c.OnAdd(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+	if memory := lastMemory(t, spyDaemonSet); memory != "100Mi" {
+		t.Errorf("Memory not equal: Expected: 100Mi Actual: %s", memory)
+	}
+	c.OnAdd(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-shared"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12346},
+	})
+	if len(spyDaemonSet.patches) != 1 {
+		t.Fatalf("Expected the unchanged memory to not be patched, got %d patches", len(spyDaemonSet.patches))
+	}
+
+	// The emitter of a stream without a buffer size holds up to 10MB.
+	c.OnAdd(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "streamed"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12347, BufferType: "memory"},
+	})
+	if memory := lastMemory(t, spyDaemonSet); memory != "110Mi" {
+		t.Errorf("Memory not equal: Expected: 110Mi Actual: %s", memory)
+	}
+}
+
+func TestSizedMemoryIsNotDrift(t *testing.T) {
+	sc := sink.NewConfig()
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	sink.NewController(spyPatcher, &spyReloader{}, sc, sink.WithResources(spyDaemonSet, 100)).OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+	c := sink.NewDriftController(spyPatcher, &spyReloader{}, sc, sink.WithResources(spyDaemonSet, 100))
+
+	ds := fluentBitDaemonSet("oratos/fluent-bit-out-syslog:v0.9")
+	sized := ds.DeepCopy()
+	sized.Spec.Template.Spec.Containers[0].Resources = coreV1.ResourceRequirements{
+		Requests: coreV1.ResourceList{coreV1.ResourceMemory: resource.MustParse("100Mi")},
+		Limits:   coreV1.ResourceList{coreV1.ResourceMemory: resource.MustParse("100Mi")},
+	}
+	c.OnUpdate(ds, sized)
+	if len(spyPatcher.patches) != 1 {
+		t.Errorf("Expected 1 patch, got %d", len(spyPatcher.patches))
+	}
+}
+
+func lastMemory(t *testing.T, spy *spyDaemonSetPatcher) string {
+	t.Helper()
+	if len(spy.patches) == 0 {
+		t.Fatalf("Expected a patch")
+	}
+	p := spy.patches[len(spy.patches)-1]
+	if p.name != sink.DaemonSetName || p.pt != types.StrategicMergePatchType {
+		t.Fatalf("Unexpected patch of %s with %s", p.name, p.pt)
+	}
+	var ds extensionsV1beta1.DaemonSet
+	err := json.Unmarshal(p.data, &ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := ds.Spec.Template.Spec.Containers
+	if len(containers) != 1 || containers[0].Name != "fluent-bit" {
+		t.Fatalf("Expected the fluent-bit container to be patched: %s", p.data)
+	}
+	requests, limits := containers[0].Resources.Requests, containers[0].Resources.Limits
+	if requests.Memory().Cmp(*limits.Memory()) != 0 {
+		t.Errorf("Expected the memory request and limit to be equal: %s", p.data)
+	}
+	return requests.Memory().String()
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"log"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// retryBaseDelay is the delay before the first retry of a write, it doubles
+// with every failure that follows.
+const retryBaseDelay = 500 * time.Millisecond
+
+// retryJitter is the largest fraction of the delay added to it, so the
+// controllers sharing a Config do not retry in lockstep.
+const retryJitter = 0.2
+
+// retryKey is the key of the config's writes in the backoff.
+const retryKey = ConfigMapName
+
+// WithRetryBackoff has the controller retry a write of the config that
+// failed transiently, e.g. while the API server is briefly unavailable.
+// The delay grows exponentially with jitter up to max and is reset by the
+// next write that succeeds. Writes rejected by the validator or by the API
+// server as invalid or forbidden fail the same way again and are not
+// retried, the validator's rejections are reported in the status of the
+// sinks. Without it a failed write waits for the next change.
+func WithRetryBackoff(max time.Duration) Option {
+	return func(rc *reconciler) {
+		base := retryBaseDelay
+		if max < base {
+			base = max
+		}
+		rc.backoff = flowcontrol.NewBackOff(base, max)
+		rc.maxDelay = max
+	}
+}
+
+// transient reports whether a failed write of the config may succeed when
+// it is retried.
+func transient(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case apierrors.IsInvalid(err),
+		apierrors.IsBadRequest(err),
+		apierrors.IsForbidden(err),
+		apierrors.IsUnauthorized(err),
+		apierrors.IsMethodNotSupported(err):
+		return false
+	}
+	return true
+}
+
+// retry schedules another write after a transient failure, unless one is
+// scheduled already. It is called with the Config's writeMu held.
+func (rc *reconciler) retry() {
+	if rc.backoff == nil || rc.retrying {
+		return
+	}
+	rc.backoff.Next(retryKey, rc.backoff.Clock.Now())
+	delay := wait.Jitter(rc.backoff.Get(retryKey), retryJitter)
+	if delay > rc.maxDelay {
+		delay = rc.maxDelay
+	}
+	rc.retrying = true
+	log.Printf("retrying the write of the fluent-bit config in %s", delay)
+	time.AfterFunc(delay, func() {
+		rc.sc.writeMu.Lock()
+		rc.retrying = false
+		rc.sc.writeMu.Unlock()
+		rc.reconcile()
+	})
+}
+
+// resetRetry resets the backoff after a write succeeded. It is called with
+// the Config's writeMu held.
+func (rc *reconciler) resetRetry() {
+	if rc.backoff != nil {
+		rc.backoff.Reset(retryKey)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestRetryTransientFailure(t *testing.T) {
+	p := &flakyConfigMapPatcher{errs: []error{
+		apierrors.NewServiceUnavailable("etcd is unavailable"),
+		errors.New("connection refused"),
+	}}
+	sc := sink.NewConfig()
+	c := sink.NewController(p, &spyReloader{}, sc, sink.WithRetryBackoff(5*time.Millisecond))
+
+	c.OnAdd(benchmarkSink(0))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// Waiting a while longer shows the successful write is not retried.
+	time.Sleep(50 * time.Millisecond)
+	if n := p.count(); n != 3 {
+		t.Errorf("Expected two failed writes and a successful one, got %d writes", n)
+	}
+}
+
+func TestPermanentValidationErrorIsNotRetried(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		validations int
+	)
+	v := stubValidator(func(string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		validations++
+		return true
+	})
+	p := &flakyConfigMapPatcher{}
+	c := sink.NewController(
+		p,
+		&spyReloader{},
+		sink.NewConfig(),
+		sink.WithValidator(v),
+		sink.WithRetryBackoff(time.Millisecond),
+	)
+
+	c.OnAdd(benchmarkSink(0))
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The config and then the only sink on its own are validated once.
+	if validations != 2 {
+		t.Errorf("Expected the config to be validated once, got %d validations", validations)
+	}
+	if n := p.count(); n != 0 {
+		t.Errorf("Expected the rejected config to not be written, got %d writes", n)
+	}
+}
+
+func TestInvalidPatchIsNotRetried(t *testing.T) {
+	p := &flakyConfigMapPatcher{errs: []error{
+		apierrors.NewInvalid(
+			schema.GroupKind{Kind: "ConfigMap"},
+			sink.ConfigMapName,
+			field.ErrorList{field.TooLong(field.NewPath("data"), "", 1<<20)},
+		),
+	}}
+	c := sink.NewController(p, &spyReloader{}, sink.NewConfig(), sink.WithRetryBackoff(time.Millisecond))
+
+	c.OnAdd(benchmarkSink(0))
+	time.Sleep(50 * time.Millisecond)
+
+	if n := p.count(); n != 1 {
+		t.Errorf("Expected the invalid write to not be retried, got %d writes", n)
+	}
+}
+
+func TestNoRetryWithoutBackoff(t *testing.T) {
+	p := &flakyConfigMapPatcher{errs: []error{errors.New("connection refused")}}
+	c := sink.NewController(p, &spyReloader{}, sink.NewConfig())
+
+	c.OnAdd(benchmarkSink(0))
+	time.Sleep(50 * time.Millisecond)
+
+	if n := p.count(); n != 1 {
+		t.Errorf("Expected the failed write to wait for the next change, got %d writes", n)
+	}
+}
+
+// flakyConfigMapPatcher is safe for concurrent use and fails its patches
+// with errs in order before the following ones succeed.
+type flakyConfigMapPatcher struct {
+	mu      sync.Mutex
+	errs    []error
+	patches int
+}
+
+func (f *flakyConfigMapPatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*coreV1.ConfigMap, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.patches++
+	if len(f.errs) != 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (f *flakyConfigMapPatcher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.patches
+}
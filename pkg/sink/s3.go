@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// awsCredentialsFile is the shared credentials file of the s3 sinks in the
+// fluent-bit-tls Secret. The fluent-bit DaemonSet points
+// AWS_SHARED_CREDENTIALS_FILE at it.
+const awsCredentialsFile = "aws-credentials"
+
+// s3MinChunkMB is the smallest part of a multipart upload, smaller objects
+// are uploaded whole.
+const s3MinChunkMB = 5
+
+var (
+	s3BucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+	s3Region     = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]*)?-[a-z]+-[0-9]+$`)
+)
+
+// s3Output returns an s3 output archiving the records to the bucket. Sinks
+// with credentials use the profile of their access key in the shared
+// credentials file, the others the default credential chain that picks up
+// the IAM role of the service account.
+func s3Output(spec v1alpha1.SinkSpec, profile string, m match) (section, error) {
+	if err := ValidateBucket(spec.Bucket); err != nil {
+		return section{}, err
+	}
+	if err := ValidateRegion(spec.Region); err != nil {
+		return section{}, err
+	}
+	if err := ValidateTotalFileSize(spec.TotalFileSizeMB); err != nil {
+		return section{}, err
+	}
+	if spec.UploadTimeoutSeconds < 0 {
+		return section{}, fmt.Errorf("upload timeout must not be negative")
+	}
+
+	o := newOutput("s3", m)
+	o.add("bucket", spec.Bucket)
+	o.add("region", spec.Region)
+	if spec.TotalFileSizeMB != 0 {
+		o.add("total_file_size", fmt.Sprintf("%dM", spec.TotalFileSizeMB))
+		if spec.TotalFileSizeMB < s3MinChunkMB {
+			o.add("use_put_object", "On")
+		}
+	}
+	if spec.UploadTimeoutSeconds != 0 {
+		o.add("upload_timeout", fmt.Sprintf("%ds", spec.UploadTimeoutSeconds))
+	}
+	if auth, ok := spec.Headers["Authorization"]; ok {
+		if _, _, err := awsAccessKey(auth); err != nil {
+			return section{}, err
+		}
+		o.add("profile", profile)
+	}
+	return o, nil
+}
+
+// ValidateBucket returns why the name is not an S3 bucket name or nil if
+// it is.
+func ValidateBucket(bucket string) error {
+	switch {
+	case bucket == "":
+		return fmt.Errorf("bucket must not be empty")
+	case strings.Contains(bucket, "${"):
+		return fmt.Errorf("bucket %q cannot be a template, an s3 sink archives all of its records to one bucket", bucket)
+	case len(bucket) < 3 || len(bucket) > 63:
+		return fmt.Errorf("bucket %q must be 3 to 63 characters long", bucket)
+	case !s3BucketName.MatchString(bucket):
+		return fmt.Errorf("bucket %q may only contain lowercase alphanumerics, dots and hyphens and must start and end with an alphanumeric", bucket)
+	case strings.Contains(bucket, ".."):
+		return fmt.Errorf("bucket %q must not contain adjacent dots", bucket)
+	case net.ParseIP(bucket) != nil:
+		return fmt.Errorf("bucket %q must not be an IP address", bucket)
+	case strings.HasPrefix(bucket, "xn--") || strings.HasSuffix(bucket, "-s3alias"):
+		return fmt.Errorf("bucket %q uses a reserved prefix or suffix", bucket)
+	}
+	return nil
+}
+
+// ValidateRegion returns why the region is not an AWS region or nil if it
+// is.
+func ValidateRegion(region string) error {
+	switch {
+	case region == "":
+		return fmt.Errorf("region must not be empty")
+	case !s3Region.MatchString(region):
+		return fmt.Errorf("region %q is not an AWS region such as us-east-1", region)
+	}
+	return nil
+}
+
+// ValidateTotalFileSize returns why objects cannot roll over at the size or
+// nil if they can. Zero keeps fluent-bit's default and S3 takes up to 50G.
+func ValidateTotalFileSize(mb int) error {
+	if mb < 0 || mb > 50*1024 {
+		return fmt.Errorf("total file size must be between 1 and 51200 MB")
+	}
+	return nil
+}
+
+// S3Endpoint returns the address of the S3 API in the region.
+func S3Endpoint(region string) string {
+	host := fmt.Sprintf("s3.%s.amazonaws.com", region)
+	if strings.HasPrefix(region, "cn-") {
+		host += ".cn"
+	}
+	return HostPort(host, 443)
+}
+
+// awsAccessKey returns the access key id and secret access key of the basic
+// Authorization header withCredentials sets from the SecretRef.
+func awsAccessKey(header string) (string, string, error) {
+	id, secret, err := basicAuth(header)
+	if err != nil || secret == "" {
+		return "", "", fmt.Errorf("credentials are not of the form access_key_id:secret_access_key")
+	}
+	return id, secret, nil
+}
+
+// addProfile adds the credentials of an s3 destination to the profiles
+// under the sink's tag, which the destination's output names.
+func addProfile(profiles map[string]string, e entry, d v1alpha1.SinkSpec) {
+	if auth, ok := d.Headers["Authorization"]; ok && d.Type == v1alpha1.SinkTypeS3 {
+		profiles[e.tag()] = auth
+	}
+}
+
+// awsCredentials returns the shared credentials file holding a profile for
+// every sink in the headers, which are keyed by the profile names.
+func awsCredentials(headers map[string]string) []byte {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		id, secret, err := awsAccessKey(headers[name])
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]\naws_access_key_id = %s\naws_secret_access_key = %s\n", name, id, secret)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// SecretController keeps the Secrets in the config so the tokens of sinks
+// with a SecretRef can be rendered, and re-renders the config when a
+// referenced Secret is rotated.
+type SecretController struct {
+	*reconciler
+}
+
+func NewSecretController(cmp ConfigMapPatcher, r Reloader, sc *Config, opts ...Option) *SecretController {
+	return &SecretController{
+		reconciler: newReconciler(cmp, r, sc, opts),
+	}
+}
+
+func (c *SecretController) OnAdd(o interface{}) {
+	s, ok := o.(*coreV1.Secret)
+	if !ok {
+		return
+	}
+
+	if !c.sc.UpsertSecret(s) {
+		return
+	}
+	c.reconcile()
+}
+
+func (c *SecretController) OnDelete(o interface{}) {
+	s, ok := o.(*coreV1.Secret)
+	if !ok {
+		return
+	}
+
+	if !c.sc.DeleteSecret(s) {
+		return
+	}
+	c.reconcile()
+}
+
+func (c *SecretController) OnUpdate(old, new interface{}) {
+	o, _ := old.(*coreV1.Secret)
+	n, ok := new.(*coreV1.Secret)
+	if !ok {
+		return
+	}
+	// The informer resyncs every Secret, only rotations change the config.
+	if o != nil && reflect.DeepEqual(o.Data, n.Data) {
+		c.sc.UpsertSecret(n)
+		return
+	}
+	c.OnAdd(n)
+}
+
+// UpsertSecret stores the Secret and reports whether any sink references
+// it.
+func (sc *Config) UpsertSecret(s *coreV1.Secret) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.secrets[secretKey(s.Namespace, s.Name)] = s.Data
+	return sc.referenced(s)
+}
+
+// DeleteSecret removes the Secret and reports whether any sink references
+// it.
+func (sc *Config) DeleteSecret(s *coreV1.Secret) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	delete(sc.secrets, secretKey(s.Namespace, s.Name))
+	return sc.referenced(s)
+}
+
+func (sc *Config) referenced(s *coreV1.Secret) bool {
+	for _, e := range sc.entries() {
+		ref := e.spec.SecretRef
+		if ref != nil && ref.Name == s.Name && secretNamespace(e, ref.Namespace) == s.Namespace {
+			return true
+		}
+		tlsRef := e.spec.TLSSecretRef
+		if tlsRef != nil && tlsRef.Name == s.Name && secretNamespace(e, tlsRef.Namespace) == s.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// token returns the bearer token the sink's SecretRef points at.
+func (sc *Config) token(e entry) (string, error) {
+	ref := e.spec.SecretRef
+	ns := secretNamespace(e, ref.Namespace)
+	if ns == "" {
+		return "", fmt.Errorf("secret %s has no namespace", ref.Name)
+	}
+	data, ok := sc.secrets[secretKey(ns, ref.Name)]
+	if !ok {
+		return "", missingSecretError{ns, ref.Name}
+	}
+	v, ok := data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %s", ns, ref.Name, ref.Key)
+	}
+	// Tokens written to files for kubectl create secret --from-file
+	// usually end in a newline.
+	token := strings.TrimSpace(string(v))
+	switch {
+	case token == "":
+		return "", fmt.Errorf("key %s of secret %s/%s is empty", ref.Key, ns, ref.Name)
+	case strings.ContainsAny(token, " \t\r\n"):
+		return "", fmt.Errorf("key %s of secret %s/%s is not a single token", ref.Key, ns, ref.Name)
+	}
+	return token, nil
+}
+
+// missingSecretError is why a sink referencing a Secret that does not exist,
+// e.g. because it was deleted, is left out of the config.
+type missingSecretError struct {
+	namespace string
+	name      string
+}
+
+func (e missingSecretError) Error() string {
+	return fmt.Sprintf("secret %s/%s not found", e.namespace, e.name)
+}
+
+// secretNamespace is the namespace of the Secret a sink references in the
+// namespace of the reference. A LogSink can only reference Secrets in its
+// own namespace.
+func secretNamespace(e entry, namespace string) string {
+	if e.cluster() {
+		return namespace
+	}
+	return e.namespace
+}
+
+// tlsDirectory is where fluent-bit mounts the fluent-bit-tls Secret, along
+// with its ConfigMap.
+const tlsDirectory = "/fluent-bit/etc"
+
+// clientCert is the paths of the certificate files of a sink in the
+// fluent-bit-tls Secret. caFile is empty when the sink verifies its
+// receivers with the system's CAs.
+type clientCert struct {
+	certFile string
+	keyFile  string
+	caFile   string
+}
+
+// clientCert returns the client certificate the sink's TLSSecretRef points
+// at, along with the files to write to the fluent-bit-tls Secret for it.
+// The files are named after the sink's tag so sinks cannot overwrite each
+// other's.
+func (sc *Config) clientCert(e entry) (*clientCert, map[string][]byte, error) {
+	ref := e.spec.TLSSecretRef
+	if !e.spec.EnableTLS || e.spec.Insecure {
+		return nil, nil, fmt.Errorf("tls_secret_ref requires enable_tls")
+	}
+	ns := secretNamespace(e, ref.Namespace)
+	if ns == "" {
+		return nil, nil, fmt.Errorf("secret %s has no namespace", ref.Name)
+	}
+	data, ok := sc.secrets[secretKey(ns, ref.Name)]
+	if !ok {
+		return nil, nil, missingSecretError{ns, ref.Name}
+	}
+
+	var (
+		cert  clientCert
+		files = make(map[string][]byte, 3)
+	)
+	for _, f := range []struct {
+		key      string
+		path     *string
+		required bool
+	}{
+		{coreV1.TLSCertKey, &cert.certFile, true},
+		{coreV1.TLSPrivateKeyKey, &cert.keyFile, true},
+		{"ca.crt", &cert.caFile, false},
+	} {
+		v := data[f.key]
+		if len(v) == 0 {
+			if f.required {
+				return nil, nil, fmt.Errorf("secret %s/%s has no key %s", ns, ref.Name, f.key)
+			}
+			continue
+		}
+		name := fmt.Sprintf("%s.%s", e.tag(), f.key)
+		files[name] = v
+		*f.path = path.Join(tlsDirectory, name)
+	}
+	return &cert, files, nil
+}
+
+// addClientCert has an output using fluent-bit's TLS present the client
+// certificate.
+func addClientCert(o *section, cert *clientCert) {
+	if cert == nil {
+		return
+	}
+	o.add("tls.crt_file", cert.certFile)
+	o.add("tls.key_file", cert.keyFile)
+	if cert.caFile != "" {
+		o.add("tls.ca_file", cert.caFile)
+	}
+}
+
+// addKafkaClientCert has a kafka output present the client certificate,
+// librdkafka handles the TLS connections to the brokers itself.
+func addKafkaClientCert(o *section, cert *clientCert) {
+	if cert == nil {
+		return
+	}
+	o.add("rdkafka.ssl.certificate.location", cert.certFile)
+	o.add("rdkafka.ssl.key.location", cert.keyFile)
+	if cert.caFile != "" {
+		o.add("rdkafka.ssl.ca.location", cert.caFile)
+	}
+}
+
+// withCredentials returns the destinations with the token added to their
+// headers. Elasticsearch and kafka destinations log in with it as
+// user:password and s3 destinations hold an access key in the same form.
+// Splunk destinations send it as a HEC token, datadog destinations as their
+// API key, forward destinations as their shared key and the others as a
+// bearer token.
+func withCredentials(specs []v1alpha1.SinkSpec, token string) ([]v1alpha1.SinkSpec, error) {
+	out := make([]v1alpha1.SinkSpec, 0, len(specs))
+	for _, s := range specs {
+		headers := make(map[string]string, len(s.Headers)+1)
+		for k, v := range s.Headers {
+			headers[k] = v
+		}
+		if s.Type == v1alpha1.SinkTypeElasticsearch ||
+			s.Type == v1alpha1.SinkTypeKafka ||
+			s.Type == v1alpha1.SinkTypeS3 {
+			auth, err := basicAuthHeader(token)
+			if err != nil {
+				return nil, err
+			}
+			headers["Authorization"] = auth
+		} else if s.Type == v1alpha1.SinkTypeSplunk {
+			headers["Authorization"] = "Splunk " + token
+		} else if s.Type == v1alpha1.SinkTypeDatadog {
+			headers["Authorization"] = "Datadog " + token
+		} else if s.Type == v1alpha1.SinkTypeForward {
+			headers["Authorization"] = "SharedKey " + token
+		} else {
+			headers["Authorization"] = "Bearer " + token
+		}
+		s.Headers = headers
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func secretKey(namespace, name string) string {
+	return fmt.Sprintf("%s|%s", namespace, name)
+}
@@ -0,0 +1,546 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestSecretRotation(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "http",
+			URI:       "https://logs.example.com/ingest",
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, spyReloader, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	s1 := secret("some-namespace", "log-service", "abc123")
+	c.OnAdd(s1)
+	expectToken(t, spyPatcher, spySecretPatcher, "abc123")
+
+	// Resyncs of an unchanged secret leave fluent-bit alone.
+	c.OnUpdate(s1, secret("some-namespace", "log-service", "abc123"))
+	if len(spyPatcher.patches) != 1 {
+		t.Fatalf("Expected 1 patch, got %d", len(spyPatcher.patches))
+	}
+
+	c.OnUpdate(s1, secret("some-namespace", "log-service", "def456"))
+	expectToken(t, spyPatcher, spySecretPatcher, "def456")
+
+	c.OnDelete(s1)
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+	if spyReloader.reloads != 3 {
+		t.Errorf("Reloads not equal: Expected: 3, Actual: %d", spyReloader.reloads)
+	}
+}
+
+func TestUnreferencedSecret(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	c := sink.NewSecretController(spyPatcher, spyReloader, sink.NewConfig())
+
+	s := secret("some-namespace", "log-service", "abc123")
+	c.OnAdd(s)
+	c.OnUpdate(s, secret("some-namespace", "log-service", "def456"))
+	c.OnDelete(s)
+
+	if spyPatcher.patchCalled {
+		t.Errorf("Expected patch to not be called")
+	}
+	if spyReloader.reloads != 0 {
+		t.Errorf("Expected reload to not be called")
+	}
+}
+
+func TestSecretControllerBadInputs(t *testing.T) {
+	spyPatcher := &spyConfigMapPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sink.NewConfig())
+
+	c.OnAdd(&coreV1.ConfigMap{})
+	c.OnUpdate(nil, &coreV1.ConfigMap{})
+	c.OnDelete(&coreV1.ConfigMap{})
+
+	if spyPatcher.patchCalled {
+		t.Errorf("Expected patch to not be called")
+	}
+}
+
+func expectToken(t *testing.T, spy *spyConfigMapPatcher, secretSpy *spySecretPatcher, token string) {
+	t.Helper()
+	conf := lastConfig(t, spy)
+	if !strings.Contains(conf, "Header Authorization Bearer ${HTTP_BEARER_TOKEN_B0E35BA9AA9F885D}\n") || strings.Contains(conf, token) {
+		t.Errorf("Expected config to reference token %s: %s", token, conf)
+	}
+	expected := "@SET HTTP_BEARER_TOKEN_B0E35BA9AA9F885D=" + token + "\n"
+	if creds := string(lastCerts(t, secretSpy)["credentials.conf"]); creds != expected {
+		t.Errorf("Credentials not equal: Expected: %q Actual: %q", expected, creds)
+	}
+}
+
+func lastConfig(t *testing.T, spy *spyConfigMapPatcher) string {
+	t.Helper()
+	if len(spy.patches) == 0 {
+		t.Fatalf("Expected a patch")
+	}
+	var jp []jsonPatch
+	err := json.Unmarshal(spy.patches[len(spy.patches)-1].data, &jp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jp[0].Value
+}
+
+func TestClientCertificates(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "http",
+			URI:          "https://logs.example.com/ingest",
+			EnableTLS:    true,
+			TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+		},
+	})
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-cluster-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         6514,
+			EnableTLS:    true,
+			TLSSecretRef: &v1alpha1.SecretReference{Namespace: "other-namespace", Name: "client-cert"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	c.OnAdd(tlsSecret("some-namespace", "client-cert", true))
+	c.OnAdd(tlsSecret("other-namespace", "client-cert", false))
+
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n" +
+		`    ClusterSinks [{"addr":"example.org:6514","tls":{"cert_file":"/fluent-bit/etc/sink.cluster.some-cluster-name.tls.crt",` +
+		`"key_file":"/fluent-bit/etc/sink.cluster.some-cluster-name.tls.key"}}]` + "\n" +
+		"\n[OUTPUT]\n    Name http\n    Match kube.*_some-namespace_*\n    Host logs.example.com\n    Port 443\n    URI /ingest\n    Format json_lines\n    tls On\n" +
+		"    tls.crt_file /fluent-bit/etc/sink.ns.some-namespace.some-name.tls.crt\n" +
+		"    tls.key_file /fluent-bit/etc/sink.ns.some-namespace.some-name.tls.key\n" +
+		"    tls.ca_file /fluent-bit/etc/sink.ns.some-namespace.some-name.ca.crt\n"
+	if conf := lastConfig(t, spyPatcher); conf != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+	}
+
+	expectedCerts := map[string][]byte{
+		"sink.ns.some-namespace.some-name.tls.crt": []byte("some-namespace-cert"),
+		"sink.ns.some-namespace.some-name.tls.key": []byte("some-namespace-key"),
+		"sink.ns.some-namespace.some-name.ca.crt":  []byte("some-namespace-ca"),
+		"sink.cluster.some-cluster-name.tls.crt":   []byte("other-namespace-cert"),
+		"sink.cluster.some-cluster-name.tls.key":   []byte("other-namespace-key"),
+	}
+	if diff := cmp.Diff(expectedCerts, lastCerts(t, spySecretPatcher)); diff != "" {
+		t.Errorf("Certificates not equal (-want +got): %v", diff)
+	}
+
+	// The certificates of sinks that are gone are dropped from the Secret.
+	c.OnDelete(tlsSecret("some-namespace", "client-cert", true))
+	if certs := lastCerts(t, spySecretPatcher); len(certs) != 2 {
+		t.Errorf("Expected the certificates of the cluster sink, got %v", certs)
+	}
+}
+
+func TestClientCertificatesNotWritten(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         6514,
+			EnableTLS:    true,
+			TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spyReloader := &spyReloader{}
+	spySecretPatcher := &spySecretPatcher{err: errors.New("forbidden")}
+	c := sink.NewSecretController(spyPatcher, spyReloader, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	// fluent-bit would not find the files the config references.
+	c.OnAdd(tlsSecret("some-namespace", "client-cert", false))
+	if spyPatcher.patchCalled || spyReloader.reloads != 0 {
+		t.Errorf("Expected the config to be left as it is")
+	}
+}
+
+func TestClientCertificateWithoutKey(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         6514,
+			EnableTLS:    true,
+			TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+		},
+	})
+	s := tlsSecret("some-namespace", "client-cert", false)
+	delete(s.Data, "tls.key")
+	spyPatcher := &spyConfigMapPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(&spySecretPatcher{}))
+
+	c.OnAdd(s)
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+}
+
+func TestClientCertificateWithoutTLS(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:         "syslog",
+			Host:         "example.org",
+			Port:         6514,
+			TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+		},
+	})
+	sc.UpsertSecret(tlsSecret("some-namespace", "client-cert", true))
+
+	if sc.String() != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, sc.String())
+	}
+}
+
+func tlsSecret(namespace, name string, ca bool) *coreV1.Secret {
+	s := &coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type: coreV1.SecretTypeTLS,
+		Data: map[string][]byte{
+			"tls.crt": []byte(namespace + "-cert"),
+			"tls.key": []byte(namespace + "-key"),
+		},
+	}
+	if ca {
+		s.Data["ca.crt"] = []byte(namespace + "-ca")
+	}
+	return s
+}
+
+type spySecretPatcher struct {
+	patches []patch
+	err     error
+}
+
+func (s *spySecretPatcher) Patch(
+	name string,
+	pt types.PatchType,
+	data []byte,
+	subresources ...string,
+) (*coreV1.Secret, error) {
+	s.patches = append(s.patches, patch{
+		name: name,
+		pt:   pt,
+		data: data,
+	})
+	return nil, s.err
+}
+
+func lastCerts(t *testing.T, spy *spySecretPatcher) map[string][]byte {
+	t.Helper()
+	if len(spy.patches) == 0 {
+		t.Fatalf("Expected a patch")
+	}
+	p := spy.patches[len(spy.patches)-1]
+	if p.name != sink.TLSSecretName {
+		t.Errorf("Secret name not equal: Expected: %s Actual: %s", sink.TLSSecretName, p.name)
+	}
+	var jp []struct {
+		Op    string            `json:"op"`
+		Path  string            `json:"path"`
+		Value map[string][]byte `json:"value"`
+	}
+	err := json.Unmarshal(p.data, &jp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jp[0].Op != "add" || jp[0].Path != "/data" {
+		t.Errorf("Expected the data of the Secret to be replaced: %s", p.data)
+	}
+	return jp[0].Value
+}
+
+func TestS3AccessKeys(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "s3",
+			Bucket:          "team-logs",
+			Region:          "us-east-1",
+			TotalFileSizeMB: 20,
+			SecretRef:       &v1alpha1.SecretKeyRef{Name: "aws", Key: "token"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	c.OnAdd(secret("some-namespace", "aws", "AKIAEXAMPLE:c2VjcmV0/a+b"))
+
+	// The keys stay out of the config, which only names their profile.
+	expected := "\n[OUTPUT]\n    Name s3\n    Match kube.*_some-namespace_*\n    bucket team-logs\n    region us-east-1\n    total_file_size 20M\n    profile sink.ns.some-namespace.some-name\n"
+	if conf := lastConfig(t, spyPatcher); conf != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+	}
+	expectedCerts := map[string][]byte{
+		"aws-credentials": []byte("[sink.ns.some-namespace.some-name]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = c2VjcmV0/a+b\n"),
+	}
+	if diff := cmp.Diff(expectedCerts, lastCerts(t, spySecretPatcher)); diff != "" {
+		t.Errorf("Credentials not equal (-want +got): %v", diff)
+	}
+
+	// Keys without a secret are not rendered.
+	c.OnUpdate(nil, secret("some-namespace", "aws", "AKIAEXAMPLE:"))
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+	if certs := lastCerts(t, spySecretPatcher); len(certs) != 0 {
+		t.Errorf("Expected no credentials, got %v", certs)
+	}
+}
+
+func TestElasticsearchPassword(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "elasticsearch",
+			Host:      "es.example.com",
+			Index:     "knative-logs",
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "es-credentials", Key: "token"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	c.OnAdd(secret("some-namespace", "es-credentials", "fluent:s3cr3t"))
+
+	// The user is rendered, the password only referenced by its variable.
+	conf := lastConfig(t, spyPatcher)
+	if !strings.Contains(conf, "    HTTP_User fluent\n    HTTP_Passwd ${ELASTICSEARCH_PASSWORD_B0E35BA9AA9F885D}\n") || strings.Contains(conf, "s3cr3t") {
+		t.Errorf("Expected the password to not be inlined in the config: %q", conf)
+	}
+	expectedCerts := map[string][]byte{
+		"credentials.conf": []byte("@SET ELASTICSEARCH_PASSWORD_B0E35BA9AA9F885D=s3cr3t\n"),
+	}
+	if diff := cmp.Diff(expectedCerts, lastCerts(t, spySecretPatcher)); diff != "" {
+		t.Errorf("Credentials not equal (-want +got): %v", diff)
+	}
+}
+
+func TestSplunkHECToken(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "splunk",
+			Host:      "hec.example.com",
+			Port:      8088,
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "hec", Key: "token"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	c.OnAdd(secret("some-namespace", "hec", "0a1b2c3d-4e5f-6789-abcd-ef0123456789"))
+
+	// The token stays out of the config, which only references its variable.
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name splunk\n    Match kube.*_some-namespace_*\n    Host hec.example.com\n    Port 8088\n    Splunk_Token ${SPLUNK_TOKEN_B0E35BA9AA9F885D}\n"
+	conf := lastConfig(t, spyPatcher)
+	if conf != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+	}
+	if strings.Contains(conf, "0a1b2c3d") {
+		t.Errorf("Expected the token to not be inlined in the config: %q", conf)
+	}
+	expectedCerts := map[string][]byte{
+		"credentials.conf": []byte("@SET SPLUNK_TOKEN_B0E35BA9AA9F885D=0a1b2c3d-4e5f-6789-abcd-ef0123456789\n"),
+	}
+	if diff := cmp.Diff(expectedCerts, lastCerts(t, spySecretPatcher)); diff != "" {
+		t.Errorf("Tokens not equal (-want +got): %v", diff)
+	}
+
+	// Without the token the sink is not rendered.
+	c.OnDelete(secret("some-namespace", "hec", ""))
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+	if certs := lastCerts(t, spySecretPatcher); len(certs) != 0 {
+		t.Errorf("Expected no tokens, got %v", certs)
+	}
+}
+
+func TestDatadogAPIKey(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "datadog",
+			Site:      "us5.datadoghq.com",
+			Tags:      []string{"env:prod"},
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "datadog", Key: "token"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	c.OnAdd(secret("some-namespace", "datadog", "0123456789abcdef0123456789abcdef"))
+
+	// The API key stays out of the config, which only references its
+	// variable.
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name datadog\n    Match kube.*_some-namespace_*\n    Host http-intake.logs.us5.datadoghq.com\n    TLS On\n    compress gzip\n" +
+		"    apikey ${DATADOG_API_KEY_B0E35BA9AA9F885D}\n    dd_tags env:prod\n"
+	conf := lastConfig(t, spyPatcher)
+	if conf != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+	}
+	if strings.Contains(conf, "0123456789abcdef") {
+		t.Errorf("Expected the API key to not be inlined in the config: %q", conf)
+	}
+	expectedCerts := map[string][]byte{
+		"credentials.conf": []byte("@SET DATADOG_API_KEY_B0E35BA9AA9F885D=0123456789abcdef0123456789abcdef\n"),
+	}
+	if diff := cmp.Diff(expectedCerts, lastCerts(t, spySecretPatcher)); diff != "" {
+		t.Errorf("API keys not equal (-want +got): %v", diff)
+	}
+
+	// Without the API key the sink is not rendered.
+	c.OnDelete(secret("some-namespace", "datadog", ""))
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+	if certs := lastCerts(t, spySecretPatcher); len(certs) != 0 {
+		t.Errorf("Expected no API keys, got %v", certs)
+	}
+}
+
+func TestForwardSharedKey(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "some-namespace",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:      "forward",
+			Host:      "fluentd.logging",
+			Port:      24224,
+			SecretRef: &v1alpha1.SecretKeyRef{Name: "fluentd", Key: "token"},
+		},
+	})
+	spyPatcher := &spyConfigMapPatcher{}
+	spySecretPatcher := &spySecretPatcher{}
+	c := sink.NewSecretController(spyPatcher, &spyReloader{}, sc, sink.WithTLSSecret(spySecretPatcher))
+
+	c.OnAdd(secret("some-namespace", "fluentd", "some-shared-key"))
+
+	// The shared key stays out of the config, which only references its
+	// variable.
+	expected := "\n@INCLUDE /fluent-bit/etc/credentials.conf\n" +
+		"\n[OUTPUT]\n    Name forward\n    Match kube.*_some-namespace_*\n    Host fluentd.logging\n    Port 24224\n" +
+		"    Shared_Key ${FORWARD_SHARED_KEY_B0E35BA9AA9F885D}\n"
+	conf := lastConfig(t, spyPatcher)
+	if conf != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, conf)
+	}
+	if strings.Contains(conf, "some-shared-key") {
+		t.Errorf("Expected the shared key to not be inlined in the config: %q", conf)
+	}
+	expectedCerts := map[string][]byte{
+		"credentials.conf": []byte("@SET FORWARD_SHARED_KEY_B0E35BA9AA9F885D=some-shared-key\n"),
+	}
+	if diff := cmp.Diff(expectedCerts, lastCerts(t, spySecretPatcher)); diff != "" {
+		t.Errorf("Shared keys not equal (-want +got): %v", diff)
+	}
+
+	// Without the shared key the sink is not rendered rather than sent
+	// anonymously.
+	c.OnDelete(secret("some-namespace", "fluentd", ""))
+	if conf := lastConfig(t, spyPatcher); conf != emptyConfig {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", emptyConfig, conf)
+	}
+	if certs := lastCerts(t, spySecretPatcher); len(certs) != 0 {
+		t.Errorf("Expected no shared keys, got %v", certs)
+	}
+}
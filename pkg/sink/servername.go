@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+var serverName = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
+// ValidateTLSServerName returns why the sink cannot connect to its
+// receiver with the tls_server_name or nil if it can.
+func ValidateTLSServerName(spec v1alpha1.SinkSpec) error {
+	name := spec.TLSServerName
+	if name == "" {
+		return nil
+	}
+	if !serverName.MatchString(name) {
+		return fmt.Errorf("tls_server_name %q is not a host name", name)
+	}
+	switch spec.Type {
+	case v1alpha1.SinkTypeKafka, v1alpha1.SinkTypeS3, v1alpha1.SinkTypeDatadog:
+		return fmt.Errorf("tls_server_name is not supported by sinks of type %s", spec.Type)
+	case v1alpha1.SinkTypeOTLP:
+		if spec.Insecure {
+			return fmt.Errorf("tls_server_name cannot be combined with insecure")
+		}
+	case v1alpha1.SinkTypeGELF:
+		if GELFMode(spec.Mode) != GELFModeTLS {
+			return fmt.Errorf("tls_server_name requires mode %s", GELFModeTLS)
+		}
+	case v1alpha1.SinkTypeHTTP, v1alpha1.SinkTypeLoki:
+		if !spec.EnableTLS && !https(spec.URI) && !https(spec.URL) {
+			return fmt.Errorf("tls_server_name requires an https URL or enable_tls")
+		}
+	default:
+		if !spec.EnableTLS {
+			return fmt.Errorf("tls_server_name requires enable_tls")
+		}
+	}
+	return nil
+}
+
+func https(uri string) bool {
+	return strings.HasPrefix(strings.ToLower(uri), "https://")
+}
+
+// validateTLSServerNames returns why any destination cannot connect with
+// the tls_server_name or nil if all of them can.
+func validateTLSServerNames(destinations []v1alpha1.SinkSpec) error {
+	for _, d := range destinations {
+		if err := ValidateTLSServerName(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addServerName has an output using fluent-bit's TLS send the server name
+// and verify the certificate against it.
+func addServerName(o *section, name string) {
+	if name != "" {
+		o.add("tls.vhost", name)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// severities are the names of the syslog severities, most severe first,
+// along with the common log levels of the same severity.
+var severities = [][]string{
+	{"emerg", "emergency", "panic"},
+	{"alert"},
+	{"crit", "critical", "fatal"},
+	{"err", "error"},
+	{"warning", "warn"},
+	{"notice"},
+	{"info", "informational"},
+	{"debug", "trace"},
+}
+
+// severityKeys are the record fields holding the severity of a parsed log
+// line.
+var severityKeys = []string{"level", "severity"}
+
+// ValidateSeverity returns why the severity is not a syslog severity or
+// log level or nil if it is.
+func ValidateSeverity(severity string) error {
+	if severityRank(severity) == -1 {
+		var names []string
+		for _, s := range severities {
+			names = append(names, s...)
+		}
+		return fmt.Errorf("severity %q must be one of %s", severity, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// ValidateSyslogSeverity returns why the severity of syslog messages is not
+// one of the syslog severity names or nil if it is. An empty severity
+// leaves it to the syslog output.
+func ValidateSyslogSeverity(severity string) error {
+	var names []string
+	for _, s := range severities {
+		if severity == "" || severity == s[0] {
+			return nil
+		}
+		names = append(names, s[0])
+	}
+	return fmt.Errorf("severity %q must be one of %s", severity, strings.Join(names, ", "))
+}
+
+func severityRank(severity string) int {
+	for i, s := range severities {
+		for _, name := range s {
+			if severity == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// severityFilter returns a grep filter dropping the records whose level or
+// severity field, in any case, is less severe than min. Records without
+// either field are forwarded. It returns nil if min lets every severity
+// through.
+func severityFilter(min string, m match) *section {
+	var lower []string
+	for _, s := range severities[severityRank(min)+1:] {
+		lower = append(lower, s...)
+	}
+	if len(lower) == 0 {
+		return nil
+	}
+	f := newFilter("grep", m)
+	for _, k := range severityKeys {
+		f.add("Exclude", fmt.Sprintf("%s ^(?i)(%s)$", k, strings.Join(lower, "|")))
+	}
+	return &f
+}
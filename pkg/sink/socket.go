@@ -0,0 +1,238 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// socketVolumePrefix starts the names of the volumes of the fluent-bit
+// DaemonSet mounting the directories of sink sockets.
+const socketVolumePrefix = "sink-socket-"
+
+// fluentBitMounts are the directories the DaemonSet mounts into the
+// fluent-bit container, a socket directory overlapping one would shadow it
+// or be shadowed by it.
+var fluentBitMounts = []string{
+	tlsDirectory,
+	"/var/log",
+	"/var/fluent-bit/storage",
+	"/var/lib/docker/containers",
+	"/var/vcap/store",
+	"/var/vcap/data",
+}
+
+// WithSocketMounts has the controller mount the directories of the
+// socket_path of the ClusterLogSinks into the fluent-bit container at the
+// same path on the node. The DaemonSet is only patched when the
+// directories change since changing its pod template rolls the fluent-bit
+// pods.
+func WithSocketMounts(ds DaemonSetPatcher) Option {
+	return func(rc *reconciler) {
+		rc.socketDS = ds
+	}
+}
+
+// ValidateSocketPath returns why the sink cannot forward to its
+// socket_path or nil if it can. The socket replaces the host and port of
+// a syslog sink, ValidateExclusiveFields rejects the fields it replaces.
+func ValidateSocketPath(spec v1alpha1.SinkSpec) error {
+	p := spec.SocketPath
+	switch {
+	case p == "":
+		return nil
+	case spec.Type != v1alpha1.SinkTypeSyslog:
+		return fmt.Errorf("socket_path is only supported by syslog sinks")
+	case !path.IsAbs(p) || path.Clean(p) != p:
+		return fmt.Errorf("socket_path %q must be a clean absolute path", p)
+	case path.Dir(p) == "/":
+		return fmt.Errorf("socket_path %q must not be in the root directory", p)
+	}
+	if m := mountOverlap(path.Dir(p)); m != "" {
+		return fmt.Errorf("socket_path %q must not be in or above %s, the fluent-bit container mounts it", p, m)
+	}
+	return nil
+}
+
+// ValidateSocketDirs returns why the directories cannot be allowed to hold
+// the sockets of sinks or nil if they can.
+func ValidateSocketDirs(dirs []string) error {
+	for _, d := range dirs {
+		if !path.IsAbs(d) || path.Clean(d) != d || d == "/" {
+			return fmt.Errorf("socket directory %q must be a clean absolute path below the root directory", d)
+		}
+		if m := mountOverlap(d); m != "" {
+			return fmt.Errorf("socket directory %q must not be in or above %s, the fluent-bit container mounts it", d, m)
+		}
+	}
+	return nil
+}
+
+// SocketPathAllowed returns why the socket_path is not in or below one of
+// the directories or nil if it is.
+func SocketPathAllowed(p string, dirs []string) error {
+	dir := path.Dir(p)
+	for _, d := range dirs {
+		if dir == d || strings.HasPrefix(dir, d+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("socket_path %q is not in a directory allowed to hold sockets", p)
+}
+
+// mountOverlap returns the directory mounted into the fluent-bit container
+// that the directory is, is in or is above, empty when there is none.
+func mountOverlap(dir string) string {
+	for _, m := range fluentBitMounts {
+		if dir == m || strings.HasPrefix(dir, m+"/") || strings.HasPrefix(m, dir+"/") {
+			return m
+		}
+	}
+	return ""
+}
+
+// SetSocketDirs allows the socket_path of ClusterLogSinks in or below the
+// directories, sinks with a socket elsewhere are not rendered. None are
+// allowed by default.
+func (sc *Config) SetSocketDirs(dirs []string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.socketDirs = dirs
+}
+
+// socketDirs returns the sorted directories of the sockets of the
+// ClusterLogSinks, only they may mount a directory of the node.
+func socketDirs(entries []entry) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, e := range entries {
+		if e.spec.SocketPath == "" || !e.cluster() {
+			continue
+		}
+		dir := path.Dir(e.spec.SocketPath)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// socketVolume returns the name of the volume mounting the directory.
+func socketVolume(dir string) string {
+	h := fnv.New32a()
+	h.Write([]byte(dir))
+	return fmt.Sprintf("%s%08x", socketVolumePrefix, h.Sum32())
+}
+
+// mountSockets patches the volumes of the fluent-bit DaemonSet when the
+// socket directories of the rendered ClusterLogSinks changed, removing
+// those of the sinks that are gone. It is called with the Config's writeMu
+// held. A failed patch is retried with the next write.
+func (rc *reconciler) mountSockets(r rendered) {
+	if rc.socketDS == nil || strings.Join(r.sockets, ",") == strings.Join(rc.sc.mounted, ",") {
+		return
+	}
+
+	var (
+		volumes []map[string]interface{}
+		mounts  []map[string]interface{}
+		current = make(map[string]bool)
+	)
+	for _, dir := range r.sockets {
+		current[dir] = true
+		volumes = append(volumes, map[string]interface{}{
+			"name": socketVolume(dir),
+			"hostPath": map[string]interface{}{
+				"path": dir,
+				"type": coreV1.HostPathDirectoryOrCreate,
+			},
+		})
+		mounts = append(mounts, map[string]interface{}{
+			"name":      socketVolume(dir),
+			"mountPath": dir,
+		})
+	}
+	for _, dir := range rc.sc.mounted {
+		if current[dir] {
+			continue
+		}
+		volumes = append(volumes, map[string]interface{}{"name": socketVolume(dir), "$patch": "delete"})
+		mounts = append(mounts, map[string]interface{}{"mountPath": dir, "$patch": "delete"})
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []map[string]interface{}{
+						{
+							"name":         fluentBitContainer,
+							"volumeMounts": mounts,
+						},
+					},
+					"volumes": volumes,
+				},
+			},
+		},
+	})
+	if err == nil {
+		_, err = rc.socketDS.Patch(DaemonSetName, types.StrategicMergePatchType, data)
+	}
+	if err != nil {
+		log.Printf("unable to mount the sink sockets into %s: %s", DaemonSetName, err)
+		return
+	}
+	rc.sc.mounted = r.sockets
+}
+
+// withoutSocketVolumes removes the volumes mounting sink sockets from the
+// pod template.
+func withoutSocketVolumes(t *coreV1.PodTemplateSpec) {
+	var volumes []coreV1.Volume
+	for _, v := range t.Spec.Volumes {
+		if !strings.HasPrefix(v.Name, socketVolumePrefix) {
+			volumes = append(volumes, v)
+		}
+	}
+	t.Spec.Volumes = volumes
+	for i := range t.Spec.Containers {
+		c := &t.Spec.Containers[i]
+		if c.Name != fluentBitContainer {
+			continue
+		}
+		var mounts []coreV1.VolumeMount
+		for _, m := range c.VolumeMounts {
+			if !strings.HasPrefix(m.Name, socketVolumePrefix) {
+				mounts = append(mounts, m)
+			}
+		}
+		c.VolumeMounts = mounts
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+// socketConfig returns a Config allowing sockets in /var/run/collector.
+func socketConfig() *sink.Config {
+	sc := sink.NewConfig()
+	sc.SetSocketDirs([]string{"/var/run/collector"})
+	return sc
+}
+
+func TestSocketDirectoriesAreMounted(t *testing.T) {
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewClusterController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		socketConfig(),
+		sink.WithSocketMounts(spyDaemonSet),
+	)
+	collector := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "collector"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock"},
+	}
+
+	c.OnAdd(collector)
+	expected := podSpecPatch{
+		Containers: []containerPatch{{
+			Name: "fluent-bit",
+			VolumeMounts: []map[string]interface{}{
+				{"name": volumeName(t, spyDaemonSet), "mountPath": "/var/run/collector"},
+			},
+		}},
+		Volumes: []map[string]interface{}{
+			{
+				"name":     volumeName(t, spyDaemonSet),
+				"hostPath": map[string]interface{}{"path": "/var/run/collector", "type": string(coreV1.HostPathDirectoryOrCreate)},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, lastPodSpec(t, spyDaemonSet)); diff != "" {
+		t.Errorf("Patch not equal (-want, +got) = %v", diff)
+	}
+
+	// Sockets in the same directory share the mount.
+	c.OnAdd(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-collector"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/other.sock"},
+	})
+	if len(spyDaemonSet.patches) != 1 {
+		t.Fatalf("Expected the unchanged mounts to not be patched, got %d patches", len(spyDaemonSet.patches))
+	}
+
+	// Sinks without sockets leave the mounts alone.
+	c.OnAdd(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "tcp"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12345},
+	})
+	if len(spyDaemonSet.patches) != 1 {
+		t.Fatalf("Expected the unchanged mounts to not be patched, got %d patches", len(spyDaemonSet.patches))
+	}
+}
+
+func TestSocketDirectoriesOfLogSinksAreNotMounted(t *testing.T) {
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		socketConfig(),
+		sink.WithSocketMounts(spyDaemonSet),
+	)
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "collector", Namespace: "some-namespace"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock"},
+	})
+	if len(spyDaemonSet.patches) != 0 {
+		t.Errorf("Expected no mounts to be patched, got %d patches", len(spyDaemonSet.patches))
+	}
+}
+
+func TestValidateSocketDirs(t *testing.T) {
+	if err := sink.ValidateSocketDirs([]string{"/var/run/collector", "/run/agent"}); err != nil {
+		t.Errorf("Expected the directories to be valid, got %s", err)
+	}
+	for _, dir := range []string{
+		"var/run/collector",
+		"/var/run/../collector",
+		"/",
+		"/var",
+		"/var/log",
+		"/var/log/collector",
+		"/fluent-bit",
+		"/fluent-bit/etc/collector",
+	} {
+		if err := sink.ValidateSocketDirs([]string{"/var/run/collector", dir}); err == nil {
+			t.Errorf("Expected socket directory %q to be invalid", dir)
+		}
+	}
+}
+
+func TestSocketDirectoriesAreUnmounted(t *testing.T) {
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	c := sink.NewClusterController(
+		&spyConfigMapPatcher{},
+		&spyReloader{},
+		socketConfig(),
+		sink.WithSocketMounts(spyDaemonSet),
+	)
+	collector := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "collector"},
+		Spec:       v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock"},
+	}
+	c.OnAdd(collector)
+	name := volumeName(t, spyDaemonSet)
+
+	c.OnDelete(collector)
+	expected := podSpecPatch{
+		Containers: []containerPatch{{
+			Name: "fluent-bit",
+			VolumeMounts: []map[string]interface{}{
+				{"mountPath": "/var/run/collector", "$patch": "delete"},
+			},
+		}},
+		Volumes: []map[string]interface{}{
+			{"name": name, "$patch": "delete"},
+		},
+	}
+	if diff := cmp.Diff(expected, lastPodSpec(t, spyDaemonSet)); diff != "" {
+		t.Errorf("Patch not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestSocketMountsAreNotDrift(t *testing.T) {
+	sc := socketConfig()
+	spyPatcher := &spyConfigMapPatcher{}
+	spyDaemonSet := &spyDaemonSetPatcher{}
+	sink.NewClusterController(spyPatcher, &spyReloader{}, sc, sink.WithSocketMounts(spyDaemonSet)).OnAdd(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock"},
+	})
+	c := sink.NewDriftController(spyPatcher, &spyReloader{}, sc, sink.WithSocketMounts(spyDaemonSet))
+
+	ds := fluentBitDaemonSet("oratos/fluent-bit-out-syslog:v0.9")
+	mounted := ds.DeepCopy()
+	name := volumeName(t, spyDaemonSet)
+	mounted.Spec.Template.Spec.Volumes = []coreV1.Volume{{
+		Name: name,
+		VolumeSource: coreV1.VolumeSource{
+			HostPath: &coreV1.HostPathVolumeSource{Path: "/var/run/collector"},
+		},
+	}}
+	mounted.Spec.Template.Spec.Containers[0].VolumeMounts = []coreV1.VolumeMount{
+		{Name: name, MountPath: "/var/run/collector"},
+	}
+	c.OnUpdate(ds, mounted)
+	if len(spyPatcher.patches) != 1 {
+		t.Errorf("Expected 1 patch, got %d", len(spyPatcher.patches))
+	}
+}
+
+type podSpecPatch struct {
+	Containers []containerPatch         `json:"containers"`
+	Volumes    []map[string]interface{} `json:"volumes"`
+}
+
+type containerPatch struct {
+	Name         string                   `json:"name"`
+	VolumeMounts []map[string]interface{} `json:"volumeMounts"`
+}
+
+func lastPodSpec(t *testing.T, spy *spyDaemonSetPatcher) podSpecPatch {
+	t.Helper()
+	if len(spy.patches) == 0 {
+		t.Fatalf("Expected a patch")
+	}
+	return podSpec(t, spy.patches[len(spy.patches)-1])
+}
+
+// volumeName returns the name of the first volume the first patch mounts.
+func volumeName(t *testing.T, spy *spyDaemonSetPatcher) string {
+	t.Helper()
+	if len(spy.patches) == 0 {
+		t.Fatalf("Expected a patch")
+	}
+	volumes := podSpec(t, spy.patches[0]).Volumes
+	if len(volumes) == 0 {
+		t.Fatalf("Expected a volume: %s", spy.patches[0].data)
+	}
+	name, _ := volumes[0]["name"].(string)
+	if !strings.HasPrefix(name, "sink-socket-") {
+		t.Fatalf("Expected the volume name to start with sink-socket-, got %q", name)
+	}
+	return name
+}
+
+func podSpec(t *testing.T, p patch) podSpecPatch {
+	t.Helper()
+	if p.name != sink.DaemonSetName || p.pt != types.StrategicMergePatchType {
+		t.Fatalf("Unexpected patch of %s with %s", p.name, p.pt)
+	}
+	var ds struct {
+		Spec struct {
+			Template struct {
+				Spec podSpecPatch `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(p.data, &ds); err != nil {
+		t.Fatal(err)
+	}
+	return ds.Spec.Template.Spec
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// DefaultSplunkPort is the port of the HTTP Event Collector sinks of type
+// splunk send to when they leave theirs unset.
+const DefaultSplunkPort = 8088
+
+var splunkIndexName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// splunkOutput returns a splunk output sending the records to the HTTP
+// Event Collector. The HEC token stays out of the config, the output
+// references its variable in the credentials file.
+func splunkOutput(spec v1alpha1.SinkSpec, tag string, m match) (section, error) {
+	if err := ValidateHost(spec.Host); err != nil {
+		return section{}, err
+	}
+	if err := ValidateSplunkIndex(spec.Index); err != nil {
+		return section{}, err
+	}
+	if err := ValidateSourceType(spec.SourceType); err != nil {
+		return section{}, err
+	}
+	auth, ok := spec.Headers["Authorization"]
+	if !ok {
+		return section{}, fmt.Errorf("splunk sinks need the HEC token of spec.secret_ref")
+	}
+	if _, err := credential(v1alpha1.SinkTypeSplunk, auth); err != nil {
+		return section{}, err
+	}
+
+	o := newOutput("splunk", m)
+	o.add("Host", spec.Host)
+	o.add("Port", strconv.Itoa(SplunkPort(spec.Port)))
+	o.add("Splunk_Token", credentialRef(v1alpha1.SinkTypeSplunk, tag))
+	if spec.Index != "" {
+		o.add("Event_Index", spec.Index)
+	}
+	if spec.SourceType != "" {
+		o.add("Event_Sourcetype", spec.SourceType)
+	}
+	if spec.EnableTLS {
+		o.add("tls", "On")
+		if spec.InsecureSkipVerify {
+			o.add("tls.verify", "Off")
+		}
+		addServerName(&o, spec.TLSServerName)
+	}
+	return o, nil
+}
+
+// SplunkPort returns the port a splunk destination sends to.
+func SplunkPort(port int) int {
+	if port == 0 {
+		return DefaultSplunkPort
+	}
+	return port
+}
+
+// ValidateSplunkIndex returns why events cannot be sent to the index or
+// nil if they can. An empty index is the default one of the token.
+func ValidateSplunkIndex(index string) error {
+	switch {
+	case index == "":
+	case !splunkIndexName.MatchString(index):
+		return fmt.Errorf("index %q may only contain lowercase alphanumerics, underscores and hyphens and must start with an alphanumeric", index)
+	case strings.Contains(index, "kvstore"):
+		return fmt.Errorf("index %q must not contain kvstore", index)
+	}
+	return nil
+}
+
+// ValidateSourceType returns why the sourcetype cannot be rendered or nil
+// if it can. An empty sourcetype is the default one of the token.
+func ValidateSourceType(sourceType string) error {
+	if strings.ContainsAny(sourceType, " \t\r\n") {
+		return fmt.Errorf("source type %q must not contain whitespace", sourceType)
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// entry is a LogSink or ClusterLogSink reduced to what is needed to render
+// its part of the config.
+type entry struct {
+	spec      v1alpha1.SinkSpec
+	name      string
+	namespace string
+	uid       types.UID
+	filters   []section
+	// destinations holds a spec per receiver, see destinations.
+	destinations []v1alpha1.SinkSpec
+	// cert is the client certificate of a sink with a TLSSecretRef.
+	cert *clientCert
+	// logParsers are the ClusterLogParsers of a sink with a ParserName or
+	// ParserNames, in the order of parserNames.
+	logParsers []v1alpha1.ParserSpec
+	// nodes are the names of the Nodes a sink with a NodeSelector
+	// selects.
+	nodes []string
+
+	// Exactly one of logSink and clusterLogSink is set.
+	logSink        *v1alpha1.LogSink
+	clusterLogSink *v1alpha1.ClusterLogSink
+}
+
+func (e entry) cluster() bool {
+	return e.clusterLogSink != nil
+}
+
+func (e entry) String() string {
+	if e.cluster() {
+		return e.name
+	}
+	return e.namespace + "/" + e.name
+}
+
+// scope returns the match for the records the sink receives. The tail input
+// tags records with the log file path, which kubelet names
+// <pod>_<namespace>_<container>-<id>.log. Neither pod names nor namespaces
+// may contain underscores so a LogSink's pattern cannot match another
+// namespace. The shared syslog output does the equivalent scoping itself
+// based on each sink's namespace.
+func (e entry) scope(all match) match {
+	if e.cluster() {
+		return all
+	}
+	return matchTag(fmt.Sprintf("kube.*_%s_*", e.namespace))
+}
+
+// tag is the tag of the sink's own stream, its Tag when set. Namespaces
+// cannot contain dots so derived tags of different sinks never collide. The
+// UID of the sink ends a derived tag, so a sink recreated under the name of
+// a deleted one does not receive the records fluent-bit still buffers for
+// the deleted one.
+func (e entry) tag() string {
+	if e.spec.Tag != "" {
+		return e.spec.Tag
+	}
+	tag := fmt.Sprintf("sink.ns.%s.%s", e.namespace, e.name)
+	if e.cluster() {
+		tag = "sink.cluster." + e.name
+	}
+	if e.uid != "" {
+		tag += "." + string(e.uid)
+	}
+	return tag
+}
+
+// match returns the match for the records in the sink's stream. The stream
+// of a sink with multiline keeps the tag of each record's container after
+// an underscore, which sink names cannot contain, so fluent-bit joins the
+// lines of every container apart from the others.
+func (e entry) match() match {
+	if e.spec.Multiline != nil {
+		return matchTag(e.tag() + "_*")
+	}
+	return matchTag(e.tag())
+}
+
+// parser is the name of the sink's multiline parser.
+func (e entry) parser() string {
+	return "multiline-" + e.tag()
+}
+
+// timeParser is the name of the parser reading the sink's timestamps.
+func (e entry) timeParser() string {
+	return "time-" + e.tag()
+}
+
+// streamed reports whether the sink needs a stream of its own, either to
+// apply filters to its records only, to buffer them separately, to share
+// them between its destinations, to tag them with its Tag or to receive the
+// lines of its host paths.
+func (e entry) streamed() bool {
+	return len(e.filters) != 0 ||
+		e.spec.BufferSizeMB != 0 ||
+		e.spec.BufferType != "" ||
+		weighted(e.spec) ||
+		e.spec.Tag != "" ||
+		len(e.spec.HostPaths) != 0 ||
+		e.audit()
+}
+
+var tagWords = regexp.MustCompile(`^[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*$`)
+
+// ValidateTag returns why the tag cannot be the tag of a sink's stream or
+// nil if it can. An empty tag is derived from the sink. Wildcards and
+// underscores are left out so the matches of the stream and the streams
+// per container of a sink with multiline only select its own records, and
+// the reserved prefixes keep the stream apart from the records of the
+// tail input and the derived streams.
+func ValidateTag(tag string) error {
+	switch {
+	case tag == "":
+	case len(tag) > 128:
+		return fmt.Errorf("tag %q is longer than 128 characters", tag)
+	case !tagWords.MatchString(tag):
+		return fmt.Errorf("tag %q must be dot separated words of alphanumerics and hyphens", tag)
+	case strings.HasPrefix(tag, "kube.") || strings.HasPrefix(tag, "sink."):
+		return fmt.Errorf("tag %q must not start with the reserved prefix kube. or sink.", tag)
+	}
+	return nil
+}
+
+// sharedTags returns the Tags set on more than one of the enabled sinks
+// along with the sinks sharing them.
+func sharedTags(entries []entry) map[string][]string {
+	sinks := make(map[string][]string)
+	for _, e := range entries {
+		if e.spec.Tag != "" && !e.spec.Disabled {
+			sinks[e.spec.Tag] = append(sinks[e.spec.Tag], e.String())
+		}
+	}
+	for tag, s := range sinks {
+		if len(s) < 2 {
+			delete(sinks, tag)
+		}
+	}
+	return sinks
+}
+
+// ownOutput reports whether the destination needs an output of its own
+// rather than an entry in the shared syslog output.
+func ownOutput(spec v1alpha1.SinkSpec) bool {
+	return spec.Type == v1alpha1.SinkTypeHTTP ||
+		spec.Type == v1alpha1.SinkTypeOTLP ||
+		spec.Type == v1alpha1.SinkTypeElasticsearch ||
+		spec.Type == v1alpha1.SinkTypeKafka ||
+		spec.Type == v1alpha1.SinkTypeLoki ||
+		spec.Type == v1alpha1.SinkTypeS3 ||
+		spec.Type == v1alpha1.SinkTypeSplunk ||
+		spec.Type == v1alpha1.SinkTypeDatadog ||
+		spec.Type == v1alpha1.SinkTypeForward ||
+		spec.Type == v1alpha1.SinkTypeGELF ||
+		spec.RetryLimit != 0 ||
+		spec.KeepAliveSeconds != nil ||
+		spec.FlushTimeoutSeconds != 0 ||
+		spec.PreserveOrder
+}
+
+func output(spec v1alpha1.SinkSpec, e entry, m match) (section, error) {
+	var (
+		o    section
+		err  error
+		cert = e.cert
+	)
+	switch spec.Type {
+	case v1alpha1.SinkTypeHTTP:
+		o, err = httpOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	case v1alpha1.SinkTypeOTLP:
+		o, err = otlpOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	case v1alpha1.SinkTypeElasticsearch:
+		o, err = esOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	case v1alpha1.SinkTypeKafka:
+		o, err = kafkaOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addKafkaClientCert(&o, cert)
+	case v1alpha1.SinkTypeLoki:
+		o, err = lokiOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	case v1alpha1.SinkTypeS3:
+		o, err = s3Output(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+	case v1alpha1.SinkTypeSplunk:
+		o, err = splunkOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	case v1alpha1.SinkTypeDatadog:
+		o, err = datadogOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+	case v1alpha1.SinkTypeForward:
+		o, err = forwardOutput(spec, e.tag(), m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	case v1alpha1.SinkTypeGELF:
+		o, err = gelfOutput(spec, m)
+		if err != nil {
+			return section{}, err
+		}
+		addClientCert(&o, cert)
+	default:
+		// Records in a sink's own stream or matched by its scope are
+		// already limited to the sink so the syslog output forwards all
+		// of them.
+		o = syslogOutput(m, []sink{}, []sink{newSink(spec, cert)})
+	}
+	addRetryLimit(&o, spec.RetryLimit)
+	if err := ValidateKeepAlive(spec.KeepAliveSeconds); err != nil {
+		return section{}, err
+	}
+	addKeepAlive(&o, spec.KeepAliveSeconds)
+	if err := ValidateFlushTimeout(spec.FlushTimeoutSeconds); err != nil {
+		return section{}, err
+	}
+	addFlushTimeout(&o, spec.FlushTimeoutSeconds)
+	// A single worker flushes the chunks one after the other.
+	if spec.PreserveOrder {
+		o.add("Workers", "1")
+	}
+	if spec.BufferType == v1alpha1.BufferTypeFilesystem && spec.BufferSizeMB != 0 {
+		o.add("storage.total_limit_size", fmt.Sprintf("%dM", spec.BufferSizeMB))
+	}
+	return o, nil
+}
+
+// addRetryLimit sets the output's Retry_Limit. fluent-bit spells an
+// unlimited number of retries as False.
+func addRetryLimit(o *section, limit int) {
+	switch {
+	case limit == 0:
+	case limit < 0:
+		o.add("Retry_Limit", "False")
+	default:
+		o.add("Retry_Limit", strconv.Itoa(limit))
+	}
+}
+
+// ValidateKeepAlive returns why fluent-bit cannot keep idle connections
+// open for the seconds or nil if it can. Unset keeps fluent-bit's default.
+func ValidateKeepAlive(seconds *int) error {
+	if seconds != nil && *seconds < 0 {
+		return fmt.Errorf("keepalive_seconds must not be negative, got %d", *seconds)
+	}
+	return nil
+}
+
+// addKeepAlive sets the output's net keepalive options, zero seconds
+// disables keepalive.
+func addKeepAlive(o *section, seconds *int) {
+	switch {
+	case seconds == nil:
+	case *seconds == 0:
+		o.add("net.keepalive", "off")
+	default:
+		o.add("net.keepalive", "on")
+		o.add("net.keepalive_idle_timeout", strconv.Itoa(*seconds))
+	}
+}
+
+// ValidateFlushTimeout returns why fluent-bit cannot time out the
+// connections of a flush after the seconds or nil if it can. Zero keeps
+// fluent-bit's defaults.
+func ValidateFlushTimeout(seconds int) error {
+	if seconds < 0 {
+		return fmt.Errorf("flush_timeout_seconds must be positive, got %d", seconds)
+	}
+	return nil
+}
+
+// addFlushTimeout sets the output's connect and io timeouts.
+func addFlushTimeout(o *section, seconds int) {
+	if seconds == 0 {
+		return
+	}
+	o.add("net.connect_timeout", strconv.Itoa(seconds))
+	o.add("net.io_timeout", strconv.Itoa(seconds))
+}
+
+// newStream returns the filter copying the records in scope into a stream of
+// their own. The sink's filters and output match the stream's tag so they do
+// not affect records sent to other sinks.
+func newStream(tag string, scope match) section {
+	f := newFilter("rewrite_tag", scope)
+	f.add("Rule", fmt.Sprintf("$log .* %s true", tag))
+	return f
+}
+
+// stream returns the filter starting the sink's stream. The records copied
+// into it are buffered by the filter's emitter, which is where the sink's
+// buffer limits apply. Buffering on the filesystem relies on the
+// storage.path set in the fluent-bit service config.
+func (e entry) stream(all match) section {
+	tag := e.tag()
+	if e.spec.Multiline != nil {
+		tag += "_$TAG"
+	}
+	f := newStream(tag, e.scope(all))
+	addEmitterBuffer(&f, e.spec)
+	return f
+}
+
+// addEmitterBuffer applies the sink's buffer limits to the emitter of a
+// rewrite_tag filter.
+func addEmitterBuffer(f *section, spec v1alpha1.SinkSpec) {
+	if spec.BufferType == v1alpha1.BufferTypeFilesystem {
+		f.add("Emitter_Storage.type", v1alpha1.BufferTypeFilesystem)
+	}
+	if spec.BufferSizeMB != 0 {
+		f.add("Emitter_Mem_Buf_Limit", fmt.Sprintf("%dM", spec.BufferSizeMB))
+	}
+}
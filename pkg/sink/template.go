@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templateKeys are the record keys every log has once the kubernetes filter
+// ran. Labels and annotations are keyed by their name below prefixes in
+// templatePrefixes.
+var templateKeys = map[string]bool{
+	"log":                       true,
+	"stream":                    true,
+	"time":                      true,
+	"kubernetes.pod_name":       true,
+	"kubernetes.namespace_name": true,
+	"kubernetes.pod_id":         true,
+	"kubernetes.host":           true,
+	"kubernetes.container_name": true,
+	"kubernetes.docker_id":      true,
+}
+
+var templatePrefixes = []string{
+	"kubernetes.labels.",
+	"kubernetes.annotations.",
+}
+
+// ValidateMessageTemplate returns an error if the syslog message template
+// is not terminated or references a key that is not part of every record.
+func ValidateMessageTemplate(t string) error {
+	for {
+		start := strings.Index(t, "{{")
+		if start == -1 {
+			return nil
+		}
+		end := strings.Index(t[start:], "}}")
+		if end == -1 {
+			return fmt.Errorf("unterminated placeholder in message template at %q", t[start:])
+		}
+		key := strings.TrimSpace(t[start+2 : start+end])
+		if !knownTemplateKey(key) {
+			return fmt.Errorf("unknown placeholder {{%s}} in message template", key)
+		}
+		t = t[start+end+2:]
+	}
+}
+
+// templateKeyPath returns the nested fields of the record the key refers
+// to. The names of labels and annotations may contain dots themselves.
+func templateKeyPath(key string) []string {
+	for _, p := range templatePrefixes {
+		if strings.HasPrefix(key, p) {
+			return append(strings.Split(strings.TrimSuffix(p, "."), "."), key[len(p):])
+		}
+	}
+	return strings.Split(key, ".")
+}
+
+func knownTemplateKey(key string) bool {
+	if templateKeys[key] {
+		return true
+	}
+	for _, p := range templatePrefixes {
+		if strings.HasPrefix(key, p) && len(key) > len(p) {
+			return true
+		}
+	}
+	return false
+}
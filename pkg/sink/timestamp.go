@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateTimeKey returns why the field cannot hold the timestamps of a
+// sink's records or nil if it can.
+func ValidateTimeKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("time key must not be empty")
+	case strings.ContainsAny(key, " \t\r\n\"\\"):
+		return fmt.Errorf("time key %q must not contain whitespace, quotes or backslashes", key)
+	case key != "time" && ReservedRecordKey(key):
+		return fmt.Errorf("time key %q is set by fluent-bit and holds no timestamp", key)
+	}
+	return nil
+}
+
+// ValidateTimeFormat returns why the format cannot be rendered into a
+// parser or nil if it can.
+func ValidateTimeFormat(format string) error {
+	switch {
+	case format == "":
+		return fmt.Errorf("time format must not be empty")
+	case strings.ContainsAny(format, "\r\n"):
+		return fmt.Errorf("time format %q must be a single line", format)
+	case strings.TrimSpace(format) != format:
+		return fmt.Errorf("time format %q must not start or end with whitespace", format)
+	case !strings.Contains(format, "%"):
+		return fmt.Errorf("time format %q has no conversions", format)
+	}
+	return nil
+}
+
+// timeParser returns the parser reading the timestamp of a record from the
+// value of its time key in the format.
+func timeParser(name, format string) section {
+	p := section{kind: "PARSER"}
+	p.add("Name", name)
+	p.add("Format", "regex")
+	p.add("Regex", "^(?<time>.+)$")
+	p.add("Time_Key", "time")
+	p.add("Time_Format", format)
+	return p
+}
+
+// timestampFilters return the filters normalizing the records' timestamps.
+// The parser filter sets the time of a record from its time key, which it
+// drops, and the lua filter writes the time back as RFC3339 in UTC. Records
+// without the key or whose key is not in the format keep the time they were
+// read at, so every record leaves with the key set in the same format.
+func timestampFilters(parser, key string, m match) []section {
+	p := newFilter("parser", m)
+	p.add("Key_Name", key)
+	p.add("Parser", parser)
+	p.add("Reserve_Data", "On")
+
+	l := newFilter("lua", m)
+	l.add("call", "normalize_time")
+	l.add("code", fmt.Sprintf(
+		`function normalize_time(tag, timestamp, record) local seconds = math.floor(timestamp) `+
+			`record[%s] = os.date("!%%Y-%%m-%%dT%%H:%%M:%%S", seconds) .. string.format(".%%03dZ", math.floor((timestamp - seconds) * 1000)) `+
+			`return 2, timestamp, record end`,
+		luaString(key),
+	))
+	return []section{p, l}
+}
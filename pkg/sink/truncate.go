@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+)
+
+// TruncationMarker ends the log lines of records truncated to the
+// MaxMessageBytes of their sink.
+const TruncationMarker = "...[truncated]"
+
+// WithBufferMaxSize has the controller log a warning for sinks whose
+// MaxMessageBytes is above n, the Buffer_Max_Size in bytes of the tail input
+// of the fluent-bit DaemonSet. The input skips lines longer than it before
+// any sink can truncate them.
+func WithBufferMaxSize(n int) Option {
+	return func(rc *reconciler) {
+		rc.bufferMaxSize = n
+	}
+}
+
+// ValidateMaxMessageBytes returns why a sink cannot truncate its log lines
+// to n bytes or nil if it can. Zero leaves the lines as they are.
+func ValidateMaxMessageBytes(n int) error {
+	switch {
+	case n < 0:
+		return fmt.Errorf("must not be negative, got %d", n)
+	case n != 0 && n <= len(TruncationMarker):
+		return fmt.Errorf("must be larger than the %d bytes of the truncation marker, got %d", len(TruncationMarker), n)
+	}
+	return nil
+}
+
+// truncateFilter returns a lua filter cutting log lines longer than n bytes
+// down to n bytes, the last of them being the TruncationMarker. Records
+// without a log line or with a short one pass through as they are.
+func truncateFilter(n int, m match) section {
+	f := newFilter("lua", m)
+	f.add("call", "truncate")
+	f.add("code", fmt.Sprintf(
+		`function truncate(tag, timestamp, record) local line = record["log"] `+
+			`if type(line) ~= "string" or #line <= %d then return 0, timestamp, record end `+
+			`record["log"] = string.sub(line, 1, %d) .. %s return 2, timestamp, record end`,
+		n,
+		n-len(TruncationMarker),
+		luaString(TruncationMarker),
+	))
+	return f
+}
+
+// warnLongLines logs the rendered sinks whose lines the tail input skips
+// before they reach the sink's truncation. Every write renders them again,
+// so they are only logged when the warnings change.
+func (rc *reconciler) warnLongLines(r rendered) {
+	if rc.bufferMaxSize <= 0 {
+		return
+	}
+	var warnings []string
+	for _, e := range r.entries {
+		if e.spec.MaxMessageBytes > rc.bufferMaxSize {
+			warnings = append(warnings, fmt.Sprintf(
+				"warning: sink %s truncates lines to %d bytes but fluent-bit skips lines longer than its Buffer_Max_Size of %d bytes",
+				e,
+				e.spec.MaxMessageBytes,
+				rc.bufferMaxSize,
+			))
+		}
+	}
+	if reflect.DeepEqual(warnings, rc.sc.longLines) {
+		return
+	}
+	rc.sc.longLines = warnings
+	for _, w := range warnings {
+		log.Println(w)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestMaxMessageBytes(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "syslog",
+			Host:            "example.com",
+			Port:            12345,
+			MaxMessageBytes: 1024,
+			ParseJSON:       true,
+		},
+	})
+
+	// Lines are truncated before they are parsed.
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call truncate\n" +
+		`    code function truncate(tag, timestamp, record) local line = record["log"] ` +
+		`if type(line) ~= "string" or #line <= 1024 then return 0, timestamp, record end ` +
+		`record["log"] = string.sub(line, 1, 1010) .. "...[truncated]" return 2, timestamp, record end` + "\n" +
+		"\n[FILTER]\n    Name parser\n    Match sink.ns.ns1.some-name\n    Key_Name log\n    Parser sink-json\n    Reserve_Data On\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:12345\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidMaxMessageBytes(t *testing.T) {
+	for _, n := range []int{-1, 1, len(sink.TruncationMarker)} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:            "syslog",
+				Host:            "example.com",
+				Port:            12345,
+				MaxMessageBytes: n,
+			},
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for max message bytes %d: Expected: %s Actual: %s", n, emptyConfig, sc.String())
+		}
+	}
+}
+
+func TestMaxMessageBytesAboveBufferMaxSize(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	c := sink.NewController(&spyConfigMapPatcher{}, &spyReloader{}, sink.NewConfig(), sink.WithBufferMaxSize(32768))
+	for name, n := range map[string]int{"short-lines": 32768, "long-lines": 65536} {
+		c.OnAdd(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:            "syslog",
+				Host:            "example.com",
+				Port:            12345,
+				MaxMessageBytes: n,
+			},
+		})
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "warning: sink ns1/long-lines truncates lines to 65536 bytes") {
+		t.Errorf("Expected a warning for the sink above the Buffer_Max_Size: %s", out)
+	}
+	if strings.Contains(out, "short-lines") {
+		t.Errorf("Expected no warning for the sink within the Buffer_Max_Size: %s", out)
+	}
+
+	// Writes leaving the affected sinks as they are do not repeat it.
+	buf.Reset()
+	c.OnAdd(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 12346},
+	})
+	if out := buf.String(); strings.Contains(out, "warning: sink") {
+		t.Errorf("Expected the warning to not be repeated: %s", out)
+	}
+}
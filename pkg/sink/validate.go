@@ -0,0 +1,178 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReasonInvalidConfig is the reason set on the Ready condition of sinks
+// whose sections fluent-bit rejected, the config holding them was not
+// written.
+const ReasonInvalidConfig = "InvalidConfig"
+
+// ConfigValidator checks a rendered config before it is written.
+type ConfigValidator interface {
+	// Validate returns why fluent-bit would not start with the outputs
+	// config, the parsers and the files of the fluent-bit-tls Secret, or
+	// nil when it would. It gives up when the context is done.
+	Validate(ctx context.Context, conf, parsers string, files map[string][]byte) error
+}
+
+// WithValidator has the controller validate the rendered config before it
+// writes it. An invalid config is not written, fluent-bit keeps running the
+// previous one and the sinks that broke it are not ready.
+func WithValidator(v ConfigValidator) Option {
+	return func(rc *reconciler) {
+		rc.validator = v
+	}
+}
+
+const (
+	// dryRunTimeout bounds a dry run of fluent-bit.
+	dryRunTimeout = 10 * time.Second
+	// validateTimeout bounds the dry runs validating a render, the sinks
+	// left when it passes are not checked on their own.
+	validateTimeout = 30 * time.Second
+)
+
+type dryRunValidator struct {
+	binary      string
+	parsersFile string
+	plugins     []string
+}
+
+// NewDryRunValidator returns a ConfigValidator running the fluent-bit binary
+// with --dry-run against the config. The parsers file is the parsers.conf of
+// the fluent-bit ConfigMap defining the parsers the config references, such
+// as sink-json. The plugins are the shared objects of the output plugins
+// fluent-bit does not ship with, such as syslog.
+func NewDryRunValidator(binary, parsersFile string, plugins ...string) ConfigValidator {
+	return &dryRunValidator{
+		binary:      binary,
+		parsersFile: parsersFile,
+		plugins:     plugins,
+	}
+}
+
+func (v *dryRunValidator) Validate(ctx context.Context, conf, parsers string, files map[string][]byte) error {
+	dir, err := ioutil.TempDir("", "fluent-bit")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// The config references the files where fluent-bit mounts the
+	// fluent-bit-tls Secret, they are written next to it instead.
+	files = copyFiles(files)
+	files["outputs.conf"] = []byte(strings.Replace(conf, tlsDirectory+"/", dir+"/", -1))
+	files["multiline-parsers.conf"] = []byte(parsers)
+	files["fluent-bit.conf"] = []byte(fmt.Sprintf(
+		"[SERVICE]\n    Parsers_File %s\n    Parsers_File %s\n    storage.path %s\n\n@INCLUDE %s\n",
+		v.parsersFile,
+		filepath.Join(dir, "multiline-parsers.conf"),
+		filepath.Join(dir, "storage"),
+		filepath.Join(dir, "outputs.conf"),
+	))
+	for name, data := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []string{"--dry-run", "-c", filepath.Join(dir, "fluent-bit.conf")}
+	for _, p := range v.plugins {
+		args = append(args, "-e", p)
+	}
+	ctx, cancel := context.WithTimeout(ctx, dryRunTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, v.binary, args...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(strings.Replace(string(out), dir+"/", tlsDirectory+"/", -1))
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("fluent-bit rejected the config: %s", msg)
+	}
+	return nil
+}
+
+func copyFiles(files map[string][]byte) map[string][]byte {
+	c := make(map[string][]byte, len(files)+3)
+	for name, data := range files {
+		c[name] = data
+	}
+	return c
+}
+
+// validate checks the render with the validator and returns the sinks that
+// broke it. When fluent-bit rejects the config the sinks whose sections it
+// rejects on their own are the ones that broke it, every rendered sink is
+// when there are none. The dry runs take a while, it is called without the
+// Config's writeMu held.
+func (rc *reconciler) validate(r rendered) (map[string]string, error) {
+	if rc.validator == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+	err := rc.validator.Validate(ctx, r.conf, r.parsers, r.certs)
+	if err == nil {
+		return nil, nil
+	}
+
+	rejected := make(map[string]string)
+	keys := make([]string, 0, len(r.sinkConfs))
+	for k := range r.sinkConfs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		conf := r.sinkConfs[k]
+		if _, ok := r.certs[credentialsFile]; ok {
+			conf = credentialsInclude() + conf
+		}
+		if sinkErr := rc.validator.Validate(ctx, conf, r.parsers, r.certs); sinkErr != nil {
+			rejected[k] = sinkErr.Error()
+		}
+	}
+	if len(rejected) == 0 {
+		for _, k := range keys {
+			rejected[k] = err.Error()
+		}
+	}
+	return rejected, err
+}
+
+// rejections returns why fluent-bit rejected the sinks of the last config
+// that was validated, by sink.
+func (sc *Config) rejections() map[string]string {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	return sc.rejected
+}
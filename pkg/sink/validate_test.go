@@ -0,0 +1,210 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestValidatorBlocksInvalidConfig(t *testing.T) {
+	good := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "good", Namespace: "test-ns", Generation: 1},
+		Spec:       v1alpha1.SinkSpec{Type: "http", URI: "https://logs.example.com/ingest"},
+	}
+	broken := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Generation: 1},
+		Spec:       v1alpha1.SinkSpec{Type: "http", URI: "https://broken.example.com/ingest"},
+	}
+	client := fake.NewSimpleClientset(good, broken)
+	rec := &spyEventRecorder{}
+	p := &spyConfigMapPatcher{}
+	reloader := &spyReloader{}
+	sc := sink.NewConfig()
+	v := stubValidator(func(conf string) bool {
+		return strings.Contains(conf, "broken.example.com")
+	})
+	c := sink.NewController(p, reloader, sc, sink.WithEventRecorder(rec), sink.WithValidator(v))
+	cc := sink.NewClusterController(p, reloader, sc, sink.WithEventRecorder(rec), sink.WithValidator(v))
+
+	c.OnAdd(good)
+	if len(p.patches) != 1 {
+		t.Fatalf("Expected the valid config to be written, got %d patches", len(p.patches))
+	}
+	cc.OnAdd(broken)
+	if len(p.patches) != 1 {
+		t.Fatalf("Expected the invalid config not to be written, got %d patches", len(p.patches))
+	}
+	if reloader.reloads != 1 {
+		t.Errorf("Expected fluent-bit to be reloaded once, got %d reloads", reloader.reloads)
+	}
+
+	msg := "fluent-bit rejected the config: [error] unknown host broken.example.com"
+	failed := "unable to apply the fluent-bit config: " + msg
+	expected := []recordedEvent{
+		{object: good, eventtype: coreV1.EventTypeNormal, reason: sink.EventReasonApplied, message: "applied to the fluent-bit config"},
+		{object: broken, eventtype: coreV1.EventTypeWarning, reason: sink.EventReasonInvalidConfig, message: msg},
+		{object: good, eventtype: coreV1.EventTypeWarning, reason: sink.EventReasonApplyFailed, message: failed},
+	}
+	if diff := cmp.Diff(expected, rec.events, cmp.AllowUnexported(recordedEvent{})); diff != "" {
+		t.Errorf("Events not equal (-want, +got) = %v", diff)
+	}
+
+	metrics := &stubMetricsGetter{
+		metrics: sink.Metrics{
+			Outputs: map[string]sink.OutputMetrics{
+				"http.0": {ProcRecords: 10},
+				"http.1": {ProcRecords: 10},
+			},
+		},
+	}
+	r := sink.NewHealthReporter(sc, metrics, client.ObservabilityV1alpha1())
+	r.Reconcile()
+	r.Reconcile()
+
+	s := getClusterLogSink(t, client, "broken")
+	expectCondition(t, s, coreV1.ConditionFalse, sink.ReasonInvalidConfig)
+	if m := s.Status.GetCondition(v1alpha1.SinkConditionReady).Message; m != msg {
+		t.Errorf("Unexpected message: %s", m)
+	}
+	expectCondition(t, getLogSink(t, client, "test-ns", "good"), coreV1.ConditionTrue, "")
+
+	// Fixing the sink writes the config again.
+	fixed := broken.DeepCopy()
+	fixed.Generation = 2
+	fixed.Spec.URI = "https://logs.example.com/cluster"
+	cc.OnUpdate(broken, fixed)
+	if len(p.patches) != 2 {
+		t.Errorf("Expected the fixed config to be written, got %d patches", len(p.patches))
+	}
+	r.Reconcile()
+	expectCondition(t, getClusterLogSink(t, client, "broken"), coreV1.ConditionTrue, "")
+}
+
+func TestValidatorRejectsEverySinkWhenNoneFailsAlone(t *testing.T) {
+	rec := &spyEventRecorder{}
+	p := &spyConfigMapPatcher{}
+	// Each sink is valid on its own but not along with the other one.
+	v := stubValidator(func(conf string) bool {
+		return strings.Count(conf, "[OUTPUT]") > 1
+	})
+	c := sink.NewController(p, &spyReloader{}, sink.NewConfig(), sink.WithEventRecorder(rec), sink.WithValidator(v))
+
+	s1 := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink-1", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "http", URI: "https://logs.example.com/ingest"},
+	}
+	s2 := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "sink-2", Namespace: "test-ns"},
+		Spec:       v1alpha1.SinkSpec{Type: "http", URI: "https://logs.example.com/other"},
+	}
+	c.OnAdd(s1)
+	c.OnAdd(s2)
+	if len(p.patches) != 1 {
+		t.Fatalf("Expected the invalid config not to be written, got %d patches", len(p.patches))
+	}
+
+	reasons := make(map[string]string)
+	for _, e := range rec.events {
+		reasons[e.object.(*v1alpha1.LogSink).Name] = e.reason
+	}
+	for _, name := range []string{"sink-1", "sink-2"} {
+		if reasons[name] != sink.EventReasonInvalidConfig {
+			t.Errorf("Expected sink %s to be rejected, got %s", name, reasons[name])
+		}
+	}
+}
+
+func TestDryRunValidator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dry-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The fake fluent-bit rejects configs referencing files that were not
+	// written, holding the word broken or not loading the parsers file.
+	binary := filepath.Join(dir, "fluent-bit")
+	script := `#!/bin/sh
+[ "$1" = "--dry-run" ] && [ "$2" = "-c" ] || exit 2
+d=$(dirname "$3")
+grep -q "@INCLUDE $d/outputs.conf" "$3" || exit 2
+grep -q "Parsers_File /fluent-bit/etc/parsers.conf$" "$3" || { echo "[error] parser sink-json not found"; exit 1; }
+grep -q broken "$d/outputs.conf" && { echo "[error] invalid section in $d/outputs.conf"; exit 1; }
+for f in $(sed -n 's/^ *tls.crt_file *//p' "$d/outputs.conf"); do
+	[ -f "$f" ] || { echo "[error] missing $f"; exit 1; }
+done
+exit 0
+`
+	err = ioutil.WriteFile(binary, []byte(script), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := sink.NewDryRunValidator(binary, "/fluent-bit/etc/parsers.conf")
+	ctx := context.Background()
+
+	conf := "\n[OUTPUT]\n    Name http\n    Match *\n    tls.crt_file /fluent-bit/etc/sink.crt\n"
+	err = v.Validate(ctx, conf, "", map[string][]byte{"sink.crt": []byte("cert")})
+	if err != nil {
+		t.Errorf("Expected the config to be valid, got %s", err)
+	}
+
+	err = v.Validate(ctx, conf, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "[error] missing /fluent-bit/etc/sink.crt") {
+		t.Errorf("Expected the missing file to be reported, got %v", err)
+	}
+
+	err = v.Validate(ctx, "\n[OUTPUT]\n    Name broken\n", "", nil)
+	expected := "fluent-bit rejected the config: [error] invalid section in /fluent-bit/etc/outputs.conf"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+
+	err = sink.NewDryRunValidator(binary, "/etc/parsers.conf").Validate(ctx, conf, "", map[string][]byte{"sink.crt": []byte("cert")})
+	expected = "fluent-bit rejected the config: [error] parser sink-json not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+
+	done, cancel := context.WithCancel(ctx)
+	cancel()
+	err = v.Validate(done, conf, "", map[string][]byte{"sink.crt": []byte("cert")})
+	if err == nil {
+		t.Error("Expected a dry run past the deadline to fail")
+	}
+}
+
+// stubValidator rejects the configs it is true for.
+type stubValidator func(conf string) bool
+
+func (v stubValidator) Validate(ctx context.Context, conf, parsers string, files map[string][]byte) error {
+	if v(conf) {
+		return errors.New("fluent-bit rejected the config: [error] unknown host broken.example.com")
+	}
+	return nil
+}
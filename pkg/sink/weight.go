@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// destinationKey is the field the balance filter sets to the index of the
+// destination a record of a weighted sink goes to. It is removed before the
+// record reaches the destination.
+const destinationKey = "_sink_destination"
+
+// ValidateWeights returns why the destinations of the sink cannot share its
+// records by weight or nil if they can. Either every destination has a
+// weight or none has one, and the implicit destination of the sink's own
+// address has none to give it.
+func ValidateWeights(spec v1alpha1.SinkSpec) error {
+	if !weighted(spec) {
+		return nil
+	}
+	if implicitDestination(spec) {
+		return fmt.Errorf("weights cannot be combined with the address of the sink, move it into destinations")
+	}
+	for i, d := range spec.Destinations {
+		if d.Weight <= 0 {
+			return fmt.Errorf("destination %d has a weight of %d, weights must be set on all destinations or none", i, d.Weight)
+		}
+	}
+	return nil
+}
+
+// weighted reports whether the sink shares its records between its
+// destinations rather than sending all of them to each.
+func weighted(spec v1alpha1.SinkSpec) bool {
+	for _, d := range spec.Destinations {
+		if d.Weight != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// destinationTag is the tag of the records the sink's stream hands to the
+// weighted destination with the index. Its second part cannot be ns or
+// cluster, so it never collides with the stream of another sink.
+func (e entry) destinationTag(index string) string {
+	return fmt.Sprintf("sink.destination.%s.%s", index, strings.TrimPrefix(e.tag(), "sink."))
+}
+
+// balanceFilters returns the filters moving every record of the sink's
+// stream to one of its destinations, weighted round-robin: of every sum of
+// the weights consecutive records, the i-th destination gets weights[i].
+func (e entry) balanceFilters(weights []int) []section {
+	var (
+		b     strings.Builder
+		total int
+	)
+	for i, w := range weights {
+		total += w
+		switch {
+		case i == 0:
+			fmt.Fprintf(&b, "if n <= %d then ", total)
+		case i == len(weights)-1:
+			b.WriteString("else ")
+		default:
+			fmt.Fprintf(&b, "elseif n <= %d then ", total)
+		}
+		fmt.Fprintf(&b, "record[%s] = %s ", luaString(destinationKey), luaString(strconv.Itoa(i)))
+	}
+	b.WriteString("end")
+
+	balance := newFilter("lua", e.match())
+	balance.add("call", "balance")
+	balance.add("code", fmt.Sprintf(
+		"local n = 0 function balance(tag, timestamp, record) n = n %% %d + 1 %s return 2, timestamp, record end",
+		total,
+		b.String(),
+	))
+
+	route := newFilter("rewrite_tag", e.match())
+	route.add("Rule", fmt.Sprintf("$%s ^([0-9]+)$ %s false", destinationKey, e.destinationTag("$1")))
+	addEmitterBuffer(&route, e.spec)
+
+	strip := newFilter("record_modifier", matchTag(e.destinationTag("*")))
+	strip.add("Remove_key", destinationKey)
+	return []section{balance, route, strip}
+}
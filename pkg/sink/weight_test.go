@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestWeightedDestinations(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertSink(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-name",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Destinations: []v1alpha1.Destination{
+				{Host: "example.com", Port: 514, Weight: 1},
+				{Host: "example.org", Port: 514, Weight: 3},
+				{Type: "http", Host: "logs.example.net", Weight: 2},
+			},
+		},
+	})
+
+	expected := "\n[FILTER]\n    Name rewrite_tag\n    Match kube.*_ns1_*\n    Rule $log .* sink.ns.ns1.some-name true\n" +
+		"\n[FILTER]\n    Name lua\n    Match sink.ns.ns1.some-name\n    call balance\n" +
+		`    code local n = 0 function balance(tag, timestamp, record) n = n % 6 + 1 ` +
+		`if n <= 1 then record["_sink_destination"] = "0" ` +
+		`elseif n <= 4 then record["_sink_destination"] = "1" ` +
+		`else record["_sink_destination"] = "2" end return 2, timestamp, record end` + "\n" +
+		"\n[FILTER]\n    Name rewrite_tag\n    Match sink.ns.ns1.some-name\n    Rule $_sink_destination ^([0-9]+)$ sink.destination.$1.ns.ns1.some-name false\n" +
+		"\n[FILTER]\n    Name record_modifier\n    Match sink.destination.*.ns.ns1.some-name\n    Remove_key _sink_destination\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.destination.0.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.com:514\"}]\n" +
+		"\n[OUTPUT]\n    Name syslog\n    Match sink.destination.1.ns.ns1.some-name\n    Sinks []\n    ClusterSinks [{\"addr\":\"example.org:514\"}]\n" +
+		"\n[OUTPUT]\n    Name http\n    Match sink.destination.2.ns.ns1.some-name\n    Host logs.example.net\n    Port 80\n    URI /\n    Format json_lines\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestUnweightedDestinations(t *testing.T) {
+	sc := sink.NewConfig()
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			Host: "example.com",
+			Port: 514,
+			Destinations: []v1alpha1.Destination{
+				{Host: "example.org", Port: 514},
+			},
+		},
+	})
+
+	// Every destination gets every record.
+	expected := "\n[OUTPUT]\n    Name syslog\n    Match *\n    Sinks []\n" +
+		"    ClusterSinks [{\"addr\":\"example.com:514\"},{\"addr\":\"example.org:514\"}]\n"
+	if sc.String() != expected {
+		t.Errorf("Config not equal: Expected: %q Actual: %q", expected, sc.String())
+	}
+}
+
+func TestInvalidWeights(t *testing.T) {
+	for _, spec := range []v1alpha1.SinkSpec{
+		{
+			Type: "syslog",
+			Destinations: []v1alpha1.Destination{
+				{Host: "example.com", Port: 514, Weight: 1},
+				{Host: "example.org", Port: 514},
+			},
+		},
+		{
+			Type: "syslog",
+			Destinations: []v1alpha1.Destination{
+				{Host: "example.com", Port: 514, Weight: -1},
+				{Host: "example.org", Port: 514, Weight: 2},
+			},
+		},
+		{
+			Type:         "syslog",
+			Host:         "example.com",
+			Port:         514,
+			Destinations: []v1alpha1.Destination{{Host: "example.org", Port: 514, Weight: 1}},
+		},
+	} {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-name",
+				Namespace: "ns1",
+			},
+			Spec: spec,
+		})
+
+		if sc.String() != emptyConfig {
+			t.Errorf("Empty Config not equal for destinations %+v: Expected: %s Actual: %s", spec.Destinations, emptyConfig, sc.String())
+		}
+		if err := sink.ValidateWeights(spec); err == nil || !strings.Contains(err.Error(), "weight") {
+			t.Errorf("Expected an error naming the weights of %+v, got %v", spec.Destinations, err)
+		}
+	}
+}
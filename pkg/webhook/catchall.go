@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sinkclient "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+)
+
+// WithClusterLogSinks has the validating webhook reject a catch-all
+// ClusterLogSink while another one exists, which it lists with
+// clusterSinks. Without it only the sink-controller keeps the second one
+// from being rendered.
+func WithClusterLogSinks(clusterSinks sinkclient.ClusterLogSinksGetter) Option {
+	return func(a *admission) {
+		a.clusterSinks = clusterSinks
+	}
+}
+
+// catchAllUnset returns a FieldError if a ClusterLogSink other than name
+// is the catch-all.
+func catchAllUnset(clusterSinks sinkclient.ClusterLogSinksGetter, name string) error {
+	list, err := clusterSinks.ClusterLogSinks("").List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, s := range list.Items {
+		if s.Spec.CatchAll && s.Name != name {
+			return FieldError{
+				"spec.catch_all",
+				fmt.Sprintf("ClusterLogSink %s is already the catch-all", s.Name),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink"
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// ConversionReview is the apiextensions.k8s.io/v1beta1 ConversionReview the
+// API server sends the conversion webhook of a CRD.
+type ConversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *ConversionRequest  `json:"request,omitempty"`
+	Response        *ConversionResponse `json:"response,omitempty"`
+}
+
+// ConversionRequest holds the objects to convert to DesiredAPIVersion.
+type ConversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+// ConversionResponse holds the converted objects in the order of the
+// request, or none if any of them failed to convert.
+type ConversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// convertibles are the served versions of the kinds the webhook converts.
+var convertibles = map[schema.GroupVersionKind]func() sink.Convertible{
+	v1alpha1.SchemeGroupVersion.WithKind("LogSink"):          func() sink.Convertible { return &v1alpha1.LogSink{} },
+	v1alpha1.SchemeGroupVersion.WithKind("ClusterLogSink"):   func() sink.Convertible { return &v1alpha1.ClusterLogSink{} },
+	v1alpha1.SchemeGroupVersion.WithKind("ClusterLogParser"): func() sink.Convertible { return &v1alpha1.ClusterLogParser{} },
+}
+
+// hubs are the versions the served versions of a kind convert through.
+var hubs = map[string]func() sink.Hub{
+	"LogSink":          func() sink.Hub { return &v1alpha1.LogSink{} },
+	"ClusterLogSink":   func() sink.Hub { return &v1alpha1.ClusterLogSink{} },
+	"ClusterLogParser": func() sink.Hub { return &v1alpha1.ClusterLogParser{} },
+}
+
+// ConversionHandler serves the conversion webhook for LogSinks,
+// ClusterLogSinks and ClusterLogParsers.
+type ConversionHandler struct{}
+
+// NewConversionHandler returns a ConversionHandler.
+func NewConversionHandler() *ConversionHandler {
+	return &ConversionHandler{}
+}
+
+func (h *ConversionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("unable to read conversion review: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var review ConversionReview
+	err = json.Unmarshal(body, &review)
+	if err != nil || review.Request == nil {
+		log.Printf("unable to decode conversion review: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	review.Response = Convert(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(review)
+	if err != nil {
+		log.Printf("unable to write conversion review: %s", err)
+	}
+}
+
+// Convert converts every object in the request to the desired version
+// through the hub of its kind.
+func Convert(req *ConversionRequest) *ConversionResponse {
+	converted := make([]runtime.RawExtension, 0, len(req.Objects))
+	for _, obj := range req.Objects {
+		raw, err := convert(obj.Raw, req.DesiredAPIVersion)
+		if err != nil {
+			return &ConversionResponse{
+				Result: metav1.Status{
+					Status:  metav1.StatusFailure,
+					Message: err.Error(),
+				},
+			}
+		}
+		converted = append(converted, runtime.RawExtension{Raw: raw})
+	}
+	return &ConversionResponse{
+		ConvertedObjects: converted,
+		Result:           metav1.Status{Status: metav1.StatusSuccess},
+	}
+}
+
+func convert(raw []byte, desiredAPIVersion string) ([]byte, error) {
+	var meta metav1.TypeMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("unable to decode object: %s", err)
+	}
+	gv, err := schema.ParseGroupVersion(meta.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := schema.ParseGroupVersion(desiredAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	from, ok := convertibles[gv.WithKind(meta.Kind)]
+	if !ok {
+		return nil, fmt.Errorf("unable to convert %s %s", meta.APIVersion, meta.Kind)
+	}
+	to, ok := convertibles[desired.WithKind(meta.Kind)]
+	if !ok {
+		return nil, fmt.Errorf("unable to convert %s to %s", meta.Kind, desiredAPIVersion)
+	}
+
+	src := from()
+	if err := json.Unmarshal(raw, src); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %s", meta.Kind, err)
+	}
+	hub := hubs[meta.Kind]()
+	if err := src.ConvertTo(hub); err != nil {
+		return nil, err
+	}
+	dst := to()
+	if err := dst.ConvertFrom(hub); err != nil {
+		return nil, err
+	}
+	dst.GetObjectKind().SetGroupVersionKind(desired.WithKind(meta.Kind))
+	return json.Marshal(dst)
+}
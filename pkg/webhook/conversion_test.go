@@ -0,0 +1,195 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestConvert(t *testing.T) {
+	objs := []interface{}{
+		v1alpha1.LogSink{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "LogSink"},
+			ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "ns1"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+		},
+		v1alpha1.ClusterLogSink{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "ClusterLogSink"},
+			ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+			Spec:       v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Stream: "stderr"},
+		},
+		v1alpha1.ClusterLogParser{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "ClusterLogParser"},
+			ObjectMeta: metav1.ObjectMeta{Name: "some-name"},
+			Spec:       v1alpha1.ParserSpec{Format: "json"},
+		},
+	}
+	req := &webhook.ConversionRequest{DesiredAPIVersion: "observability.knative.dev/v1alpha1"}
+	for _, obj := range objs {
+		req.Objects = append(req.Objects, rawObject(t, obj))
+	}
+
+	resp := webhook.Convert(req)
+
+	if resp.Result.Status != metav1.StatusSuccess {
+		t.Fatalf("Expected conversion to succeed: %s", resp.Result.Message)
+	}
+	if len(resp.ConvertedObjects) != len(objs) {
+		t.Fatalf("Converted objects not equal: Expected: %d Actual: %d", len(objs), len(resp.ConvertedObjects))
+	}
+	// v1alpha1 converts to itself unchanged.
+	for i, obj := range resp.ConvertedObjects {
+		var expected, actual map[string]interface{}
+		mustUnmarshal(t, req.Objects[i].Raw, &expected)
+		mustUnmarshal(t, obj.Raw, &actual)
+		if diff := cmp.Diff(expected, actual); diff != "" {
+			t.Errorf("Converted object not equal (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestConvertUnknownVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		obj     metav1.TypeMeta
+		desired string
+	}{
+		{
+			"unknown desired version",
+			metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "LogSink"},
+			"observability.knative.dev/v1beta1",
+		},
+		{
+			"unknown object version",
+			metav1.TypeMeta{APIVersion: "observability.knative.dev/v1beta1", Kind: "LogSink"},
+			"observability.knative.dev/v1alpha1",
+		},
+		{
+			"unknown kind",
+			metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "MetricSink"},
+			"observability.knative.dev/v1alpha1",
+		},
+		{
+			"invalid desired version",
+			metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "LogSink"},
+			"observability.knative.dev/v1alpha1/v1beta1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := webhook.Convert(&webhook.ConversionRequest{
+				DesiredAPIVersion: test.desired,
+				Objects: []runtime.RawExtension{
+					rawObject(t, v1alpha1.LogSink{TypeMeta: metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "LogSink"}}),
+					rawObject(t, test.obj),
+				},
+			})
+
+			if resp.Result.Status != metav1.StatusFailure {
+				t.Errorf("Expected conversion to fail")
+			}
+			if len(resp.ConvertedObjects) != 0 {
+				t.Errorf("Expected no converted objects: %d", len(resp.ConvertedObjects))
+			}
+		})
+	}
+}
+
+func TestConversionHandler(t *testing.T) {
+	body, err := json.Marshal(webhook.ConversionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "ConversionReview"},
+		Request: &webhook.ConversionRequest{
+			UID:               "some-uid",
+			DesiredAPIVersion: "observability.knative.dev/v1alpha1",
+			Objects: []runtime.RawExtension{
+				rawObject(t, v1alpha1.LogSink{
+					TypeMeta: metav1.TypeMeta{APIVersion: "observability.knative.dev/v1alpha1", Kind: "LogSink"},
+					Spec:     v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+				}),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	webhook.NewConversionHandler().ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body)),
+	)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code: %d", recorder.Code)
+	}
+	var review webhook.ConversionReview
+	mustUnmarshal(t, recorder.Body.Bytes(), &review)
+	if review.Kind != "ConversionReview" {
+		t.Errorf("Kind not equal: Expected: ConversionReview, Actual: %s", review.Kind)
+	}
+	if review.Request != nil {
+		t.Errorf("Expected the request to be dropped")
+	}
+	if review.Response == nil {
+		t.Fatalf("Expected a response")
+	}
+	if review.Response.UID != "some-uid" {
+		t.Errorf("UID not equal: Expected: some-uid, Actual: %s", review.Response.UID)
+	}
+	if len(review.Response.ConvertedObjects) != 1 {
+		t.Errorf("Expected one converted object: %d", len(review.Response.ConvertedObjects))
+	}
+}
+
+func TestConversionHandlerBadRequest(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	webhook.NewConversionHandler().ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodPost, "/convert", strings.NewReader("not json")),
+	)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status code: %d", recorder.Code)
+	}
+}
+
+func rawObject(t *testing.T, obj interface{}) runtime.RawExtension {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func mustUnmarshal(t *testing.T, data []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
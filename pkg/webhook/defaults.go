@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+// The defaults filled into the unset optional fields of sinks.
+const (
+	DefaultProtocol     = "tcp"
+	DefaultSyslogFormat = v1alpha1.SyslogFormatRFC5424
+	DefaultFormat       = v1alpha1.FormatJSONLines
+	DefaultRetryLimit   = 5
+	// DefaultFlushTimeoutSeconds gives slow receivers time to respond
+	// while a stuck one fails its flush well before fluent-bit's next.
+	DefaultFlushTimeoutSeconds = 30
+)
+
+// jsonPatch is a single JSON Patch operation.
+type jsonPatch struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// Default returns the response patching the defaults into the unset
+// optional fields of the sink in the request, so they are stored with the
+// sink rather than applied when it is rendered. Only creates and updates
+// are defaulted, anything else is allowed as it is.
+func Default(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return allowed()
+	}
+
+	var spec v1alpha1.SinkSpec
+	switch req.Kind.Kind {
+	case "LogSink":
+		var s v1alpha1.LogSink
+		if err := json.Unmarshal(req.Object.Raw, &s); err != nil {
+			return denied(fmt.Sprintf("unable to decode LogSink: %s", err))
+		}
+		spec = s.Spec
+	case "ClusterLogSink":
+		var s v1alpha1.ClusterLogSink
+		if err := json.Unmarshal(req.Object.Raw, &s); err != nil {
+			return denied(fmt.Sprintf("unable to decode ClusterLogSink: %s", err))
+		}
+		spec = s.Spec
+	default:
+		return allowed()
+	}
+
+	patches := defaultPatches(spec)
+	if len(patches) == 0 {
+		return allowed()
+	}
+	patch, err := json.Marshal(patches)
+	if err != nil {
+		return denied(fmt.Sprintf("unable to encode defaults: %s", err))
+	}
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	resp := allowed()
+	resp.Patch = patch
+	resp.PatchType = &patchType
+	return resp
+}
+
+// defaultPatches returns the patches adding the defaults of the fields the
+// spec leaves unset. Protocol and SyslogFormat only apply to syslog sinks,
+// Protocol not to those with a SocketPath, and Format to http sinks.
+// Splunk sinks with a Host default to the port of the HTTP Event
+// Collector, forward and gelf sinks with a Host to the port of their
+// inputs, gelf sinks to udp and datadog sinks to the site of the US1
+// region. A RetryLimit of zero would keep fluent-bit's single retry and
+// a FlushTimeoutSeconds of zero waits on a stuck receiver indefinitely.
+func defaultPatches(spec v1alpha1.SinkSpec) []jsonPatch {
+	var patches []jsonPatch
+	add := func(field string, value interface{}) {
+		patches = append(patches, jsonPatch{Op: "add", Path: "/spec/" + field, Value: value})
+	}
+	switch spec.Type {
+	case v1alpha1.SinkTypeSyslog:
+		// A socket has no protocol to pick.
+		if spec.Protocol == "" && spec.SocketPath == "" {
+			add("protocol", DefaultProtocol)
+		}
+		if spec.SyslogFormat == "" {
+			add("syslog_format", DefaultSyslogFormat)
+		}
+	case v1alpha1.SinkTypeHTTP:
+		if spec.Format == "" {
+			add("format", DefaultFormat)
+		}
+	case v1alpha1.SinkTypeSplunk:
+		if spec.Host != "" && spec.Port == 0 {
+			add("port", sink.DefaultSplunkPort)
+		}
+	case v1alpha1.SinkTypeForward:
+		if spec.Host != "" && spec.Port == 0 {
+			add("port", sink.DefaultForwardPort)
+		}
+	case v1alpha1.SinkTypeGELF:
+		if spec.Host != "" && spec.Port == 0 {
+			add("port", sink.DefaultGELFPort)
+		}
+		if spec.Mode == "" {
+			add("mode", sink.DefaultGELFMode)
+		}
+	case v1alpha1.SinkTypeDatadog:
+		if spec.Site == "" {
+			add("site", sink.DefaultDatadogSite)
+		}
+	}
+	if spec.RetryLimit == 0 {
+		add("retry_limit", DefaultRetryLimit)
+	}
+	if spec.FlushTimeoutSeconds == 0 {
+		add("flush_timeout_seconds", DefaultFlushTimeoutSeconds)
+	}
+	return patches
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/go-cmp/cmp"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestDefault(t *testing.T) {
+	var tests = []struct {
+		name     string
+		spec     v1alpha1.SinkSpec
+		expected v1alpha1.SinkSpec
+	}{
+		{
+			"syslog",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+			v1alpha1.SinkSpec{
+				Type:                "syslog",
+				Host:                "example.com",
+				Port:                514,
+				Protocol:            "tcp",
+				SyslogFormat:        "rfc5424",
+				RetryLimit:          5,
+				FlushTimeoutSeconds: 30,
+			},
+		},
+		{
+			"http",
+			v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs"},
+			v1alpha1.SinkSpec{
+				Type:                "http",
+				URI:                 "https://example.com/logs",
+				Format:              "json_lines",
+				RetryLimit:          5,
+				FlushTimeoutSeconds: 30,
+			},
+		},
+		{
+			"otlp",
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "collector:4318"},
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "collector:4318", RetryLimit: 5, FlushTimeoutSeconds: 30},
+		},
+		{
+			"splunk",
+			v1alpha1.SinkSpec{Type: "splunk", Host: "hec.example.com"},
+			v1alpha1.SinkSpec{Type: "splunk", Host: "hec.example.com", Port: 8088, RetryLimit: 5, FlushTimeoutSeconds: 30},
+		},
+		{
+			"forward",
+			v1alpha1.SinkSpec{Type: "forward", Host: "fluentd.logging"},
+			v1alpha1.SinkSpec{Type: "forward", Host: "fluentd.logging", Port: 24224, RetryLimit: 5, FlushTimeoutSeconds: 30},
+		},
+		{
+			"gelf",
+			v1alpha1.SinkSpec{Type: "gelf", Host: "graylog.logging"},
+			v1alpha1.SinkSpec{Type: "gelf", Host: "graylog.logging", Port: 12201, Mode: "udp", RetryLimit: 5, FlushTimeoutSeconds: 30},
+		},
+		{
+			"datadog",
+			v1alpha1.SinkSpec{Type: "datadog"},
+			v1alpha1.SinkSpec{Type: "datadog", Site: "datadoghq.com", RetryLimit: 5, FlushTimeoutSeconds: 30},
+		},
+		{
+			"set fields",
+			v1alpha1.SinkSpec{
+				Type:                "syslog",
+				Host:                "example.com",
+				Port:                514,
+				Protocol:            "udp",
+				SyslogFormat:        "rfc3164",
+				RetryLimit:          -1,
+				FlushTimeoutSeconds: 5,
+			},
+			v1alpha1.SinkSpec{
+				Type:                "syslog",
+				Host:                "example.com",
+				Port:                514,
+				Protocol:            "udp",
+				SyslogFormat:        "rfc3164",
+				RetryLimit:          -1,
+				FlushTimeoutSeconds: 5,
+			},
+		},
+	}
+	for _, test := range tests {
+		for _, kind := range []string{"LogSink", "ClusterLogSink"} {
+			t.Run(kind+" "+test.name, func(t *testing.T) {
+				req := request(t, kind, admissionv1beta1.Create, test.spec)
+				resp := webhook.Default(req)
+				if !resp.Allowed {
+					t.Fatalf("Expected sink to be allowed: %v", resp.Result)
+				}
+
+				if diff := cmp.Diff(test.expected, defaulted(t, req.Object.Raw, resp)); diff != "" {
+					t.Errorf("Spec not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestDefaultUpdate(t *testing.T) {
+	req := request(t, "LogSink", admissionv1beta1.Update, v1alpha1.SinkSpec{Type: "otlp", Endpoint: "collector:4318"})
+	resp := webhook.Default(req)
+
+	expected := v1alpha1.SinkSpec{Type: "otlp", Endpoint: "collector:4318", RetryLimit: 5, FlushTimeoutSeconds: 30}
+	if diff := cmp.Diff(expected, defaulted(t, req.Object.Raw, resp)); diff != "" {
+		t.Errorf("Spec not equal (-want, +got) = %v", diff)
+	}
+}
+
+func TestDefaultDelete(t *testing.T) {
+	req := &admissionv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "LogSink"},
+		Operation: admissionv1beta1.Delete,
+	}
+	resp := webhook.Default(req)
+	if !resp.Allowed || resp.Patch != nil {
+		t.Errorf("Expected delete to be allowed without a patch: %+v", resp)
+	}
+}
+
+func TestDefaultingHandler(t *testing.T) {
+	req := request(t, "ClusterLogSink", admissionv1beta1.Create, v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514})
+	req.UID = "some-uid"
+	body, err := json.Marshal(admissionv1beta1.AdmissionReview{Request: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	webhook.NewDefaultingHandler().ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodPost, "/default", bytes.NewReader(body)),
+	)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code: %d", recorder.Code)
+	}
+	var review admissionv1beta1.AdmissionReview
+	err = json.Unmarshal(recorder.Body.Bytes(), &review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.Response == nil {
+		t.Fatalf("Expected a response")
+	}
+	if review.Response.UID != "some-uid" {
+		t.Errorf("UID not equal: Expected: some-uid, Actual: %s", review.Response.UID)
+	}
+	if review.Response.PatchType == nil || *review.Response.PatchType != admissionv1beta1.PatchTypeJSONPatch {
+		t.Errorf("Expected a JSON patch: %+v", review.Response)
+	}
+}
+
+// defaulted applies the patch of the response to the sink and returns its
+// spec.
+func defaulted(t *testing.T, raw []byte, resp *admissionv1beta1.AdmissionResponse) v1alpha1.SinkSpec {
+	t.Helper()
+	if resp.Patch != nil {
+		patch, err := jsonpatch.DecodePatch(resp.Patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err = patch.Apply(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var s v1alpha1.LogSink
+	if err := json.Unmarshal(raw, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s.Spec
+}
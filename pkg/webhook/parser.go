@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	sinkclient "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+)
+
+// WithClusterLogParsers has the validating webhook reject sinks
+// referencing a ClusterLogParser that does not exist, which it gets with
+// parsers. Without it the sink-controller leaves such sinks out until the
+// parser is created.
+func WithClusterLogParsers(parsers sinkclient.ClusterLogParsersGetter) Option {
+	return func(a *admission) {
+		a.parsers = parsers
+	}
+}
+
+// parsersExist returns the FieldErrors of the ClusterLogParsers the sink
+// references that do not exist.
+func parsersExist(parsers sinkclient.ClusterLogParsersGetter, spec v1alpha1.SinkSpec) (FieldErrors, error) {
+	type ref struct{ field, name string }
+	var refs []ref
+	if spec.ParserName != "" {
+		refs = append(refs, ref{"spec.parser_name", spec.ParserName})
+	}
+	for i, name := range spec.ParserNames {
+		refs = append(refs, ref{fmt.Sprintf("spec.parser_names[%d]", i), name})
+	}
+
+	var errs FieldErrors
+	for _, r := range refs {
+		_, err := parsers.ClusterLogParsers("").Get(r.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			errs = append(errs, FieldError{r.field, fmt.Sprintf("ClusterLogParser %s not found", r.name)})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to get ClusterLogParser %s: %s", r.name, err)
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	sinkclient "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+// SkipPreflightAnnotation set to "true" admits a sink without connecting
+// to its receivers, for receivers the sink-webhook cannot reach.
+const SkipPreflightAnnotation = "observability.knative.dev/skipPreflight"
+
+// Dialer connects to the receivers of sinks. A net.Dialer with a Timeout
+// keeps admission from waiting on unresponsive receivers.
+type Dialer interface {
+	Dial(network, address string) (net.Conn, error)
+}
+
+// Option configures the validating webhook.
+type Option func(*admission)
+
+type admission struct {
+	dialer       Dialer
+	clusterSinks sinkclient.ClusterLogSinksGetter
+	parsers      sinkclient.ClusterLogParsersGetter
+	socketDirs   []string
+}
+
+// WithPreflight has the validating webhook only admit sinks once it was
+// able to open a TCP connection to each of their receivers with d.
+func WithPreflight(d Dialer) Option {
+	return func(a *admission) {
+		a.dialer = d
+	}
+}
+
+// dialed reports whether admitting the sink connects to its receivers.
+// Disabled sinks send to none. Updates only dial when they change the
+// receivers or enable the sink, so status, label and other edits are
+// admitted while a receiver is down.
+func dialed(req *admissionv1beta1.AdmissionRequest, spec v1alpha1.SinkSpec) bool {
+	if spec.Disabled {
+		return false
+	}
+	if req.Operation != admissionv1beta1.Update {
+		return true
+	}
+	var old struct {
+		Spec v1alpha1.SinkSpec `json:"spec"`
+	}
+	if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil || old.Spec.Disabled {
+		return true
+	}
+	return !reflect.DeepEqual(receiverAddresses(old.Spec), receiverAddresses(spec))
+}
+
+// preflight returns a FieldError for the first receiver of the sink that
+// cannot be connected to.
+func preflight(d Dialer, spec v1alpha1.SinkSpec) error {
+	for _, addr := range receiverAddresses(spec) {
+		conn, err := d.Dial("tcp", addr)
+		if err != nil {
+			return FieldError{
+				"spec",
+				fmt.Sprintf(
+					"unable to connect to %s: %s, annotate the sink with %s: \"true\" if the sink-webhook cannot reach it",
+					addr,
+					err,
+					SkipPreflightAnnotation,
+				),
+			}
+		}
+		conn.Close()
+	}
+	return nil
+}
+
+// receiverAddresses returns the host:port of every receiver of the valid
+// sink.
+func receiverAddresses(spec v1alpha1.SinkSpec) []string {
+	var addrs []string
+	for _, d := range sink.Destinations(spec) {
+		switch d.Type {
+		case v1alpha1.SinkTypeHTTP:
+			addrs = append(addrs, urlAddress(d.URI))
+		case v1alpha1.SinkTypeLoki:
+			addrs = append(addrs, urlAddress(d.URL))
+		case v1alpha1.SinkTypeOTLP:
+			addrs = append(addrs, d.Endpoint)
+		case v1alpha1.SinkTypeKafka:
+			addrs = append(addrs, d.Brokers...)
+		case v1alpha1.SinkTypeS3:
+			addrs = append(addrs, sink.S3Endpoint(d.Region))
+		case v1alpha1.SinkTypeSplunk:
+			if sink.TemplatedHost(d.Host) {
+				continue
+			}
+			addrs = append(addrs, sink.HostPort(d.Host, sink.SplunkPort(d.Port)))
+		case v1alpha1.SinkTypeDatadog:
+			addrs = append(addrs, sink.HostPort(sink.DatadogHost(d.Site), 443))
+		case v1alpha1.SinkTypeForward:
+			if sink.TemplatedHost(d.Host) {
+				continue
+			}
+			addrs = append(addrs, sink.HostPort(d.Host, sink.ForwardPort(d.Port)))
+		case v1alpha1.SinkTypeGELF:
+			// A UDP input cannot be dialed.
+			if sink.GELFMode(d.Mode) == sink.GELFModeUDP || sink.TemplatedHost(d.Host) {
+				continue
+			}
+			addrs = append(addrs, sink.HostPort(d.Host, sink.GELFPort(d.Port)))
+		default:
+			// Sockets are only on the nodes, as are the variables of
+			// templated hosts.
+			if d.SocketPath != "" || sink.TemplatedHost(d.Host) {
+				continue
+			}
+			addrs = append(addrs, sink.HostPort(d.Host, d.Port))
+		}
+	}
+	return addrs
+}
+
+// urlAddress returns the host and port fluent-bit sends to for the URL,
+// the port defaults to that of its scheme.
+func urlAddress(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+// WithSocketDirs has the validating webhook only admit ClusterLogSinks
+// whose socket_path is in or below one of the directories, the
+// sink-controller does not render the others. Without it no socket_path is
+// admitted.
+func WithSocketDirs(dirs []string) Option {
+	return func(a *admission) {
+		a.socketDirs = dirs
+	}
+}
@@ -0,0 +1,708 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+// FieldError describes why the value of a single field is invalid.
+type FieldError struct {
+	Field  string
+	Detail string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Detail)
+}
+
+// FieldErrors is every problem found with a spec.
+type FieldErrors []FieldError
+
+func (errs FieldErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateSinkSpec returns the problems that would keep the sink from being
+// rendered into the fluent-bit config or nil if there are none.
+func ValidateSinkSpec(spec v1alpha1.SinkSpec) error {
+	var errs FieldErrors
+	if !knownType(spec.Type) {
+		errs = append(errs, unknownType("spec.type", spec.Type))
+	}
+
+	// With destinations the top level address is optional, but one that
+	// is partly set must still be complete.
+	implicit := len(spec.Destinations) == 0 ||
+		spec.Host != "" ||
+		spec.Port != 0 ||
+		spec.URI != "" ||
+		spec.Endpoint != "" ||
+		len(spec.Brokers) != 0 ||
+		spec.URL != "" ||
+		spec.Bucket != "" ||
+		spec.Site != "" ||
+		spec.SocketPath != ""
+	switch {
+	case !implicit:
+	case spec.SocketPath != "":
+		if err := sink.ValidateSocketPath(spec); err != nil {
+			errs = append(errs, FieldError{"spec.socket_path", err.Error()})
+		}
+	case spec.Type == v1alpha1.SinkTypeSyslog, spec.Type == v1alpha1.SinkTypeElasticsearch:
+		errs = append(errs, validateAddress("spec", spec.Host, spec.Port)...)
+	case spec.Type == v1alpha1.SinkTypeHTTP:
+		if spec.URI == "" {
+			errs = append(errs, FieldError{"spec.uri", "must not be empty"})
+		}
+	case spec.Type == v1alpha1.SinkTypeOTLP:
+		if _, _, err := sink.SplitEndpoint(spec.Endpoint); err != nil {
+			errs = append(errs, FieldError{"spec.endpoint", err.Error()})
+		}
+	case spec.Type == v1alpha1.SinkTypeKafka:
+		if err := sink.ValidateBrokers(spec.Brokers); err != nil {
+			errs = append(errs, FieldError{"spec.brokers", err.Error()})
+		}
+	case spec.Type == v1alpha1.SinkTypeLoki:
+		if err := sink.ValidateLokiURL(spec.URL); err != nil {
+			errs = append(errs, FieldError{"spec.url", err.Error()})
+		}
+	case spec.Type == v1alpha1.SinkTypeS3:
+		errs = append(errs, validateS3(spec)...)
+	case spec.Type == v1alpha1.SinkTypeSplunk, spec.Type == v1alpha1.SinkTypeForward, spec.Type == v1alpha1.SinkTypeGELF:
+		if err := sink.ValidateHost(spec.Host); err != nil {
+			errs = append(errs, FieldError{"spec.host", err.Error()})
+		}
+		if spec.Port < 0 || spec.Port > 65535 {
+			errs = append(errs, portError("spec", spec.Port))
+		}
+	case spec.Type == v1alpha1.SinkTypeDatadog:
+		errs = append(errs, validateDatadog(spec)...)
+	}
+
+	if hasDestination(spec, v1alpha1.SinkTypeElasticsearch) {
+		errs = append(errs, validateIndex(spec)...)
+	}
+	if hasDestination(spec, v1alpha1.SinkTypeKafka) {
+		if err := sink.ValidateTopic(spec.Topic); err != nil {
+			errs = append(errs, FieldError{"spec.topic", err.Error()})
+		}
+	}
+	if hasDestination(spec, v1alpha1.SinkTypeLoki) {
+		errs = append(errs, validateLoki(spec)...)
+	}
+	if hasDestination(spec, v1alpha1.SinkTypeSplunk) {
+		errs = append(errs, validateSplunk(spec)...)
+	}
+	if hasDestination(spec, v1alpha1.SinkTypeForward) {
+		if err := sink.ValidateSelfHostname(spec.SelfHostname); err != nil {
+			errs = append(errs, FieldError{"spec.self_hostname", err.Error()})
+		}
+	}
+	if hasDestination(spec, v1alpha1.SinkTypeGELF) {
+		errs = append(errs, validateGELF(spec)...)
+	}
+	if spec.SecretRef != nil {
+		errs = append(errs, validateSecretRef(spec)...)
+	}
+	if spec.TLSSecretRef != nil {
+		errs = append(errs, validateTLSSecretRef(spec)...)
+	}
+	for _, d := range sink.Destinations(spec) {
+		if err := sink.ValidateTLSServerName(d); err != nil {
+			errs = append(errs, FieldError{"spec.tls_server_name", err.Error()})
+			break
+		}
+	}
+	if err := sink.ValidateCompression(spec); err != nil {
+		errs = append(errs, FieldError{"spec.compression", err.Error()})
+	}
+
+	switch spec.SyslogFormat {
+	case "", v1alpha1.SyslogFormatRFC3164, v1alpha1.SyslogFormatRFC5424:
+	default:
+		errs = append(errs, FieldError{
+			"spec.syslog_format",
+			fmt.Sprintf(
+				"unknown syslog format %q, must be one of %s, %s",
+				spec.SyslogFormat,
+				v1alpha1.SyslogFormatRFC3164,
+				v1alpha1.SyslogFormatRFC5424,
+			),
+		})
+	}
+	if err := sink.ValidateMessageTemplate(spec.MessageTemplate); err != nil {
+		errs = append(errs, FieldError{"spec.message_template", err.Error()})
+	}
+	if err := sink.ValidateSyslogFacility(spec.SyslogFacility); err != nil {
+		errs = append(errs, FieldError{"spec.syslog_facility", err.Error()})
+	}
+	if err := sink.ValidateSyslogSeverity(spec.DefaultSeverity); err != nil {
+		errs = append(errs, FieldError{"spec.default_severity", err.Error()})
+	}
+
+	if spec.ParserName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.ParserName) {
+			errs = append(errs, FieldError{"spec.parser_name", msg})
+		}
+	}
+	valid := true
+	for i, name := range spec.ParserNames {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.parser_names[%d]", i), msg})
+			valid = false
+		}
+	}
+	if valid {
+		if err := sink.ValidateParserNames(spec.ParserNames); err != nil {
+			errs = append(errs, FieldError{"spec.parser_names", err.Error()})
+		}
+	}
+
+	if spec.Multiline != nil {
+		if err := sink.ValidateMultiline(*spec.Multiline); err != nil {
+			errs = append(errs, FieldError{"spec.multiline", err.Error()})
+		}
+	}
+
+	for i, p := range spec.DropPatterns {
+		if err := sink.ValidateDropPattern(p); err != nil {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.drop_patterns[%d]", i), err.Error()})
+		}
+	}
+	for i, r := range spec.RedactPatterns {
+		if err := sink.ValidateRedactRule(r); err != nil {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.redact_patterns[%d].pattern", i), err.Error()})
+		}
+	}
+
+	if spec.TimeKey != "" || spec.TimeFormat != "" {
+		if err := sink.ValidateTimeKey(spec.TimeKey); err != nil {
+			errs = append(errs, FieldError{"spec.time_key", err.Error()})
+		}
+		if err := sink.ValidateTimeFormat(spec.TimeFormat); err != nil {
+			errs = append(errs, FieldError{"spec.time_format", err.Error()})
+		}
+	}
+
+	if spec.MinSeverity != "" {
+		if err := sink.ValidateSeverity(spec.MinSeverity); err != nil {
+			errs = append(errs, FieldError{"spec.min_severity", err.Error()})
+		} else if !spec.ParseJSON && spec.ParserName == "" && len(spec.ParserNames) == 0 {
+			errs = append(errs, FieldError{"spec.min_severity", "requires spec.parse_json, spec.parser_name or spec.parser_names"})
+		}
+	}
+	if spec.MatchExpr != nil {
+		if err := sink.ValidateMatchExpr(*spec.MatchExpr); err != nil {
+			errs = append(errs, FieldError{"spec.match_expr", err.Error()})
+		}
+	}
+
+	if err := sink.ValidateNodeNameKey(spec); err != nil {
+		errs = append(errs, FieldError{"spec.node_name_key", err.Error()})
+	}
+
+	for i, f := range spec.MetadataFields {
+		if err := sink.ValidateMetadataField(f); err != nil {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.metadata_fields[%d]", i), err.Error()})
+		}
+	}
+
+	keys := make([]string, 0, len(spec.Labels))
+	for k := range spec.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		field := fmt.Sprintf("spec.labels[%s]", k)
+		v := spec.Labels[k]
+		switch {
+		case k == "":
+			errs = append(errs, FieldError{"spec.labels", "keys must not be empty"})
+		case strings.ContainsAny(k, " \t\r\n"):
+			errs = append(errs, FieldError{field, "key must not contain whitespace"})
+		case sink.ReservedRecordKey(k):
+			errs = append(errs, FieldError{field, "key collides with the kubernetes metadata of the record"})
+		case strings.TrimSpace(v) == "":
+			errs = append(errs, FieldError{field, "value must not be empty"})
+		case strings.ContainsAny(v, "\r\n"):
+			errs = append(errs, FieldError{field, "value must be a single line"})
+		}
+	}
+
+	keys = keys[:0]
+	for k := range spec.RenameKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	targets := make(map[string]string, len(spec.RenameKeys))
+	for _, k := range keys {
+		field := fmt.Sprintf("spec.rename_keys[%s]", k)
+		v := spec.RenameKeys[k]
+		if err := sink.ValidateRenameKey(k); err != nil {
+			errs = append(errs, FieldError{"spec.rename_keys", err.Error()})
+			continue
+		}
+		if err := sink.ValidateRenameKey(v); err != nil {
+			errs = append(errs, FieldError{field, err.Error()})
+			continue
+		}
+		if prev, ok := targets[v]; ok {
+			errs = append(errs, FieldError{field, fmt.Sprintf("%q is already the new name of spec.rename_keys[%s]", v, prev)})
+			continue
+		}
+		targets[v] = k
+	}
+
+	outputFields := make(map[string]bool, len(spec.OutputFields))
+	for i, f := range spec.OutputFields {
+		field := fmt.Sprintf("spec.output_fields[%d]", i)
+		if err := sink.ValidateOutputField(f); err != nil {
+			errs = append(errs, FieldError{field, err.Error()})
+			continue
+		}
+		if outputFields[f] {
+			errs = append(errs, FieldError{field, fmt.Sprintf("%q is listed more than once", f)})
+		}
+		outputFields[f] = true
+	}
+
+	for _, f := range sink.ExclusiveFields(spec) {
+		errs = append(errs, FieldError{"spec." + f[0], "must not be set with spec." + f[1]})
+	}
+
+	for i, ns := range spec.ExcludeNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.exclude_namespaces[%d]", i), msg})
+		}
+	}
+
+	for i, g := range spec.NamespaceGlobs {
+		if err := sink.ValidateNamespaceGlob(g); err != nil {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.namespace_globs[%d]", i), err.Error()})
+		}
+	}
+
+	if len(spec.SystemNamespaces) != 0 && !spec.SystemOnly {
+		errs = append(errs, FieldError{"spec.system_namespaces", "requires spec.system_only"})
+	}
+	for i, ns := range spec.SystemNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.system_namespaces[%d]", i), msg})
+		}
+	}
+
+	if err := sink.ValidateSource(spec); err != nil {
+		errs = append(errs, FieldError{"spec.source", err.Error()})
+	}
+
+	if err := sink.ValidateStream(spec.Stream); err != nil {
+		errs = append(errs, FieldError{"spec.stream", err.Error()})
+	}
+
+	keys = keys[:0]
+	for k := range spec.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := sink.ValidateHeader(k, spec.Headers[k]); err != nil {
+			errs = append(errs, FieldError{"spec.headers", err.Error()})
+		}
+	}
+
+	if err := sink.ValidatePodSelector(spec.PodSelector); err != nil {
+		errs = append(errs, FieldError{"spec.pod_selector", err.Error()})
+	}
+
+	if err := sink.ValidateNodeSelector(spec.NodeSelector); err != nil {
+		errs = append(errs, FieldError{"spec.node_selector", err.Error()})
+	}
+
+	if err := sink.ValidateHostPaths(spec.HostPaths); err != nil {
+		errs = append(errs, FieldError{"spec.host_paths", err.Error()})
+	}
+
+	if spec.MaxRecordsPerSecond < 0 {
+		errs = append(errs, FieldError{
+			"spec.max_records_per_second",
+			fmt.Sprintf("must not be negative, got %d", spec.MaxRecordsPerSecond),
+		})
+	}
+
+	if spec.SampleRate != nil {
+		if err := sink.ValidateSampleRate(*spec.SampleRate); err != nil {
+			errs = append(errs, FieldError{"spec.sample_rate", err.Error()})
+		}
+	}
+
+	if err := sink.ValidateDedupWindow(spec.DedupWindowSeconds); err != nil {
+		errs = append(errs, FieldError{"spec.dedup_window_seconds", err.Error()})
+	}
+
+	if err := sink.ValidateMaxMessageBytes(spec.MaxMessageBytes); err != nil {
+		errs = append(errs, FieldError{"spec.max_message_bytes", err.Error()})
+	}
+
+	if err := sink.ValidateKeepAlive(spec.KeepAliveSeconds); err != nil {
+		errs = append(errs, FieldError{"spec.keepalive_seconds", err.Error()})
+	}
+	if err := sink.ValidateFlushTimeout(spec.FlushTimeoutSeconds); err != nil {
+		errs = append(errs, FieldError{"spec.flush_timeout_seconds", err.Error()})
+	}
+
+	if err := sink.ValidateEncoding(spec.Encoding); err != nil {
+		errs = append(errs, FieldError{"spec.encoding", err.Error()})
+	}
+
+	if err := sink.ValidateTag(spec.Tag); err != nil {
+		errs = append(errs, FieldError{"spec.tag", err.Error()})
+	}
+
+	weighted := false
+	for _, d := range spec.Destinations {
+		weighted = weighted || d.Weight != 0
+	}
+	for i, d := range spec.Destinations {
+		field := fmt.Sprintf("spec.destinations[%d]", i)
+		t := d.Type
+		if t == "" {
+			t = spec.Type
+		}
+		switch t {
+		case v1alpha1.SinkTypeSyslog, v1alpha1.SinkTypeOTLP, v1alpha1.SinkTypeElasticsearch, v1alpha1.SinkTypeKafka:
+			errs = append(errs, validateAddress(field, d.Host, d.Port)...)
+		case v1alpha1.SinkTypeHTTP, v1alpha1.SinkTypeLoki, v1alpha1.SinkTypeSplunk, v1alpha1.SinkTypeForward, v1alpha1.SinkTypeGELF:
+			if err := sink.ValidateHost(d.Host); err != nil {
+				errs = append(errs, FieldError{field + ".host", err.Error()})
+			}
+			// The host is placed in a URL, which cannot hold a reference.
+			if (t == v1alpha1.SinkTypeHTTP || t == v1alpha1.SinkTypeLoki) && sink.TemplatedHost(d.Host) {
+				errs = append(errs, FieldError{
+					field + ".host",
+					fmt.Sprintf("destinations of type %s do not support environment variables", t),
+				})
+			}
+			if d.Port < 0 || d.Port > 65535 {
+				errs = append(errs, portError(field, d.Port))
+			}
+		case v1alpha1.SinkTypeS3:
+			errs = append(errs, FieldError{field, "sinks of type s3 upload to spec.bucket and have no destinations"})
+		case v1alpha1.SinkTypeDatadog:
+			errs = append(errs, FieldError{field, "sinks of type datadog ship to spec.site and have no destinations"})
+		default:
+			if d.Type != "" {
+				errs = append(errs, unknownType(field+".type", d.Type))
+			}
+		}
+		if weighted && d.Weight <= 0 {
+			errs = append(errs, FieldError{
+				field + ".weight",
+				fmt.Sprintf("must be positive when another destination has a weight, got %d", d.Weight),
+			})
+		}
+	}
+	if weighted && implicit {
+		errs = append(errs, FieldError{
+			"spec.destinations",
+			"weights cannot be combined with the address of the sink, move it into spec.destinations",
+		})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateParserSpec returns FieldErrors for the fields of the
+// ClusterLogParser spec that cannot be rendered into a parser.
+func ValidateParserSpec(spec v1alpha1.ParserSpec) error {
+	var errs FieldErrors
+	switch spec.Format {
+	case v1alpha1.ParserFormatRegex:
+		if err := sink.ValidateParserRegex(spec.Regex); err != nil {
+			errs = append(errs, FieldError{"spec.regex", err.Error()})
+		}
+	case v1alpha1.ParserFormatJSON:
+		if spec.Regex != "" {
+			errs = append(errs, FieldError{
+				"spec.regex",
+				fmt.Sprintf("is only supported by parsers of format %s", v1alpha1.ParserFormatRegex),
+			})
+		}
+	default:
+		errs = append(errs, FieldError{
+			"spec.format",
+			fmt.Sprintf(
+				"unknown parser format %q, must be one of %s, %s",
+				spec.Format,
+				v1alpha1.ParserFormatRegex,
+				v1alpha1.ParserFormatJSON,
+			),
+		})
+	}
+	if err := sink.ValidateParserKeyName(spec.KeyName); err != nil {
+		errs = append(errs, FieldError{"spec.key_name", err.Error()})
+	}
+	if spec.TimeKey != "" || spec.TimeFormat != "" {
+		if err := sink.ValidateTimeKey(spec.TimeKey); err != nil {
+			errs = append(errs, FieldError{"spec.time_key", err.Error()})
+		}
+		if err := sink.ValidateTimeFormat(spec.TimeFormat); err != nil {
+			errs = append(errs, FieldError{"spec.time_format", err.Error()})
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateSecretRef(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	ref := spec.SecretRef
+	if spec.Type == v1alpha1.SinkTypeSyslog {
+		errs = append(errs, FieldError{
+			"spec.secret_ref",
+			fmt.Sprintf(
+				"is only supported by sinks of type %s, %s, %s, %s, %s, %s, %s, %s and %s",
+				v1alpha1.SinkTypeHTTP,
+				v1alpha1.SinkTypeOTLP,
+				v1alpha1.SinkTypeElasticsearch,
+				v1alpha1.SinkTypeKafka,
+				v1alpha1.SinkTypeLoki,
+				v1alpha1.SinkTypeS3,
+				v1alpha1.SinkTypeSplunk,
+				v1alpha1.SinkTypeDatadog,
+				v1alpha1.SinkTypeForward,
+			),
+		})
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+		errs = append(errs, FieldError{"spec.secret_ref.name", msg})
+	}
+	for _, msg := range validation.IsConfigMapKey(ref.Key) {
+		errs = append(errs, FieldError{"spec.secret_ref.key", msg})
+	}
+	for k := range spec.Headers {
+		if strings.EqualFold(k, "Authorization") {
+			errs = append(errs, FieldError{
+				fmt.Sprintf("spec.headers[%s]", k),
+				"must not be set with spec.secret_ref",
+			})
+		}
+	}
+	return errs
+}
+
+func validateTLSSecretRef(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	ref := spec.TLSSecretRef
+	if !spec.EnableTLS {
+		errs = append(errs, FieldError{"spec.enable_tls", "must be true with spec.tls_secret_ref"})
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+		errs = append(errs, FieldError{"spec.tls_secret_ref.name", msg})
+	}
+	if ref.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(ref.Namespace) {
+			errs = append(errs, FieldError{"spec.tls_secret_ref.namespace", msg})
+		}
+	}
+	return errs
+}
+
+// hasDestination reports whether the sink or any of its destinations is of
+// the type.
+func hasDestination(spec v1alpha1.SinkSpec, t string) bool {
+	for _, d := range sink.Destinations(spec) {
+		if d.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+func validateIndex(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateIndex(spec.Index); err != nil {
+		errs = append(errs, FieldError{"spec.index", err.Error()})
+	}
+	if err := sink.ValidateIndexDateFormat(spec.IndexDateFormat); err != nil {
+		errs = append(errs, FieldError{"spec.index_date_format", err.Error()})
+	} else if sink.TemplatedIndex(spec.Index) && spec.IndexDateFormat == "" {
+		errs = append(errs, FieldError{"spec.index_date_format", "must be set when spec.index is a template"})
+	}
+	if err := sink.ValidatePipeline(spec.Pipeline); err != nil {
+		errs = append(errs, FieldError{"spec.pipeline", err.Error()})
+	}
+	return errs
+}
+
+func validateLoki(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateTenantID(spec.TenantID); err != nil {
+		errs = append(errs, FieldError{"spec.tenant_id", err.Error()})
+	}
+	valid := true
+	for i, k := range spec.LabelKeys {
+		if err := sink.ValidateLabelKey(k); err != nil {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.label_keys[%d]", i), err.Error()})
+			valid = false
+		}
+	}
+	if valid {
+		if err := sink.ValidateLabelKeys(spec.LabelKeys); err != nil {
+			errs = append(errs, FieldError{"spec.label_keys", err.Error()})
+		}
+	}
+	return errs
+}
+
+func validateS3(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateBucket(spec.Bucket); err != nil {
+		errs = append(errs, FieldError{"spec.bucket", err.Error()})
+	}
+	if err := sink.ValidateRegion(spec.Region); err != nil {
+		errs = append(errs, FieldError{"spec.region", err.Error()})
+	}
+	if err := sink.ValidateTotalFileSize(spec.TotalFileSizeMB); err != nil {
+		errs = append(errs, FieldError{"spec.total_file_size_mb", err.Error()})
+	}
+	if spec.UploadTimeoutSeconds < 0 {
+		errs = append(errs, FieldError{
+			"spec.upload_timeout_seconds",
+			fmt.Sprintf("must not be negative, got %d", spec.UploadTimeoutSeconds),
+		})
+	}
+	return errs
+}
+
+func validateSplunk(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateSplunkIndex(spec.Index); err != nil {
+		errs = append(errs, FieldError{"spec.index", err.Error()})
+	}
+	if err := sink.ValidateSourceType(spec.SourceType); err != nil {
+		errs = append(errs, FieldError{"spec.source_type", err.Error()})
+	}
+	if spec.SecretRef == nil {
+		errs = append(errs, FieldError{"spec.secret_ref", "must reference the HEC token of sinks of type splunk"})
+	}
+	return errs
+}
+
+func validateDatadog(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateDatadogSite(spec.Site); err != nil {
+		errs = append(errs, FieldError{"spec.site", err.Error()})
+	}
+	if err := sink.ValidateDatadogAttribute(spec.Service); err != nil {
+		errs = append(errs, FieldError{"spec.service", err.Error()})
+	}
+	if err := sink.ValidateDatadogAttribute(spec.DDSource); err != nil {
+		errs = append(errs, FieldError{"spec.dd_source", err.Error()})
+	}
+	for i, t := range spec.Tags {
+		if err := sink.ValidateDatadogTag(t); err != nil {
+			errs = append(errs, FieldError{fmt.Sprintf("spec.tags[%d]", i), err.Error()})
+		}
+	}
+	if spec.SecretRef == nil {
+		errs = append(errs, FieldError{"spec.secret_ref", "must reference the API key of sinks of type datadog"})
+	}
+	return errs
+}
+
+func validateGELF(spec v1alpha1.SinkSpec) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateGELFMode(spec.Mode); err != nil {
+		errs = append(errs, FieldError{"spec.mode", err.Error()})
+	} else if spec.EnableTLS && sink.GELFMode(spec.Mode) != sink.GELFModeTLS {
+		errs = append(errs, FieldError{"spec.enable_tls", fmt.Sprintf("requires spec.mode %s for sinks of type gelf", sink.GELFModeTLS)})
+	}
+	if err := sink.ValidateGELFKeys(sink.GELFKeys(spec.GELFKeys)); err != nil {
+		errs = append(errs, FieldError{"spec.gelf_keys", err.Error()})
+	}
+	return errs
+}
+
+func knownType(t string) bool {
+	return t == v1alpha1.SinkTypeSyslog ||
+		t == v1alpha1.SinkTypeHTTP ||
+		t == v1alpha1.SinkTypeOTLP ||
+		t == v1alpha1.SinkTypeElasticsearch ||
+		t == v1alpha1.SinkTypeKafka ||
+		t == v1alpha1.SinkTypeLoki ||
+		t == v1alpha1.SinkTypeS3 ||
+		t == v1alpha1.SinkTypeSplunk ||
+		t == v1alpha1.SinkTypeDatadog ||
+		t == v1alpha1.SinkTypeForward ||
+		t == v1alpha1.SinkTypeGELF
+}
+
+func validateAddress(field, host string, port int) FieldErrors {
+	var errs FieldErrors
+	if err := sink.ValidateHost(host); err != nil {
+		errs = append(errs, FieldError{field + ".host", err.Error()})
+	}
+	if port < 1 || port > 65535 {
+		errs = append(errs, portError(field, port))
+	}
+	return errs
+}
+
+func portError(field string, port int) FieldError {
+	return FieldError{
+		field + ".port",
+		fmt.Sprintf("must be between 1 and 65535, got %d", port),
+	}
+}
+
+func unknownType(field, t string) FieldError {
+	return FieldError{
+		field,
+		fmt.Sprintf(
+			"unknown sink type %q, must be one of %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s",
+			t,
+			v1alpha1.SinkTypeSyslog,
+			v1alpha1.SinkTypeHTTP,
+			v1alpha1.SinkTypeOTLP,
+			v1alpha1.SinkTypeElasticsearch,
+			v1alpha1.SinkTypeKafka,
+			v1alpha1.SinkTypeLoki,
+			v1alpha1.SinkTypeS3,
+			v1alpha1.SinkTypeSplunk,
+			v1alpha1.SinkTypeDatadog,
+			v1alpha1.SinkTypeForward,
+			v1alpha1.SinkTypeGELF,
+		),
+	}
+}
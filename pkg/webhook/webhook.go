@@ -0,0 +1,351 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	coreV1Types "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+// Handler serves an admission webhook for LogSinks, ClusterLogSinks and
+// ClusterLogParsers.
+type Handler struct {
+	admit func(*admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse
+}
+
+// NewHandler returns a Handler serving the validating webhook that looks up
+// the Secrets sinks reference with secrets.
+func NewHandler(secrets coreV1.SecretsGetter, opts ...Option) *Handler {
+	return &Handler{
+		admit: func(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+			return Admit(req, secrets, opts...)
+		},
+	}
+}
+
+// NewDefaultingHandler returns a Handler serving the mutating webhook that
+// fills in the defaults of sinks.
+func NewDefaultingHandler() *Handler {
+	return &Handler{
+		admit: Default,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("unable to read admission review: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var review admissionv1beta1.AdmissionReview
+	err = json.Unmarshal(body, &review)
+	if err != nil || review.Request == nil {
+		log.Printf("unable to decode admission review: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	review.Response = h.admit(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(review)
+	if err != nil {
+		log.Printf("unable to write admission review: %s", err)
+	}
+}
+
+// Admit decides whether the sink in the request is allowed. Only creates and
+// updates are validated, anything else is allowed. The Secrets a sink
+// references must exist when it is admitted, and with WithPreflight its
+// receivers must accept connections unless it is annotated with
+// SkipPreflightAnnotation. With WithClusterLogSinks a ClusterLogSink only
+// becomes the catch-all while there is none, with WithClusterLogParsers the
+// ClusterLogParsers a sink references must exist and a socket_path must be
+// in one of the directories of WithSocketDirs.
+func Admit(
+	req *admissionv1beta1.AdmissionRequest,
+	secrets coreV1.SecretsGetter,
+	opts ...Option,
+) *admissionv1beta1.AdmissionResponse {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return allowed()
+	}
+
+	var a admission
+	for _, o := range opts {
+		o(&a)
+	}
+
+	var (
+		spec      v1alpha1.SinkSpec
+		meta      metav1.ObjectMeta
+		namespace string
+	)
+	switch req.Kind.Kind {
+	case "LogSink":
+		var s v1alpha1.LogSink
+		if err := json.Unmarshal(req.Object.Raw, &s); err != nil {
+			return denied(fmt.Sprintf("unable to decode LogSink: %s", err))
+		}
+		spec = s.Spec
+		meta = s.ObjectMeta
+		namespace = req.Namespace
+		if namespace == "" {
+			namespace = s.Namespace
+		}
+	case "ClusterLogSink":
+		var s v1alpha1.ClusterLogSink
+		if err := json.Unmarshal(req.Object.Raw, &s); err != nil {
+			return denied(fmt.Sprintf("unable to decode ClusterLogSink: %s", err))
+		}
+		spec = s.Spec
+		meta = s.ObjectMeta
+	case "ClusterLogParser":
+		return admitParser(req)
+	default:
+		return allowed()
+	}
+
+	var errs FieldErrors
+	if err := ValidateSinkSpec(spec); err != nil {
+		fes, ok := err.(FieldErrors)
+		if !ok {
+			fes = FieldErrors{{"spec", err.Error()}}
+		}
+		errs = fes
+	}
+	if req.Kind.Kind == "LogSink" && len(spec.ExcludeNamespaces) != 0 {
+		errs = append(errs, FieldError{
+			"spec.exclude_namespaces",
+			"is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "LogSink" && len(spec.NamespaceGlobs) != 0 {
+		errs = append(errs, FieldError{
+			"spec.namespace_globs",
+			"is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "LogSink" && spec.Source == v1alpha1.LogSourceAudit {
+		errs = append(errs, FieldError{
+			"spec.source",
+			"audit is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "LogSink" && len(spec.HostPaths) != 0 {
+		errs = append(errs, FieldError{
+			"spec.host_paths",
+			"is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "LogSink" && spec.SocketPath != "" {
+		errs = append(errs, FieldError{
+			"spec.socket_path",
+			"is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "ClusterLogSink" && spec.SocketPath != "" {
+		if err := sink.SocketPathAllowed(spec.SocketPath, a.socketDirs); err != nil {
+			errs = append(errs, FieldError{"spec.socket_path", err.Error()})
+		}
+	}
+	if req.Kind.Kind == "LogSink" && spec.SystemOnly {
+		errs = append(errs, FieldError{
+			"spec.system_only",
+			"is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "LogSink" && spec.CatchAll {
+		errs = append(errs, FieldError{
+			"spec.catch_all",
+			"is only supported by ClusterLogSinks",
+		})
+	}
+	if req.Kind.Kind == "ClusterLogSink" && spec.CatchAll && a.clusterSinks != nil {
+		err := catchAllUnset(a.clusterSinks, meta.Name)
+		if fe, ok := err.(FieldError); ok {
+			errs = append(errs, fe)
+		} else if err != nil {
+			return denied(fmt.Sprintf("unable to list ClusterLogSinks: %s", err))
+		}
+	}
+	if req.Kind.Kind == "ClusterLogSink" && spec.ExclusiveMatch {
+		errs = append(errs, FieldError{
+			"spec.exclusive_match",
+			"is only supported by LogSinks",
+		})
+	}
+	if ref := spec.SecretRef; ref != nil {
+		switch {
+		case req.Kind.Kind == "LogSink" && ref.Namespace != "":
+			errs = append(errs, FieldError{
+				"spec.secret_ref.namespace",
+				"is only supported by ClusterLogSinks, LogSinks reference Secrets in their namespace",
+			})
+		case req.Kind.Kind == "ClusterLogSink" && ref.Namespace == "":
+			errs = append(errs, FieldError{"spec.secret_ref.namespace", "must not be empty"})
+		case req.Kind.Kind == "ClusterLogSink":
+			namespace = ref.Namespace
+		}
+		if len(errs) == 0 {
+			err := secretKeyExists(secrets, namespace, ref)
+			if fe, ok := err.(FieldError); ok {
+				errs = append(errs, fe)
+			} else if err != nil {
+				return denied(fmt.Sprintf("unable to get secret %s/%s: %s", namespace, ref.Name, err))
+			}
+		}
+	}
+	if ref := spec.TLSSecretRef; ref != nil {
+		tlsNamespace := namespace
+		switch {
+		case req.Kind.Kind == "LogSink" && ref.Namespace != "":
+			errs = append(errs, FieldError{
+				"spec.tls_secret_ref.namespace",
+				"is only supported by ClusterLogSinks, LogSinks reference Secrets in their namespace",
+			})
+		case req.Kind.Kind == "ClusterLogSink" && ref.Namespace == "":
+			errs = append(errs, FieldError{"spec.tls_secret_ref.namespace", "must not be empty"})
+		case req.Kind.Kind == "ClusterLogSink":
+			tlsNamespace = ref.Namespace
+		}
+		if len(errs) == 0 {
+			err := tlsSecretExists(secrets, tlsNamespace, ref)
+			if fe, ok := err.(FieldError); ok {
+				errs = append(errs, fe)
+			} else if err != nil {
+				return denied(fmt.Sprintf("unable to get secret %s/%s: %s", tlsNamespace, ref.Name, err))
+			}
+		}
+	}
+	if a.parsers != nil && len(errs) == 0 {
+		fes, err := parsersExist(a.parsers, spec)
+		if err != nil {
+			return denied(err.Error())
+		}
+		errs = append(errs, fes...)
+	}
+	// Only valid sinks are dialed, their addresses are known to parse.
+	if a.dialer != nil && len(errs) == 0 && meta.Annotations[SkipPreflightAnnotation] != "true" && dialed(req, spec) {
+		err := preflight(a.dialer, spec)
+		if fe, ok := err.(FieldError); ok {
+			errs = append(errs, fe)
+		} else if err != nil {
+			errs = append(errs, FieldError{"spec", err.Error()})
+		}
+	}
+	if len(errs) != 0 {
+		return denied(fmt.Sprintf("invalid %s: %s", req.Kind.Kind, errs))
+	}
+	return allowed()
+}
+
+// admitParser decides whether the ClusterLogParser in the request is
+// allowed. Parsers sinks reference may still be deleted, the
+// sink-controller leaves those sinks out until the parser is created again.
+func admitParser(req *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	var p v1alpha1.ClusterLogParser
+	if err := json.Unmarshal(req.Object.Raw, &p); err != nil {
+		return denied(fmt.Sprintf("unable to decode ClusterLogParser: %s", err))
+	}
+	if err := ValidateParserSpec(p.Spec); err != nil {
+		return denied(fmt.Sprintf("invalid ClusterLogParser: %s", err))
+	}
+	return allowed()
+}
+
+// tlsSecretExists returns a FieldError if the Secret does not exist or does
+// not hold a certificate and its key.
+func tlsSecretExists(secrets coreV1.SecretsGetter, namespace string, ref *v1alpha1.SecretReference) error {
+	s, err := secrets.Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return FieldError{
+			"spec.tls_secret_ref.name",
+			fmt.Sprintf("secret %s/%s not found", namespace, ref.Name),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, k := range []string{coreV1Types.TLSCertKey, coreV1Types.TLSPrivateKeyKey} {
+		if len(s.Data[k]) == 0 {
+			return FieldError{
+				"spec.tls_secret_ref.name",
+				fmt.Sprintf("secret %s/%s has no key %s", namespace, ref.Name, k),
+			}
+		}
+	}
+	return nil
+}
+
+// secretKeyExists returns a FieldError if the Secret or its key does not
+// exist.
+func secretKeyExists(secrets coreV1.SecretsGetter, namespace string, ref *v1alpha1.SecretKeyRef) error {
+	s, err := secrets.Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return FieldError{
+			"spec.secret_ref.name",
+			fmt.Sprintf("secret %s/%s not found", namespace, ref.Name),
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := s.Data[ref.Key]; !ok {
+		return FieldError{
+			"spec.secret_ref.key",
+			fmt.Sprintf("secret %s/%s has no key %s", namespace, ref.Name, ref.Key),
+		}
+	}
+	return nil
+}
+
+func allowed() *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{Allowed: true}
+}
+
+func denied(msg string) *admissionv1beta1.AdmissionResponse {
+	return &admissionv1beta1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: msg,
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}
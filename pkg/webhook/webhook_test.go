@@ -0,0 +1,2244 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestAdmit(t *testing.T) {
+	var tests = []struct {
+		name    string
+		spec    v1alpha1.SinkSpec
+		allowed bool
+		fields  []string
+	}{
+		{
+			"valid syslog sink",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514},
+			true,
+			nil,
+		},
+		{
+			"valid http sink",
+			v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs"},
+			true,
+			nil,
+		},
+		{
+			"valid otlp sink",
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "otel-collector:4318", Insecure: true},
+			true,
+			nil,
+		},
+		{
+			"otlp header value with newline",
+			v1alpha1.SinkSpec{
+				Type:     "otlp",
+				Endpoint: "otel-collector:4318",
+				Headers:  map[string]string{"X-Scope-OrgID": "tenant\r\n[OUTPUT]"},
+			},
+			false,
+			[]string{"spec.headers"},
+		},
+		{
+			"otlp header name with whitespace",
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "otel-collector:4318", Headers: map[string]string{"X-Scope-OrgID tenant": "a"}},
+			false,
+			[]string{"spec.headers"},
+		},
+		{
+			"otlp sink without port",
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "otel-collector"},
+			false,
+			[]string{"spec.endpoint"},
+		},
+		{
+			"otlp sink with bad port",
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "otel-collector:0"},
+			false,
+			[]string{"spec.endpoint"},
+		},
+		{
+			"otlp destination without port",
+			v1alpha1.SinkSpec{
+				Type:         "otlp",
+				Destinations: []v1alpha1.Destination{{Host: "otel-collector"}},
+			},
+			false,
+			[]string{"spec.destinations[0].port"},
+		},
+		{
+			"empty host",
+			v1alpha1.SinkSpec{Type: "syslog", Port: 514},
+			false,
+			[]string{"spec.host"},
+		},
+		{
+			"port zero",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com"},
+			false,
+			[]string{"spec.port"},
+		},
+		{
+			"port too high",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 65536},
+			false,
+			[]string{"spec.port"},
+		},
+		{
+			"empty host and port",
+			v1alpha1.SinkSpec{Type: "syslog"},
+			false,
+			[]string{"spec.host", "spec.port"},
+		},
+		{
+			"http sink without uri",
+			v1alpha1.SinkSpec{Type: "http"},
+			false,
+			[]string{"spec.uri"},
+		},
+		{
+			"http header name with whitespace",
+			v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs", Headers: map[string]string{"X-Scope\n[OUTPUT]": "a"}},
+			false,
+			[]string{"spec.headers"},
+		},
+		{
+			"http header value with newline",
+			v1alpha1.SinkSpec{
+				Type:    "http",
+				URI:     "https://example.com/logs",
+				Headers: map[string]string{"X-Scope": "tenant\n[OUTPUT]\n    Name stdout\n    Match *"},
+			},
+			false,
+			[]string{"spec.headers"},
+		},
+		{
+			"destinations only",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Destinations: []v1alpha1.Destination{
+					{Host: "example.com", Port: 514},
+					{Type: "http", Host: "example.org"},
+				},
+			},
+			true,
+			nil,
+		},
+		{
+			"destination without host",
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         514,
+				Destinations: []v1alpha1.Destination{{Port: 514}},
+			},
+			false,
+			[]string{"spec.destinations[0].host"},
+		},
+		{
+			"destination with bad port and type",
+			v1alpha1.SinkSpec{
+				Type: "http",
+				Destinations: []v1alpha1.Destination{
+					{Type: "syslog", Host: "example.com"},
+					{Type: "graylog", Host: "example.com", Port: 12201},
+				},
+			},
+			false,
+			[]string{"spec.destinations[0].port", "spec.destinations[1].type"},
+		},
+		{
+			"partial address with destinations",
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Port:         514,
+				Destinations: []v1alpha1.Destination{{Host: "example.com", Port: 514}},
+			},
+			false,
+			[]string{"spec.host"},
+		},
+		{
+			"negative max records per second",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MaxRecordsPerSecond: -1},
+			false,
+			[]string{"spec.max_records_per_second"},
+		},
+		{
+			"node selector",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, NodeSelector: map[string]string{"accelerator": "gpu"}},
+			true,
+			nil,
+		},
+		{
+			"pod selector label key breaking out of the quoting",
+			v1alpha1.SinkSpec{
+				Type:        "syslog",
+				Host:        "example.com",
+				Port:        514,
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"a']['b": "web"}},
+			},
+			false,
+			[]string{"spec.pod_selector"},
+		},
+		{
+			"pod selector label value with newline",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.com",
+				Port: 514,
+				PodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "app", Operator: "In", Values: []string{"web\n[OUTPUT]\n    Name stdout\n    Match *"}},
+					},
+				},
+			},
+			false,
+			[]string{"spec.pod_selector"},
+		},
+		{
+			"pod selector unknown operator",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.com",
+				Port: 514,
+				PodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Like"}},
+				},
+			},
+			false,
+			[]string{"spec.pod_selector"},
+		},
+		{
+			"invalid node selector",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, NodeSelector: map[string]string{"accelerator": "gpu nodes"}},
+			false,
+			[]string{"spec.node_selector"},
+		},
+		{
+			"syslog facility and severity",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SyslogFacility: "local4", DefaultSeverity: "warning"},
+			true,
+			nil,
+		},
+		{
+			"unknown syslog facility and severity",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SyslogFacility: "local8", DefaultSeverity: "warn"},
+			false,
+			[]string{"spec.syslog_facility", "spec.default_severity"},
+		},
+		{
+			"stdout stream",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Stream: "stdout"},
+			true,
+			nil,
+		},
+		{
+			"unknown stream",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Stream: "stdin"},
+			false,
+			[]string{"spec.stream"},
+		},
+		{
+			"negative dedup window",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, DedupWindowSeconds: -1},
+			false,
+			[]string{"spec.dedup_window_seconds"},
+		},
+		{
+			"rfc3164 with message template",
+			v1alpha1.SinkSpec{
+				Type:            "syslog",
+				Host:            "example.com",
+				Port:            514,
+				SyslogFormat:    "rfc3164",
+				AppName:         "my-app",
+				MessageTemplate: "{{kubernetes.pod_name}} {{ kubernetes.labels.app }}: {{log}}",
+			},
+			true,
+			nil,
+		},
+		{
+			"unknown syslog format",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SyslogFormat: "rfc822"},
+			false,
+			[]string{"spec.syslog_format"},
+		},
+		{
+			"unknown message template placeholder",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MessageTemplate: "{{user}}: {{log}}"},
+			false,
+			[]string{"spec.message_template"},
+		},
+		{
+			"unterminated message template placeholder",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MessageTemplate: "{{log"},
+			false,
+			[]string{"spec.message_template"},
+		},
+		{
+			"invalid excluded namespace",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ExcludeNamespaces: []string{"kube-system", "Not_A_Namespace"}},
+			false,
+			[]string{"spec.exclude_namespaces[1]"},
+		},
+		{
+			"invalid namespace glob",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, NamespaceGlobs: []string{"team-*", "team_*"}},
+			false,
+			[]string{"spec.namespace_globs[1]"},
+		},
+		{
+			"invalid system namespace",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SystemOnly: true, SystemNamespaces: []string{"kube-system", "Istio_System"}},
+			false,
+			[]string{"spec.system_namespaces[1]"},
+		},
+		{
+			"system namespaces without system only",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SystemNamespaces: []string{"kube-system"}},
+			false,
+			[]string{"spec.system_namespaces"},
+		},
+		{
+			"system only with namespace globs",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SystemOnly: true, NamespaceGlobs: []string{"team-*"}},
+			false,
+			[]string{"spec.system_only"},
+		},
+		{
+			"relative socket path",
+			v1alpha1.SinkSpec{Type: "syslog", SocketPath: "run/collector/syslog.sock"},
+			false,
+			[]string{"spec.socket_path"},
+		},
+		{
+			"socket path with host and port",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SocketPath: "/var/run/collector/syslog.sock"},
+			false,
+			[]string{"spec.socket_path"},
+		},
+		{
+			"min severity",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParseJSON: true, MinSeverity: "warning"},
+			true,
+			nil,
+		},
+		{
+			"unknown min severity",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParseJSON: true, MinSeverity: "severe"},
+			false,
+			[]string{"spec.min_severity"},
+		},
+		{
+			"min severity without parsing",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MinSeverity: "warning"},
+			false,
+			[]string{"spec.min_severity"},
+		},
+		{
+			"rename keys",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, RenameKeys: map[string]string{"log": "message", "level": "severity"}},
+			true,
+			nil,
+		},
+		{
+			"empty rename key",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, RenameKeys: map[string]string{"log": " "}},
+			false,
+			[]string{"spec.rename_keys[log]"},
+		},
+		{
+			"keys renamed to the same key",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, RenameKeys: map[string]string{"log": "message", "msg": "message"}},
+			false,
+			[]string{"spec.rename_keys[msg]"},
+		},
+		{
+			"unknown source",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Source: "journald"},
+			false,
+			[]string{"spec.source"},
+		},
+		{
+			"audit source with namespace globs",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Source: "audit", NamespaceGlobs: []string{"team-*"}},
+			false,
+			[]string{"spec.source"},
+		},
+		{
+			"audit source with system only",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Source: "audit", SystemOnly: true},
+			false,
+			[]string{"spec.source"},
+		},
+		{
+			"namespace globs with host paths",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, NamespaceGlobs: []string{"team-*"}, HostPaths: []string{"/data/app/*.log"}},
+			false,
+			[]string{"spec.host_paths"},
+		},
+		{
+			"labels",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Labels: map[string]string{"cluster_name": "us-east-1", "environment": "production"}},
+			true,
+			nil,
+		},
+		{
+			"empty label key",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Labels: map[string]string{"": "production"}},
+			false,
+			[]string{"spec.labels"},
+		},
+		{
+			"label colliding with kubernetes metadata",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Labels: map[string]string{"kubernetes": "x", "stream": "stdout"}},
+			false,
+			[]string{"spec.labels[kubernetes]", "spec.labels[stream]"},
+		},
+		{
+			"empty label value",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Labels: map[string]string{"environment": " "}},
+			false,
+			[]string{"spec.labels[environment]"},
+		},
+		{
+			"multiline",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Multiline: &v1alpha1.Multiline{StartPattern: `\S`, MaxLines: 200}},
+			true,
+			nil,
+		},
+		{
+			"invalid multiline start pattern",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Multiline: &v1alpha1.Multiline{StartPattern: "(at"}},
+			false,
+			[]string{"spec.multiline"},
+		},
+		{
+			"gzip compression",
+			v1alpha1.SinkSpec{Type: "otlp", Endpoint: "collector:4318", Compression: "gzip"},
+			true,
+			nil,
+		},
+		{
+			"compression on syslog sink",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Compression: "gzip"},
+			false,
+			[]string{"spec.compression"},
+		},
+		{
+			"unknown compression",
+			v1alpha1.SinkSpec{Type: "http", URI: "https://example.com", Compression: "zstd"},
+			false,
+			[]string{"spec.compression"},
+		},
+		{
+			"exclusive match with pod selector",
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				ExclusiveMatch: true,
+				PodSelector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			false,
+			[]string{"spec.exclusive_match"},
+		},
+		{
+			"drop patterns",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, DropPatterns: []string{`\b(?:\d{4}[ -]?){3}\d{4}\b`}},
+			true,
+			nil,
+		},
+		{
+			"invalid drop pattern",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, DropPatterns: []string{"card", "(\\d{16}"}},
+			false,
+			[]string{"spec.drop_patterns[1]"},
+		},
+		{
+			"redact patterns",
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				RedactPatterns: []v1alpha1.RedactRule{{Pattern: `[a-z.]+@example\.com`, Replacement: "***"}},
+			},
+			true,
+			nil,
+		},
+		{
+			"unsupported redact pattern",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.com",
+				Port: 514,
+				RedactPatterns: []v1alpha1.RedactRule{
+					{Pattern: "token=\\S+", Replacement: "token=***"},
+					{Pattern: "(user|admin)@", Replacement: "***@"},
+				},
+			},
+			false,
+			[]string{"spec.redact_patterns[1].pattern"},
+		},
+		{
+			"tls secret ref without tls",
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         6514,
+				TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+			},
+			false,
+			[]string{"spec.enable_tls"},
+		},
+		{
+			"insecure otlp sink with a tls secret ref",
+			v1alpha1.SinkSpec{
+				Type:         "otlp",
+				Endpoint:     "otel-collector:4318",
+				EnableTLS:    true,
+				Insecure:     true,
+				TLSSecretRef: &v1alpha1.SecretReference{Name: "Client_Cert"},
+			},
+			false,
+			[]string{"spec.insecure", "spec.tls_secret_ref.name"},
+		},
+		{
+			"metadata fields",
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				MetadataFields: []string{"pod_name", "namespace_name", "labels"},
+			},
+			true,
+			nil,
+		},
+		{
+			"unknown metadata field",
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				MetadataFields: []string{"pod_name", "pod_ip"},
+			},
+			false,
+			[]string{"spec.metadata_fields[1]"},
+		},
+		{
+			"elasticsearch",
+			v1alpha1.SinkSpec{
+				Type:            "elasticsearch",
+				Host:            "es.example.com",
+				Port:            9200,
+				Index:           "logs",
+				IndexDateFormat: "%Y.%m.%d",
+			},
+			true,
+			nil,
+		},
+		{
+			"invalid elasticsearch index",
+			v1alpha1.SinkSpec{
+				Type:            "elasticsearch",
+				Port:            9200,
+				Index:           "Logs",
+				IndexDateFormat: "%B",
+				Pipeline:        "add cluster",
+			},
+			false,
+			[]string{"spec.host", "spec.index", "spec.index_date_format", "spec.pipeline"},
+		},
+		{
+			"elasticsearch index template",
+			v1alpha1.SinkSpec{
+				Type:            "elasticsearch",
+				Host:            "es.example.com",
+				Port:            9200,
+				Index:           "logs-${kubernetes.namespace_name}",
+				IndexDateFormat: "%Y.%m.%d",
+			},
+			true,
+			nil,
+		},
+		{
+			"elasticsearch index template without date format",
+			v1alpha1.SinkSpec{
+				Type:  "elasticsearch",
+				Host:  "es.example.com",
+				Port:  9200,
+				Index: "logs-${kubernetes.namespace_name}",
+			},
+			false,
+			[]string{"spec.index_date_format"},
+		},
+		{
+			"elasticsearch index template with unknown key",
+			v1alpha1.SinkSpec{
+				Type:            "elasticsearch",
+				Host:            "es.example.com",
+				Port:            9200,
+				Index:           "logs-${namespace}",
+				IndexDateFormat: "%Y.%m.%d",
+			},
+			false,
+			[]string{"spec.index"},
+		},
+		{
+			"s3 bucket template",
+			v1alpha1.SinkSpec{
+				Type:   "s3",
+				Bucket: "logs-${kubernetes.namespace_name}",
+				Region: "us-east-1",
+			},
+			false,
+			[]string{"spec.bucket"},
+		},
+		{
+			"elasticsearch destination without index",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.com",
+				Port: 514,
+				Destinations: []v1alpha1.Destination{
+					{Type: "elasticsearch", Host: "es.example.com", Port: 9200},
+				},
+			},
+			false,
+			[]string{"spec.index"},
+		},
+		{
+			"kafka",
+			v1alpha1.SinkSpec{
+				Type:    "kafka",
+				Brokers: []string{"kafka-0.kafka:9092", "kafka-1.kafka:9092"},
+				Topic:   "logs",
+			},
+			true,
+			nil,
+		},
+		{
+			"kafka without brokers or topic",
+			v1alpha1.SinkSpec{Type: "kafka"},
+			false,
+			[]string{"spec.brokers", "spec.topic"},
+		},
+		{
+			"invalid kafka broker",
+			v1alpha1.SinkSpec{Type: "kafka", Brokers: []string{"kafka-0.kafka"}, Topic: "logs"},
+			false,
+			[]string{"spec.brokers"},
+		},
+		{
+			"loki",
+			v1alpha1.SinkSpec{
+				Type:      "loki",
+				URL:       "http://loki.logging:3100",
+				TenantID:  "team-a",
+				LabelKeys: []string{"kubernetes.pod_name"},
+			},
+			true,
+			nil,
+		},
+		{
+			"loki without url",
+			v1alpha1.SinkSpec{Type: "loki"},
+			false,
+			[]string{"spec.url"},
+		},
+		{
+			"invalid loki tenant and label keys",
+			v1alpha1.SinkSpec{
+				Type:      "loki",
+				URL:       "http://loki.logging:3100",
+				TenantID:  "team/a",
+				LabelKeys: []string{"kubernetes.pod_name", "kubernetes.labels.app-name"},
+			},
+			false,
+			[]string{"spec.tenant_id", "spec.label_keys[1]"},
+		},
+		{
+			"loki label keys naming the same label",
+			v1alpha1.SinkSpec{
+				Type:      "loki",
+				URL:       "http://loki.logging:3100",
+				LabelKeys: []string{"kubernetes.labels.app", "kubernetes.annotations.app"},
+			},
+			false,
+			[]string{"spec.label_keys"},
+		},
+		{
+			"s3",
+			v1alpha1.SinkSpec{
+				Type:                 "s3",
+				Bucket:               "team-logs",
+				Region:               "us-east-1",
+				TotalFileSizeMB:      50,
+				UploadTimeoutSeconds: 300,
+			},
+			true,
+			nil,
+		},
+		{
+			"invalid s3 bucket and region",
+			v1alpha1.SinkSpec{Type: "s3", Bucket: "Team_Logs", Region: "us-east", TotalFileSizeMB: -1, UploadTimeoutSeconds: -1},
+			false,
+			[]string{"spec.bucket", "spec.region", "spec.total_file_size_mb", "spec.upload_timeout_seconds"},
+		},
+		{
+			"s3 with destinations",
+			v1alpha1.SinkSpec{
+				Type:         "s3",
+				Bucket:       "team-logs",
+				Region:       "us-east-1",
+				Destinations: []v1alpha1.Destination{{Host: "example.com", Port: 443}},
+			},
+			false,
+			[]string{"spec.destinations[0]"},
+		},
+		{
+			"splunk without token",
+			v1alpha1.SinkSpec{Type: "splunk", Host: "hec.example.com", Port: 8088},
+			false,
+			[]string{"spec.secret_ref"},
+		},
+		{
+			"invalid splunk address, index and source type",
+			v1alpha1.SinkSpec{
+				Type:       "splunk",
+				Port:       70000,
+				Index:      "_internal",
+				SourceType: "kube container",
+				SecretRef:  &v1alpha1.SecretKeyRef{Name: "hec", Key: "token"},
+			},
+			false,
+			[]string{"spec.host", "spec.port", "spec.index", "spec.source_type"},
+		},
+		{
+			"output fields",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, OutputFields: []string{"log", "kubernetes"}},
+			true,
+			nil,
+		},
+		{
+			"invalid output fields",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, OutputFields: []string{"log", "", "log"}},
+			false,
+			[]string{"spec.output_fields[1]", "spec.output_fields[2]"},
+		},
+		{
+			"tls server name",
+			v1alpha1.SinkSpec{Type: "forward", Host: "lb.example.com", EnableTLS: true, TLSServerName: "fluentd.example.com"},
+			true,
+			nil,
+		},
+		{
+			"tls server name without tls",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "lb.example.com", Port: 514, TLSServerName: "syslog.example.com"},
+			false,
+			[]string{"spec.tls_server_name"},
+		},
+		{
+			"tls server name of a kafka destination",
+			v1alpha1.SinkSpec{
+				Type:          "syslog",
+				Host:          "lb.example.com",
+				Port:          6514,
+				EnableTLS:     true,
+				TLSServerName: "syslog.example.com",
+				Topic:         "logs",
+				Destinations:  []v1alpha1.Destination{{Type: "kafka", Host: "kafka-0.kafka", Port: 9093}},
+			},
+			false,
+			[]string{"spec.tls_server_name"},
+		},
+		{
+			"templated host",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "${RECEIVER_HOST}", Port: 514},
+			true,
+			nil,
+		},
+		{
+			"partly templated host",
+			v1alpha1.SinkSpec{Type: "elasticsearch", Host: "logs.${ENVIRONMENT}.example.com", Port: 9200, Index: "logs"},
+			true,
+			nil,
+		},
+		{
+			"dollar outside a reference",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "$RECEIVER_HOST", Port: 514},
+			false,
+			[]string{"spec.host"},
+		},
+		{
+			"unterminated reference",
+			v1alpha1.SinkSpec{Type: "splunk", Host: "${RECEIVER_HOST", Port: 8088},
+			false,
+			[]string{"spec.host"},
+		},
+		{
+			"reference to the credentials of a sink",
+			v1alpha1.SinkSpec{Type: "forward", Host: "${SPLUNK_TOKEN_0123456789ABCDEF}"},
+			false,
+			[]string{"spec.host"},
+		},
+		{
+			"templated http destination",
+			v1alpha1.SinkSpec{
+				Type:         "http",
+				URI:          "https://example.com/logs",
+				Destinations: []v1alpha1.Destination{{Host: "${RECEIVER_HOST}"}},
+			},
+			false,
+			[]string{"spec.destinations[0].host"},
+		},
+		{
+			"match expression",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MatchExpr: &v1alpha1.MatchExpr{Key: "log", Regex: "panic"}},
+			true,
+			nil,
+		},
+		{
+			"invalid match expression",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MatchExpr: &v1alpha1.MatchExpr{Key: "log", Regex: "panic("}},
+			false,
+			[]string{"spec.match_expr"},
+		},
+		{
+			"anonymous forward",
+			v1alpha1.SinkSpec{Type: "forward", Host: "fluentd.logging", Port: 24224},
+			true,
+			nil,
+		},
+		{
+			"invalid forward address and self hostname",
+			v1alpha1.SinkSpec{Type: "forward", Port: 70000, SelfHostname: "node agent"},
+			false,
+			[]string{"spec.host", "spec.port", "spec.self_hostname"},
+		},
+		{
+			"gelf",
+			v1alpha1.SinkSpec{
+				Type:     "gelf",
+				Host:     "graylog.logging",
+				Port:     12201,
+				Mode:     "tcp",
+				GELFKeys: &v1alpha1.GELFKeys{ShortMessage: "msg", Level: "severity"},
+			},
+			true,
+			nil,
+		},
+		{
+			"invalid gelf mode and keys",
+			v1alpha1.SinkSpec{
+				Type:     "gelf",
+				Host:     "graylog.logging",
+				Mode:     "http",
+				GELFKeys: &v1alpha1.GELFKeys{Host: "kubernetes.host"},
+			},
+			false,
+			[]string{"spec.mode", "spec.gelf_keys"},
+		},
+		{
+			"gelf with tls outside of mode tls",
+			v1alpha1.SinkSpec{Type: "gelf", Host: "graylog.logging", Mode: "udp", EnableTLS: true},
+			false,
+			[]string{"spec.enable_tls"},
+		},
+		{
+			"datadog without API key",
+			v1alpha1.SinkSpec{Type: "datadog", Site: "datadoghq.com"},
+			false,
+			[]string{"spec.secret_ref"},
+		},
+		{
+			"invalid datadog site, service, source and tags",
+			v1alpha1.SinkSpec{
+				Type:      "datadog",
+				Site:      "datadoghq.io",
+				Service:   "check out",
+				DDSource:  "kube rnetes",
+				Tags:      []string{"env:prod", "team:a,team:b"},
+				SecretRef: &v1alpha1.SecretKeyRef{Name: "datadog", Key: "api-key"},
+			},
+			false,
+			[]string{"spec.site", "spec.service", "spec.dd_source", "spec.tags[1]"},
+		},
+		{
+			"datadog destinations",
+			v1alpha1.SinkSpec{
+				Type:         "datadog",
+				SecretRef:    &v1alpha1.SecretKeyRef{Name: "datadog", Key: "api-key"},
+				Destinations: []v1alpha1.Destination{{Host: "example.com", Port: 443}},
+			},
+			false,
+			[]string{"spec.destinations[0]"},
+		},
+		{
+			"sample rate",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SampleRate: rate(0.25)},
+			true,
+			nil,
+		},
+		{
+			"sample rate above 1",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SampleRate: rate(1.5)},
+			false,
+			[]string{"spec.sample_rate"},
+		},
+		{
+			"weighted destinations",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Destinations: []v1alpha1.Destination{
+					{Host: "example.com", Port: 514, Weight: 1},
+					{Host: "example.org", Port: 514, Weight: 3},
+				},
+			},
+			true,
+			nil,
+		},
+		{
+			"partly weighted destinations",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "example.com",
+				Port: 514,
+				Destinations: []v1alpha1.Destination{
+					{Host: "example.org", Port: 514, Weight: 3},
+					{Host: "example.net", Port: 514},
+				},
+			},
+			false,
+			[]string{"spec.destinations[1].weight", "spec.destinations"},
+		},
+		{
+			"max message bytes",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MaxMessageBytes: 16384},
+			true,
+			nil,
+		},
+		{
+			"max message bytes within the truncation marker",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, MaxMessageBytes: 8},
+			false,
+			[]string{"spec.max_message_bytes"},
+		},
+		{
+			"keepalive disabled",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, KeepAliveSeconds: seconds(0)},
+			true,
+			nil,
+		},
+		{
+			"negative keepalive",
+			v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs", KeepAliveSeconds: seconds(-5)},
+			false,
+			[]string{"spec.keepalive_seconds"},
+		},
+		{
+			"flush timeout",
+			v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs", FlushTimeoutSeconds: 10},
+			true,
+			nil,
+		},
+		{
+			"negative flush timeout",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, FlushTimeoutSeconds: -10},
+			false,
+			[]string{"spec.flush_timeout_seconds"},
+		},
+		{
+			"encoding",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Encoding: "iso-8859-1"},
+			true,
+			nil,
+		},
+		{
+			"unknown encoding",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Encoding: "latin-1"},
+			false,
+			[]string{"spec.encoding"},
+		},
+		{
+			"parser name",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx-access"},
+			true,
+			nil,
+		},
+		{
+			"invalid parser name",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "Nginx_Access"},
+			false,
+			[]string{"spec.parser_name"},
+		},
+		{
+			"parser name with parse json",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx-access", ParseJSON: true},
+			false,
+			[]string{"spec.parser_name"},
+		},
+		{
+			"include node name",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, IncludeNodeName: true, NodeNameKey: "node"},
+			true,
+			nil,
+		},
+		{
+			"node name key without include node name",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, NodeNameKey: "node"},
+			false,
+			[]string{"spec.node_name_key"},
+		},
+		{
+			"reserved node name key",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, IncludeNodeName: true, NodeNameKey: "log"},
+			false,
+			[]string{"spec.node_name_key"},
+		},
+		{
+			"parser names",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserNames: []string{"json", "nginx-access"}},
+			true,
+			nil,
+		},
+		{
+			"invalid parser names",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserNames: []string{"json", "Nginx_Access"}},
+			false,
+			[]string{"spec.parser_names[1]"},
+		},
+		{
+			"duplicate parser names",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserNames: []string{"json", "json"}},
+			false,
+			[]string{"spec.parser_names"},
+		},
+		{
+			"parser names with parser name",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "json", ParserNames: []string{"nginx-access"}},
+			false,
+			[]string{"spec.parser_names"},
+		},
+		{
+			"time key",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, TimeKey: "ts", TimeFormat: "%d/%b/%Y:%H:%M:%S %z"},
+			true,
+			nil,
+		},
+		{
+			"time format without time key",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, TimeFormat: "%s"},
+			false,
+			[]string{"spec.time_key"},
+		},
+		{
+			"invalid time format",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, TimeKey: "kubernetes", TimeFormat: "unix"},
+			false,
+			[]string{"spec.time_key", "spec.time_format"},
+		},
+		{
+			"ipv6 hosts",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "2001:db8::1",
+				Port: 24903,
+				Destinations: []v1alpha1.Destination{
+					{Host: "[2001:db8::2]", Port: 24903},
+					{Type: "http", Host: "::1"},
+				},
+			},
+			true,
+			nil,
+		},
+		{
+			"invalid ipv6 hosts",
+			v1alpha1.SinkSpec{
+				Type: "syslog",
+				Host: "2001:db8::1:24903",
+				Port: 24903,
+				Destinations: []v1alpha1.Destination{
+					{Host: "[example.com]", Port: 24903},
+					{Type: "http", Host: "[2001:db8::3"},
+				},
+			},
+			false,
+			[]string{"spec.host", "spec.destinations[0].host", "spec.destinations[1].host"},
+		},
+		{
+			"secret ref on syslog sink",
+			v1alpha1.SinkSpec{
+				Type:      "syslog",
+				Host:      "example.com",
+				Port:      514,
+				SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+			},
+			false,
+			[]string{"spec.secret_ref"},
+		},
+		{
+			"secret ref with bad name and key",
+			v1alpha1.SinkSpec{
+				Type:      "http",
+				URI:       "https://example.com/logs",
+				SecretRef: &v1alpha1.SecretKeyRef{Name: "Log_Service", Key: "to ken"},
+			},
+			false,
+			[]string{"spec.secret_ref.name", "spec.secret_ref.key"},
+		},
+		{
+			"secret ref with authorization header",
+			v1alpha1.SinkSpec{
+				Type:      "otlp",
+				Endpoint:  "otel-collector:4318",
+				Headers:   map[string]string{"authorization": "Basic dXNlcjpwYXNz"},
+				SecretRef: &v1alpha1.SecretKeyRef{Name: "otel-collector", Key: "token"},
+			},
+			false,
+			[]string{"spec.headers[authorization]"},
+		},
+		{
+			"tag",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Tag: "team-a.audit"},
+			true,
+			nil,
+		},
+		{
+			"reserved tag",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, Tag: "kube.team-a"},
+			false,
+			[]string{"spec.tag"},
+		},
+		{
+			"unknown type",
+			v1alpha1.SinkSpec{Type: "graylog", Host: "example.com", Port: 12201},
+			false,
+			[]string{"spec.type"},
+		},
+	}
+	for _, test := range tests {
+		for _, kind := range []string{"LogSink", "ClusterLogSink"} {
+			t.Run(kind+" "+test.name, func(t *testing.T) {
+				resp := webhook.Admit(request(t, kind, admissionv1beta1.Create, test.spec), &stubSecrets{})
+				if resp.Allowed != test.allowed {
+					t.Fatalf("Allowed not equal: Expected: %t, Actual: %t", test.allowed, resp.Allowed)
+				}
+				if test.allowed {
+					return
+				}
+				if resp.Result == nil {
+					t.Fatalf("Expected a result explaining the denial")
+				}
+				for _, f := range test.fields {
+					if !strings.Contains(resp.Result.Message, f+": ") {
+						t.Errorf("Expected message to name %s: %s", f, resp.Result.Message)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestAdmitExclusiveFields(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	tests := []struct {
+		name    string
+		spec    v1alpha1.SinkSpec
+		message string
+	}{
+		{
+			"socket path and host",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", SocketPath: "/var/run/collector/syslog.sock"},
+			"spec.socket_path: must not be set with spec.host",
+		},
+		{
+			"socket path and port",
+			v1alpha1.SinkSpec{Type: "syslog", Port: 514, SocketPath: "/var/run/collector/syslog.sock"},
+			"spec.socket_path: must not be set with spec.port",
+		},
+		{
+			"socket path and protocol",
+			v1alpha1.SinkSpec{Type: "syslog", Protocol: "udp", SocketPath: "/var/run/collector/syslog.sock"},
+			"spec.socket_path: must not be set with spec.protocol",
+		},
+		{
+			"socket path and tls",
+			v1alpha1.SinkSpec{Type: "syslog", EnableTLS: true, SocketPath: "/var/run/collector/syslog.sock"},
+			"spec.socket_path: must not be set with spec.enable_tls",
+		},
+		{
+			"socket path and destinations",
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				SocketPath:   "/var/run/collector/syslog.sock",
+				Destinations: []v1alpha1.Destination{{Host: "example.com", Port: 514}},
+			},
+			"spec.socket_path: must not be set with spec.destinations",
+		},
+		{
+			"sample rate and max records",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, SampleRate: rate(0.5), MaxRecordsPerSecond: 100},
+			"spec.sample_rate: must not be set with spec.max_records_per_second",
+		},
+		{
+			"parser name and parse json",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx", ParseJSON: true},
+			"spec.parser_name: must not be set with spec.parse_json",
+		},
+		{
+			"exclusive match and pod selector",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ExclusiveMatch: true, PodSelector: selector},
+			"spec.exclusive_match: must not be set with spec.pod_selector",
+		},
+		{
+			"host paths and namespace globs",
+			v1alpha1.SinkSpec{
+				Type:           "syslog",
+				Host:           "example.com",
+				Port:           514,
+				HostPaths:      []string{"/var/log/app.log"},
+				NamespaceGlobs: []string{"team-*"},
+			},
+			"spec.host_paths: must not be set with spec.namespace_globs",
+		},
+		{
+			"insecure and tls secret ref",
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "example.com",
+				Port:         6514,
+				EnableTLS:    true,
+				Insecure:     true,
+				TLSSecretRef: &v1alpha1.SecretReference{Name: "client-cert"},
+			},
+			"spec.insecure: must not be set with spec.tls_secret_ref",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, test.spec), &stubSecrets{})
+			if resp.Allowed {
+				t.Fatalf("Expected the sink to be denied")
+			}
+			if !strings.Contains(resp.Result.Message, test.message) {
+				t.Errorf("Expected message to contain %q: %s", test.message, resp.Result.Message)
+			}
+		})
+	}
+}
+
+func TestAdmitExcludeNamespaces(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:              "syslog",
+		Host:              "example.com",
+		Port:              514,
+		ExcludeNamespaces: []string{"kube-system"},
+	}
+
+	resp := webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if !resp.Allowed {
+		t.Errorf("Expected ClusterLogSink to be allowed: %v", resp.Result)
+	}
+
+	resp = webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if resp.Allowed {
+		t.Fatalf("Expected LogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.exclude_namespaces: ") {
+		t.Errorf("Expected message to name spec.exclude_namespaces: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitNamespaceGlobs(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:           "syslog",
+		Host:           "example.com",
+		Port:           514,
+		NamespaceGlobs: []string{"team-*"},
+	}
+
+	resp := webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if !resp.Allowed {
+		t.Errorf("Expected ClusterLogSink to be allowed: %v", resp.Result)
+	}
+
+	resp = webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if resp.Allowed {
+		t.Fatalf("Expected LogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.namespace_globs: ") {
+		t.Errorf("Expected message to name spec.namespace_globs: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitSystemOnly(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:             "syslog",
+		Host:             "example.com",
+		Port:             514,
+		SystemOnly:       true,
+		SystemNamespaces: []string{"kube-system", "istio-system"},
+	}
+
+	resp := webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if !resp.Allowed {
+		t.Errorf("Expected ClusterLogSink to be allowed: %v", resp.Result)
+	}
+
+	resp = webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if resp.Allowed {
+		t.Fatalf("Expected LogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.system_only: ") {
+		t.Errorf("Expected message to name spec.system_only: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitAuditSource(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:   "syslog",
+		Host:   "example.com",
+		Port:   514,
+		Source: "audit",
+	}
+
+	resp := webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if !resp.Allowed {
+		t.Errorf("Expected ClusterLogSink to be allowed: %v", resp.Result)
+	}
+
+	resp = webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if resp.Allowed {
+		t.Fatalf("Expected LogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.source: ") {
+		t.Errorf("Expected message to name spec.source: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitHostPaths(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:      "syslog",
+		Host:      "example.com",
+		Port:      514,
+		HostPaths: []string{"/data/app/*.log"},
+	}
+
+	resp := webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if !resp.Allowed {
+		t.Errorf("Expected ClusterLogSink to be allowed: %v", resp.Result)
+	}
+
+	for _, s := range []struct {
+		kind  string
+		paths []string
+	}{
+		{"LogSink", []string{"/data/app/*.log"}},
+		{"ClusterLogSink", []string{"data/app/*.log"}},
+	} {
+		spec.HostPaths = s.paths
+		resp = webhook.Admit(request(t, s.kind, admissionv1beta1.Create, spec), &stubSecrets{})
+		if resp.Allowed {
+			t.Fatalf("Expected %s with host paths %q to be denied", s.kind, s.paths)
+		}
+		if !strings.Contains(resp.Result.Message, "spec.host_paths: ") {
+			t.Errorf("Expected message to name spec.host_paths: %s", resp.Result.Message)
+		}
+	}
+}
+
+func TestAdmitSocketPath(t *testing.T) {
+	spec := v1alpha1.SinkSpec{Type: "syslog", SocketPath: "/var/run/collector/syslog.sock"}
+	dirs := webhook.WithSocketDirs([]string{"/var/run/collector"})
+
+	// The defaults of the sink are admitted, a socket has no protocol.
+	req := request(t, "ClusterLogSink", admissionv1beta1.Create, spec)
+	d := defaulted(t, req.Object.Raw, webhook.Default(req))
+	if d.Protocol != "" {
+		t.Errorf("Expected no protocol to be defaulted, got %q", d.Protocol)
+	}
+	resp := webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, d), &stubSecrets{}, dirs)
+	if !resp.Allowed {
+		t.Errorf("Expected the defaulted ClusterLogSink to be allowed: %v", resp.Result)
+	}
+
+	for _, s := range []struct {
+		name string
+		kind string
+		path string
+		opts []webhook.Option
+	}{
+		{"log sink", "LogSink", "/var/run/collector/syslog.sock", []webhook.Option{dirs}},
+		{"no allowed directories", "ClusterLogSink", "/var/run/collector/syslog.sock", nil},
+		{"outside the allowed directories", "ClusterLogSink", "/var/run/agent/syslog.sock", []webhook.Option{dirs}},
+		{"in a mounted directory", "ClusterLogSink", "/var/log/syslog.sock", []webhook.Option{webhook.WithSocketDirs([]string{"/var/log"})}},
+	} {
+		spec.SocketPath = s.path
+		resp = webhook.Admit(request(t, s.kind, admissionv1beta1.Create, spec), &stubSecrets{}, s.opts...)
+		if resp.Allowed {
+			t.Fatalf("Expected %s to be denied", s.name)
+		}
+		if !strings.Contains(resp.Result.Message, "spec.socket_path: ") {
+			t.Errorf("Expected message to name spec.socket_path for %s: %s", s.name, resp.Result.Message)
+		}
+	}
+}
+
+func TestAdmitExclusiveMatch(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:           "syslog",
+		Host:           "example.com",
+		Port:           514,
+		ExclusiveMatch: true,
+	}
+
+	resp := webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if !resp.Allowed {
+		t.Errorf("Expected LogSink to be allowed: %v", resp.Result)
+	}
+
+	resp = webhook.Admit(request(t, "ClusterLogSink", admissionv1beta1.Create, spec), &stubSecrets{})
+	if resp.Allowed {
+		t.Fatalf("Expected ClusterLogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.exclusive_match: ") {
+		t.Errorf("Expected message to name spec.exclusive_match: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitCatchAll(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:     "syslog",
+		Host:     "example.com",
+		Port:     514,
+		CatchAll: true,
+	}
+	catchAll := func(name string) *admissionv1beta1.AdmissionRequest {
+		req := request(t, "ClusterLogSink", admissionv1beta1.Create, spec)
+		raw, err := json.Marshal(v1alpha1.ClusterLogSink{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Object.Raw = raw
+		return req
+	}
+	client := fake.NewSimpleClientset(
+		&v1alpha1.ClusterLogSink{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: v1alpha1.SinkSpec{Type: "syslog"}},
+		&v1alpha1.ClusterLogSink{ObjectMeta: metav1.ObjectMeta{Name: "rest"}, Spec: spec},
+	)
+	opt := webhook.WithClusterLogSinks(client.ObservabilityV1alpha1())
+
+	// The catch-all itself may be updated.
+	resp := webhook.Admit(catchAll("rest"), &stubSecrets{}, opt)
+	if !resp.Allowed {
+		t.Errorf("Expected the catch-all to be allowed: %v", resp.Result)
+	}
+
+	resp = webhook.Admit(catchAll("other-rest"), &stubSecrets{}, opt)
+	if resp.Allowed {
+		t.Fatalf("Expected a second catch-all to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.catch_all: ClusterLogSink rest is already the catch-all") {
+		t.Errorf("Expected message to name spec.catch_all: %s", resp.Result.Message)
+	}
+
+	resp = webhook.Admit(request(t, "LogSink", admissionv1beta1.Create, spec), &stubSecrets{}, opt)
+	if resp.Allowed {
+		t.Fatalf("Expected LogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "spec.catch_all: ") {
+		t.Errorf("Expected message to name spec.catch_all: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitCatchAllListFailure(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "clusterlogsinks", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	spec := v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, CatchAll: true}
+
+	resp := webhook.Admit(
+		request(t, "ClusterLogSink", admissionv1beta1.Create, spec),
+		&stubSecrets{},
+		webhook.WithClusterLogSinks(client.ObservabilityV1alpha1()),
+	)
+	if resp.Allowed {
+		t.Fatalf("Expected ClusterLogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "unable to list ClusterLogSinks: connection refused") {
+		t.Errorf("Unexpected message: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitParserReferences(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1alpha1.ClusterLogParser{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}, Spec: v1alpha1.ParserSpec{Format: "json"}},
+	)
+	opt := webhook.WithClusterLogParsers(client.ObservabilityV1alpha1())
+	var tests = []struct {
+		name    string
+		spec    v1alpha1.SinkSpec
+		allowed bool
+		message string
+	}{
+		{
+			"existing parser",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx"},
+			true,
+			"",
+		},
+		{
+			"missing parser",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "apache"},
+			false,
+			"spec.parser_name: ClusterLogParser apache not found",
+		},
+		{
+			"missing parser in the list",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserNames: []string{"nginx", "apache"}},
+			false,
+			"spec.parser_names[1]: ClusterLogParser apache not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := request(t, "LogSink", admissionv1beta1.Create, test.spec)
+			req.Namespace = "test-ns"
+
+			resp := webhook.Admit(req, &stubSecrets{}, opt)
+			if resp.Allowed != test.allowed {
+				t.Fatalf("Allowed not equal: Expected: %t, Actual: %t (%v)", test.allowed, resp.Allowed, resp.Result)
+			}
+			if !test.allowed && !strings.Contains(resp.Result.Message, test.message) {
+				t.Errorf("Expected message to contain %q: %s", test.message, resp.Result.Message)
+			}
+		})
+	}
+}
+
+func TestAdmitParserGetFailure(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("get", "clusterlogparsers", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	spec := v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514, ParserName: "nginx"}
+
+	resp := webhook.Admit(
+		request(t, "ClusterLogSink", admissionv1beta1.Create, spec),
+		&stubSecrets{},
+		webhook.WithClusterLogParsers(client.ObservabilityV1alpha1()),
+	)
+	if resp.Allowed {
+		t.Fatalf("Expected ClusterLogSink to be denied")
+	}
+	if !strings.Contains(resp.Result.Message, "unable to get ClusterLogParser nginx: connection refused") {
+		t.Errorf("Unexpected message: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitSplunk(t *testing.T) {
+	secrets := &stubSecrets{
+		secrets: map[string]*coreV1.Secret{
+			"test-ns/hec": {
+				Data: map[string][]byte{"token": []byte("0a1b2c3d-4e5f-6789-abcd-ef0123456789")},
+			},
+		},
+	}
+	spec := v1alpha1.SinkSpec{
+		Type:       "splunk",
+		Host:       "hec.example.com",
+		Index:      "k8s_logs",
+		SourceType: "kube:container",
+		SecretRef:  &v1alpha1.SecretKeyRef{Name: "hec", Key: "token"},
+		Destinations: []v1alpha1.Destination{
+			{Host: "hec-backup.example.com", Port: 443},
+		},
+	}
+	req := request(t, "LogSink", admissionv1beta1.Create, spec)
+	req.Namespace = "test-ns"
+
+	resp := webhook.Admit(req, secrets)
+	if !resp.Allowed {
+		t.Errorf("Expected LogSink to be allowed: %v", resp.Result)
+	}
+}
+
+func TestAdmitDatadog(t *testing.T) {
+	secrets := &stubSecrets{
+		secrets: map[string]*coreV1.Secret{
+			"test-ns/datadog": {
+				Data: map[string][]byte{"api-key": []byte("0123456789abcdef0123456789abcdef")},
+			},
+		},
+	}
+	spec := v1alpha1.SinkSpec{
+		Type:      "datadog",
+		Site:      "datadoghq.eu",
+		Service:   "checkout",
+		DDSource:  "kubernetes",
+		Tags:      []string{"env:prod", "team:payments"},
+		SecretRef: &v1alpha1.SecretKeyRef{Name: "datadog", Key: "api-key"},
+	}
+	req := request(t, "LogSink", admissionv1beta1.Create, spec)
+	req.Namespace = "test-ns"
+
+	resp := webhook.Admit(req, secrets)
+	if !resp.Allowed {
+		t.Errorf("Expected LogSink to be allowed: %v", resp.Result)
+	}
+}
+
+func TestAdmitForwardSharedKey(t *testing.T) {
+	secrets := &stubSecrets{
+		secrets: map[string]*coreV1.Secret{
+			"test-ns/fluentd": {
+				Data: map[string][]byte{"shared-key": []byte("some-shared-key")},
+			},
+		},
+	}
+	spec := v1alpha1.SinkSpec{
+		Type:         "forward",
+		Host:         "fluentd.logging",
+		Port:         24224,
+		EnableTLS:    true,
+		SelfHostname: "node-agent",
+		SecretRef:    &v1alpha1.SecretKeyRef{Name: "fluentd", Key: "shared-key"},
+	}
+	req := request(t, "LogSink", admissionv1beta1.Create, spec)
+	req.Namespace = "test-ns"
+
+	resp := webhook.Admit(req, secrets)
+	if !resp.Allowed {
+		t.Errorf("Expected LogSink to be allowed: %v", resp.Result)
+	}
+
+	// A shared key that is not in the Secret is rejected.
+	spec.SecretRef.Key = "missing"
+	req = request(t, "LogSink", admissionv1beta1.Create, spec)
+	req.Namespace = "test-ns"
+	resp = webhook.Admit(req, secrets)
+	if resp.Allowed {
+		t.Error("Expected LogSink with a missing shared key to be rejected")
+	}
+}
+
+func TestAdmitSecretRef(t *testing.T) {
+	secrets := &stubSecrets{
+		secrets: map[string]*coreV1.Secret{
+			"test-ns/log-service": {
+				Data: map[string][]byte{"token": []byte("abc123")},
+			},
+		},
+	}
+	var tests = []struct {
+		name    string
+		kind    string
+		ref     v1alpha1.SecretKeyRef
+		allowed bool
+		field   string
+	}{
+		{
+			"secret in the sink's namespace",
+			"LogSink",
+			v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+			true,
+			"",
+		},
+		{
+			"missing secret",
+			"LogSink",
+			v1alpha1.SecretKeyRef{Name: "other-service", Key: "token"},
+			false,
+			"spec.secret_ref.name",
+		},
+		{
+			"missing key",
+			"LogSink",
+			v1alpha1.SecretKeyRef{Name: "log-service", Key: "password"},
+			false,
+			"spec.secret_ref.key",
+		},
+		{
+			"LogSink naming a namespace",
+			"LogSink",
+			v1alpha1.SecretKeyRef{Namespace: "test-ns", Name: "log-service", Key: "token"},
+			false,
+			"spec.secret_ref.namespace",
+		},
+		{
+			"ClusterLogSink naming a namespace",
+			"ClusterLogSink",
+			v1alpha1.SecretKeyRef{Namespace: "test-ns", Name: "log-service", Key: "token"},
+			true,
+			"",
+		},
+		{
+			"ClusterLogSink without a namespace",
+			"ClusterLogSink",
+			v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+			false,
+			"spec.secret_ref.namespace",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref := test.ref
+			spec := v1alpha1.SinkSpec{Type: "http", URI: "https://example.com/logs", SecretRef: &ref}
+			req := request(t, test.kind, admissionv1beta1.Create, spec)
+			if test.kind == "LogSink" {
+				req.Namespace = "test-ns"
+			}
+
+			resp := webhook.Admit(req, secrets)
+			if resp.Allowed != test.allowed {
+				t.Fatalf("Allowed not equal: Expected: %t, Actual: %t (%v)", test.allowed, resp.Allowed, resp.Result)
+			}
+			if !test.allowed && !strings.Contains(resp.Result.Message, test.field+": ") {
+				t.Errorf("Expected message to name %s: %s", test.field, resp.Result.Message)
+			}
+		})
+	}
+}
+
+func TestAdmitSecretRefLookupFailure(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type:      "http",
+		URI:       "https://example.com/logs",
+		SecretRef: &v1alpha1.SecretKeyRef{Name: "log-service", Key: "token"},
+	}
+	req := request(t, "LogSink", admissionv1beta1.Create, spec)
+	req.Namespace = "test-ns"
+
+	resp := webhook.Admit(req, &stubSecrets{err: errors.New("forbidden")})
+	if resp.Allowed {
+		t.Fatalf("Expected sink to be denied when the secret cannot be read")
+	}
+	if !strings.Contains(resp.Result.Message, "unable to get secret test-ns/log-service") {
+		t.Errorf("Unexpected message: %s", resp.Result.Message)
+	}
+}
+
+func TestAdmitTLSSecretRef(t *testing.T) {
+	secrets := &stubSecrets{
+		secrets: map[string]*coreV1.Secret{
+			"test-ns/client-cert": {
+				Data: map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key")},
+			},
+			"test-ns/ca-only": {
+				Data: map[string][]byte{"ca.crt": []byte("ca")},
+			},
+		},
+	}
+	var tests = []struct {
+		name    string
+		kind    string
+		ref     v1alpha1.SecretReference
+		allowed bool
+		field   string
+	}{
+		{
+			"secret in the sink's namespace",
+			"LogSink",
+			v1alpha1.SecretReference{Name: "client-cert"},
+			true,
+			"",
+		},
+		{
+			"missing secret",
+			"LogSink",
+			v1alpha1.SecretReference{Name: "other-cert"},
+			false,
+			"spec.tls_secret_ref.name",
+		},
+		{
+			"secret without a certificate",
+			"LogSink",
+			v1alpha1.SecretReference{Name: "ca-only"},
+			false,
+			"spec.tls_secret_ref.name",
+		},
+		{
+			"LogSink naming a namespace",
+			"LogSink",
+			v1alpha1.SecretReference{Namespace: "test-ns", Name: "client-cert"},
+			false,
+			"spec.tls_secret_ref.namespace",
+		},
+		{
+			"ClusterLogSink naming a namespace",
+			"ClusterLogSink",
+			v1alpha1.SecretReference{Namespace: "test-ns", Name: "client-cert"},
+			true,
+			"",
+		},
+		{
+			"ClusterLogSink without a namespace",
+			"ClusterLogSink",
+			v1alpha1.SecretReference{Name: "client-cert"},
+			false,
+			"spec.tls_secret_ref.namespace",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref := test.ref
+			spec := v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 6514, EnableTLS: true, TLSSecretRef: &ref}
+			req := request(t, test.kind, admissionv1beta1.Create, spec)
+			if test.kind == "LogSink" {
+				req.Namespace = "test-ns"
+			}
+
+			resp := webhook.Admit(req, secrets)
+			if resp.Allowed != test.allowed {
+				t.Fatalf("Allowed not equal: Expected: %t, Actual: %t (%v)", test.allowed, resp.Allowed, resp.Result)
+			}
+			if !test.allowed && !strings.Contains(resp.Result.Message, test.field+": ") {
+				t.Errorf("Expected message to name %s: %s", test.field, resp.Result.Message)
+			}
+		})
+	}
+}
+
+func TestAdmitPreflight(t *testing.T) {
+	spec := v1alpha1.SinkSpec{
+		Type: "syslog",
+		Host: "example.com",
+		Port: 514,
+		Destinations: []v1alpha1.Destination{
+			{Type: "http", Host: "backup.example.com"},
+			{Type: "kafka", Host: "kafka-0.kafka", Port: 9092},
+		},
+		Topic: "logs",
+	}
+	var tests = []struct {
+		name        string
+		kind        string
+		spec        v1alpha1.SinkSpec
+		annotations map[string]string
+		unreachable string
+		allowed     bool
+		dialed      []string
+	}{
+		{
+			"reachable receivers",
+			"LogSink",
+			spec,
+			nil,
+			"",
+			true,
+			[]string{"example.com:514", "backup.example.com:80", "kafka-0.kafka:9092"},
+		},
+		{
+			"unreachable receiver",
+			"ClusterLogSink",
+			spec,
+			nil,
+			"backup.example.com:80",
+			false,
+			[]string{"example.com:514", "backup.example.com:80"},
+		},
+		{
+			"skipped preflight",
+			"LogSink",
+			spec,
+			map[string]string{webhook.SkipPreflightAnnotation: "true"},
+			"backup.example.com:80",
+			true,
+			nil,
+		},
+		{
+			"loki url with default port",
+			"ClusterLogSink",
+			v1alpha1.SinkSpec{Type: "loki", URL: "https://loki.example.com"},
+			nil,
+			"",
+			true,
+			[]string{"loki.example.com:443"},
+		},
+		{
+			"s3 region endpoint",
+			"LogSink",
+			v1alpha1.SinkSpec{Type: "s3", Bucket: "team-logs", Region: "cn-north-1"},
+			nil,
+			"",
+			true,
+			[]string{"s3.cn-north-1.amazonaws.com.cn:443"},
+		},
+		{
+			"templated host",
+			"ClusterLogSink",
+			v1alpha1.SinkSpec{
+				Type:         "syslog",
+				Host:         "${RECEIVER_HOST}",
+				Port:         514,
+				Destinations: []v1alpha1.Destination{{Type: "forward", Host: "fluentd.example.com"}},
+			},
+			nil,
+			"",
+			true,
+			[]string{"fluentd.example.com:24224"},
+		},
+		{
+			"gelf over udp",
+			"LogSink",
+			v1alpha1.SinkSpec{
+				Type:         "gelf",
+				Host:         "graylog-udp.example.com",
+				Destinations: []v1alpha1.Destination{{Host: "graylog-backup.example.com"}},
+			},
+			nil,
+			"",
+			true,
+			nil,
+		},
+		{
+			"invalid sink",
+			"LogSink",
+			v1alpha1.SinkSpec{Type: "syslog", Host: "example.com"},
+			nil,
+			"",
+			false,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := request(t, test.kind, admissionv1beta1.Create, test.spec)
+			meta := metav1.ObjectMeta{Annotations: test.annotations}
+			var err error
+			if test.kind == "LogSink" {
+				req.Object.Raw, err = json.Marshal(v1alpha1.LogSink{ObjectMeta: meta, Spec: test.spec})
+			} else {
+				req.Object.Raw, err = json.Marshal(v1alpha1.ClusterLogSink{ObjectMeta: meta, Spec: test.spec})
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			dialer := &spyDialer{unreachable: test.unreachable}
+
+			resp := webhook.Admit(req, &stubSecrets{}, webhook.WithPreflight(dialer))
+			if resp.Allowed != test.allowed {
+				t.Fatalf("Allowed not equal: Expected: %t, Actual: %t (%v)", test.allowed, resp.Allowed, resp.Result)
+			}
+			if diff := cmp.Diff(test.dialed, dialer.dialed); diff != "" {
+				t.Errorf("Dialed addresses not equal (-want, +got) = %v", diff)
+			}
+			if test.unreachable != "" && !test.allowed {
+				for _, s := range []string{"spec: unable to connect to " + test.unreachable, webhook.SkipPreflightAnnotation} {
+					if !strings.Contains(resp.Result.Message, s) {
+						t.Errorf("Expected message to contain %q: %s", s, resp.Result.Message)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAdmitPreflightUpdates(t *testing.T) {
+	spec := v1alpha1.SinkSpec{Type: "syslog", Host: "example.com", Port: 514}
+	moved := spec
+	moved.Host = "example.org"
+	disabled := spec
+	disabled.Disabled = true
+	var tests = []struct {
+		name   string
+		op     admissionv1beta1.Operation
+		old    v1alpha1.SinkSpec
+		spec   v1alpha1.SinkSpec
+		dialed []string
+	}{
+		{"create", admissionv1beta1.Create, v1alpha1.SinkSpec{}, spec, []string{"example.com:514"}},
+		{"create disabled", admissionv1beta1.Create, v1alpha1.SinkSpec{}, disabled, nil},
+		{"unchanged receivers", admissionv1beta1.Update, spec, spec, nil},
+		{"changed receiver", admissionv1beta1.Update, spec, moved, []string{"example.org:514"}},
+		{"enabled", admissionv1beta1.Update, disabled, spec, []string{"example.com:514"}},
+		{"disabled", admissionv1beta1.Update, spec, disabled, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := request(t, "LogSink", test.op, test.spec)
+			old, err := json.Marshal(v1alpha1.LogSink{Spec: test.old})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.OldObject.Raw = old
+			dialer := &spyDialer{unreachable: "example.org:514"}
+
+			webhook.Admit(req, &stubSecrets{}, webhook.WithPreflight(dialer))
+			if diff := cmp.Diff(test.dialed, dialer.dialed); diff != "" {
+				t.Errorf("Dialed addresses not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestAdmitClusterLogParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1alpha1.ParserSpec
+		allowed bool
+		fields  []string
+	}{
+		{
+			"regex",
+			v1alpha1.ParserSpec{
+				Format:     "regex",
+				Regex:      `^(?<remote>[^ ]*) (?<method>\S+) (?<path>[^ ]*)$`,
+				TimeKey:    "time",
+				TimeFormat: "%d/%b/%Y:%H:%M:%S %z",
+			},
+			true,
+			nil,
+		},
+		{
+			"json",
+			v1alpha1.ParserSpec{Format: "json"},
+			true,
+			nil,
+		},
+		{
+			"unknown format",
+			v1alpha1.ParserSpec{Format: "ltsv"},
+			false,
+			[]string{"spec.format"},
+		},
+		{
+			"regex without named groups",
+			v1alpha1.ParserSpec{Format: "regex", Regex: "^(.*)$"},
+			false,
+			[]string{"spec.regex"},
+		},
+		{
+			"key name",
+			v1alpha1.ParserSpec{Format: "regex", Regex: "^(?<level>[A-Z]+)$", KeyName: "message"},
+			true,
+			nil,
+		},
+		{
+			"invalid key name",
+			v1alpha1.ParserSpec{Format: "json", KeyName: "wrapped message"},
+			false,
+			[]string{"spec.key_name"},
+		},
+		{
+			"regex on json parser",
+			v1alpha1.ParserSpec{Format: "json", Regex: "^(?<level>[A-Z]+)$", TimeFormat: "%s"},
+			false,
+			[]string{"spec.regex", "spec.time_key"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := json.Marshal(v1alpha1.ClusterLogParser{Spec: test.spec})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &admissionv1beta1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Group: "observability.knative.dev", Version: "v1alpha1", Kind: "ClusterLogParser"},
+				Operation: admissionv1beta1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			}
+
+			resp := webhook.Admit(req, &stubSecrets{})
+			if resp.Allowed != test.allowed {
+				t.Fatalf("Allowed not equal: Expected: %t, Actual: %t", test.allowed, resp.Allowed)
+			}
+			for _, f := range test.fields {
+				if !strings.Contains(resp.Result.Message, f+": ") {
+					t.Errorf("Expected message to name %s: %s", f, resp.Result.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestAdmitUpdate(t *testing.T) {
+	resp := webhook.Admit(request(t, "LogSink", admissionv1beta1.Update, v1alpha1.SinkSpec{Type: "syslog"}), &stubSecrets{})
+	if resp.Allowed {
+		t.Errorf("Expected invalid update to be denied")
+	}
+}
+
+func TestAdmitDelete(t *testing.T) {
+	req := &admissionv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "LogSink"},
+		Operation: admissionv1beta1.Delete,
+	}
+	if resp := webhook.Admit(req, &stubSecrets{}); !resp.Allowed {
+		t.Errorf("Expected delete to be allowed: %v", resp.Result)
+	}
+}
+
+func TestAdmitUndecodableObject(t *testing.T) {
+	req := &admissionv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "LogSink"},
+		Operation: admissionv1beta1.Create,
+		Object:    runtime.RawExtension{Raw: []byte(`{"spec":{"port":"514"}}`)},
+	}
+	if resp := webhook.Admit(req, &stubSecrets{}); resp.Allowed {
+		t.Errorf("Expected undecodable sink to be denied")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	req := request(t, "LogSink", admissionv1beta1.Create, v1alpha1.SinkSpec{Type: "syslog", Port: 514})
+	req.UID = "some-uid"
+	body, err := json.Marshal(admissionv1beta1.AdmissionReview{Request: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	webhook.NewHandler(&stubSecrets{}).ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)),
+	)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code: %d", recorder.Code)
+	}
+	var review admissionv1beta1.AdmissionReview
+	err = json.Unmarshal(recorder.Body.Bytes(), &review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.Response == nil {
+		t.Fatalf("Expected a response")
+	}
+	if review.Response.UID != "some-uid" {
+		t.Errorf("UID not equal: Expected: some-uid, Actual: %s", review.Response.UID)
+	}
+	if review.Response.Allowed {
+		t.Errorf("Expected sink without host to be denied")
+	}
+}
+
+func TestHandlerBadRequest(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	webhook.NewHandler(&stubSecrets{}).ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader("not json")),
+	)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status code: %d", recorder.Code)
+	}
+}
+
+func request(
+	t *testing.T,
+	kind string,
+	op admissionv1beta1.Operation,
+	spec v1alpha1.SinkSpec,
+) *admissionv1beta1.AdmissionRequest {
+	t.Helper()
+	var (
+		raw []byte
+		err error
+	)
+	switch kind {
+	case "LogSink":
+		raw, err = json.Marshal(v1alpha1.LogSink{Spec: spec})
+	default:
+		raw, err = json.Marshal(v1alpha1.ClusterLogSink{Spec: spec})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &admissionv1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "observability.knative.dev", Version: "v1alpha1", Kind: kind},
+		Operation: op,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+}
+
+type spyDialer struct {
+	unreachable string
+	dialed      []string
+}
+
+func (d *spyDialer) Dial(network, address string) (net.Conn, error) {
+	if network != "tcp" {
+		return nil, fmt.Errorf("unexpected network %s", network)
+	}
+	d.dialed = append(d.dialed, address)
+	if address == d.unreachable {
+		return nil, errors.New("connection refused")
+	}
+	conn, peer := net.Pipe()
+	peer.Close()
+	return conn, nil
+}
+
+type stubSecrets struct {
+	secrets map[string]*coreV1.Secret
+	err     error
+}
+
+func (s *stubSecrets) Secrets(namespace string) typedCoreV1.SecretInterface {
+	return &stubSecretInterface{namespace: namespace, stub: s}
+}
+
+type stubSecretInterface struct {
+	typedCoreV1.SecretInterface
+	namespace string
+	stub      *stubSecrets
+}
+
+func (s *stubSecretInterface) Get(name string, _ metav1.GetOptions) (*coreV1.Secret, error) {
+	if s.stub.err != nil {
+		return nil, s.stub.err
+	}
+	secret, ok := s.stub.secrets[s.namespace+"/"+name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	return secret, nil
+}
+
+func seconds(n int) *int {
+	return &n
+}
+
+func rate(f float64) *float64 {
+	return &f
+}
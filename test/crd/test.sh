@@ -41,6 +41,15 @@ function cleanup {
     if [ "$created_cluster_log_sink_crd" -eq 0 ]; then
         kubectl delete -f "$working_dir/../config/100-cluster-log-sink-crd.yaml" > /dev/null 2>&1
     fi
+    if [ "$created_metric_sink_crd" -eq 0 ]; then
+        kubectl delete -f "$working_dir/../config/100-metric-sink-crd.yaml" > /dev/null 2>&1
+    fi
+    if [ "$created_cluster_metric_sink_crd" -eq 0 ]; then
+        kubectl delete -f "$working_dir/../config/100-cluster-metric-sink-crd.yaml" > /dev/null 2>&1
+    fi
+    if [ "$created_cluster_log_parser_crd" -eq 0 ]; then
+        kubectl delete -f "$working_dir/../config/100-cluster-log-parser-crd.yaml" > /dev/null 2>&1
+    fi
 }
 trap cleanup EXIT
 
@@ -50,6 +59,12 @@ kubectl create -f "$working_dir/../config/100-log-sink-crd.yaml" > /dev/null 2>&
 created_log_sink_crd=$?
 kubectl create -f "$working_dir/../config/100-cluster-log-sink-crd.yaml" > /dev/null 2>&1
 created_cluster_log_sink_crd=$?
+kubectl create -f "$working_dir/../config/100-metric-sink-crd.yaml" > /dev/null 2>&1
+created_metric_sink_crd=$?
+kubectl create -f "$working_dir/../config/100-cluster-metric-sink-crd.yaml" > /dev/null 2>&1
+created_cluster_metric_sink_crd=$?
+kubectl create -f "$working_dir/../config/100-cluster-log-parser-crd.yaml" > /dev/null 2>&1
+created_cluster_log_parser_crd=$?
 set -e
 
 failed=false
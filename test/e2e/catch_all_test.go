@@ -0,0 +1,94 @@
+// +build e2e
+
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+func TestClusterLogSinkCatchAll(t *testing.T) {
+	prefix := "cluster-log-sink-catch-all-"
+	logger := logging.GetContextLogger("TestClusterLogSinkCatchAll")
+	clients, err := newClients()
+	assertErr(t, "Error creating newClients: %v", err)
+
+	logger.Info("Creating the ClusterLogSink selecting stderr")
+	_, err = clients.sinkClient.ClusterLogSink.Create(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: prefix + "stderr",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:   "syslog",
+			Host:   prefix + "stderr-receiver." + observabilityTestNamespace,
+			Port:   24903,
+			Stream: "stderr",
+		},
+	})
+	assertErr(t, "Error creating ClusterLogSink: %v", err)
+	// It would keep forwarding the stderr of later tests to a receiver
+	// that does not exist.
+	defer func() {
+		err := clients.sinkClient.ClusterLogSink.Delete(prefix+"stderr", &metav1.DeleteOptions{})
+		assertErr(t, "Error deleting ClusterLogSink: %v", err)
+	}()
+
+	logger.Info("Creating the catch-all ClusterLogSink")
+	_, err = clients.sinkClient.ClusterLogSink.Create(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: prefix + "test",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:     "syslog",
+			Host:     prefix + "syslog-receiver." + observabilityTestNamespace,
+			Port:     24903,
+			CatchAll: true,
+		},
+	})
+	assertErr(t, "Error creating ClusterLogSink: %v", err)
+	// Another run could not create its catch-all while this one exists.
+	defer func() {
+		err := clients.sinkClient.ClusterLogSink.Delete(prefix+"test", &metav1.DeleteOptions{})
+		assertErr(t, "Error deleting ClusterLogSink: %v", err)
+	}()
+
+	createSyslogReceiver(t, logger, prefix, corev1.ProtocolTCP, clients.kubeClient)
+	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
+	// Every line is also written to stderr, which the other sink selects.
+	// The catch-all only counts ten messages when it receives the stdout
+	// lines no sink selected and none of the stderr lines.
+	runLogEmitter(
+		t,
+		logger,
+		prefix,
+		fmt.Sprintf(
+			`for i in {1..10}; do echo %stest-log-message-$i; echo %stest-log-message-$i >&2; sleep 0.5; done`,
+			prefix,
+			prefix,
+		),
+		clients.kubeClient,
+	)
+	assertTheLogsGotThere(t, logger, prefix, clients.kubeClient)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
 	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -34,7 +35,22 @@ func TestClusterLogSink(t *testing.T) {
 	assertErr(t, "Error creating newClients: %v", err)
 
 	createClusterLogSink(t, logger, prefix, clients.sinkClient)
-	createSyslogReceiver(t, logger, prefix, clients.kubeClient)
+	createSyslogReceiver(t, logger, prefix, corev1.ProtocolTCP, clients.kubeClient)
+	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
+	emitLogs(t, logger, prefix, clients.kubeClient)
+	assertTheLogsGotThere(t, logger, prefix, clients.kubeClient)
+}
+
+func TestClusterLogSinkExcludeNamespaces(t *testing.T) {
+	const prefix = "cluster-log-sink-exclude-"
+
+	logger := logging.GetContextLogger("TestClusterLogSinkExcludeNamespaces")
+	clients, err := newClients()
+	assertErr(t, "Error creating newClients: %v", err)
+
+	// Excluding kube-system must not drop the logs of other namespaces.
+	createClusterLogSink(t, logger, prefix, clients.sinkClient, "kube-system")
+	createSyslogReceiver(t, logger, prefix, corev1.ProtocolTCP, clients.kubeClient)
 	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
 	emitLogs(t, logger, prefix, clients.kubeClient)
 	assertTheLogsGotThere(t, logger, prefix, clients.kubeClient)
@@ -45,6 +61,7 @@ func createClusterLogSink(
 	logger *logging.BaseLogger,
 	prefix string,
 	sc sinkClient,
+	excludeNamespaces ...string,
 ) {
 	logger.Info("Creating the ClusterLogSink")
 	_, err := sc.ClusterLogSink.Create(&v1alpha1.ClusterLogSink{
@@ -52,9 +69,10 @@ func createClusterLogSink(
 			Name: prefix + "test",
 		},
 		Spec: v1alpha1.SinkSpec{
-			Type: "syslog",
-			Host: prefix + "syslog-receiver." + observabilityTestNamespace,
-			Port: 24903,
+			Type:              "syslog",
+			Host:              prefix + "syslog-receiver." + observabilityTestNamespace,
+			Port:              24903,
+			ExcludeNamespaces: excludeNamespaces,
 		},
 	})
 	assertErr(t, "Error creating ClusterLogSink: %v", err)
@@ -0,0 +1,64 @@
+// +build e2e
+
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"testing"
+
+	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+func TestLogSinkExclusiveMatch(t *testing.T) {
+	prefix := "log-sink-exclusive-"
+	logger := logging.GetContextLogger("TestLogSinkExclusiveMatch")
+	clients, err := newClients()
+	assertErr(t, "Error creating newClients: %v", err)
+
+	logger.Info("Creating the exclusive log sink")
+	_, err = clients.sinkClient.LogSink.Create(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: prefix + "test",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:           "syslog",
+			Host:           prefix + "syslog-receiver." + observabilityTestNamespace,
+			Port:           24903,
+			ExclusiveMatch: true,
+		},
+	})
+	assertErr(t, "Error creating LogSink: %v", err)
+	// The claim would keep the logs of the test namespace from the
+	// ClusterLogSinks of later tests.
+	defer func() {
+		err := clients.sinkClient.LogSink.Delete(prefix+"test", &metav1.DeleteOptions{})
+		assertErr(t, "Error deleting LogSink: %v", err)
+	}()
+
+	// Both sinks match the emitter and forward to the same receiver, which
+	// only counts ten messages when the ClusterLogSink skips them.
+	createClusterLogSink(t, logger, prefix, clients.sinkClient)
+	createSyslogReceiver(t, logger, prefix, corev1.ProtocolTCP, clients.kubeClient)
+	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
+	emitLogs(t, logger, prefix, clients.kubeClient)
+	assertTheLogsGotThere(t, logger, prefix, clients.kubeClient)
+}
@@ -19,21 +19,33 @@ limitations under the License.
 package e2e
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestLogSink(t *testing.T) {
-	const prefix = "log-sink-"
-
-	logger := logging.GetContextLogger("TestLogSink")
-	clients, err := newClients()
-	assertErr(t, "Error creating newClients: %v", err)
-
-	createLogSink(t, logger, prefix, clients.sinkClient)
-	createSyslogReceiver(t, logger, prefix, clients.kubeClient)
-	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
-	emitLogs(t, logger, prefix, clients.kubeClient)
-	assertTheLogsGotThere(t, logger, prefix, clients.kubeClient)
+	var tests = []struct {
+		prefix   string
+		protocol corev1.Protocol
+	}{
+		{"log-sink-tcp-", corev1.ProtocolTCP},
+		{"log-sink-udp-", corev1.ProtocolUDP},
+	}
+	for _, test := range tests {
+		t.Run(string(test.protocol), func(t *testing.T) {
+			logger := logging.GetContextLogger("TestLogSink")
+			clients, err := newClients()
+			assertErr(t, "Error creating newClients: %v", err)
+
+			protocol := strings.ToLower(string(test.protocol))
+			createLogSink(t, logger, test.prefix, protocol, clients.sinkClient)
+			createSyslogReceiver(t, logger, test.prefix, test.protocol, clients.kubeClient)
+			waitForFluentBitToBeReady(t, logger, test.prefix, clients.kubeClient)
+			emitLogs(t, logger, test.prefix, clients.kubeClient)
+			assertTheLogsGotThere(t, logger, test.prefix, clients.kubeClient)
+		})
+	}
 }
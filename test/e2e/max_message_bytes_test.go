@@ -0,0 +1,75 @@
+// +build e2e
+
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestLogSinkMaxMessageBytes(t *testing.T) {
+	prefix := "log-sink-max-message-bytes-"
+	logger := logging.GetContextLogger("TestLogSinkMaxMessageBytes")
+	clients, err := newClients()
+	assertErr(t, "Error creating newClients: %v", err)
+
+	logger.Info("Creating the log sink truncating lines to 1KiB")
+	_, err = clients.sinkClient.LogSink.Create(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: prefix + "test",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:            "syslog",
+			Host:            prefix + "syslog-receiver." + observabilityTestNamespace,
+			Port:            24903,
+			MaxMessageBytes: 1024,
+		},
+	})
+	assertErr(t, "Error creating LogSink: %v", err)
+
+	// The emitted lines are 64KiB of x, the receiver only counts the ones
+	// ending in the marker.
+	createSyslogReceiverCounting(
+		t,
+		logger,
+		prefix,
+		"xxxxxxxx"+sink.TruncationMarker,
+		corev1.ProtocolTCP,
+		clients.kubeClient,
+	)
+	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
+	runLogEmitter(
+		t,
+		logger,
+		prefix,
+		fmt.Sprintf(
+			`for _ in {1..10}; do echo %s$(head -c 65536 /dev/zero | tr '\0' x); sleep 0.5; done`,
+			prefix,
+		),
+		clients.kubeClient,
+	)
+	assertTheLogsGotThere(t, logger, prefix, clients.kubeClient)
+}
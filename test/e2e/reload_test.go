@@ -0,0 +1,78 @@
+// +build e2e
+
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/knative/pkg/test"
+	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestConfigChangeKeepsFluentBitPods(t *testing.T) {
+	prefix := "reload-"
+	logger := logging.GetContextLogger("TestConfigChangeKeepsFluentBitPods")
+	clients, err := newClients()
+	assertErr(t, "Error creating newClients: %v", err)
+
+	logger.Info("Getting the fluent-bit pods")
+	before, err := fluentBitPods(clients.kubeClient)
+	assertErr(t, "Error getting the fluent-bit pods: %v", err)
+	if len(before) == 0 {
+		t.Fatal("Expected fluent-bit pods to be running")
+	}
+
+	createLogSink(t, logger, prefix, "tcp", clients.sinkClient)
+
+	// The kubelet only updates mounted ConfigMaps on its sync period.
+	logger.Info("Giving fluent-bit time to reload its config")
+	time.Sleep(90 * time.Second)
+
+	logger.Info("Checking no fluent-bit pod was replaced")
+	after, err := fluentBitPods(clients.kubeClient)
+	assertErr(t, "Error getting the fluent-bit pods: %v", err)
+	for uid, name := range before {
+		if _, ok := after[uid]; !ok {
+			t.Errorf("Expected fluent-bit pod %s to keep running", name)
+		}
+	}
+}
+
+// fluentBitPods returns the names of the fluent-bit pods that are not being
+// deleted keyed by their UID.
+func fluentBitPods(kc *test.KubeClient) (map[types.UID]string, error) {
+	pods, err := kc.Kube.CoreV1().Pods("knative-observability").List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit-ds",
+	})
+	if err != nil {
+		return nil, err
+	}
+	uids := make(map[types.UID]string)
+	for _, p := range pods.Items {
+		if p.DeletionTimestamp != nil || p.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		uids[p.UID] = p.Name
+	}
+	return uids, nil
+}
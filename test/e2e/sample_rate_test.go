@@ -0,0 +1,79 @@
+// +build e2e
+
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/knative/pkg/test/logging"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+var logsReceived = regexp.MustCompile(`Logs Received: (\d+)`)
+
+func TestLogSinkSampleRate(t *testing.T) {
+	prefix := "log-sink-sample-rate-"
+	logger := logging.GetContextLogger("TestLogSinkSampleRate")
+	clients, err := newClients()
+	assertErr(t, "Error creating newClients: %v", err)
+
+	logger.Info("Creating the log sink forwarding half of the logs")
+	rate := 0.5
+	_, err = clients.sinkClient.LogSink.Create(&v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: prefix + "test",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type:       "syslog",
+			Host:       prefix + "syslog-receiver." + observabilityTestNamespace,
+			Port:       24903,
+			SampleRate: &rate,
+		},
+	})
+	assertErr(t, "Error creating LogSink: %v", err)
+
+	createSyslogReceiver(t, logger, prefix, corev1.ProtocolTCP, clients.kubeClient)
+	waitForFluentBitToBeReady(t, logger, prefix, clients.kubeClient)
+	runLogEmitter(
+		t,
+		logger,
+		prefix,
+		fmt.Sprintf("for _ in {1..1000}; do echo %stest-log-message; done", prefix),
+		clients.kubeClient,
+	)
+
+	// The count of a fair sample of 1000 logs is 500 give or take 16, the
+	// tolerance keeps the test from failing on unlucky runs.
+	b := observeLogs(t, logger, prefix, clients.kubeClient)
+	counts := logsReceived.FindAllStringSubmatch(b, -1)
+	if len(counts) == 0 {
+		t.Fatalf("No log count received: \n%s\n", b)
+	}
+	n, err := strconv.Atoi(counts[len(counts)-1][1])
+	assertErr(t, "Error parsing the log count: %v", err)
+	if n < 400 || n > 600 {
+		t.Fatalf("Received log count %d is not within 400 and 600: \n%s\n", n, b)
+	}
+}
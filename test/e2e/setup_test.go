@@ -172,17 +172,19 @@ func createLogSink(
 	t *testing.T,
 	logger *logging.BaseLogger,
 	prefix string,
+	protocol string,
 	sc sinkClient,
 ) {
-	logger.Info("Creating the log sink")
+	logger.Infof("Creating the %s log sink", protocol)
 	_, err := sc.LogSink.Create(&v1alpha1.LogSink{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: prefix + "test",
 		},
 		Spec: v1alpha1.SinkSpec{
-			Type: "syslog",
-			Host: prefix + "syslog-receiver." + observabilityTestNamespace,
-			Port: 24903,
+			Type:     "syslog",
+			Host:     prefix + "syslog-receiver." + observabilityTestNamespace,
+			Port:     24903,
+			Protocol: protocol,
 		},
 	})
 	assertErr(t, "Error creating LogSink: %v", err)
@@ -192,6 +194,20 @@ func createSyslogReceiver(
 	t *testing.T,
 	logger *logging.BaseLogger,
 	prefix string,
+	protocol corev1.Protocol,
+	kc *test.KubeClient,
+) {
+	createSyslogReceiverCounting(t, logger, prefix, prefix+"test-log-message", protocol, kc)
+}
+
+// createSyslogReceiverCounting creates a syslog receiver counting the
+// messages that contain message.
+func createSyslogReceiverCounting(
+	t *testing.T,
+	logger *logging.BaseLogger,
+	prefix string,
+	message string,
+	protocol corev1.Protocol,
 	kc *test.KubeClient,
 ) {
 	logger.Info("Creating the service for the syslog receiver")
@@ -201,8 +217,9 @@ func createSyslogReceiver(
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{{
-				Name: "syslog",
-				Port: 24903,
+				Name:     "syslog",
+				Port:     24903,
+				Protocol: protocol,
 			}, {
 				Name: "metrics",
 				Port: 6060,
@@ -229,6 +246,7 @@ func createSyslogReceiver(
 				Ports: []corev1.ContainerPort{{
 					Name:          "syslog-port",
 					ContainerPort: 24903,
+					Protocol:      protocol,
 				}, {
 					Name:          "metrics-port",
 					ContainerPort: 6060,
@@ -241,7 +259,7 @@ func createSyslogReceiver(
 					Value: "6060",
 				}, {
 					Name:  "MESSAGE",
-					Value: prefix + "test-log-message",
+					Value: message,
 				}},
 			}},
 		},
@@ -272,7 +290,7 @@ func waitForFluentBitToBeReady(
 	prefix string,
 	kc *test.KubeClient,
 ) {
-	logger.Info("Giving sink-controller time to delete fluentbit pods")
+	logger.Info("Giving fluentbit time to pick up the config")
 	time.Sleep(5 * time.Second)
 
 	logger.Info("Getting cluster nodes")
@@ -316,6 +334,20 @@ func assertTheLogsGotThere(
 	prefix string,
 	kc *test.KubeClient,
 ) {
+	b := observeLogs(t, logger, prefix, kc)
+	if !strings.Contains(b, "Logs Received: 10") {
+		t.Fatalf("Received log count is not 10: \n%s\n", b)
+	}
+}
+
+// observeLogs returns the output of a job printing the number of logs the
+// syslog receiver counted every second for ten seconds.
+func observeLogs(
+	t *testing.T,
+	logger *logging.BaseLogger,
+	prefix string,
+	kc *test.KubeClient,
+) string {
 	logger.Info("Get the count for number of logs received")
 	_, err := kc.Kube.Batch().Jobs(observabilityTestNamespace).Create(&batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -380,10 +412,7 @@ func assertTheLogsGotThere(
 
 	b, err := req.Do().Raw()
 	assertErr(t, "Error reading logs from the log-observer: %v", err)
-
-	if !strings.Contains(string(b), "Logs Received: 10") {
-		t.Fatalf("Received log count is not 10: \n%s\n", string(b))
-	}
+	return string(b)
 }
 
 func emitLogs(
@@ -391,6 +420,42 @@ func emitLogs(
 	logger *logging.BaseLogger,
 	prefix string,
 	kc *test.KubeClient,
+) {
+	runLogEmitter(
+		t,
+		logger,
+		prefix,
+		fmt.Sprintf("for _ in {1..10}; do echo %stest-log-message; sleep 0.5; done", prefix),
+		kc,
+	)
+}
+
+// emitMixedLogs emits ten lines alternating between JSON and plain text.
+func emitMixedLogs(
+	t *testing.T,
+	logger *logging.BaseLogger,
+	prefix string,
+	kc *test.KubeClient,
+) {
+	runLogEmitter(
+		t,
+		logger,
+		prefix,
+		fmt.Sprintf(
+			`for i in {1..5}; do echo "{\"msg\":\"%stest-log-message\",\"i\":$i}"; echo %stest-log-message; sleep 0.5; done`,
+			prefix,
+			prefix,
+		),
+		kc,
+	)
+}
+
+func runLogEmitter(
+	t *testing.T,
+	logger *logging.BaseLogger,
+	prefix string,
+	script string,
+	kc *test.KubeClient,
 ) {
 	logger.Info("Emitting logs")
 	_, err := kc.Kube.Batch().Jobs(observabilityTestNamespace).Create(&batchv1.Job{
@@ -415,7 +480,7 @@ func emitLogs(
 						Command: []string{
 							"bash",
 							"-c",
-							fmt.Sprintf("for _ in {1..10}; do echo %stest-log-message; sleep 0.5; done", prefix),
+							script,
 						},
 					}},
 				},